@@ -3,19 +3,23 @@ package subcmd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 	"unicode"
 
 	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/apply/rebalancers"
 	"github.com/segmentio/topicctl/pkg/cli"
 	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/notify"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -23,7 +27,7 @@ import (
 var applyCmd = &cobra.Command{
 	Use:     "apply [topic configs]",
 	Short:   "apply one or more topic configs",
-	Args:    cobra.MinimumNArgs(1),
+	Args:    applyArgs,
 	PreRunE: applyPreRun,
 	RunE:    applyRun,
 }
@@ -32,10 +36,14 @@ type applyCmdConfig struct {
 	brokersToRemove              []int
 	brokerThrottleMBsOverride    int
 	dryRun                       bool
+	events                       string
 	jsonOutput                   bool
 	partitionBatchSizeOverride   int
 	pathPrefix                   string
+	plan                         string
 	rebalance                    bool
+	rebalanceBy                  string
+	diskTolerance                float64
 	autoContinueRebalance        bool
 	retentionDropStepDurationStr string
 	skipConfirm                  bool
@@ -43,10 +51,16 @@ type applyCmdConfig struct {
 	destructive                  bool
 	sleepLoopDuration            time.Duration
 	failFast                     bool
+	migrationStateDir            string
+	historyDir                   string
+	resume                       bool
+	parallelism                  int
+	maxMovesPerBroker            int
 
 	shared sharedOptions
 
 	retentionDropStepDuration time.Duration
+	eventWriter               *apply.EventWriter
 }
 
 var applyConfig applyCmdConfig
@@ -82,12 +96,33 @@ func init() {
 		os.Getenv("TOPICCTL_APPLY_PATH_PREFIX"),
 		"Prefix for topic config paths",
 	)
+	applyCmd.Flags().StringVar(
+		&applyConfig.plan,
+		"plan",
+		"",
+		"Path to a plan file created by the plan subcommand; if set, exactly this plan is applied",
+	)
 	applyCmd.Flags().BoolVar(
 		&applyConfig.rebalance,
 		"rebalance",
 		false,
 		"Explicitly rebalance broker partition assignments",
 	)
+	applyCmd.Flags().StringVar(
+		&applyConfig.rebalanceBy,
+		"rebalance-by",
+		string(apply.RebalanceStrategyCount),
+		fmt.Sprintf(
+			"What to balance across brokers when rebalancing; one of %+v",
+			apply.AllRebalanceStrategies,
+		),
+	)
+	applyCmd.Flags().Float64Var(
+		&applyConfig.diskTolerance,
+		"disk-tolerance",
+		rebalancers.DefaultDiskTolerance,
+		"Fraction that the bytes on a broker or log dir can be above the mean when rebalancing by disk",
+	)
 	applyCmd.Flags().BoolVar(
 		&applyConfig.autoContinueRebalance,
 		"auto-continue-rebalance",
@@ -130,6 +165,12 @@ func init() {
 		true,
 		"Fail upon the first error encountered during apply process",
 	)
+	applyCmd.Flags().StringVar(
+		&applyConfig.events,
+		"events",
+		"",
+		"Path to write structured progress events to as newline-delimited JSON; use - for stdout",
+	)
 	applyCmd.Flags().BoolVar(
 		&applyConfig.jsonOutput,
 		"json-output",
@@ -137,11 +178,68 @@ func init() {
 		"Only logs changes as json objects to stdout",
 	)
 
+	applyCmd.Flags().IntVar(
+		&applyConfig.parallelism,
+		"parallelism",
+		1,
+		"Number of topics to apply at once; requires --skip-confirm if > 1",
+	)
+	applyCmd.Flags().IntVar(
+		&applyConfig.maxMovesPerBroker,
+		"max-moves-per-broker",
+		0,
+		"Max number of partitions moving to or from each broker at once when parallelism > 1; 0 for no limit",
+	)
+	applyCmd.Flags().StringVar(
+		&applyConfig.migrationStateDir,
+		"migration-state-dir",
+		defaultMigrationStateDir(),
+		"Directory for recording the progress of partition migrations; set to empty to disable",
+	)
+	applyCmd.Flags().BoolVar(
+		&applyConfig.resume,
+		"resume",
+		false,
+		"Resume partition migrations that were interrupted in a previous run",
+	)
+	applyCmd.Flags().StringVar(
+		&applyConfig.historyDir,
+		"history-dir",
+		defaultHistoryDir(),
+		"Directory for recording the state of topics before they're changed, for rollbacks; set to empty to disable",
+	)
+
 	addSharedConfigOnlyFlags(applyCmd, &applyConfig.shared)
+	addSnapshotFlag(applyCmd, &applyConfig.shared)
 	RootCmd.AddCommand(applyCmd)
 }
 
+func applyArgs(cmd *cobra.Command, args []string) error {
+	if applyConfig.plan != "" {
+		return cobra.NoArgs(cmd, args)
+	}
+	return cobra.MinimumNArgs(1)(cmd, args)
+}
+
 func applyPreRun(cmd *cobra.Command, args []string) error {
+	if applyConfig.parallelism > 1 && !applyConfig.skipConfirm && !applyConfig.dryRun {
+		return errors.New("--skip-confirm must be set when --parallelism is greater than 1")
+	}
+	if applyConfig.shared.snapshot != "" {
+		if !applyConfig.dryRun {
+			return errors.New("--dry-run must be set when using --snapshot")
+		}
+		if applyConfig.plan != "" {
+			return errors.New("--snapshot can't be used with --plan")
+		}
+	}
+	if err := validateRebalanceStrategy(
+		applyConfig.rebalanceBy,
+		applyConfig.diskTolerance,
+	); err != nil {
+		return err
+	}
+
 	if applyConfig.retentionDropStepDurationStr != "" {
 		var err error
 		applyConfig.retentionDropStepDuration, err = time.ParseDuration(
@@ -175,6 +273,17 @@ func applyRun(cmd *cobra.Command, args []string) error {
 		cancel()
 	}()
 
+	if applyConfig.plan != "" {
+		return applyPlanRun(ctx)
+	}
+
+	eventWriter, closeEvents, err := openEventWriter(applyConfig.events)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+	applyConfig.eventWriter = eventWriter
+
 	// Keep a cache of the admin clients with the cluster config path as the key
 	adminClients := map[string]admin.Client{}
 	// Keep track of any errors that occur during the apply process
@@ -186,7 +295,7 @@ func applyRun(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
-	matchCount := 0
+	topicConfigPaths := []string{}
 
 	for _, arg := range args {
 		if applyConfig.pathPrefix != "" && !filepath.IsAbs(arg) {
@@ -197,22 +306,106 @@ func applyRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		topicConfigPaths = append(topicConfigPaths, matches...)
+	}
 
-		for _, match := range matches {
-			matchCount++
-			if err := applyTopic(ctx, match, adminClients); err != nil {
-				if applyConfig.failFast {
-					return err
-				}
-				errs = appendError(errs, err)
+	if len(topicConfigPaths) == 0 {
+		return fmt.Errorf("No topic configs match the provided args (%+v)", args)
+	}
+
+	if applyConfig.parallelism > 1 {
+		return applyParallel(ctx, topicConfigPaths, adminClients)
+	}
+
+	for _, topicConfigPath := range topicConfigPaths {
+		if err := applyTopic(ctx, topicConfigPath, adminClients, nil); err != nil {
+			if applyConfig.failFast {
+				return err
 			}
+			errs = appendError(errs, err)
 		}
 	}
 
-	if matchCount == 0 {
-		return fmt.Errorf("No topic configs match the provided args (%+v)", args)
+	return errs
+}
+
+// applyParallel applies the argument topic configs concurrently, using a scheduler to share
+// the broker throttles and limit the partition moves on each broker. All of the configs must
+// be for the same cluster.
+func applyParallel(
+	ctx context.Context,
+	topicConfigPaths []string,
+	adminClients map[string]admin.Client,
+) error {
+	var clusterConfigPath string
+
+	for _, topicConfigPath := range topicConfigPaths {
+		topicClusterConfigPath, err := clusterConfigForTopicApply(topicConfigPath)
+		if err != nil {
+			return err
+		}
+		if clusterConfigPath == "" {
+			clusterConfigPath = topicClusterConfigPath
+		} else if clusterConfigPath != topicClusterConfigPath {
+			return fmt.Errorf(
+				"All topic configs must use the same cluster config when parallelism > 1 (found %s and %s)",
+				clusterConfigPath,
+				topicClusterConfigPath,
+			)
+		}
+	}
+
+	clusterConfig, adminClient, err := applyAdminClient(ctx, clusterConfigPath, adminClients)
+	if err != nil {
+		return err
+	}
+
+	scheduler := apply.NewMigrationScheduler(
+		adminClient,
+		apply.MigrationSchedulerConfig{
+			ClusterConfig:     clusterConfig,
+			DryRun:            applyConfig.dryRun,
+			Parallelism:       applyConfig.parallelism,
+			MaxMovesPerBroker: applyConfig.maxMovesPerBroker,
+			ThrottleBytes: sharedThrottleBytes(
+				applyConfig.brokerThrottleMBsOverride,
+				clusterConfig,
+			),
+			Events: applyConfig.eventWriter,
+		},
+	)
+
+	// With fail-fast, migrations that haven't started yet are skipped after an error, but
+	// the ones that are already running are allowed to finish
+	var failed int32
+	migrations := []func(ctx context.Context) error{}
+
+	for _, topicConfigPath := range topicConfigPaths {
+		topicConfigPath := topicConfigPath
+		migrations = append(migrations, func(ctx context.Context) error {
+			if applyConfig.failFast && atomic.LoadInt32(&failed) > 0 {
+				return fmt.Errorf("Skipped %s because of an earlier error", topicConfigPath)
+			}
+
+			err := applyTopic(ctx, topicConfigPath, adminClients, scheduler)
+			if err != nil {
+				atomic.StoreInt32(&failed, 1)
+			}
+			return err
+		})
+	}
+
+	migrationErrs, err := scheduler.Run(ctx, migrations)
+	if err != nil {
+		return err
 	}
 
+	var errs error
+	for _, migrationErr := range migrationErrs {
+		if migrationErr != nil {
+			errs = appendError(errs, migrationErr)
+		}
+	}
 	return errs
 }
 
@@ -264,6 +457,7 @@ func applyTopic(
 	ctx context.Context,
 	topicConfigPath string,
 	adminClients map[string]admin.Client,
+	scheduler *apply.MigrationScheduler,
 ) error {
 	clusterConfigPath, err := clusterConfigForTopicApply(topicConfigPath)
 	if err != nil {
@@ -275,29 +469,11 @@ func applyTopic(
 		return err
 	}
 
-	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, applyConfig.shared.expandEnv)
+	clusterConfig, adminClient, err := applyAdminClient(ctx, clusterConfigPath, adminClients)
 	if err != nil {
 		return err
 	}
 
-	adminClient, ok := adminClients[clusterConfigPath]
-	if !ok {
-		adminClient, err = clusterConfig.NewAdminClient(
-			ctx,
-			nil,
-			config.AdminClientOpts{
-				ReadOnly:                  applyConfig.dryRun,
-				UsernameOverride:          applyConfig.shared.saslUsername,
-				PasswordOverride:          applyConfig.shared.saslPassword,
-				SecretsManagerArnOverride: applyConfig.shared.saslSecretsManagerArn,
-			},
-		)
-		if err != nil {
-			return err
-		}
-		adminClients[clusterConfigPath] = adminClient
-	}
-
 	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
 
 	for _, topicConfig := range topicConfigs {
@@ -317,6 +493,8 @@ func applyTopic(
 			JsonOutput:                 applyConfig.jsonOutput,
 			PartitionBatchSizeOverride: applyConfig.partitionBatchSizeOverride,
 			Rebalance:                  applyConfig.rebalance,
+			RebalanceBy:                apply.RebalanceStrategy(applyConfig.rebalanceBy),
+			DiskTolerance:              applyConfig.diskTolerance,
 			AutoContinueRebalance:      applyConfig.autoContinueRebalance,
 			RetentionDropStepDuration:  applyConfig.retentionDropStepDuration,
 			SkipConfirm:                applyConfig.skipConfirm,
@@ -324,8 +502,24 @@ func applyTopic(
 			Destructive:                applyConfig.destructive,
 			SleepLoopDuration:          applyConfig.sleepLoopDuration,
 			TopicConfig:                topicConfig,
+			MigrationStateDir:          applyConfig.migrationStateDir,
+			HistoryDir:                 applyConfig.historyDir,
+			Resume:                     applyConfig.resume,
+			Events:                     applyConfig.eventWriter,
+			Scheduler:                  scheduler,
 		}
 		topicChanges, err := cliRunner.ApplyTopic(ctx, applierConfig)
+		notify.Send(
+			ctx,
+			clusterConfig,
+			notify.TopicApplyNotification(
+				clusterConfig,
+				topicConfig.Meta.Name,
+				applyConfig.dryRun,
+				topicChanges,
+				err,
+			),
+		)
 		if err != nil {
 			// If one of the steps after updateSettings errors when updating a topic,
 			// we can be in a state where some (but not all) changes were applied.
@@ -356,6 +550,34 @@ func applyTopic(
 	return nil
 }
 
+// applyAdminClient loads the argument cluster config and returns an admin client for it,
+// using the cached client if there is one.
+func applyAdminClient(
+	ctx context.Context,
+	clusterConfigPath string,
+	adminClients map[string]admin.Client,
+) (config.ClusterConfig, admin.Client, error) {
+	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, applyConfig.shared.expandEnv)
+	if err != nil {
+		return clusterConfig, nil, err
+	}
+
+	adminClient, ok := adminClients[clusterConfigPath]
+	if !ok {
+		adminClient, err = applyConfig.shared.getClusterAdminClient(
+			ctx,
+			clusterConfig,
+			applyConfig.dryRun,
+		)
+		if err != nil {
+			return clusterConfig, nil, err
+		}
+		adminClients[clusterConfigPath] = adminClient
+	}
+
+	return clusterConfig, adminClient, nil
+}
+
 func clusterConfigForTopicApply(topicConfigPath string) (string, error) {
 	if applyConfig.shared.clusterConfig != "" {
 		return applyConfig.shared.clusterConfig, nil
@@ -369,3 +591,68 @@ func clusterConfigForTopicApply(topicConfigPath string) (string, error) {
 		),
 	)
 }
+
+func applyPlanRun(ctx context.Context) error {
+	if applyConfig.rebalance ||
+		applyConfig.rebalanceBy != string(apply.RebalanceStrategyCount) ||
+		len(applyConfig.brokersToRemove) > 0 ||
+		applyConfig.brokerThrottleMBsOverride > 0 ||
+		applyConfig.partitionBatchSizeOverride > 0 ||
+		applyConfig.retentionDropStepDurationStr != "" ||
+		applyConfig.destructive ||
+		applyConfig.resume ||
+		applyConfig.parallelism > 1 {
+		return errors.New(
+			"Migration and rebalance flags cannot be set with --plan; pass them to plan instead",
+		)
+	}
+	if applyConfig.events != "" {
+		return errors.New("--events cannot be set with --plan")
+	}
+
+	plan, err := apply.LoadPlanFile(applyConfig.plan)
+	if err != nil {
+		return err
+	}
+
+	clusterConfigPath := applyConfig.shared.clusterConfig
+	if clusterConfigPath == "" {
+		clusterConfigPath = plan.ClusterConfigPath
+	}
+	if clusterConfigPath == "" {
+		return errors.New("Must set --cluster-config if plan does not include a cluster config path")
+	}
+
+	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, applyConfig.shared.expandEnv)
+	if err != nil {
+		return err
+	}
+
+	adminClient, err := clusterConfig.NewAdminClient(
+		ctx,
+		nil,
+		config.AdminClientOpts{
+			ReadOnly:                  applyConfig.dryRun,
+			UsernameOverride:          applyConfig.shared.saslUsername,
+			PasswordOverride:          applyConfig.shared.saslPassword,
+			SecretsManagerArnOverride: applyConfig.shared.saslSecretsManagerArn,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+	return cliRunner.ApplyPlan(
+		ctx,
+		plan,
+		apply.PlanExecutorConfig{
+			ClusterConfig:     clusterConfig,
+			DryRun:            applyConfig.dryRun,
+			SkipConfirm:       applyConfig.skipConfirm,
+			SleepLoopDuration: applyConfig.sleepLoopDuration,
+			HistoryDir:        applyConfig.historyDir,
+		},
+	)
+}
@@ -0,0 +1,228 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"syscall"
+
+	"github.com/segmentio/topicctl/pkg/acl"
+	"github.com/segmentio/topicctl/pkg/cli"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+type applyACLsCmdConfig struct {
+	destructive bool
+	dryRun      bool
+	pathPrefix  string
+	scope       string
+	skipConfirm bool
+
+	shared sharedOptions
+}
+
+var applyACLsConfig applyACLsCmdConfig
+
+func init() {
+	applyCmd.AddCommand(applyACLsCmd())
+}
+
+func applyACLsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "acls [acl configs]",
+		Short: "apply one or more ACL configs",
+		Long: "Reconciles the ACLs for the principals (or resources) in the configs with the " +
+			"cluster. ACLs that are declared but missing are created; ACLs for these principals " +
+			"(or resources) that aren't declared in any of the configs are removed if " +
+			"--destructive is set.",
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: applyACLsPreRun,
+		RunE:    applyACLsRun,
+	}
+
+	cmd.Flags().BoolVar(
+		&applyACLsConfig.destructive,
+		"destructive",
+		false,
+		"Deletes ACLs from the cluster if they aren't in the configs for their principal or resource",
+	)
+	cmd.Flags().BoolVar(
+		&applyACLsConfig.dryRun,
+		"dry-run",
+		false,
+		"Do a dry-run",
+	)
+	cmd.Flags().StringVar(
+		&applyACLsConfig.pathPrefix,
+		"path-prefix",
+		os.Getenv("TOPICCTL_ACL_PATH_PREFIX"),
+		"Prefix for ACL config paths",
+	)
+	cmd.Flags().StringVar(
+		&applyACLsConfig.scope,
+		"scope",
+		string(acl.ApplyScopePrincipal),
+		fmt.Sprintf(
+			"Which ACLs in the cluster are reconciled with the configs; one of %+v",
+			acl.AllApplyScopes,
+		),
+	)
+	cmd.Flags().BoolVar(
+		&applyACLsConfig.skipConfirm,
+		"skip-confirm",
+		false,
+		"Skip confirmation prompts during apply process",
+	)
+
+	addSharedConfigOnlyFlags(cmd, &applyACLsConfig.shared)
+	return cmd
+}
+
+func applyACLsPreRun(cmd *cobra.Command, args []string) error {
+	if !slices.Contains(acl.AllApplyScopes, acl.ApplyScope(applyACLsConfig.scope)) {
+		return fmt.Errorf(
+			"Unrecognized scope %s; must be one of %+v",
+			applyACLsConfig.scope,
+			acl.AllApplyScopes,
+		)
+	}
+	return nil
+}
+
+func applyACLsRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	// Group the ACL configs by their cluster config paths so that all of the configs for a
+	// cluster are reconciled together; otherwise, a destructive apply of one config would
+	// remove the ACLs declared in another one for the same principal.
+	clusterConfigPaths := []string{}
+	aclConfigsByCluster := map[string][]config.ACLConfig{}
+
+	for _, arg := range args {
+		if applyACLsConfig.pathPrefix != "" && !filepath.IsAbs(arg) {
+			arg = filepath.Join(applyACLsConfig.pathPrefix, arg)
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return err
+		}
+
+		for _, match := range matches {
+			clusterConfigPath, err := clusterConfigForACLApply(match)
+			if err != nil {
+				return err
+			}
+
+			aclConfigs, err := config.LoadACLsFile(match)
+			if err != nil {
+				return err
+			}
+
+			if _, ok := aclConfigsByCluster[clusterConfigPath]; !ok {
+				clusterConfigPaths = append(clusterConfigPaths, clusterConfigPath)
+			}
+
+			for _, aclConfig := range aclConfigs {
+				aclConfig.SetDefaults()
+				log.Infof(
+					"Processing ACL %s in config %s with cluster config %s",
+					aclConfig.Meta.Name,
+					match,
+					clusterConfigPath,
+				)
+				aclConfigsByCluster[clusterConfigPath] = append(
+					aclConfigsByCluster[clusterConfigPath],
+					aclConfig,
+				)
+			}
+		}
+	}
+
+	if len(clusterConfigPaths) == 0 {
+		return fmt.Errorf("No ACL configs match the provided args (%+v)", args)
+	}
+
+	for _, clusterConfigPath := range clusterConfigPaths {
+		if err := applyACLs(
+			ctx,
+			clusterConfigPath,
+			aclConfigsByCluster[clusterConfigPath],
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyACLs(
+	ctx context.Context,
+	clusterConfigPath string,
+	aclConfigs []config.ACLConfig,
+) error {
+	if len(aclConfigs) == 0 {
+		return nil
+	}
+
+	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, applyACLsConfig.shared.expandEnv)
+	if err != nil {
+		return err
+	}
+
+	adminClient, err := clusterConfig.NewAdminClient(
+		ctx,
+		nil,
+		config.AdminClientOpts{
+			ReadOnly:                  applyACLsConfig.dryRun,
+			UsernameOverride:          applyACLsConfig.shared.saslUsername,
+			PasswordOverride:          applyACLsConfig.shared.saslPassword,
+			SecretsManagerArnOverride: applyACLsConfig.shared.saslSecretsManagerArn,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+
+	aclAdminConfig := acl.ACLAdminConfig{
+		DryRun:        applyACLsConfig.dryRun,
+		SkipConfirm:   applyACLsConfig.skipConfirm,
+		Destructive:   applyACLsConfig.destructive,
+		ACLConfig:     aclConfigs[0],
+		ApplyConfigs:  aclConfigs,
+		ApplyScope:    acl.ApplyScope(applyACLsConfig.scope),
+		ClusterConfig: clusterConfig,
+	}
+
+	return cliRunner.ApplyACL(ctx, aclAdminConfig)
+}
+
+func clusterConfigForACLApply(aclConfigPath string) (string, error) {
+	if applyACLsConfig.shared.clusterConfig != "" {
+		return applyACLsConfig.shared.clusterConfig, nil
+	}
+
+	return filepath.Abs(
+		filepath.Join(
+			filepath.Dir(aclConfigPath),
+			"..",
+			"cluster.yaml",
+		),
+	)
+}
@@ -2,6 +2,8 @@ package subcmd
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/segmentio/topicctl/pkg/cli"
 	"github.com/segmentio/topicctl/pkg/config"
@@ -22,33 +24,47 @@ type bootstrapCmdConfig struct {
 	overwrite     bool
 
 	allowInternalTopics bool
+	aclGroupBy          string
 
 	shared sharedOptions
 }
 
 var bootstrapConfig bootstrapCmdConfig
 
+type bootstrapClusterCmdConfig struct {
+	name        string
+	environment string
+	region      string
+	shard       int
+	description string
+	zkLockPath  string
+
+	shared sharedOptions
+}
+
+var bootstrapClusterConfig bootstrapClusterCmdConfig
+
 func init() {
-	bootstrapCmd.Flags().StringVar(
+	bootstrapCmd.PersistentFlags().StringVar(
 		&bootstrapConfig.matchRegexp,
 		"match",
 		".*",
 		"Match regexp",
 	)
-	bootstrapCmd.Flags().StringVar(
+	bootstrapCmd.PersistentFlags().StringVar(
 		&bootstrapConfig.excludeRegexp,
 		"exclude",
 		".^",
 		"Exclude regexp",
 	)
-	bootstrapCmd.Flags().StringVarP(
+	bootstrapCmd.PersistentFlags().StringVarP(
 		&bootstrapConfig.outputDir,
 		"output",
 		"o",
 		"",
 		"Output directory",
 	)
-	bootstrapCmd.Flags().BoolVar(
+	bootstrapCmd.PersistentFlags().BoolVar(
 		&bootstrapConfig.overwrite,
 		"overwrite",
 		false,
@@ -62,19 +78,254 @@ func init() {
 
 	addSharedConfigOnlyFlags(bootstrapCmd, &bootstrapConfig.shared)
 	bootstrapCmd.MarkFlagRequired("cluster-config")
+	bootstrapCmd.AddCommand(
+		bootstrapACLsCmd(),
+		bootstrapClusterCmd(),
+		bootstrapUsersCmd(),
+	)
 	RootCmd.AddCommand(bootstrapCmd)
 }
 
+func bootstrapACLsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "acls",
+		Short: "bootstrap ACL configs from the existing ACLs in a cluster",
+		Args:  cobra.NoArgs,
+		RunE:  bootstrapACLsRun,
+	}
+
+	cmd.Flags().StringVar(
+		&bootstrapConfig.aclGroupBy,
+		"group-by",
+		cli.ACLGroupByPrincipal,
+		fmt.Sprintf(
+			"How to group ACLs into configs (choices: %s, %s); match and exclude regexps are applied to the group key",
+			cli.ACLGroupByPrincipal,
+			cli.ACLGroupByResource,
+		),
+	)
+
+	addSharedConfigOnlyFlags(cmd, &bootstrapConfig.shared)
+	cmd.MarkFlagRequired("cluster-config")
+	return cmd
+}
+
+func bootstrapClusterCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cluster",
+		Short:   "bootstrap a cluster config and config directory from a live cluster",
+		Args:    cobra.NoArgs,
+		PreRunE: bootstrapClusterPreRun,
+		RunE:    bootstrapClusterRun,
+	}
+
+	cmd.Flags().StringVar(
+		&bootstrapClusterConfig.name,
+		"name",
+		"",
+		"Name of the cluster",
+	)
+	cmd.Flags().StringVar(
+		&bootstrapClusterConfig.environment,
+		"environment",
+		"",
+		"Environment of the cluster",
+	)
+	cmd.Flags().StringVar(
+		&bootstrapClusterConfig.region,
+		"region",
+		"",
+		"Region of the cluster",
+	)
+	cmd.Flags().IntVar(
+		&bootstrapClusterConfig.shard,
+		"shard",
+		0,
+		"Shard index of the cluster (optional)",
+	)
+	cmd.Flags().StringVar(
+		&bootstrapClusterConfig.description,
+		"description",
+		"",
+		"Description of the cluster (optional)",
+	)
+	cmd.Flags().StringVar(
+		&bootstrapClusterConfig.zkLockPath,
+		"zk-lock-path",
+		"",
+		"Path used for apply locks; only applies if zk is reachable (defaults to /topicctl/locks)",
+	)
+
+	addSharedFlags(cmd, &bootstrapClusterConfig.shared)
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("environment")
+	cmd.MarkFlagRequired("region")
+	cmd.MarkPersistentFlagRequired("broker-addr")
+	return cmd
+}
+
+func bootstrapClusterPreRun(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("cluster-config") {
+		return errors.New("Cannot use cluster-config when bootstrapping a cluster")
+	}
+	// Ignore any value set via the environment
+	bootstrapClusterConfig.shared.clusterConfig = ""
+
+	// The cluster is always accessed via the broker address here; the zk address is only
+	// checked for reachability, so it's fine to set both.
+	shared := bootstrapClusterConfig.shared
+	shared.zkAddr = ""
+	return shared.validate()
+}
+
+func bootstrapUsersCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users [users]",
+		Short: "bootstrap ACL configs for the existing users in a cluster",
+		RunE:  bootstrapUsersRun,
+	}
+
+	addSharedConfigOnlyFlags(cmd, &bootstrapConfig.shared)
+	cmd.MarkFlagRequired("cluster-config")
+	return cmd
+}
+
 func bootstrapRun(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	cliRunner, clusterConfig, err := bootstrapCLIRunner(ctx)
+	if err != nil {
+		return err
+	}
+	return cliRunner.BootstrapTopics(
+		ctx,
+		args,
+		clusterConfig,
+		bootstrapConfig.matchRegexp,
+		bootstrapConfig.excludeRegexp,
+		bootstrapConfig.outputDir,
+		bootstrapConfig.overwrite,
+		bootstrapConfig.allowInternalTopics,
+	)
+}
+
+func bootstrapACLsRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cliRunner, clusterConfig, err := bootstrapCLIRunner(ctx)
+	if err != nil {
+		return err
+	}
+	return cliRunner.BootstrapACLs(
+		ctx,
+		clusterConfig,
+		bootstrapConfig.aclGroupBy,
+		bootstrapConfig.matchRegexp,
+		bootstrapConfig.excludeRegexp,
+		bootstrapConfig.outputDir,
+		bootstrapConfig.overwrite,
+	)
+}
+
+func bootstrapClusterRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Only the broker address is used for the admin client; the zk address (if any)
+	// is checked separately.
+	shared := bootstrapClusterConfig.shared
+	shared.zkAddr = ""
+
+	adminClient, err := shared.getAdminClient(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	tlsEnabled := (shared.tlsEnabled ||
+		shared.tlsCACert != "" ||
+		shared.tlsCert != "" ||
+		shared.tlsKey != "")
+	saslEnabled := (shared.saslMechanism != "" ||
+		shared.saslPassword != "" ||
+		shared.saslUsername != "" ||
+		shared.saslSecretsManagerArn != "")
+
+	clusterConfig := config.ClusterConfig{
+		Meta: config.ClusterMeta{
+			Name:        bootstrapClusterConfig.name,
+			Environment: bootstrapClusterConfig.environment,
+			Region:      bootstrapClusterConfig.region,
+			Shard:       bootstrapClusterConfig.shard,
+			Description: bootstrapClusterConfig.description,
+		},
+		Spec: config.ClusterSpec{
+			BootstrapAddrs: []string{shared.brokerAddr},
+			ZKPrefix:       shared.zkPrefix,
+			ZKLockPath:     bootstrapClusterConfig.zkLockPath,
+			TLS: config.TLSConfig{
+				Enabled:    tlsEnabled,
+				CACertPath: shared.tlsCACert,
+				CertPath:   shared.tlsCert,
+				KeyPath:    shared.tlsKey,
+				ServerName: shared.tlsServerName,
+				SkipVerify: shared.tlsSkipVerify,
+			},
+			SASL: config.SASLConfig{
+				Enabled:           saslEnabled,
+				Mechanism:         shared.saslMechanism,
+				Username:          shared.saslUsername,
+				Password:          shared.saslPassword,
+				SecretsManagerArn: shared.saslSecretsManagerArn,
+			},
+		},
+	}
+
+	var zkAddrs []string
+	if bootstrapClusterConfig.shared.zkAddr != "" {
+		zkAddrs = []string{bootstrapClusterConfig.shared.zkAddr}
+	}
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, !noSpinner)
+	return cliRunner.BootstrapCluster(
+		ctx,
+		clusterConfig,
+		zkAddrs,
+		bootstrapConfig.outputDir,
+		bootstrapConfig.overwrite,
+	)
+}
+
+func bootstrapUsersRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cliRunner, clusterConfig, err := bootstrapCLIRunner(ctx)
+	if err != nil {
+		return err
+	}
+	return cliRunner.BootstrapUsers(
+		ctx,
+		args,
+		clusterConfig,
+		bootstrapConfig.matchRegexp,
+		bootstrapConfig.excludeRegexp,
+		bootstrapConfig.outputDir,
+		bootstrapConfig.overwrite,
+	)
+}
+
+func bootstrapCLIRunner(
+	ctx context.Context,
+) (*cli.CLIRunner, config.ClusterConfig, error) {
 	clusterConfig, err := config.LoadClusterFile(
 		bootstrapConfig.shared.clusterConfig,
 		bootstrapConfig.shared.expandEnv,
 	)
 	if err != nil {
-		return err
+		return nil, clusterConfig, err
 	}
 	adminClient, err := clusterConfig.NewAdminClient(
 		ctx,
@@ -87,18 +338,8 @@ func bootstrapRun(cmd *cobra.Command, args []string) error {
 		},
 	)
 	if err != nil {
-		return err
+		return nil, clusterConfig, err
 	}
 
-	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
-	return cliRunner.BootstrapTopics(
-		ctx,
-		args,
-		clusterConfig,
-		bootstrapConfig.matchRegexp,
-		bootstrapConfig.excludeRegexp,
-		bootstrapConfig.outputDir,
-		bootstrapConfig.overwrite,
-		bootstrapConfig.allowInternalTopics,
-	)
+	return cli.NewCLIRunner(adminClient, log.Infof, false), clusterConfig, nil
 }
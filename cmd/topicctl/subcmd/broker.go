@@ -0,0 +1,630 @@
+package subcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/cli"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var brokerCmd = &cobra.Command{
+	Use:   "broker [command]",
+	Short: "run maintenance operations on a broker",
+	Long: strings.Join(
+		[]string{
+			"Runs maintenance operations on a single broker.",
+		},
+		"\n",
+	),
+}
+
+type brokerCmdConfig struct {
+	brokerThrottleMBsOverride  int
+	dryRun                     bool
+	fromBrokerID               int
+	historyDir                 string
+	migrationStateDir          string
+	partitionBatchSizeOverride int
+	skipConfirm                bool
+	sleepLoopDuration          time.Duration
+	stateDir                   string
+	toBrokerID                 int
+
+	shared sharedOptions
+}
+
+var brokerConfig brokerCmdConfig
+
+func init() {
+	brokerCmd.PersistentFlags().BoolVar(
+		&brokerConfig.dryRun,
+		"dry-run",
+		false,
+		"Do a dry-run",
+	)
+	brokerCmd.PersistentFlags().BoolVar(
+		&brokerConfig.skipConfirm,
+		"skip-confirm",
+		false,
+		"Skip confirmation prompts",
+	)
+	brokerCmd.PersistentFlags().DurationVar(
+		&brokerConfig.sleepLoopDuration,
+		"sleep-loop-duration",
+		10*time.Second,
+		"Amount of time to wait between partition checks",
+	)
+	brokerCmd.PersistentFlags().StringVar(
+		&brokerConfig.stateDir,
+		"state-dir",
+		defaultMaintenanceStateDir(),
+		"Directory where the original replica orderings of drained brokers are recorded",
+	)
+
+	brokerCmd.AddCommand(
+		brokerDrainCmd(),
+		brokerRestoreCmd(),
+		brokerDecommissionCmd(),
+		brokerReplaceCmd(),
+	)
+	RootCmd.AddCommand(brokerCmd)
+}
+
+func brokerPreRun(cmd *cobra.Command, args []string) error {
+	if brokerConfig.shared.clusterConfig == "" {
+		return errors.New("Requires arg --cluster-config (or) env variable TOPICCTL_CLUSTER_CONFIG")
+	}
+	return nil
+}
+
+func brokerMaintenancePreRun(cmd *cobra.Command, args []string) error {
+	if err := brokerPreRun(cmd, args); err != nil {
+		return err
+	}
+	if brokerConfig.stateDir == "" {
+		return errors.New(
+			"Requires arg --state-dir (or) env variable TOPICCTL_MAINTENANCE_STATE_DIR",
+		)
+	}
+	return nil
+}
+
+func brokerDrainCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drain [broker id]",
+		Short: "move the leadership of all partitions off of a broker",
+		Long: strings.Join(
+			[]string{
+				"Moves the leadership of all partitions off of a broker by reordering their replicas",
+				"and running leader elections. No data is moved. The original replica orderings are",
+				"recorded so that they can be put back with 'broker restore'.",
+			},
+			"\n",
+		),
+		Args:    cobra.ExactArgs(1),
+		PreRunE: brokerMaintenancePreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return brokerMaintenanceRun(args[0], apply.DrainBroker)
+		},
+	}
+
+	addSharedConfigOnlyFlags(cmd, &brokerConfig.shared)
+	return cmd
+}
+
+func brokerRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [broker id]",
+		Short: "restore the leadership of a drained broker",
+		Long: strings.Join(
+			[]string{
+				"Puts back the replica orderings that were changed by 'broker drain' and runs leader",
+				"elections so that the broker leads its preferred partitions again.",
+			},
+			"\n",
+		),
+		Args:    cobra.ExactArgs(1),
+		PreRunE: brokerMaintenancePreRun,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return brokerMaintenanceRun(args[0], apply.RestoreBroker)
+		},
+	}
+
+	addSharedConfigOnlyFlags(cmd, &brokerConfig.shared)
+	return cmd
+}
+
+func brokerMaintenanceRun(
+	brokerIDStr string,
+	runFunc func(context.Context, admin.Client, apply.BrokerMaintenanceConfig) error,
+) error {
+	brokerIDs, err := parseBrokerIDs([]string{brokerIDStr})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := brokerContext()
+	defer cancel()
+
+	clusterConfig, adminClient, err := brokerConfig.getAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	return runFunc(
+		ctx,
+		adminClient,
+		apply.BrokerMaintenanceConfig{
+			ClusterConfig:     clusterConfig,
+			BrokerID:          brokerIDs[0],
+			DryRun:            brokerConfig.dryRun,
+			SkipConfirm:       brokerConfig.skipConfirm,
+			SleepLoopDuration: brokerConfig.sleepLoopDuration,
+			StateDir:          brokerConfig.stateDir,
+		},
+	)
+}
+
+func brokerDecommissionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "decommission [broker ids]",
+		Short: "move all replicas off of brokers so that they can be shut down",
+		Long: strings.Join(
+			[]string{
+				"Moves the replicas of every topic in the cluster, including unmanaged and internal ones,",
+				"off of the argument brokers while keeping the rack placement of each topic. It then",
+				"checks that the brokers hold no replicas or leaders and reports whether they're safe",
+				"to shut down.",
+			},
+			"\n",
+		),
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: brokerPreRun,
+		RunE:    brokerDecommissionRun,
+	}
+
+	brokerConfig.addMigrationFlags(cmd)
+	addSharedConfigOnlyFlags(cmd, &brokerConfig.shared)
+	return cmd
+}
+
+func brokerReplaceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replace",
+		Short: "substitute a new broker for an old one in every partition",
+		Long: strings.Join(
+			[]string{
+				"Substitutes the --to broker for the --from broker in every partition of every topic in",
+				"the cluster, keeping the replica positions. This is useful when a host is replaced",
+				"with a broker that has a new ID. None of the other replicas are moved.",
+			},
+			"\n",
+		),
+		Args:    cobra.NoArgs,
+		PreRunE: brokerReplacePreRun,
+		RunE:    brokerReplaceRun,
+	}
+
+	cmd.Flags().IntVar(
+		&brokerConfig.fromBrokerID,
+		"from",
+		-1,
+		"ID of the broker to replace",
+	)
+	cmd.Flags().IntVar(
+		&brokerConfig.toBrokerID,
+		"to",
+		-1,
+		"ID of the broker to replace it with",
+	)
+
+	brokerConfig.addMigrationFlags(cmd)
+	addSharedConfigOnlyFlags(cmd, &brokerConfig.shared)
+	return cmd
+}
+
+func (c *brokerCmdConfig) addMigrationFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&c.brokerThrottleMBsOverride,
+		"broker-throttle-mb",
+		0,
+		"Broker throttle override (MB/sec)",
+	)
+	cmd.Flags().StringVar(
+		&c.historyDir,
+		"history-dir",
+		defaultHistoryDir(),
+		"Directory where the state of topics before they're changed is recorded; set to empty to disable",
+	)
+	cmd.Flags().StringVar(
+		&c.migrationStateDir,
+		"migration-state-dir",
+		defaultMigrationStateDir(),
+		"Directory for recording the progress of partition migrations; set to empty to disable",
+	)
+	cmd.Flags().IntVar(
+		&c.partitionBatchSizeOverride,
+		"partition-batch-size",
+		0,
+		"Partition batch size override",
+	)
+}
+
+func brokerReplacePreRun(cmd *cobra.Command, args []string) error {
+	if err := brokerPreRun(cmd, args); err != nil {
+		return err
+	}
+	if brokerConfig.fromBrokerID < 0 || brokerConfig.toBrokerID < 0 {
+		return errors.New("Must set both --from and --to")
+	}
+	if brokerConfig.fromBrokerID == brokerConfig.toBrokerID {
+		return errors.New("--from and --to must be different brokers")
+	}
+	return nil
+}
+
+func brokerDecommissionRun(cmd *cobra.Command, args []string) error {
+	brokerIDs, err := parseBrokerIDs(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := brokerContext()
+	defer cancel()
+
+	clusterConfig, adminClient, err := brokerConfig.getAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	brokers, err := adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		return err
+	}
+	clusterBrokerIDs := admin.BrokerIDs(brokers)
+	for _, brokerID := range brokerIDs {
+		if !slices.Contains(clusterBrokerIDs, brokerID) {
+			return fmt.Errorf(
+				"Broker %d isn't in the cluster (brokers: %+v)",
+				brokerID,
+				clusterBrokerIDs,
+			)
+		}
+	}
+	if len(brokerIDs) >= len(brokers) {
+		return errors.New("Cannot decommission every broker in the cluster")
+	}
+
+	topics, err := getSortedTopics(ctx, adminClient)
+	if err != nil {
+		return err
+	}
+
+	// Plan all topics up-front so that nothing is changed if any of them can't be moved
+	replicaCounts := apply.BrokerReplicaCounts(topics)
+	topicConfigs := []config.TopicConfig{}
+	planErrors := []string{}
+
+	for _, topic := range topics {
+		targetAssignments, err := apply.DecommissionAssignments(
+			topic,
+			brokers,
+			brokerIDs,
+			replicaCounts,
+		)
+		if err != nil {
+			planErrors = append(planErrors, err.Error())
+			continue
+		}
+
+		if topicConfig, ok := brokerTopicConfig(
+			clusterConfig,
+			topic,
+			targetAssignments,
+			"Broker decommission",
+		); ok {
+			topicConfigs = append(topicConfigs, topicConfig)
+		}
+	}
+
+	if len(planErrors) > 0 {
+		return fmt.Errorf(
+			"Cannot decommission brokers %+v:\n%s",
+			brokerIDs,
+			strings.Join(planErrors, "\n"),
+		)
+	}
+
+	if err := brokerConfig.reassignTopics(
+		ctx,
+		clusterConfig,
+		adminClient,
+		topicConfigs,
+		fmt.Sprintf("OK to decommission brokers %+v?", brokerIDs),
+		nil,
+	); err != nil {
+		return err
+	}
+
+	return brokerConfig.shutdownCheck(ctx, adminClient, brokers, brokerIDs)
+}
+
+func brokerReplaceRun(cmd *cobra.Command, args []string) error {
+	fromBrokerID := brokerConfig.fromBrokerID
+	toBrokerID := brokerConfig.toBrokerID
+
+	ctx, cancel := brokerContext()
+	defer cancel()
+
+	clusterConfig, adminClient, err := brokerConfig.getAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	brokers, err := adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		return err
+	}
+	brokerRacks := admin.BrokerRacks(brokers)
+
+	// The old broker is often gone already, so only the new one needs to be in the cluster
+	toRack, ok := brokerRacks[toBrokerID]
+	if !ok {
+		return fmt.Errorf(
+			"Broker %d isn't in the cluster (brokers: %+v)",
+			toBrokerID,
+			admin.BrokerIDs(brokers),
+		)
+	}
+	if fromRack, ok := brokerRacks[fromBrokerID]; !ok {
+		log.Warnf(
+			"Broker %d isn't in the cluster, so its rack can't be checked; make sure that broker %d (rack %s) is in the right rack",
+			fromBrokerID,
+			toBrokerID,
+			toRack,
+		)
+	} else if fromRack != toRack {
+		log.Warnf(
+			"Broker %d is in rack %s but broker %d is in rack %s; the rack placement of some topics may change",
+			fromBrokerID,
+			fromRack,
+			toBrokerID,
+			toRack,
+		)
+	}
+
+	topics, err := getSortedTopics(ctx, adminClient)
+	if err != nil {
+		return err
+	}
+
+	topicConfigs := []config.TopicConfig{}
+	planErrors := []string{}
+
+	for _, topic := range topics {
+		targetAssignments, err := apply.ReplaceBrokerAssignments(topic, fromBrokerID, toBrokerID)
+		if err != nil {
+			planErrors = append(planErrors, err.Error())
+			continue
+		}
+
+		if topicConfig, ok := brokerTopicConfig(
+			clusterConfig,
+			topic,
+			targetAssignments,
+			fmt.Sprintf("Broker replacement of %d with %d", fromBrokerID, toBrokerID),
+		); ok {
+			topicConfigs = append(topicConfigs, topicConfig)
+		}
+	}
+
+	if len(planErrors) > 0 {
+		return fmt.Errorf(
+			"Cannot replace broker %d with %d:\n%s",
+			fromBrokerID,
+			toBrokerID,
+			strings.Join(planErrors, "\n"),
+		)
+	}
+
+	if err := brokerConfig.reassignTopics(
+		ctx,
+		clusterConfig,
+		adminClient,
+		topicConfigs,
+		fmt.Sprintf("OK to replace broker %d with %d?", fromBrokerID, toBrokerID),
+		nil,
+	); err != nil {
+		return err
+	}
+
+	return brokerConfig.shutdownCheck(ctx, adminClient, brokers, []int{fromBrokerID})
+}
+
+func getSortedTopics(ctx context.Context, adminClient admin.Client) ([]admin.TopicInfo, error) {
+	topics, err := adminClient.GetTopics(ctx, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(topics, func(a, b int) bool {
+		return topics[a].Name < topics[b].Name
+	})
+	return topics, nil
+}
+
+// brokerTopicConfig returns the config for moving a topic to the argument target assignments,
+// or false if no partitions need to be moved.
+func brokerTopicConfig(
+	clusterConfig config.ClusterConfig,
+	topic admin.TopicInfo,
+	targetAssignments []admin.PartitionAssignment,
+	description string,
+) (config.TopicConfig, bool) {
+	diffs := admin.AssignmentsToUpdate(topic.ToAssignments(), targetAssignments)
+	if len(diffs) == 0 {
+		return config.TopicConfig{}, false
+	}
+	log.Infof("Topic %s: moving %d partition(s)", topic.Name, len(diffs))
+
+	return apply.ReassignTopicConfig(clusterConfig, topic, targetAssignments, description), true
+}
+
+// reassignTopics applies each of the argument topic configs, which only change replica
+// assignments, after a single confirmation. If onConfirm is set, it's called after the
+// confirmation and before any changes are made, except in dry runs.
+func (c *brokerCmdConfig) reassignTopics(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	adminClient admin.Client,
+	topicConfigs []config.TopicConfig,
+	confirmMessage string,
+	onConfirm func() error,
+) error {
+	if len(topicConfigs) == 0 {
+		log.Infof("No replicas need to be moved")
+		return nil
+	}
+
+	log.Infof("Moving replicas in %d topic(s)", len(topicConfigs))
+
+	ok, _ := util.Confirm(confirmMessage, c.skipConfirm || c.dryRun)
+	if !ok {
+		return errors.New("Stopping because of user response")
+	}
+	if onConfirm != nil && !c.dryRun {
+		if err := onConfirm(); err != nil {
+			return err
+		}
+	}
+
+	retentionDropStepDuration, err := clusterConfig.GetDefaultRetentionDropStepDuration()
+	if err != nil {
+		return err
+	}
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+	for _, topicConfig := range topicConfigs {
+		if _, err := cliRunner.ApplyTopic(
+			ctx,
+			apply.TopicApplierConfig{
+				BrokerThrottleMBsOverride:  c.brokerThrottleMBsOverride,
+				ClusterConfig:              clusterConfig,
+				DryRun:                     c.dryRun,
+				PartitionBatchSizeOverride: c.partitionBatchSizeOverride,
+				AutoContinueRebalance:      true,
+				RetentionDropStepDuration:  retentionDropStepDuration,
+				SkipConfirm:                true,
+				SleepLoopDuration:          c.sleepLoopDuration,
+				TopicConfig:                topicConfig,
+				MigrationStateDir:          c.migrationStateDir,
+				HistoryDir:                 c.historyDir,
+			},
+		); err != nil {
+			return fmt.Errorf("Error moving topic %s: %+v", topicConfig.Meta.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// shutdownCheck checks that the argument brokers hold no replicas or leaders and prints a
+// report saying whether each one is safe to shut down.
+func (c *brokerCmdConfig) shutdownCheck(
+	ctx context.Context,
+	adminClient admin.Client,
+	brokers []admin.BrokerInfo,
+	brokerIDs []int,
+) error {
+	if c.dryRun {
+		log.Infof("Skipping shut down check because dryRun is set to true")
+		return nil
+	}
+
+	topics, err := adminClient.GetTopics(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+	statuses := apply.BrokerDecommissionStatuses(topics, brokers, brokerIDs)
+	log.Infof("Shut down report:\n%s", apply.FormatBrokerDecommissionStatuses(statuses))
+
+	for _, status := range statuses {
+		if !status.Safe() {
+			return fmt.Errorf("Broker %d still has replicas or leaders", status.Broker)
+		}
+	}
+	log.Infof("Brokers %+v are safe to shut down", brokerIDs)
+
+	return nil
+}
+
+func parseBrokerIDs(args []string) ([]int, error) {
+	brokerIDs := []int{}
+
+	for _, arg := range args {
+		brokerID, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid broker id %s: %+v", arg, err)
+		}
+		brokerIDs = append(brokerIDs, brokerID)
+	}
+
+	return brokerIDs, nil
+}
+
+// brokerContext returns a context that's cancelled on an interrupt.
+func brokerContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	return ctx, cancel
+}
+
+func (c *brokerCmdConfig) getAdminClient(ctx context.Context) (config.ClusterConfig, admin.Client, error) {
+	clusterConfig, err := config.LoadClusterFile(
+		c.shared.clusterConfig,
+		c.shared.expandEnv,
+	)
+	if err != nil {
+		return config.ClusterConfig{}, nil, err
+	}
+
+	adminClient, err := clusterConfig.NewAdminClient(
+		ctx,
+		nil,
+		config.AdminClientOpts{
+			ReadOnly:                  c.dryRun,
+			UsernameOverride:          c.shared.saslUsername,
+			PasswordOverride:          c.shared.saslPassword,
+			SecretsManagerArnOverride: c.shared.saslSecretsManagerArn,
+		},
+	)
+	if err != nil {
+		return config.ClusterConfig{}, nil, err
+	}
+
+	return clusterConfig, adminClient, nil
+}
@@ -2,6 +2,7 @@ package subcmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -15,9 +16,10 @@ import (
 )
 
 var checkCmd = &cobra.Command{
-	Use:   "check [topic configs]",
-	Short: "check that configs are valid and (optionally) match cluster state",
-	RunE:  checkRun,
+	Use:     "check [topic configs]",
+	Short:   "check that configs are valid and (optionally) match cluster state",
+	PreRunE: checkPreRun,
+	RunE:    checkRun,
 }
 
 type checkCmdConfig struct {
@@ -25,6 +27,10 @@ type checkCmdConfig struct {
 	pathPrefix   string
 	validateOnly bool
 
+	unmanaged       bool
+	unmanagedOutput string
+	overwrite       bool
+
 	shared sharedOptions
 }
 
@@ -50,13 +56,58 @@ func init() {
 		"Validate configs only, without connecting to cluster",
 	)
 
+	checkCmd.Flags().BoolVar(
+		&checkConfig.unmanaged,
+		"unmanaged",
+		false,
+		"Check for topics in the cluster without configs and configs without topics",
+	)
+	checkCmd.Flags().StringVar(
+		&checkConfig.unmanagedOutput,
+		"output",
+		"",
+		"Output directory for bootstrapped configs of unmanaged topics; only applies with --unmanaged",
+	)
+	checkCmd.Flags().BoolVar(
+		&checkConfig.overwrite,
+		"overwrite",
+		false,
+		"Overwrite existing configs in output directory; only applies with --unmanaged",
+	)
+
 	addSharedConfigOnlyFlags(checkCmd, &checkConfig.shared)
+	addSnapshotFlag(checkCmd, &checkConfig.shared)
 	RootCmd.AddCommand(checkCmd)
 }
 
+func checkPreRun(cmd *cobra.Command, args []string) error {
+	if !checkConfig.unmanaged {
+		if checkConfig.unmanagedOutput != "" || checkConfig.overwrite {
+			return errors.New("--output and --overwrite can only be used with --unmanaged")
+		}
+		return nil
+	}
+
+	if checkConfig.validateOnly {
+		return errors.New("--unmanaged cannot be used with --validate-only")
+	}
+	if len(args) > 0 {
+		return errors.New("Topic config args cannot be used with --unmanaged; use --path-prefix instead")
+	}
+	if checkConfig.shared.clusterConfig == "" || checkConfig.pathPrefix == "" {
+		return errors.New("--cluster-config and --path-prefix must be set with --unmanaged")
+	}
+
+	return nil
+}
+
 func checkRun(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	if checkConfig.unmanaged {
+		return checkUnmanagedRun(ctx)
+	}
+
 	// Keep a cache of the admin clients with the cluster config path as the key
 	adminClients := map[string]admin.Client{}
 
@@ -134,15 +185,10 @@ func checkTopicFile(
 		var ok bool
 		adminClient, ok = adminClients[clusterConfigPath]
 		if !ok {
-			adminClient, err = clusterConfig.NewAdminClient(
+			adminClient, err = checkConfig.shared.getClusterAdminClient(
 				ctx,
-				nil,
-				config.AdminClientOpts{
-					ReadOnly:                  true,
-					UsernameOverride:          checkConfig.shared.saslUsername,
-					PasswordOverride:          checkConfig.shared.saslPassword,
-					SecretsManagerArnOverride: checkConfig.shared.saslSecretsManagerArn,
-				},
+				clusterConfig,
+				true,
 			)
 			if err != nil {
 				return false, err
@@ -186,6 +232,67 @@ func checkTopicFile(
 	return true, nil
 }
 
+func checkUnmanagedRun(ctx context.Context) error {
+	clusterConfigPath := checkConfig.shared.clusterConfig
+	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, checkConfig.shared.expandEnv)
+	if err != nil {
+		return err
+	}
+
+	adminClient, err := checkConfig.shared.getClusterAdminClient(ctx, clusterConfig, true)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	// Like in rebalance, files that aren't valid topic configs or that are for other clusters
+	// are ignored.
+	log.Infof("Getting all topic configs from path prefix %v", checkConfig.pathPrefix)
+	topicFiles, err := getAllFiles(checkConfig.pathPrefix)
+	if err != nil {
+		return err
+	}
+
+	managedTopics := map[string]string{}
+
+	for _, topicFile := range topicFiles {
+		topicConfigs, err := config.LoadTopicsFile(topicFile)
+		if err != nil {
+			log.Debugf("Skipping file %s that isn't a valid topic config: %+v", topicFile, err)
+			continue
+		}
+
+		for _, topicConfig := range topicConfigs {
+			if err := config.CheckConsistency(topicConfig.Meta, clusterConfig); err != nil {
+				log.Debugf(
+					"Skipping topic config %s that's inconsistent with cluster: %+v",
+					topicFile,
+					err,
+				)
+				continue
+			}
+			managedTopics[topicConfig.Meta.Name] = topicFile
+		}
+	}
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, !noSpinner)
+	ok, err := cliRunner.CheckUnmanagedTopics(
+		ctx,
+		clusterConfig,
+		managedTopics,
+		checkConfig.unmanagedOutput,
+		checkConfig.overwrite,
+	)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Check failed: cluster topics do not match configs")
+	}
+
+	return nil
+}
+
 func clusterConfigForTopicCheck(topicConfigPath string) (string, error) {
 	if checkConfig.shared.clusterConfig != "" {
 		return checkConfig.shared.clusterConfig, nil
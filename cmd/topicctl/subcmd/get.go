@@ -9,6 +9,7 @@ import (
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/cli"
+	"github.com/segmentio/topicctl/pkg/config"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -57,6 +58,7 @@ func init() {
 		"Sort by value instead of name; only applies for lags at the moment",
 	)
 	addSharedFlags(getCmd, &getConfig.shared)
+	addSnapshotFlag(getCmd, &getConfig.shared)
 	getCmd.AddCommand(
 		balanceCmd(),
 		brokersCmd(),
@@ -89,6 +91,7 @@ func balanceCmd() *cobra.Command {
 		Long: strings.Join([]string{
 			"Displays the number of replicas per broker position.",
 			"Accepts an optional argument of a topic, which will just scope this to that topic. If topic is omitted, the balance displayed will be for the entire cluster.",
+			"If a cluster config with broker weights is used, the deviation of each broker from its weighted target is also displayed.",
 		},
 			"\n",
 		),
@@ -109,7 +112,21 @@ func balanceCmd() *cobra.Command {
 			if len(args) == 1 {
 				topicName = args[0]
 			}
-			return cliRunner.GetBrokerBalance(ctx, topicName)
+
+			// Broker weights are only available when a cluster config is used
+			var brokerWeights *config.BrokerWeightsConfig
+			if getConfig.shared.clusterConfig != "" {
+				clusterConfig, err := config.LoadClusterFile(
+					getConfig.shared.clusterConfig,
+					getConfig.shared.expandEnv,
+				)
+				if err != nil {
+					return err
+				}
+				brokerWeights = clusterConfig.Spec.BrokerWeights
+			}
+
+			return cliRunner.GetBrokerBalance(ctx, topicName, brokerWeights)
 		},
 		PreRunE: getPreRun,
 	}
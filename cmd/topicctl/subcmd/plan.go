@@ -0,0 +1,254 @@
+package subcmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/cli"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var planCmd = &cobra.Command{
+	Use:     "plan [topic configs]",
+	Short:   "compute the changes for one or more topic configs and write them to a plan file",
+	Args:    cobra.MinimumNArgs(1),
+	PreRunE: planPreRun,
+	RunE:    planRun,
+}
+
+type planCmdConfig struct {
+	brokersToRemove              []int
+	brokerThrottleMBsOverride    int
+	output                       string
+	partitionBatchSizeOverride   int
+	pathPrefix                   string
+	rebalance                    bool
+	retentionDropStepDurationStr string
+	ignoreFewerPartitionsError   bool
+	destructive                  bool
+
+	shared sharedOptions
+
+	retentionDropStepDuration time.Duration
+}
+
+var planConfig planCmdConfig
+
+func init() {
+	planCmd.Flags().IntSliceVar(
+		&planConfig.brokersToRemove,
+		"to-remove",
+		[]int{},
+		"Brokers to remove; only applies if rebalance is also set",
+	)
+	planCmd.Flags().IntVar(
+		&planConfig.brokerThrottleMBsOverride,
+		"broker-throttle-mb",
+		0,
+		"Broker throttle override (MB/sec)",
+	)
+	planCmd.Flags().StringVarP(
+		&planConfig.output,
+		"output",
+		"o",
+		"",
+		"Path to write plan to; if not set, the plan is written to stdout",
+	)
+	planCmd.Flags().IntVar(
+		&planConfig.partitionBatchSizeOverride,
+		"partition-batch-size",
+		0,
+		"Partition batch size override",
+	)
+	planCmd.Flags().StringVar(
+		&planConfig.pathPrefix,
+		"path-prefix",
+		os.Getenv("TOPICCTL_APPLY_PATH_PREFIX"),
+		"Prefix for topic config paths",
+	)
+	planCmd.Flags().BoolVar(
+		&planConfig.rebalance,
+		"rebalance",
+		false,
+		"Explicitly rebalance broker partition assignments",
+	)
+	planCmd.Flags().StringVar(
+		&planConfig.retentionDropStepDurationStr,
+		"retention-drop-step-duration",
+		"",
+		"Amount of time to use for retention drop steps",
+	)
+	planCmd.Flags().BoolVar(
+		&planConfig.ignoreFewerPartitionsError,
+		"ignore-fewer-partitions-error",
+		false,
+		"Don't return error when topic's config specifies fewer partitions than it currently has",
+	)
+	planCmd.Flags().BoolVar(
+		&planConfig.destructive,
+		"destructive",
+		false,
+		"Deletes topic settings from the broker if the settings are present on the broker but not in the config",
+	)
+
+	addSharedConfigOnlyFlags(planCmd, &planConfig.shared)
+	RootCmd.AddCommand(planCmd)
+}
+
+func planPreRun(cmd *cobra.Command, args []string) error {
+	if planConfig.retentionDropStepDurationStr != "" {
+		var err error
+		planConfig.retentionDropStepDuration, err = time.ParseDuration(
+			planConfig.retentionDropStepDurationStr,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func planRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	topicConfigPaths := []string{}
+
+	for _, arg := range args {
+		if planConfig.pathPrefix != "" && !filepath.IsAbs(arg) {
+			arg = filepath.Join(planConfig.pathPrefix, arg)
+		}
+
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return err
+		}
+		topicConfigPaths = append(topicConfigPaths, matches...)
+	}
+
+	if len(topicConfigPaths) == 0 {
+		return fmt.Errorf("No topic configs match the provided args (%+v)", args)
+	}
+
+	// All topics in a plan must be in the same cluster
+	var clusterConfigPath string
+
+	for _, topicConfigPath := range topicConfigPaths {
+		topicClusterConfigPath := planConfig.shared.clusterConfig
+		if topicClusterConfigPath == "" {
+			var err error
+			topicClusterConfigPath, err = filepath.Abs(
+				filepath.Join(
+					filepath.Dir(topicConfigPath),
+					"..",
+					"cluster.yaml",
+				),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		if clusterConfigPath == "" {
+			clusterConfigPath = topicClusterConfigPath
+		} else if clusterConfigPath != topicClusterConfigPath {
+			return fmt.Errorf(
+				"All topic configs in a plan must use the same cluster config (found %s and %s)",
+				clusterConfigPath,
+				topicClusterConfigPath,
+			)
+		}
+	}
+
+	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, planConfig.shared.expandEnv)
+	if err != nil {
+		return err
+	}
+
+	adminClient, err := clusterConfig.NewAdminClient(
+		ctx,
+		nil,
+		config.AdminClientOpts{
+			ReadOnly:                  true,
+			UsernameOverride:          planConfig.shared.saslUsername,
+			PasswordOverride:          planConfig.shared.saslPassword,
+			SecretsManagerArnOverride: planConfig.shared.saslSecretsManagerArn,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+	plan := apply.NewPlan(clusterConfig, clusterConfigPath)
+
+	for _, topicConfigPath := range topicConfigPaths {
+		topicConfigs, err := config.LoadTopicsFile(topicConfigPath)
+		if err != nil {
+			return err
+		}
+
+		for _, topicConfig := range topicConfigs {
+			topicConfig.SetDefaults()
+			log.Infof(
+				"Processing topic %s in config %s with cluster config %s",
+				topicConfig.Meta.Name,
+				topicConfigPath,
+				clusterConfigPath,
+			)
+
+			topicPlan, err := cliRunner.PlanTopic(
+				ctx,
+				apply.TopicApplierConfig{
+					BrokerThrottleMBsOverride:  planConfig.brokerThrottleMBsOverride,
+					BrokersToRemove:            planConfig.brokersToRemove,
+					ClusterConfig:              clusterConfig,
+					PartitionBatchSizeOverride: planConfig.partitionBatchSizeOverride,
+					Rebalance:                  planConfig.rebalance,
+					RetentionDropStepDuration:  planConfig.retentionDropStepDuration,
+					IgnoreFewerPartitionsError: planConfig.ignoreFewerPartitionsError,
+					Destructive:                planConfig.destructive,
+					TopicConfig:                topicConfig,
+				},
+			)
+			if err != nil {
+				return err
+			}
+			plan.Topics = append(plan.Topics, topicPlan)
+		}
+	}
+
+	if planConfig.output == "" {
+		contents, err := plan.ToJSON()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(contents))
+		return nil
+	}
+
+	if err := apply.WritePlanFile(plan, planConfig.output); err != nil {
+		return err
+	}
+	log.Infof("Wrote plan to %s", planConfig.output)
+
+	return nil
+}
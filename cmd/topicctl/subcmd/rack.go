@@ -0,0 +1,337 @@
+package subcmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rackCmd = &cobra.Command{
+	Use:   "rack [command]",
+	Short: "run maintenance operations on a rack",
+	Long: strings.Join(
+		[]string{
+			"Runs maintenance operations on all of the brokers in a rack.",
+		},
+		"\n",
+	),
+}
+
+// The rack commands use the same options and helpers as the broker ones.
+var rackConfig brokerCmdConfig
+
+func init() {
+	rackCmd.PersistentFlags().BoolVar(
+		&rackConfig.dryRun,
+		"dry-run",
+		false,
+		"Do a dry-run",
+	)
+	rackCmd.PersistentFlags().BoolVar(
+		&rackConfig.skipConfirm,
+		"skip-confirm",
+		false,
+		"Skip confirmation prompts",
+	)
+	rackCmd.PersistentFlags().DurationVar(
+		&rackConfig.sleepLoopDuration,
+		"sleep-loop-duration",
+		10*time.Second,
+		"Amount of time to wait between partition checks",
+	)
+	rackCmd.PersistentFlags().StringVar(
+		&rackConfig.stateDir,
+		"state-dir",
+		defaultMaintenanceStateDir(),
+		"Directory where the original replica assignments of evacuated racks are recorded",
+	)
+
+	rackCmd.AddCommand(
+		rackEvacuateCmd(),
+		rackRestoreCmd(),
+	)
+	RootCmd.AddCommand(rackCmd)
+}
+
+func rackPreRun(cmd *cobra.Command, args []string) error {
+	if rackConfig.shared.clusterConfig == "" {
+		return errors.New("Requires arg --cluster-config (or) env variable TOPICCTL_CLUSTER_CONFIG")
+	}
+	if rackConfig.stateDir == "" {
+		return errors.New(
+			"Requires arg --state-dir (or) env variable TOPICCTL_MAINTENANCE_STATE_DIR",
+		)
+	}
+	return nil
+}
+
+func rackEvacuateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "evacuate [rack]",
+		Short: "move all replicas and leaders off of the brokers in a rack",
+		Long: strings.Join(
+			[]string{
+				"Moves the replicas of every topic in the cluster off of the brokers in a rack while",
+				"keeping the rack placement of each topic with the remaining racks. The original",
+				"assignments are recorded so that they can be put back with 'rack restore'.",
+			},
+			"\n",
+		),
+		Args:    cobra.ExactArgs(1),
+		PreRunE: rackPreRun,
+		RunE:    rackEvacuateRun,
+	}
+
+	rackConfig.addMigrationFlags(cmd)
+	addSharedConfigOnlyFlags(cmd, &rackConfig.shared)
+	return cmd
+}
+
+func rackRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [rack]",
+		Short: "move replicas back to the brokers in an evacuated rack",
+		Long: strings.Join(
+			[]string{
+				"Puts back the replica assignments that were changed by 'rack evacuate'. Partitions",
+				"that were moved by something else since the evacuation are left alone.",
+			},
+			"\n",
+		),
+		Args:    cobra.ExactArgs(1),
+		PreRunE: rackPreRun,
+		RunE:    rackRestoreRun,
+	}
+
+	rackConfig.addMigrationFlags(cmd)
+	addSharedConfigOnlyFlags(cmd, &rackConfig.shared)
+	return cmd
+}
+
+func rackEvacuateRun(cmd *cobra.Command, args []string) error {
+	rack := args[0]
+
+	ctx, cancel := brokerContext()
+	defer cancel()
+
+	clusterConfig, adminClient, err := rackConfig.getAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	path := rackStatePath(clusterConfig, rack)
+	existingState, err := apply.LoadRackEvacuationState(path)
+	if err != nil {
+		return err
+	}
+	if existingState != nil {
+		return fmt.Errorf(
+			"Rack %s was already evacuated at %s; restore it before evacuating it again, or remove %s",
+			rack,
+			existingState.EvacuatedAt.Format(time.RFC3339),
+			path,
+		)
+	}
+
+	brokers, err := adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		return err
+	}
+	rackBrokers := admin.BrokersPerRack(brokers)[rack]
+	if len(rackBrokers) == 0 {
+		return fmt.Errorf(
+			"No brokers found in rack %s (racks: %+v)",
+			rack,
+			admin.DistinctRacks(brokers),
+		)
+	}
+	if len(rackBrokers) == len(brokers) {
+		return fmt.Errorf("Cannot evacuate rack %s since it has every broker in the cluster", rack)
+	}
+	log.Infof("Evacuating brokers %+v in rack %s", rackBrokers, rack)
+
+	topics, err := getSortedTopics(ctx, adminClient)
+	if err != nil {
+		return err
+	}
+
+	// Plan all topics up-front so that nothing is changed if any of them can't be moved
+	state := apply.NewRackEvacuationState(
+		path,
+		clusterConfig.Meta.Name,
+		clusterConfig.Meta.Environment,
+		clusterConfig.Meta.Region,
+		rack,
+		rackBrokers,
+	)
+	replicaCounts := apply.BrokerReplicaCounts(topics)
+	topicConfigs := []config.TopicConfig{}
+	planErrors := []string{}
+
+	for _, topic := range topics {
+		targetAssignments, err := apply.EvacuateRackAssignments(
+			topic,
+			brokers,
+			rack,
+			replicaCounts,
+		)
+		if err != nil {
+			planErrors = append(planErrors, err.Error())
+			continue
+		}
+
+		if topicConfig, ok := brokerTopicConfig(
+			clusterConfig,
+			topic,
+			targetAssignments,
+			fmt.Sprintf("Evacuation of rack %s", rack),
+		); ok {
+			topicConfigs = append(topicConfigs, topicConfig)
+			state.Topics = append(
+				state.Topics,
+				apply.EvacuatedTopic{
+					Topic:                topic.Name,
+					OriginalAssignments:  topic.ToAssignments(),
+					EvacuatedAssignments: targetAssignments,
+				},
+			)
+		}
+	}
+
+	if len(planErrors) > 0 {
+		return fmt.Errorf(
+			"Cannot evacuate rack %s:\n%s",
+			rack,
+			strings.Join(planErrors, "\n"),
+		)
+	}
+
+	saveState := func() error {
+		// Record the original assignments before changing anything so that an interrupted
+		// evacuation can still be restored.
+		log.Infof("Recording original replica assignments in %s", path)
+		return state.Save()
+	}
+
+	// reassignTopics returns early without calling saveState if nothing needs to be moved,
+	// so save the (empty) state here; otherwise, the rack couldn't be restored afterwards.
+	if len(topicConfigs) == 0 && !rackConfig.dryRun {
+		if err := saveState(); err != nil {
+			return err
+		}
+	}
+
+	if err := rackConfig.reassignTopics(
+		ctx,
+		clusterConfig,
+		adminClient,
+		topicConfigs,
+		fmt.Sprintf("OK to evacuate rack %s?", rack),
+		saveState,
+	); err != nil {
+		return err
+	}
+
+	return rackConfig.shutdownCheck(ctx, adminClient, brokers, rackBrokers)
+}
+
+func rackRestoreRun(cmd *cobra.Command, args []string) error {
+	rack := args[0]
+
+	ctx, cancel := brokerContext()
+	defer cancel()
+
+	clusterConfig, adminClient, err := rackConfig.getAdminClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	path := rackStatePath(clusterConfig, rack)
+	state, err := apply.LoadRackEvacuationState(path)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("No evacuation state found for rack %s in %s", rack, path)
+	}
+	log.Infof(
+		"Found evacuation of rack %s from %s with %d moved topic(s)",
+		rack,
+		state.EvacuatedAt.Format(time.RFC3339),
+		len(state.Topics),
+	)
+
+	brokers, err := adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		return err
+	}
+	brokerRacks := admin.BrokerRacks(brokers)
+	for _, brokerID := range state.Brokers {
+		if brokerRack, ok := brokerRacks[brokerID]; !ok {
+			return fmt.Errorf("Broker %d in rack %s isn't back in the cluster yet", brokerID, rack)
+		} else if brokerRack != rack {
+			return fmt.Errorf(
+				"Broker %d is now in rack %s instead of %s",
+				brokerID,
+				brokerRack,
+				rack,
+			)
+		}
+	}
+
+	topicConfigs := []config.TopicConfig{}
+	for _, evacuatedTopic := range state.Topics {
+		topicInfo, err := adminClient.GetTopic(ctx, evacuatedTopic.Topic, true)
+		if err == admin.ErrTopicDoesNotExist {
+			log.Warnf("Topic %s no longer exists; skipping it", evacuatedTopic.Topic)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if topicConfig, ok := brokerTopicConfig(
+			clusterConfig,
+			topicInfo,
+			evacuatedTopic.RestoreAssignments(topicInfo.ToAssignments()),
+			fmt.Sprintf("Restore of rack %s", rack),
+		); ok {
+			topicConfigs = append(topicConfigs, topicConfig)
+		}
+	}
+
+	if err := rackConfig.reassignTopics(
+		ctx,
+		clusterConfig,
+		adminClient,
+		topicConfigs,
+		fmt.Sprintf("OK to restore rack %s?", rack),
+		nil,
+	); err != nil {
+		return err
+	}
+
+	if rackConfig.dryRun {
+		return nil
+	}
+	log.Infof("Rack %s is restored", rack)
+	return state.Delete()
+}
+
+func rackStatePath(clusterConfig config.ClusterConfig, rack string) string {
+	return apply.RackEvacuationStatePath(
+		rackConfig.stateDir,
+		clusterConfig.Meta.Name,
+		clusterConfig.Meta.Environment,
+		clusterConfig.Meta.Region,
+		rack,
+	)
+}
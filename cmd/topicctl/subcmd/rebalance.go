@@ -2,11 +2,13 @@ package subcmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
@@ -14,8 +16,10 @@ import (
 
 	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/apply/rebalancers"
 	"github.com/segmentio/topicctl/pkg/cli"
 	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/notify"
 	"github.com/segmentio/topicctl/pkg/util"
 	log "github.com/sirupsen/logrus"
 )
@@ -31,12 +35,24 @@ type rebalanceCmdConfig struct {
 	brokersToRemove            []int
 	brokerThrottleMBsOverride  int
 	dryRun                     bool
+	events                     string
+	global                     bool
+	maxMoves                   int
+	strategy                   string
+	diskTolerance              float64
 	partitionBatchSizeOverride int
 	pathPrefix                 string
 	sleepLoopDuration          time.Duration
 	showProgressInterval       time.Duration
+	migrationStateDir          string
+	historyDir                 string
+	resume                     bool
+	parallelism                int
+	maxMovesPerBroker          int
 
 	shared sharedOptions
+
+	eventWriter *apply.EventWriter
 }
 
 var rebalanceConfig rebalanceCmdConfig
@@ -60,6 +76,39 @@ func init() {
 		false,
 		"Do a dry-run",
 	)
+	rebalanceCmd.Flags().StringVar(
+		&rebalanceConfig.events,
+		"events",
+		"",
+		"Path to write structured progress events to as newline-delimited JSON; use - for stdout",
+	)
+	rebalanceCmd.Flags().BoolVar(
+		&rebalanceConfig.global,
+		"global",
+		false,
+		"Balance the total replicas and leaders on each broker across all topics instead of balancing each topic on its own",
+	)
+	rebalanceCmd.Flags().IntVar(
+		&rebalanceConfig.maxMoves,
+		"max-moves",
+		0,
+		"Max number of replica moves in a global rebalance; 0 for no limit",
+	)
+	rebalanceCmd.Flags().StringVar(
+		&rebalanceConfig.strategy,
+		"strategy",
+		string(apply.RebalanceStrategyCount),
+		fmt.Sprintf(
+			"What to balance across brokers in each topic; one of %+v",
+			apply.AllRebalanceStrategies,
+		),
+	)
+	rebalanceCmd.Flags().Float64Var(
+		&rebalanceConfig.diskTolerance,
+		"disk-tolerance",
+		rebalancers.DefaultDiskTolerance,
+		"Fraction that the bytes on a broker or log dir can be above the mean when the strategy is disk",
+	)
 	rebalanceCmd.Flags().IntVar(
 		&rebalanceConfig.partitionBatchSizeOverride,
 		"partition-batch-size",
@@ -85,7 +134,39 @@ func init() {
 		"Interval of time to show progress during rebalance",
 	)
 
+	rebalanceCmd.Flags().IntVar(
+		&rebalanceConfig.parallelism,
+		"parallelism",
+		1,
+		"Number of topics to rebalance at once",
+	)
+	rebalanceCmd.Flags().IntVar(
+		&rebalanceConfig.maxMovesPerBroker,
+		"max-moves-per-broker",
+		0,
+		"Max number of partitions moving to or from each broker at once when parallelism > 1; 0 for no limit",
+	)
+	rebalanceCmd.Flags().StringVar(
+		&rebalanceConfig.migrationStateDir,
+		"migration-state-dir",
+		defaultMigrationStateDir(),
+		"Directory for recording the progress of partition migrations; set to empty to disable",
+	)
+	rebalanceCmd.Flags().BoolVar(
+		&rebalanceConfig.resume,
+		"resume",
+		false,
+		"Resume partition migrations that were interrupted in a previous run",
+	)
+	rebalanceCmd.Flags().StringVar(
+		&rebalanceConfig.historyDir,
+		"history-dir",
+		defaultHistoryDir(),
+		"Directory for recording the state of topics before they're changed, for rollbacks; set to empty to disable",
+	)
+
 	addSharedConfigOnlyFlags(rebalanceCmd, &rebalanceConfig.shared)
+	addSnapshotFlag(rebalanceCmd, &rebalanceConfig.shared)
 	RootCmd.AddCommand(rebalanceCmd)
 }
 
@@ -93,6 +174,25 @@ func rebalancePreRun(cmd *cobra.Command, args []string) error {
 	if rebalanceConfig.shared.clusterConfig == "" || rebalanceConfig.pathPrefix == "" {
 		return fmt.Errorf("Requires args --cluster-config & --path-prefix (or) env variables TOPICCTL_CLUSTER_CONFIG & TOPICCTL_APPLY_PATH_PREFIX")
 	}
+	if rebalanceConfig.global && len(rebalanceConfig.brokersToRemove) > 0 {
+		return errors.New("--to-remove can't be used with --global; use 'broker decommission' instead")
+	}
+	if rebalanceConfig.maxMoves < 0 {
+		return errors.New("--max-moves must be >= 0")
+	}
+	if rebalanceConfig.shared.snapshot != "" && !rebalanceConfig.dryRun {
+		return errors.New("--dry-run must be set when using --snapshot")
+	}
+	if err := validateRebalanceStrategy(
+		rebalanceConfig.strategy,
+		rebalanceConfig.diskTolerance,
+	); err != nil {
+		return err
+	}
+	if rebalanceConfig.global &&
+		rebalanceConfig.strategy != string(apply.RebalanceStrategyCount) {
+		return errors.New("--global only supports the count strategy")
+	}
 
 	return nil
 }
@@ -116,6 +216,13 @@ func rebalanceRun(cmd *cobra.Command, args []string) error {
 		os.Exit(1)
 	}()
 
+	eventWriter, closeEvents, err := openEventWriter(rebalanceConfig.events)
+	if err != nil {
+		return err
+	}
+	defer closeEvents()
+	rebalanceConfig.eventWriter = eventWriter
+
 	clusterConfigPath := rebalanceConfig.shared.clusterConfig
 	topicConfigDir := rebalanceConfig.pathPrefix
 	clusterConfig, err := config.LoadClusterFile(clusterConfigPath, rebalanceConfig.shared.expandEnv)
@@ -123,14 +230,10 @@ func rebalanceRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	adminClient, err := clusterConfig.NewAdminClient(ctx,
-		nil,
-		config.AdminClientOpts{
-			ReadOnly:                  rebalanceConfig.dryRun,
-			UsernameOverride:          rebalanceConfig.shared.saslUsername,
-			PasswordOverride:          rebalanceConfig.shared.saslPassword,
-			SecretsManagerArnOverride: rebalanceConfig.shared.saslSecretsManagerArn,
-		},
+	adminClient, err := rebalanceConfig.shared.getClusterAdminClient(
+		ctx,
+		clusterConfig,
+		rebalanceConfig.dryRun,
 	)
 	if err != nil {
 		log.Fatal(err)
@@ -150,43 +253,57 @@ func rebalanceRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	var scheduler *apply.MigrationScheduler
+	if rebalanceConfig.parallelism > 1 {
+		scheduler = apply.NewMigrationScheduler(
+			adminClient,
+			apply.MigrationSchedulerConfig{
+				ClusterConfig:     clusterConfig,
+				DryRun:            rebalanceConfig.dryRun,
+				Parallelism:       rebalanceConfig.parallelism,
+				MaxMovesPerBroker: rebalanceConfig.maxMovesPerBroker,
+				ThrottleBytes: sharedThrottleBytes(
+					rebalanceConfig.brokerThrottleMBsOverride,
+					clusterConfig,
+				),
+				Events: rebalanceConfig.eventWriter,
+			},
+		)
+	}
+
 	// iterate through each topic config and initiate rebalance
 	topicConfigs := []config.TopicConfig{}
+	globalTopicConfigs := []config.TopicConfig{}
 	topicErrorDict := make(map[string]error)
-	for _, topicFile := range topicFiles {
-		// do not consider invalid topic yaml files for rebalance
-		topicConfigs, err = config.LoadTopicsFile(topicFile)
-		if err != nil {
-			log.Errorf("Invalid topic yaml file: %s", topicFile)
-			continue
-		}
+	topicChangesDict := make(map[string]apply.NewOrUpdatedChanges)
+	topicErrorDictMutex := sync.Mutex{}
+	migrations := []func(ctx context.Context) error{}
 
-		for _, topicConfig := range topicConfigs {
-			// topic config should be consistent with the cluster config
-			if err := config.CheckConsistency(topicConfig.Meta, clusterConfig); err != nil {
-				log.Errorf("topic file: %s inconsistent with cluster: %s", topicFile, clusterConfigPath)
-				continue
-			}
+	addMigration := func(
+		topicName string,
+		applyTopic func(ctx context.Context) (apply.NewOrUpdatedChanges, error),
+	) {
+		topicErrorDict[topicName] = nil
 
-			log.Infof(
-				"Rebalancing topic %s from config file %s with cluster config %s",
-				topicConfig.Meta.Name,
-				topicFile,
-				clusterConfigPath,
-			)
-
-			topicErrorDict[topicConfig.Meta.Name] = nil
+		migrations = append(migrations, func(ctx context.Context) error {
 			rebalanceTopicProgressConfig := util.RebalanceTopicProgressConfig{
-				TopicName:          topicConfig.Meta.Name,
+				TopicName:          topicName,
 				ClusterName:        clusterConfig.Meta.Name,
 				ClusterEnvironment: clusterConfig.Meta.Environment,
 				ToRemove:           rebalanceConfig.brokersToRemove,
 				RebalanceError:     false,
 			}
-			if err := rebalanceApplyTopic(ctx, topicConfig, clusterConfig, adminClient); err != nil {
-				topicErrorDict[topicConfig.Meta.Name] = err
+			changes, err := applyTopic(ctx)
+			topicErrorDictMutex.Lock()
+			topicChangesDict[topicName] = changes
+			topicErrorDictMutex.Unlock()
+			if err != nil {
+				topicErrorDictMutex.Lock()
+				topicErrorDict[topicName] = err
+				topicErrorDictMutex.Unlock()
+
 				rebalanceTopicProgressConfig.RebalanceError = true
-				log.Errorf("topic: %s rebalance failed with error: %v", topicConfig.Meta.Name, err)
+				log.Errorf("topic: %s rebalance failed with error: %v", topicName, err)
 			}
 
 			// show topic final progress
@@ -198,15 +315,111 @@ func rebalanceRun(cmd *cobra.Command, args []string) error {
 					log.Infof("Rebalance Progress: %s", progressStr)
 				}
 			}
+			return err
+		})
+	}
+
+	for _, topicFile := range topicFiles {
+		// do not consider invalid topic yaml files for rebalance
+		topicConfigs, err = config.LoadTopicsFile(topicFile)
+		if err != nil {
+			log.Errorf("Invalid topic yaml file: %s", topicFile)
+			continue
+		}
+
+		for _, topicConfig := range topicConfigs {
+			// topic config should be consistent with the cluster config
+			if err := config.CheckConsistency(topicConfig.Meta, clusterConfig); err != nil {
+				log.Errorf("topic file: %s inconsistent with cluster: %s", topicFile, clusterConfigPath)
+				continue
+			}
+
+			topicConfig := topicConfig
+			topicFile := topicFile
+
+			if rebalanceConfig.global {
+				// topics are only moved after the plan for the whole cluster is made
+				globalTopicConfigs = append(globalTopicConfigs, topicConfig)
+				continue
+			}
+
+			addMigration(
+				topicConfig.Meta.Name,
+				func(ctx context.Context) (apply.NewOrUpdatedChanges, error) {
+					log.Infof(
+						"Rebalancing topic %s from config file %s with cluster config %s",
+						topicConfig.Meta.Name,
+						topicFile,
+						clusterConfigPath,
+					)
+					return rebalanceApplyTopic(
+						ctx,
+						topicConfig,
+						clusterConfig,
+						adminClient,
+						scheduler,
+					)
+				},
+			)
+		}
+	}
+
+	if rebalanceConfig.global {
+		reassignConfigs, planErrors, err := rebalanceGlobalPlan(
+			ctx,
+			globalTopicConfigs,
+			clusterConfig,
+			adminClient,
+		)
+		if err != nil {
+			return err
+		}
+		for topicName, planErr := range planErrors {
+			topicErrorDict[topicName] = planErr
+			log.Errorf("topic: %s rebalance failed with error: %v", topicName, planErr)
+		}
+
+		for _, topicConfig := range reassignConfigs {
+			topicConfig := topicConfig
+
+			addMigration(
+				topicConfig.Meta.Name,
+				func(ctx context.Context) (apply.NewOrUpdatedChanges, error) {
+					log.Infof(
+						"Moving topic %s for global rebalance with cluster config %s",
+						topicConfig.Meta.Name,
+						clusterConfigPath,
+					)
+					return rebalanceApplyReassignment(
+						ctx,
+						topicConfig,
+						clusterConfig,
+						adminClient,
+						scheduler,
+					)
+				},
+			)
+		}
+	}
+
+	if scheduler != nil {
+		if _, err := scheduler.Run(ctx, migrations); err != nil {
+			return err
+		}
+	} else {
+		for _, migration := range migrations {
+			migration(ctx)
 		}
 	}
 
 	// audit at the end of all topic rebalances
 	successTopics := 0
 	errorTopics := 0
+	topicErrors := map[string]string{}
 	for thisTopicName, thisTopicError := range topicErrorDict {
 		if thisTopicError != nil {
 			errorTopics += 1
+			topicErrors[thisTopicName] = thisTopicError.Error()
 			log.Errorf("topic: %s rebalance failed with error: %v", thisTopicName, thisTopicError)
 		} else {
 			log.Infof("topic: %s rebalance is successful", thisTopicName)
@@ -230,6 +443,29 @@ func rebalanceRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	rebalanceConfig.eventWriter.Emit(
+		apply.Event{
+			Type:          apply.EventTypeSummary,
+			Cluster:       clusterConfig.Meta.Name,
+			Environment:   clusterConfig.Meta.Environment,
+			Region:        clusterConfig.Meta.Region,
+			SuccessTopics: successTopics,
+			ErrorTopics:   errorTopics,
+			TopicErrors:   topicErrors,
+		},
+	)
+
+	notify.Send(
+		ctx,
+		clusterConfig,
+		notify.RebalanceNotification(
+			clusterConfig,
+			rebalanceConfig.dryRun,
+			topicErrorDict,
+			topicChangesDict,
+		),
+	)
+
 	log.Infof("Rebalance complete! %d topics rebalanced successfully, %d topics had errors", successTopics, errorTopics)
 	return nil
 }
@@ -277,48 +513,192 @@ func rebalanceApplyTopic(
 	topicConfig config.TopicConfig,
 	clusterConfig config.ClusterConfig,
 	adminClient admin.Client,
-) error {
+	scheduler *apply.MigrationScheduler,
+) (apply.NewOrUpdatedChanges, error) {
 	topicConfig.SetDefaults()
 	topicInfo, err := adminClient.GetTopic(ctx, topicConfig.Meta.Name, true)
 	if err != nil {
 		if err == admin.ErrTopicDoesNotExist {
-			return fmt.Errorf("Topic: %s does not exist in Kafka cluster", topicConfig.Meta.Name)
+			return nil, fmt.Errorf(
+				"Topic: %s does not exist in Kafka cluster",
+				topicConfig.Meta.Name,
+			)
 		}
-		return err
+		return nil, err
 	}
 	log.Debugf("topicInfo from kafka: %+v", topicInfo)
 
 	if err := rebalanceTopicCheck(topicConfig, topicInfo); err != nil {
-		return err
+		return nil, err
 	}
 
+	applierConfig, err := rebalanceApplierConfig(topicConfig, clusterConfig, scheduler)
+	if err != nil {
+		return nil, err
+	}
+	applierConfig.BrokersToRemove = rebalanceConfig.brokersToRemove
+	applierConfig.Rebalance = true // to enforce action: rebalance
+	applierConfig.RebalanceBy = apply.RebalanceStrategy(rebalanceConfig.strategy)
+	applierConfig.DiskTolerance = rebalanceConfig.diskTolerance
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+	return cliRunner.ApplyTopic(ctx, applierConfig)
+}
+
+// Move a topic to the assignments from a global rebalance plan. The argument topic config has
+// static placements with the target assignments, so the usual throttled, batched migration path
+// is used.
+func rebalanceApplyReassignment(
+	ctx context.Context,
+	topicConfig config.TopicConfig,
+	clusterConfig config.ClusterConfig,
+	adminClient admin.Client,
+	scheduler *apply.MigrationScheduler,
+) (apply.NewOrUpdatedChanges, error) {
+	applierConfig, err := rebalanceApplierConfig(topicConfig, clusterConfig, scheduler)
+	if err != nil {
+		return nil, err
+	}
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+	return cliRunner.ApplyTopic(ctx, applierConfig)
+}
+
+// Get the applier config shared by all topic rebalances
+func rebalanceApplierConfig(
+	topicConfig config.TopicConfig,
+	clusterConfig config.ClusterConfig,
+	scheduler *apply.MigrationScheduler,
+) (apply.TopicApplierConfig, error) {
 	retentionDropStepDuration, err := clusterConfig.GetDefaultRetentionDropStepDuration()
 	if err != nil {
-		return err
+		return apply.TopicApplierConfig{}, err
 	}
 
-	applierConfig := apply.TopicApplierConfig{
+	return apply.TopicApplierConfig{
 		BrokerThrottleMBsOverride:  rebalanceConfig.brokerThrottleMBsOverride,
-		BrokersToRemove:            rebalanceConfig.brokersToRemove,
 		ClusterConfig:              clusterConfig,
 		DryRun:                     rebalanceConfig.dryRun,
 		PartitionBatchSizeOverride: rebalanceConfig.partitionBatchSizeOverride,
-		Rebalance:                  true,                      // to enforce action: rebalance
 		AutoContinueRebalance:      true,                      // to continue without prompts
 		RetentionDropStepDuration:  retentionDropStepDuration, // not needed for rebalance
 		SkipConfirm:                true,                      // to enforce action: rebalance
 		Destructive:                false,                     // Irrelevant here
 		SleepLoopDuration:          rebalanceConfig.sleepLoopDuration,
 		TopicConfig:                topicConfig,
+		MigrationStateDir:          rebalanceConfig.migrationStateDir,
+		HistoryDir:                 rebalanceConfig.historyDir,
+		Resume:                     rebalanceConfig.resume,
+		Events:                     rebalanceConfig.eventWriter,
+		Scheduler:                  scheduler,
+	}, nil
+}
+
+// Make a plan that balances replicas and leaders across the brokers for all topics in the
+// cluster. Topics in the argument configs are moved according to their placement configs; all
+// other topics are left as-is but count towards the broker totals. Returns the argument configs of
+// the changed topics, with their placements replaced by the target assignments, and the errors
+// for topic configs that can't be rebalanced.
+func rebalanceGlobalPlan(
+	ctx context.Context,
+	topicConfigs []config.TopicConfig,
+	clusterConfig config.ClusterConfig,
+	adminClient admin.Client,
+) ([]config.TopicConfig, map[string]error, error) {
+	brokers, err := adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		return nil, nil, err
 	}
+	brokers = clusterConfig.Spec.BrokerTags.TagBrokers(brokers)
 
-	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
-	// TODO: add changes map from rebalancing
-	if _, err := cliRunner.ApplyTopic(ctx, applierConfig); err != nil {
-		return err
+	topics, err := getSortedTopics(ctx, adminClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	topicInfos := map[string]admin.TopicInfo{}
+	for _, topic := range topics {
+		topicInfos[topic.Name] = topic
 	}
 
-	return nil
+	replicaSizes, err := adminClient.GetReplicaSizes(ctx, admin.BrokerIDs(brokers), nil)
+	if err != nil {
+		log.Warnf(
+			"Could not get replica sizes (%+v), so partition sizes won't be considered",
+			err,
+		)
+		replicaSizes = []admin.ReplicaSize{}
+	}
+
+	planErrors := map[string]error{}
+	managed := map[string]config.TopicConfig{}
+
+	for _, topicConfig := range topicConfigs {
+		topicConfig.SetDefaults()
+		topicInfo, ok := topicInfos[topicConfig.Meta.Name]
+		if !ok {
+			planErrors[topicConfig.Meta.Name] = fmt.Errorf(
+				"Topic: %s does not exist in Kafka cluster",
+				topicConfig.Meta.Name,
+			)
+			continue
+		}
+		if err := rebalanceTopicCheck(topicConfig, topicInfo); err != nil {
+			planErrors[topicConfig.Meta.Name] = err
+			continue
+		}
+		managed[topicConfig.Meta.Name] = topicConfig
+	}
+
+	globalTopics := []apply.GlobalTopic{}
+	for _, topic := range topics {
+		globalTopic := apply.GlobalTopic{
+			Info:           topic,
+			PartitionSizes: admin.PartitionSizes(replicaSizes, topic.Name),
+		}
+		if topicConfig, ok := managed[topic.Name]; ok {
+			globalTopic.Managed = true
+			globalTopic.PlacementConfig = topicConfig.Spec.PlacementConfig
+		}
+		globalTopics = append(globalTopics, globalTopic)
+	}
+
+	plan, err := apply.OptimizeGlobal(
+		globalTopics,
+		apply.GlobalOptimizerConfig{
+			Brokers:       brokers,
+			BrokerWeights: clusterConfig.Spec.BrokerWeights.Weights(brokers),
+			MaxMoves:      rebalanceConfig.maxMoves,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof(
+		"Global rebalance plan:\n%s",
+		apply.FormatGlobalBrokerBalances(plan.Brokers),
+	)
+	log.Infof(
+		"Moving %d replica(s) (%s) and changing %d leader(s) in %d topic(s)",
+		plan.ReplicaMoves,
+		util.PrettyBytes(plan.BytesMoved),
+		plan.LeaderChanges,
+		len(plan.Topics),
+	)
+
+	reassignConfigs := []config.TopicConfig{}
+	for _, topicPlan := range plan.Topics {
+		// Only managed topics are moved; keep their settings and migration configs so that
+		// only the placement changes
+		reassignConfig := managed[topicPlan.Topic]
+		reassignConfig.Spec.PlacementConfig = apply.StaticPlacementConfig(
+			topicPlan.TargetAssignments,
+		)
+		reassignConfig.SetDefaults()
+		reassignConfigs = append(reassignConfigs, reassignConfig)
+	}
+
+	return reassignConfigs, planErrors, nil
 }
 
 // build ctx map for rebalance progress
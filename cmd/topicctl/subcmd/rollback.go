@@ -0,0 +1,229 @@
+package subcmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/cli"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var rollbackCmd = &cobra.Command{
+	Use:     "rollback [topic]",
+	Short:   "restore the settings and placement of a topic from a previous revision",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: rollbackPreRun,
+	RunE:    rollbackRun,
+}
+
+type rollbackCmdConfig struct {
+	brokerThrottleMBsOverride    int
+	dryRun                       bool
+	historyDir                   string
+	list                         bool
+	migrationStateDir            string
+	partitionBatchSizeOverride   int
+	retentionDropStepDurationStr string
+	revision                     int
+	skipConfirm                  bool
+	sleepLoopDuration            time.Duration
+
+	shared sharedOptions
+
+	retentionDropStepDuration time.Duration
+}
+
+var rollbackConfig rollbackCmdConfig
+
+func init() {
+	rollbackCmd.Flags().IntVar(
+		&rollbackConfig.brokerThrottleMBsOverride,
+		"broker-throttle-mb",
+		0,
+		"Broker throttle override (MB/sec)",
+	)
+	rollbackCmd.Flags().BoolVar(
+		&rollbackConfig.dryRun,
+		"dry-run",
+		false,
+		"Do a dry-run",
+	)
+	rollbackCmd.Flags().StringVar(
+		&rollbackConfig.historyDir,
+		"history-dir",
+		defaultHistoryDir(),
+		"Directory where the state of topics before they're changed is recorded",
+	)
+	rollbackCmd.Flags().BoolVar(
+		&rollbackConfig.list,
+		"list",
+		false,
+		"List the recorded revisions of the topic instead of rolling back",
+	)
+	rollbackCmd.Flags().StringVar(
+		&rollbackConfig.migrationStateDir,
+		"migration-state-dir",
+		defaultMigrationStateDir(),
+		"Directory for recording the progress of partition migrations; set to empty to disable",
+	)
+	rollbackCmd.Flags().IntVar(
+		&rollbackConfig.partitionBatchSizeOverride,
+		"partition-batch-size",
+		0,
+		"Partition batch size override",
+	)
+	rollbackCmd.Flags().StringVar(
+		&rollbackConfig.retentionDropStepDurationStr,
+		"retention-drop-step-duration",
+		"",
+		"Amount of time to use for retention drop steps",
+	)
+	rollbackCmd.Flags().IntVar(
+		&rollbackConfig.revision,
+		"to",
+		0,
+		"Revision to roll back to; defaults to the latest one",
+	)
+	rollbackCmd.Flags().BoolVar(
+		&rollbackConfig.skipConfirm,
+		"skip-confirm",
+		false,
+		"Skip confirmation prompts during rollback process",
+	)
+	rollbackCmd.Flags().DurationVar(
+		&rollbackConfig.sleepLoopDuration,
+		"sleep-loop-duration",
+		10*time.Second,
+		"Amount of time to wait between partition checks",
+	)
+
+	addSharedConfigOnlyFlags(rollbackCmd, &rollbackConfig.shared)
+	RootCmd.AddCommand(rollbackCmd)
+}
+
+func rollbackPreRun(cmd *cobra.Command, args []string) error {
+	if rollbackConfig.shared.clusterConfig == "" {
+		return errors.New("Requires arg --cluster-config (or) env variable TOPICCTL_CLUSTER_CONFIG")
+	}
+	if rollbackConfig.historyDir == "" {
+		return errors.New("Requires arg --history-dir (or) env variable TOPICCTL_HISTORY_DIR")
+	}
+	if rollbackConfig.revision < 0 {
+		return errors.New("--to must be a positive revision number")
+	}
+
+	if rollbackConfig.retentionDropStepDurationStr != "" {
+		var err error
+		rollbackConfig.retentionDropStepDuration, err = time.ParseDuration(
+			rollbackConfig.retentionDropStepDurationStr,
+		)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func rollbackRun(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	topicName := args[0]
+
+	clusterConfig, err := config.LoadClusterFile(
+		rollbackConfig.shared.clusterConfig,
+		rollbackConfig.shared.expandEnv,
+	)
+	if err != nil {
+		return err
+	}
+
+	topicDir := apply.TopicHistoryDir(
+		rollbackConfig.historyDir,
+		clusterConfig.Meta.Name,
+		clusterConfig.Meta.Environment,
+		clusterConfig.Meta.Region,
+		topicName,
+	)
+
+	if rollbackConfig.list {
+		revisions, err := apply.ListTopicRevisions(topicDir)
+		if err != nil {
+			return err
+		}
+		if len(revisions) == 0 {
+			return fmt.Errorf("No revisions found for topic %s in %s", topicName, topicDir)
+		}
+		log.Infof(
+			"Revisions for topic %s:\n%s",
+			topicName,
+			apply.FormatTopicRevisions(revisions),
+		)
+		return nil
+	}
+
+	revision, err := apply.GetTopicRevision(topicDir, rollbackConfig.revision)
+	if err != nil {
+		return err
+	}
+
+	adminClient, err := clusterConfig.NewAdminClient(
+		ctx,
+		nil,
+		config.AdminClientOpts{
+			ReadOnly:                  rollbackConfig.dryRun,
+			UsernameOverride:          rollbackConfig.shared.saslUsername,
+			PasswordOverride:          rollbackConfig.shared.saslPassword,
+			SecretsManagerArnOverride: rollbackConfig.shared.saslSecretsManagerArn,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, false)
+	changes, err := cliRunner.RollbackTopic(
+		ctx,
+		revision,
+		apply.TopicApplierConfig{
+			BrokerThrottleMBsOverride:  rollbackConfig.brokerThrottleMBsOverride,
+			ClusterConfig:              clusterConfig,
+			DryRun:                     rollbackConfig.dryRun,
+			PartitionBatchSizeOverride: rollbackConfig.partitionBatchSizeOverride,
+			RetentionDropStepDuration:  rollbackConfig.retentionDropStepDuration,
+			SkipConfirm:                rollbackConfig.skipConfirm,
+			SleepLoopDuration:          rollbackConfig.sleepLoopDuration,
+			MigrationStateDir:          rollbackConfig.migrationStateDir,
+			// Rollbacks are recorded too so that they can also be reverted
+			HistoryDir: rollbackConfig.historyDir,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if isStructNotNil(changes) {
+		if _, err := printJson(changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
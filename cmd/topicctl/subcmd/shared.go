@@ -3,11 +3,15 @@ package subcmd
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/hashicorp/go-multierror"
 	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply"
 	"github.com/segmentio/topicctl/pkg/config"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -21,6 +25,7 @@ type sharedOptions struct {
 	saslPassword          string
 	saslUsername          string
 	saslSecretsManagerArn string
+	snapshot              string
 	tlsCACert             string
 	tlsCert               string
 	tlsEnabled            bool
@@ -34,12 +39,15 @@ type sharedOptions struct {
 func (s sharedOptions) validate() error {
 	var err error
 
-	if s.clusterConfig == "" && s.zkAddr == "" && s.brokerAddr == "" {
+	if s.clusterConfig == "" && s.zkAddr == "" && s.brokerAddr == "" && s.snapshot == "" {
 		err = multierror.Append(
 			err,
-			errors.New("Must set either broker-addr, cluster-config, or zk-addr"),
+			errors.New("Must set either broker-addr, cluster-config, snapshot, or zk-addr"),
 		)
 	}
+	if s.snapshot != "" && (s.zkAddr != "" || s.brokerAddr != "") {
+		log.Warn("Broker and zk flags are ignored when using snapshot")
+	}
 
 	if s.clusterConfig != "" {
 		clusterConfig, clusterConfigErr := config.LoadClusterFile(s.clusterConfig, s.expandEnv)
@@ -110,7 +118,24 @@ func (s sharedOptions) getAdminClient(
 	sess *session.Session,
 	readOnly bool,
 ) (admin.Client, error) {
-	if s.clusterConfig != "" {
+	if s.snapshot != "" {
+		var expectedClusterID string
+
+		if s.clusterConfig != "" {
+			clusterConfig, err := config.LoadClusterFile(s.clusterConfig, s.expandEnv)
+			if err != nil {
+				return nil, err
+			}
+			expectedClusterID = clusterConfig.Spec.ClusterID
+		}
+
+		return admin.NewSnapshotAdminClient(
+			admin.SnapshotAdminClientConfig{
+				Path:              s.snapshot,
+				ExpectedClusterID: expectedClusterID,
+			},
+		)
+	} else if s.clusterConfig != "" {
 		clusterConfig, err := config.LoadClusterFile(s.clusterConfig, s.expandEnv)
 		if err != nil {
 			return nil, err
@@ -181,6 +206,123 @@ func (s sharedOptions) getAdminClient(
 	}
 }
 
+// getClusterAdminClient returns an admin client for the argument cluster config. If a
+// snapshot is set, then the client reads from it instead of the cluster.
+func (s sharedOptions) getClusterAdminClient(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	readOnly bool,
+) (admin.Client, error) {
+	if s.snapshot != "" {
+		return admin.NewSnapshotAdminClient(
+			admin.SnapshotAdminClientConfig{
+				Path:              s.snapshot,
+				ExpectedClusterID: clusterConfig.Spec.ClusterID,
+			},
+		)
+	}
+
+	return clusterConfig.NewAdminClient(
+		ctx,
+		nil,
+		config.AdminClientOpts{
+			ReadOnly:                  readOnly,
+			UsernameOverride:          s.saslUsername,
+			PasswordOverride:          s.saslPassword,
+			SecretsManagerArnOverride: s.saslSecretsManagerArn,
+		},
+	)
+}
+
+// sharedThrottleBytes returns the broker throttle budget to share across migrations that are
+// scheduled together. Unlike the throttles for a single topic, this doesn't depend on the
+// topic migration configs.
+func sharedThrottleBytes(brokerThrottleMBsOverride int, clusterConfig config.ClusterConfig) int64 {
+	if brokerThrottleMBsOverride > 0 {
+		return int64(brokerThrottleMBsOverride) * 1000000
+	} else if clusterConfig.Spec.DefaultThrottleMB > 0 {
+		return clusterConfig.Spec.DefaultThrottleMB * 1000000
+	}
+
+	// Default to 120MB / sec, matching the topic applier
+	return 120000000
+}
+
+// validateRebalanceStrategy checks the flags that control what rebalances even out across the
+// brokers.
+func validateRebalanceStrategy(strategy string, diskTolerance float64) error {
+	if !slices.Contains(apply.AllRebalanceStrategies, apply.RebalanceStrategy(strategy)) {
+		return fmt.Errorf(
+			"Unrecognized rebalance strategy %s; must be one of %+v",
+			strategy,
+			apply.AllRebalanceStrategies,
+		)
+	}
+	if diskTolerance <= 0 {
+		return errors.New("Disk tolerance must be > 0")
+	}
+	return nil
+}
+
+// defaultMigrationStateDir returns the directory where the progress of placement migrations
+// is recorded by default.
+func defaultMigrationStateDir() string {
+	if stateDir := os.Getenv("TOPICCTL_MIGRATION_STATE_DIR"); stateDir != "" {
+		return stateDir
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".topicctl", "migrations")
+}
+
+// defaultHistoryDir returns the directory where the state of topics before they're changed
+// is recorded by default.
+func defaultHistoryDir() string {
+	if historyDir := os.Getenv("TOPICCTL_HISTORY_DIR"); historyDir != "" {
+		return historyDir
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".topicctl", "history")
+}
+
+// defaultMaintenanceStateDir returns the directory where the original replica orderings of
+// drained brokers are recorded by default.
+func defaultMaintenanceStateDir() string {
+	if stateDir := os.Getenv("TOPICCTL_MAINTENANCE_STATE_DIR"); stateDir != "" {
+		return stateDir
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(homeDir, ".topicctl", "maintenance")
+}
+
+// openEventWriter opens the destination for structured progress events; "-" is stdout. It
+// returns a nil writer if the path is empty. The returned function closes the destination.
+func openEventWriter(path string) (*apply.EventWriter, func(), error) {
+	if path == "" {
+		return nil, func() {}, nil
+	}
+	if path == "-" {
+		return apply.NewEventWriter(os.Stdout), func() {}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apply.NewEventWriter(file), func() { file.Close() }, nil
+}
+
 func addSharedFlags(cmd *cobra.Command, options *sharedOptions) {
 	cmd.PersistentFlags().StringVarP(
 		&options.brokerAddr,
@@ -304,3 +446,15 @@ func addSharedConfigOnlyFlags(cmd *cobra.Command, options *sharedOptions) {
 		"SASL username if using SASL; will override value set in cluster config",
 	)
 }
+
+// addSnapshotFlag adds the flag for reading the state of the cluster from a snapshot
+// instead of the cluster itself. It should only be added to commands that can run without
+// changing the cluster.
+func addSnapshotFlag(cmd *cobra.Command, options *sharedOptions) {
+	cmd.PersistentFlags().StringVar(
+		&options.snapshot,
+		"snapshot",
+		"",
+		"Path to a cluster snapshot to read from instead of the cluster; see 'snapshot export'",
+	)
+}
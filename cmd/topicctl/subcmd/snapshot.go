@@ -0,0 +1,103 @@
+package subcmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/segmentio/topicctl/pkg/cli"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot [command]",
+	Short: "export offline snapshots of a cluster",
+	Long: strings.Join(
+		[]string{
+			"Exports offline snapshots of the state of a cluster.",
+			"Snapshots can be used in place of the cluster with the --snapshot flag of the get,",
+			"check, apply --dry-run, and rebalance --dry-run commands.",
+		},
+		"\n",
+	),
+}
+
+type snapshotCmdConfig struct {
+	includeGroups bool
+	includeSizes  bool
+	output        string
+
+	shared sharedOptions
+}
+
+var snapshotConfig snapshotCmdConfig
+
+func init() {
+	snapshotCmd.AddCommand(snapshotExportCmd())
+	RootCmd.AddCommand(snapshotCmd)
+}
+
+func snapshotExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export a snapshot of the cluster to a JSON file",
+		Long: strings.Join(
+			[]string{
+				"Exports the brokers, racks, topics, partition assignments, ISRs, and configs in a",
+				"cluster to a JSON file. Replica sizes and consumer group offsets can optionally be",
+				"included as well.",
+			},
+			"\n",
+		),
+		Args:    cobra.NoArgs,
+		PreRunE: snapshotExportPreRun,
+		RunE:    snapshotExportRun,
+	}
+
+	cmd.Flags().BoolVar(
+		&snapshotConfig.includeGroups,
+		"include-groups",
+		false,
+		"Include the offsets of each consumer group",
+	)
+	cmd.Flags().BoolVar(
+		&snapshotConfig.includeSizes,
+		"include-sizes",
+		false,
+		"Include the on-disk size of each replica",
+	)
+	cmd.Flags().StringVarP(
+		&snapshotConfig.output,
+		"output",
+		"o",
+		"-",
+		"Path to write the snapshot to; '-' is stdout",
+	)
+
+	addSharedFlags(cmd, &snapshotConfig.shared)
+	return cmd
+}
+
+func snapshotExportPreRun(cmd *cobra.Command, args []string) error {
+	return snapshotConfig.shared.validate()
+}
+
+func snapshotExportRun(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	sess := session.Must(session.NewSession())
+
+	adminClient, err := snapshotConfig.shared.getAdminClient(ctx, sess, true)
+	if err != nil {
+		return err
+	}
+	defer adminClient.Close()
+
+	cliRunner := cli.NewCLIRunner(adminClient, log.Infof, !noSpinner)
+	return cliRunner.ExportSnapshot(
+		ctx,
+		snapshotConfig.output,
+		snapshotConfig.includeSizes,
+		snapshotConfig.includeGroups,
+	)
+}
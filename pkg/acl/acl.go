@@ -5,21 +5,52 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/config"
 	"github.com/segmentio/topicctl/pkg/util"
+	"github.com/segmentio/topicctl/pkg/zk"
 	log "github.com/sirupsen/logrus"
 )
 
+// ApplyScope determines which of the ACLs in the cluster are reconciled by an apply.
+type ApplyScope string
+
+const (
+	// ApplyScopePrincipal reconciles all of the ACLs for each declared principal.
+	ApplyScopePrincipal ApplyScope = "principal"
+
+	// ApplyScopeResource reconciles all of the ACLs for each declared resource, regardless of
+	// their principals.
+	ApplyScopeResource ApplyScope = "resource"
+)
+
+// AllApplyScopes contains all of the supported apply scopes.
+var AllApplyScopes = []ApplyScope{
+	ApplyScopePrincipal,
+	ApplyScopeResource,
+}
+
 // ACLCreatorConfig contains the configuration for an ACL admin.
 type ACLAdminConfig struct {
 	ClusterConfig config.ClusterConfig
 	DryRun        bool
 	SkipConfirm   bool
+	Destructive   bool
 	ACLConfig     config.ACLConfig
+
+	// ApplyConfigs are the ACL configs that are reconciled together by Apply. An ACL in the
+	// cluster is only removed if it isn't declared in any of them. If empty, ACLConfig is
+	// used.
+	ApplyConfigs []config.ACLConfig
+
+	// ApplyScope determines which ACLs in the cluster are compared with the declared ones.
+	// If empty, ApplyScopePrincipal is used.
+	ApplyScope ApplyScope
 }
 
 // ACLAdmin executes operations on ACLs by comparing the current ACLs with the desired ACLs.
@@ -29,6 +60,7 @@ type ACLAdmin struct {
 
 	clusterConfig config.ClusterConfig
 	aclConfig     config.ACLConfig
+	applyConfigs  []config.ACLConfig
 }
 
 func NewACLAdmin(
@@ -40,11 +72,17 @@ func NewACLAdmin(
 		return nil, fmt.Errorf("ACLs are not supported by this cluster")
 	}
 
+	applyConfigs := aclAdminConfig.ApplyConfigs
+	if len(applyConfigs) == 0 {
+		applyConfigs = []config.ACLConfig{aclAdminConfig.ACLConfig}
+	}
+
 	return &ACLAdmin{
 		config:        aclAdminConfig,
 		adminClient:   adminClient,
 		clusterConfig: aclAdminConfig.ClusterConfig,
 		aclConfig:     aclAdminConfig.ACLConfig,
+		applyConfigs:  applyConfigs,
 	}, nil
 }
 
@@ -64,6 +102,14 @@ func (a *ACLAdmin) Create(ctx context.Context) error {
 		return err
 	}
 
+	if len(a.aclConfig.Spec.Roles) > 0 {
+		log.Infof(
+			"Expanded %d role(s) into ACLs:\n%s",
+			len(a.aclConfig.Spec.Roles),
+			formatNewACLsConfig(a.aclConfig.RoleACLEntries()),
+		)
+	}
+
 	log.Info("Checking if ACLs already exists...")
 
 	acls := a.aclConfig.ToNewACLEntries()
@@ -131,6 +177,300 @@ func (a *ACLAdmin) Create(ctx context.Context) error {
 	return nil
 }
 
+// Apply reconciles the ACLs in the cluster with the ACL configs. ACLs that are declared
+// but missing are created. ACLs that exist in the cluster for one of the declared principals
+// (or resources, depending on the scope) but aren't declared in any of the configs are
+// removed, provided that the Destructive option is set.
+func (a *ACLAdmin) Apply(ctx context.Context) error {
+	log.Info("Validating configs...")
+
+	if err := a.clusterConfig.Validate(); err != nil {
+		return err
+	}
+
+	desiredACLs := []kafka.ACLEntry{}
+
+	for _, aclConfig := range a.applyConfigs {
+		if err := aclConfig.Validate(); err != nil {
+			return err
+		}
+
+		if err := config.CheckConsistency(aclConfig.Meta, a.clusterConfig); err != nil {
+			return err
+		}
+
+		if len(aclConfig.Spec.Roles) > 0 {
+			log.Infof(
+				"Expanded %d role(s) in %s into ACLs:\n%s",
+				len(aclConfig.Spec.Roles),
+				aclConfig.Meta.Name,
+				formatNewACLsConfig(aclConfig.RoleACLEntries()),
+			)
+		}
+
+		desiredACLs = append(desiredACLs, aclConfig.ToNewACLEntries()...)
+	}
+
+	currACLs, err := a.scopedACLs(ctx, desiredACLs)
+	if err != nil {
+		return err
+	}
+
+	additions, removals := DiffACLs(desiredACLs, currACLs)
+
+	if len(additions) == 0 && len(removals) == 0 {
+		log.Info("ACLs in cluster match the config; no changes needed")
+		return nil
+	}
+
+	log.Infof(
+		"Found %d ACL(s) to add and %d ACL(s) to remove:\n%s",
+		len(additions),
+		len(removals),
+		FormatACLDiffs(additions, removals),
+	)
+
+	if !a.config.Destructive && len(removals) > 0 {
+		log.Infof(
+			"Not removing %d undeclared ACL(s) because destructive is set to false",
+			len(removals),
+		)
+		removals = nil
+
+		if len(additions) == 0 {
+			return nil
+		}
+	}
+
+	if a.config.DryRun {
+		log.Info("Skipping update because dryRun is set to true")
+		return nil
+	}
+
+	lock, path, err := a.acquireLock(ctx)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		defer func() {
+			log.Infof("Releasing ACL lock: %s", path)
+			lock.Unlock()
+		}()
+	}
+
+	ok, _ := util.Confirm("OK to apply?", a.config.SkipConfirm)
+	if !ok {
+		return errors.New("Stopping because of user response")
+	}
+
+	if len(additions) > 0 {
+		log.Infof("Creating %d ACL(s)", len(additions))
+		if err := a.adminClient.CreateACLs(ctx, additions); err != nil {
+			return fmt.Errorf("error creating new ACLs: %v", err)
+		}
+	}
+
+	if len(removals) > 0 {
+		log.Infof("Removing %d ACL(s)", len(removals))
+
+		filters := []kafka.DeleteACLsFilter{}
+		for _, removal := range removals {
+			filters = append(filters, kafka.DeleteACLsFilter{
+				ResourceTypeFilter:        removal.ResourceType,
+				ResourceNameFilter:        removal.ResourceName,
+				ResourcePatternTypeFilter: removal.ResourcePatternType,
+				PrincipalFilter:           removal.Principal,
+				HostFilter:                removal.Host,
+				Operation:                 removal.Operation,
+				PermissionType:            removal.PermissionType,
+			})
+		}
+
+		resp, err := a.adminClient.DeleteACLs(ctx, filters)
+		if err != nil {
+			return fmt.Errorf("error removing ACLs: %v", err)
+		}
+
+		respErrors := []error{}
+		for _, result := range resp.Results {
+			if result.Error != nil {
+				respErrors = append(respErrors, result.Error)
+			}
+			for _, matchingACL := range result.MatchingACLs {
+				if matchingACL.Error != nil {
+					respErrors = append(respErrors, matchingACL.Error)
+				}
+			}
+		}
+		if len(respErrors) > 0 {
+			return fmt.Errorf("Got errors while removing ACLs: \n%+v", respErrors)
+		}
+	}
+
+	return nil
+}
+
+// DiffACLs compares the desired ACLs from a config with the current ACLs in the
+// cluster. It returns the ACLs that need to be added and the ones that exist in the
+// cluster but aren't desired.
+func DiffACLs(
+	desired []kafka.ACLEntry,
+	curr []admin.ACLInfo,
+) ([]kafka.ACLEntry, []kafka.ACLEntry) {
+	desiredSet := map[kafka.ACLEntry]struct{}{}
+	for _, entry := range desired {
+		desiredSet[entry] = struct{}{}
+	}
+
+	currSet := map[kafka.ACLEntry]struct{}{}
+	removals := []kafka.ACLEntry{}
+
+	for _, info := range curr {
+		entry := aclInfoToEntry(info)
+		if _, ok := currSet[entry]; ok {
+			continue
+		}
+		currSet[entry] = struct{}{}
+
+		if _, ok := desiredSet[entry]; !ok {
+			removals = append(removals, entry)
+		}
+	}
+
+	additions := []kafka.ACLEntry{}
+	for _, entry := range desired {
+		if _, ok := currSet[entry]; !ok {
+			additions = append(additions, entry)
+			// Don't add duplicates
+			currSet[entry] = struct{}{}
+		}
+	}
+
+	return additions, removals
+}
+
+func aclInfoToEntry(info admin.ACLInfo) kafka.ACLEntry {
+	return kafka.ACLEntry{
+		ResourceType:        kafka.ResourceType(info.ResourceType),
+		ResourceName:        info.ResourceName,
+		ResourcePatternType: kafka.PatternType(info.PatternType),
+		Principal:           info.Principal,
+		Host:                info.Host,
+		Operation:           kafka.ACLOperationType(info.Operation),
+		PermissionType:      kafka.ACLPermissionType(info.PermissionType),
+	}
+}
+
+// scopedACLs gets the ACLs in the cluster that the argument desired ACLs are reconciled
+// against, based on the apply scope.
+func (a *ACLAdmin) scopedACLs(
+	ctx context.Context,
+	desiredACLs []kafka.ACLEntry,
+) ([]admin.ACLInfo, error) {
+	currACLs := []admin.ACLInfo{}
+
+	if a.config.ApplyScope == ApplyScopeResource {
+		log.Info("Fetching current ACLs for the resources in the configs...")
+
+		for _, resource := range aclResources(desiredACLs) {
+			resourceACLs, err := a.adminClient.GetACLs(ctx, kafka.ACLFilter{
+				ResourceTypeFilter:        resource.ResourceType,
+				ResourceNameFilter:        resource.ResourceName,
+				ResourcePatternTypeFilter: resource.ResourcePatternType,
+				Operation:                 kafka.ACLOperationTypeAny,
+				PermissionType:            kafka.ACLPermissionTypeAny,
+			})
+			if err != nil {
+				return nil, fmt.Errorf(
+					"error fetching ACLs for resource %s: %v",
+					resource.ResourceName,
+					err,
+				)
+			}
+			currACLs = append(currACLs, resourceACLs...)
+		}
+
+		return currACLs, nil
+	}
+
+	log.Info("Fetching current ACLs for the principals in the configs...")
+
+	for _, principal := range aclPrincipals(desiredACLs) {
+		principalACLs, err := a.adminClient.GetACLs(ctx, kafka.ACLFilter{
+			ResourceTypeFilter:        kafka.ResourceTypeAny,
+			ResourcePatternTypeFilter: kafka.PatternTypeAny,
+			PrincipalFilter:           principal,
+			Operation:                 kafka.ACLOperationTypeAny,
+			PermissionType:            kafka.ACLPermissionTypeAny,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error fetching ACLs for principal %s: %v", principal, err)
+		}
+		currACLs = append(currACLs, principalACLs...)
+	}
+
+	return currACLs, nil
+}
+
+func aclPrincipals(acls []kafka.ACLEntry) []string {
+	principals := []string{}
+	seen := map[string]struct{}{}
+
+	for _, acl := range acls {
+		if _, ok := seen[acl.Principal]; ok {
+			continue
+		}
+		seen[acl.Principal] = struct{}{}
+		principals = append(principals, acl.Principal)
+	}
+
+	return principals
+}
+
+// aclResources returns the distinct resources in the argument ACLs. Only the resource fields
+// of the results are set.
+func aclResources(acls []kafka.ACLEntry) []kafka.ACLEntry {
+	resources := []kafka.ACLEntry{}
+	seen := map[kafka.ACLEntry]struct{}{}
+
+	for _, acl := range acls {
+		resource := kafka.ACLEntry{
+			ResourceType:        acl.ResourceType,
+			ResourceName:        acl.ResourceName,
+			ResourcePatternType: acl.ResourcePatternType,
+		}
+		if _, ok := seen[resource]; ok {
+			continue
+		}
+		seen[resource] = struct{}{}
+		resources = append(resources, resource)
+	}
+
+	return resources
+}
+
+func (a *ACLAdmin) acquireLock(ctx context.Context) (zk.Lock, string, error) {
+	if a.config.DryRun || a.clusterConfig.Spec.ZKLockPath == "" {
+		return nil, "", nil
+	}
+
+	lockPath := filepath.Join(
+		a.clusterConfig.Spec.ZKLockPath,
+		fmt.Sprintf(
+			"%s-%s-%s-acls",
+			a.applyConfigs[0].Meta.Cluster,
+			a.applyConfigs[0].Meta.Environment,
+			a.applyConfigs[0].Meta.Region,
+		),
+	)
+	log.Infof("Acquiring ACL lock: %s", lockPath)
+	lockCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	lock, err := a.adminClient.AcquireLock(lockCtx, lockPath)
+	return lock, lockPath, err
+}
+
 // formatNewACLsConfig generates a pretty string representation of kafka-go
 // ACL configurations.
 func formatNewACLsConfig(config []kafka.ACLEntry) string {
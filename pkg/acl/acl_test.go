@@ -10,6 +10,7 @@ import (
 	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/config"
 	"github.com/segmentio/topicctl/pkg/util"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -689,3 +690,330 @@ func testACLAdmin(
 	require.NoError(t, err)
 	return aclAdmin
 }
+
+func TestDiffACLs(t *testing.T) {
+	entry := func(
+		name string,
+		operation kafka.ACLOperationType,
+	) kafka.ACLEntry {
+		return kafka.ACLEntry{
+			ResourceType:        kafka.ResourceTypeTopic,
+			ResourceName:        name,
+			ResourcePatternType: kafka.PatternTypeLiteral,
+			Principal:           "User:Alice",
+			Host:                "*",
+			Operation:           operation,
+			PermissionType:      kafka.ACLPermissionTypeAllow,
+		}
+	}
+	info := func(
+		name string,
+		operation kafka.ACLOperationType,
+	) admin.ACLInfo {
+		return admin.ACLInfo{
+			ResourceType:   admin.ResourceType(kafka.ResourceTypeTopic),
+			ResourceName:   name,
+			PatternType:    admin.PatternType(kafka.PatternTypeLiteral),
+			Principal:      "User:Alice",
+			Host:           "*",
+			Operation:      admin.ACLOperationType(operation),
+			PermissionType: admin.ACLPermissionType(kafka.ACLPermissionTypeAllow),
+		}
+	}
+
+	type testCase struct {
+		description  string
+		desired      []kafka.ACLEntry
+		curr         []admin.ACLInfo
+		expAdditions []kafka.ACLEntry
+		expRemovals  []kafka.ACLEntry
+	}
+
+	testCases := []testCase{
+		{
+			description: "no changes",
+			desired: []kafka.ACLEntry{
+				entry("topic1", kafka.ACLOperationTypeRead),
+			},
+			curr: []admin.ACLInfo{
+				info("topic1", kafka.ACLOperationTypeRead),
+			},
+			expAdditions: []kafka.ACLEntry{},
+			expRemovals:  []kafka.ACLEntry{},
+		},
+		{
+			description: "additions and removals",
+			desired: []kafka.ACLEntry{
+				entry("topic1", kafka.ACLOperationTypeRead),
+				entry("topic2", kafka.ACLOperationTypeRead),
+				entry("topic2", kafka.ACLOperationTypeRead),
+			},
+			curr: []admin.ACLInfo{
+				info("topic1", kafka.ACLOperationTypeRead),
+				info("topic1", kafka.ACLOperationTypeWrite),
+			},
+			expAdditions: []kafka.ACLEntry{
+				entry("topic2", kafka.ACLOperationTypeRead),
+			},
+			expRemovals: []kafka.ACLEntry{
+				entry("topic1", kafka.ACLOperationTypeWrite),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		additions, removals := DiffACLs(testCase.desired, testCase.curr)
+		assert.Equal(t, testCase.expAdditions, additions, testCase.description)
+		assert.Equal(t, testCase.expRemovals, removals, testCase.description)
+	}
+}
+
+func TestApplyACLs(t *testing.T) {
+	if !util.CanTestBrokerAdminSecurity() {
+		t.Skip("Skipping because KAFKA_TOPICS_TEST_BROKER_ADMIN_SECURITY is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	principal := util.RandomString("User:acl-apply-", 6)
+	topicName := util.RandomString("acl-apply-", 6)
+
+	aclConfig := config.ACLConfig{
+		Meta: config.ResourceMeta{
+			Name:        "test-acl",
+			Cluster:     "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+		Spec: config.ACLSpec{
+			ACLs: []config.ACL{
+				{
+					Resource: config.ACLResource{
+						Type:        kafka.ResourceTypeTopic,
+						Name:        topicName,
+						PatternType: kafka.PatternTypeLiteral,
+						Principal:   principal,
+						Host:        "*",
+						Permission:  kafka.ACLPermissionTypeAllow,
+					},
+					Operations: []kafka.ACLOperationType{
+						kafka.ACLOperationTypeRead,
+						kafka.ACLOperationTypeDescribe,
+					},
+				},
+			},
+		},
+	}
+	aclAdmin := testACLAdmin(ctx, t, aclConfig)
+	defer aclAdmin.adminClient.Close()
+
+	defer func() {
+		_, err := aclAdmin.adminClient.GetConnector().KafkaClient.DeleteACLs(ctx,
+			&kafka.DeleteACLsRequest{
+				Filters: []kafka.DeleteACLsFilter{
+					{
+						ResourceTypeFilter:        kafka.ResourceTypeTopic,
+						ResourceNameFilter:        topicName,
+						ResourcePatternTypeFilter: kafka.PatternTypeLiteral,
+						PrincipalFilter:           principal,
+						HostFilter:                "*",
+						PermissionType:            kafka.ACLPermissionTypeAllow,
+						Operation:                 kafka.ACLOperationTypeAny,
+					},
+				},
+			},
+		)
+
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to clean up ACL, err: %v", err))
+		}
+	}()
+
+	err := aclAdmin.Apply(ctx)
+	require.NoError(t, err)
+
+	getFilter := kafka.ACLFilter{
+		ResourceTypeFilter:        kafka.ResourceTypeTopic,
+		ResourceNameFilter:        topicName,
+		ResourcePatternTypeFilter: kafka.PatternTypeLiteral,
+		PrincipalFilter:           principal,
+		HostFilter:                "*",
+		PermissionType:            kafka.ACLPermissionTypeAllow,
+		Operation:                 kafka.ACLOperationTypeAny,
+	}
+	acls, err := aclAdmin.adminClient.GetACLs(ctx, getFilter)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(acls))
+
+	// Drop the describe operation from the config; this should only be removed from the
+	// cluster if destructive is set.
+	aclAdmin.applyConfigs[0].Spec.ACLs[0].Operations = []kafka.ACLOperationType{
+		kafka.ACLOperationTypeRead,
+	}
+
+	err = aclAdmin.Apply(ctx)
+	require.NoError(t, err)
+	acls, err = aclAdmin.adminClient.GetACLs(ctx, getFilter)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(acls))
+
+	aclAdmin.config.Destructive = true
+	err = aclAdmin.Apply(ctx)
+	require.NoError(t, err)
+	acls, err = aclAdmin.adminClient.GetACLs(ctx, getFilter)
+	require.NoError(t, err)
+	require.Equal(t, []admin.ACLInfo{
+		{
+			ResourceType:   admin.ResourceType(kafka.ResourceTypeTopic),
+			ResourceName:   topicName,
+			PatternType:    admin.PatternType(kafka.PatternTypeLiteral),
+			Principal:      principal,
+			Host:           "*",
+			Operation:      admin.ACLOperationType(kafka.ACLOperationTypeRead),
+			PermissionType: admin.ACLPermissionType(kafka.ACLPermissionTypeAllow),
+		},
+	}, acls)
+}
+
+func TestApplyACLsMultipleConfigs(t *testing.T) {
+	if !util.CanTestBrokerAdminSecurity() {
+		t.Skip("Skipping because KAFKA_TOPICS_TEST_BROKER_ADMIN_SECURITY is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	principal := util.RandomString("User:acl-apply-", 6)
+	topicName1 := util.RandomString("acl-apply-", 6)
+	topicName2 := util.RandomString("acl-apply-", 6)
+
+	topicACLConfig := func(topicName string) config.ACLConfig {
+		return config.ACLConfig{
+			Meta: config.ResourceMeta{
+				Name:        topicName,
+				Cluster:     "test-cluster",
+				Region:      "test-region",
+				Environment: "test-environment",
+			},
+			Spec: config.ACLSpec{
+				ACLs: []config.ACL{
+					{
+						Resource: config.ACLResource{
+							Type:        kafka.ResourceTypeTopic,
+							Name:        topicName,
+							PatternType: kafka.PatternTypeLiteral,
+							Principal:   principal,
+							Host:        "*",
+							Permission:  kafka.ACLPermissionTypeAllow,
+						},
+						Operations: []kafka.ACLOperationType{
+							kafka.ACLOperationTypeRead,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	aclAdmin := testACLAdmin(ctx, t, topicACLConfig(topicName1))
+	defer aclAdmin.adminClient.Close()
+
+	// Both configs declare ACLs for the same principal, so applying them together
+	// destructively shouldn't remove the ACLs from either one.
+	aclAdmin.applyConfigs = []config.ACLConfig{
+		topicACLConfig(topicName1),
+		topicACLConfig(topicName2),
+	}
+	aclAdmin.config.Destructive = true
+
+	defer func() {
+		_, err := aclAdmin.adminClient.GetConnector().KafkaClient.DeleteACLs(ctx,
+			&kafka.DeleteACLsRequest{
+				Filters: []kafka.DeleteACLsFilter{
+					{
+						ResourceTypeFilter:        kafka.ResourceTypeAny,
+						ResourcePatternTypeFilter: kafka.PatternTypeAny,
+						PrincipalFilter:           principal,
+						PermissionType:            kafka.ACLPermissionTypeAny,
+						Operation:                 kafka.ACLOperationTypeAny,
+					},
+				},
+			},
+		)
+
+		if err != nil {
+			t.Fatal(fmt.Errorf("failed to clean up ACL, err: %v", err))
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		err := aclAdmin.Apply(ctx)
+		require.NoError(t, err)
+
+		acls, err := aclAdmin.adminClient.GetACLs(ctx, kafka.ACLFilter{
+			ResourceTypeFilter:        kafka.ResourceTypeAny,
+			ResourcePatternTypeFilter: kafka.PatternTypeAny,
+			PrincipalFilter:           principal,
+			PermissionType:            kafka.ACLPermissionTypeAny,
+			Operation:                 kafka.ACLOperationTypeAny,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 2, len(acls))
+	}
+}
+
+func TestACLResources(t *testing.T) {
+	acls := []kafka.ACLEntry{
+		{
+			ResourceType:        kafka.ResourceTypeTopic,
+			ResourceName:        "topic1",
+			ResourcePatternType: kafka.PatternTypeLiteral,
+			Principal:           "User:Alice",
+			Operation:           kafka.ACLOperationTypeRead,
+		},
+		{
+			ResourceType:        kafka.ResourceTypeTopic,
+			ResourceName:        "topic1",
+			ResourcePatternType: kafka.PatternTypeLiteral,
+			Principal:           "User:Bob",
+			Operation:           kafka.ACLOperationTypeWrite,
+		},
+		{
+			ResourceType:        kafka.ResourceTypeTopic,
+			ResourceName:        "topic1",
+			ResourcePatternType: kafka.PatternTypePrefixed,
+			Principal:           "User:Alice",
+			Operation:           kafka.ACLOperationTypeRead,
+		},
+		{
+			ResourceType:        kafka.ResourceTypeGroup,
+			ResourceName:        "topic1",
+			ResourcePatternType: kafka.PatternTypeLiteral,
+			Principal:           "User:Alice",
+			Operation:           kafka.ACLOperationTypeRead,
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]kafka.ACLEntry{
+			{
+				ResourceType:        kafka.ResourceTypeTopic,
+				ResourceName:        "topic1",
+				ResourcePatternType: kafka.PatternTypeLiteral,
+			},
+			{
+				ResourceType:        kafka.ResourceTypeTopic,
+				ResourceName:        "topic1",
+				ResourcePatternType: kafka.PatternTypePrefixed,
+			},
+			{
+				ResourceType:        kafka.ResourceTypeGroup,
+				ResourceName:        "topic1",
+				ResourcePatternType: kafka.PatternTypeLiteral,
+			},
+		},
+		aclResources(acls),
+	)
+}
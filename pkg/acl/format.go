@@ -0,0 +1,81 @@
+package acl
+
+import (
+	"bytes"
+
+	"github.com/fatih/color"
+	"github.com/olekukonko/tablewriter"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/util"
+)
+
+// FormatACLDiffs generates a pretty table that shows the ACLs that will be added to
+// and removed from the cluster.
+func FormatACLDiffs(additions []kafka.ACLEntry, removals []kafka.ACLEntry) string {
+	buf := &bytes.Buffer{}
+
+	headers := []string{
+		"Action",
+		"Resource Type",
+		"Pattern Type",
+		"Resource Name",
+		"Principal",
+		"Host",
+		"Operation",
+		"Permission Type",
+	}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(headers)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	addStr := "add"
+	removeStr := "remove"
+	if util.InTerminal() {
+		addStr = color.New(color.FgGreen).Sprint(addStr)
+		removeStr = color.New(color.FgRed).Sprint(removeStr)
+	}
+
+	for _, acl := range additions {
+		table.Append(aclDiffRow(addStr, acl))
+	}
+	for _, acl := range removals {
+		table.Append(aclDiffRow(removeStr, acl))
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+func aclDiffRow(action string, acl kafka.ACLEntry) []string {
+	return []string{
+		action,
+		acl.ResourceType.String(),
+		acl.ResourcePatternType.String(),
+		acl.ResourceName,
+		acl.Principal,
+		acl.Host,
+		acl.Operation.String(),
+		acl.PermissionType.String(),
+	}
+}
@@ -399,6 +399,15 @@ func (c *BrokerAdminClient) GetTopic(
 	return topicInfos[0], nil
 }
 
+// GetReplicaSizes gets the on-disk size of each partition replica in the argument brokers.
+func (c *BrokerAdminClient) GetReplicaSizes(
+	ctx context.Context,
+	brokerIDs []int,
+	topics []string,
+) ([]ReplicaSize, error) {
+	return getReplicaSizes(ctx, c.connector, brokerIDs, topics)
+}
+
 func (c *BrokerAdminClient) GetUsers(
 	ctx context.Context,
 	names []string,
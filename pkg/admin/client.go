@@ -50,6 +50,14 @@ type Client interface {
 	// GetAllTopicsMetadata performs kafka-go metadata call to get topic information
 	GetAllTopicsMetadata(ctx context.Context) (*kafka.MetadataResponse, error)
 
+	// GetReplicaSizes gets the on-disk size of each partition replica in the argument
+	// brokers. If topics is empty, then replicas for all topics are returned.
+	GetReplicaSizes(
+		ctx context.Context,
+		brokerIDs []int,
+		topics []string,
+	) ([]ReplicaSize, error)
+
 	// GetUsers gets information about users in the cluster.
 	GetUsers(
 		ctx context.Context,
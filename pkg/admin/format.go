@@ -363,6 +363,108 @@ func FormatBrokerRackReplicas(brokers []BrokerInfo, topics []TopicInfo) string {
 	return string(bytes.TrimRight(buf.Bytes(), "\n"))
 }
 
+// FormatBrokerWeightedBalance creates a pretty table that compares the number of leaders and
+// replicas on each broker across all topics to the targets implied by the argument broker
+// weights. Brokers that aren't in the weights have a weight of 1.
+func FormatBrokerWeightedBalance(
+	brokers []BrokerInfo,
+	topics []TopicInfo,
+	weights map[int]float64,
+) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"ID",
+			"Rack",
+			"Weight",
+			"Leaders",
+			"Target Leaders",
+			"Leader Deviation",
+			"Replicas",
+			"Target Replicas",
+			"Replica Deviation",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	brokerWeights := map[int]float64{}
+	var totalWeight float64
+
+	for _, broker := range brokers {
+		weight, ok := weights[broker.ID]
+		if !ok {
+			weight = 1.0
+		}
+		brokerWeights[broker.ID] = weight
+		totalWeight += weight
+	}
+
+	brokerLeaders := map[int]int{}
+	brokerReplicas := map[int]int{}
+	var totalLeaders, totalReplicas int
+
+	for _, topic := range topics {
+		for _, partition := range topic.Partitions {
+			if _, ok := brokerWeights[partition.Leader]; ok {
+				brokerLeaders[partition.Leader]++
+				totalLeaders++
+			}
+			for _, replica := range partition.Replicas {
+				if _, ok := brokerWeights[replica]; ok {
+					brokerReplicas[replica]++
+					totalReplicas++
+				}
+			}
+		}
+	}
+
+	for _, broker := range brokers {
+		share := brokerWeights[broker.ID] / totalWeight
+		targetLeaders := float64(totalLeaders) * share
+		targetReplicas := float64(totalReplicas) * share
+
+		table.Append(
+			[]string{
+				fmt.Sprintf("%d", broker.ID),
+				broker.Rack,
+				fmt.Sprintf("%g", brokerWeights[broker.ID]),
+				fmt.Sprintf("%d", brokerLeaders[broker.ID]),
+				fmt.Sprintf("%.1f", targetLeaders),
+				formatDeviation(brokerLeaders[broker.ID], targetLeaders),
+				fmt.Sprintf("%d", brokerReplicas[broker.ID]),
+				fmt.Sprintf("%.1f", targetReplicas),
+				formatDeviation(brokerReplicas[broker.ID], targetReplicas),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
 // FormatBrokersPerRack creates a pretty table that shows the number of
 // brokers per rack.
 func FormatBrokersPerRack(brokers []BrokerInfo) string {
@@ -1242,3 +1344,10 @@ func maxMapValues(inputMap map[int]int) int {
 
 	return maxValue
 }
+
+func formatDeviation(actual int, target float64) string {
+	if target == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", 100.0*(float64(actual)-target)/target)
+}
@@ -0,0 +1,147 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+	log "github.com/sirupsen/logrus"
+)
+
+// The version of kafka-go that we use doesn't support the DescribeLogDirs API, so we define
+// the messages for it here and register them with the kafka-go protocol package. See
+// https://kafka.apache.org/protocol#The_Messages_DescribeLogDirs for details.
+func init() {
+	protocol.Register(&describeLogDirsRequest{}, &describeLogDirsResponse{})
+}
+
+type describeLogDirsRequest struct {
+	// Topics is the set of topics to describe; if nil, all topics are described.
+	Topics []describeLogDirsRequestTopic `kafka:"min=v0,max=v1,nullable"`
+
+	// brokerID is the broker that the request should be sent to. It's not part of
+	// the wire format.
+	brokerID int32
+}
+
+type describeLogDirsRequestTopic struct {
+	Topic      string  `kafka:"min=v0,max=v1"`
+	Partitions []int32 `kafka:"min=v0,max=v1"`
+}
+
+func (r *describeLogDirsRequest) ApiKey() protocol.ApiKey { return protocol.DescribeLogDirs }
+
+func (r *describeLogDirsRequest) Broker(cluster protocol.Cluster) (protocol.Broker, error) {
+	broker, ok := cluster.Brokers[r.brokerID]
+	if !ok {
+		return protocol.Broker{}, fmt.Errorf("Broker %d not found in cluster", r.brokerID)
+	}
+	return broker, nil
+}
+
+type describeLogDirsResponse struct {
+	ThrottleTimeMs int32                           `kafka:"min=v0,max=v1"`
+	Results        []describeLogDirsResponseResult `kafka:"min=v0,max=v1"`
+}
+
+type describeLogDirsResponseResult struct {
+	ErrorCode int16                          `kafka:"min=v0,max=v1"`
+	LogDir    string                         `kafka:"min=v0,max=v1"`
+	Topics    []describeLogDirsResponseTopic `kafka:"min=v0,max=v1"`
+}
+
+type describeLogDirsResponseTopic struct {
+	Name       string                             `kafka:"min=v0,max=v1"`
+	Partitions []describeLogDirsResponsePartition `kafka:"min=v0,max=v1"`
+}
+
+type describeLogDirsResponsePartition struct {
+	PartitionIndex int32 `kafka:"min=v0,max=v1"`
+	PartitionSize  int64 `kafka:"min=v0,max=v1"`
+	OffsetLag      int64 `kafka:"min=v0,max=v1"`
+	IsFutureKey    bool  `kafka:"min=v0,max=v1"`
+}
+
+func (r *describeLogDirsResponse) ApiKey() protocol.ApiKey { return protocol.DescribeLogDirs }
+
+// getReplicaSizes describes the log dirs in each of the argument brokers and returns the
+// sizes of the replicas in them, optionally filtered to a subset of topics.
+func getReplicaSizes(
+	ctx context.Context,
+	connector *Connector,
+	brokerIDs []int,
+	topics []string,
+) ([]ReplicaSize, error) {
+	transport := connector.KafkaClient.Transport
+	if transport == nil {
+		transport = kafka.DefaultTransport
+	}
+
+	topicsMap := map[string]struct{}{}
+	for _, topic := range topics {
+		topicsMap[topic] = struct{}{}
+	}
+
+	replicaSizes := []ReplicaSize{}
+
+	for _, brokerID := range brokerIDs {
+		req := &describeLogDirsRequest{brokerID: int32(brokerID)}
+		log.Debugf("DescribeLogDirs request for broker %d", brokerID)
+
+		msg, err := transport.RoundTrip(ctx, connector.KafkaClient.Addr, req)
+		if err != nil {
+			return nil, fmt.Errorf("Error describing log dirs for broker %d: %+v", brokerID, err)
+		}
+		resp, ok := msg.(*describeLogDirsResponse)
+		if !ok {
+			return nil, fmt.Errorf("Unexpected response type for DescribeLogDirs: %T", msg)
+		}
+		log.Debugf("DescribeLogDirs response for broker %d: %+v", brokerID, resp)
+
+		for _, result := range resp.Results {
+			if result.ErrorCode != 0 {
+				return nil, fmt.Errorf(
+					"Error describing log dir %s for broker %d: %+v",
+					result.LogDir,
+					brokerID,
+					kafka.Error(result.ErrorCode),
+				)
+			}
+
+			for _, topic := range result.Topics {
+				if _, ok := topicsMap[topic.Name]; !ok && len(topicsMap) > 0 {
+					continue
+				}
+
+				for _, partition := range topic.Partitions {
+					replicaSizes = append(
+						replicaSizes,
+						ReplicaSize{
+							Topic:     topic.Name,
+							Partition: int(partition.PartitionIndex),
+							Broker:    brokerID,
+							LogDir:    result.LogDir,
+							Size:      partition.PartitionSize,
+							OffsetLag: partition.OffsetLag,
+							IsFuture:  partition.IsFutureKey,
+						},
+					)
+				}
+			}
+		}
+	}
+
+	sort.Slice(replicaSizes, func(a, b int) bool {
+		if replicaSizes[a].Topic != replicaSizes[b].Topic {
+			return replicaSizes[a].Topic < replicaSizes[b].Topic
+		}
+		if replicaSizes[a].Partition != replicaSizes[b].Partition {
+			return replicaSizes[a].Partition < replicaSizes[b].Partition
+		}
+		return replicaSizes[a].Broker < replicaSizes[b].Broker
+	})
+
+	return replicaSizes, nil
+}
@@ -0,0 +1,541 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/util"
+	"github.com/segmentio/topicctl/pkg/zk"
+	log "github.com/sirupsen/logrus"
+)
+
+// SnapshotVersion is the version of the snapshot format written by this version of topicctl.
+const SnapshotVersion = 1
+
+var (
+	// ErrSnapshotReadOnly is returned by snapshot-backed clients for any operation that would
+	// change the cluster.
+	ErrSnapshotReadOnly = errors.New("Cannot make changes to a cluster snapshot")
+
+	// ErrNotInSnapshot is returned by snapshot-backed clients for information that snapshots
+	// don't include.
+	ErrNotInSnapshot = errors.New("Information is not included in cluster snapshots")
+)
+
+// Snapshot is an offline copy of the state of a cluster at a point in time.
+type Snapshot struct {
+	Version      int           `json:"version"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	ClusterID    string        `json:"clusterID"`
+	ControllerID int           `json:"controllerID"`
+	Brokers      []BrokerInfo  `json:"brokers"`
+	Topics       []TopicInfo   `json:"topics"`
+	ReplicaSizes []ReplicaSize `json:"replicaSizes,omitempty"`
+	GroupOffsets []GroupOffset `json:"groupOffsets,omitempty"`
+}
+
+// GroupOffset is the offset committed by a consumer group for a single topic partition.
+type GroupOffset struct {
+	GroupID      string `json:"groupID"`
+	Coordinator  int    `json:"coordinator"`
+	Topic        string `json:"topic"`
+	Partition    int    `json:"partition"`
+	Offset       int64  `json:"offset"`
+	NewestOffset int64  `json:"newestOffset"`
+}
+
+// NewSnapshot gets the current state of the cluster from the argument client. Replica sizes
+// are only included if includeSizes is set since getting them requires a request to every
+// broker.
+func NewSnapshot(
+	ctx context.Context,
+	client Client,
+	includeSizes bool,
+) (Snapshot, error) {
+	snapshot := Snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	var err error
+
+	snapshot.ClusterID, err = client.GetClusterID(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.ControllerID, err = client.GetControllerID(ctx)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Brokers, err = client.GetBrokers(ctx, nil)
+	if err != nil {
+		return snapshot, err
+	}
+	sort.Slice(snapshot.Brokers, func(a, b int) bool {
+		return snapshot.Brokers[a].ID < snapshot.Brokers[b].ID
+	})
+
+	snapshot.Topics, err = client.GetTopics(ctx, nil, true)
+	if err != nil {
+		return snapshot, err
+	}
+	sort.Slice(snapshot.Topics, func(a, b int) bool {
+		return snapshot.Topics[a].Name < snapshot.Topics[b].Name
+	})
+
+	if includeSizes {
+		snapshot.ReplicaSizes, err = client.GetReplicaSizes(ctx, BrokerIDs(snapshot.Brokers), nil)
+		if err != nil {
+			return snapshot, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+// LoadSnapshotFile loads a Snapshot from a JSON file path.
+func LoadSnapshotFile(path string) (Snapshot, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{}
+	if err := json.Unmarshal(contents, &snapshot); err != nil {
+		return Snapshot{}, fmt.Errorf("Error parsing snapshot %s: %+v", path, err)
+	}
+	if snapshot.Version < 1 || snapshot.Version > SnapshotVersion {
+		return Snapshot{}, fmt.Errorf(
+			"Snapshot %s has unsupported version %d; this version of topicctl supports up to %d",
+			path,
+			snapshot.Version,
+			SnapshotVersion,
+		)
+	}
+
+	return snapshot, nil
+}
+
+// SnapshotAdminClient is a read-only Client implementation that serves the state of a
+// cluster from a snapshot instead of the cluster itself. Any attempts to change the cluster
+// return ErrSnapshotReadOnly.
+type SnapshotAdminClient struct {
+	snapshot Snapshot
+	topics   map[string]TopicInfo
+}
+
+var _ Client = (*SnapshotAdminClient)(nil)
+
+// SnapshotAdminClientConfig contains the configuration settings to construct a
+// SnapshotAdminClient instance.
+type SnapshotAdminClientConfig struct {
+	Path              string
+	ExpectedClusterID string
+}
+
+// NewSnapshotAdminClient constructs a new SnapshotAdminClient instance.
+func NewSnapshotAdminClient(
+	config SnapshotAdminClientConfig,
+) (*SnapshotAdminClient, error) {
+	snapshot, err := LoadSnapshotFile(config.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.ExpectedClusterID != "" && snapshot.ClusterID != config.ExpectedClusterID {
+		return nil, fmt.Errorf(
+			"ID in snapshot (%s) does not match expected one (%s)",
+			snapshot.ClusterID,
+			config.ExpectedClusterID,
+		)
+	}
+
+	log.Infof(
+		"Using snapshot of cluster %s taken at %s",
+		snapshot.ClusterID,
+		snapshot.CreatedAt.Format(time.RFC3339),
+	)
+
+	topics := map[string]TopicInfo{}
+	for _, topic := range snapshot.Topics {
+		topics[topic.Name] = topic
+	}
+
+	return &SnapshotAdminClient{
+		snapshot: snapshot,
+		topics:   topics,
+	}, nil
+}
+
+// GetClusterID gets the ID of the cluster.
+func (c *SnapshotAdminClient) GetClusterID(ctx context.Context) (string, error) {
+	return c.snapshot.ClusterID, nil
+}
+
+// GetBrokers gets information about all brokers in the cluster.
+func (c *SnapshotAdminClient) GetBrokers(ctx context.Context, ids []int) (
+	[]BrokerInfo,
+	error,
+) {
+	idsMap := map[int]struct{}{}
+	for _, id := range ids {
+		idsMap[id] = struct{}{}
+	}
+
+	brokerInfos := []BrokerInfo{}
+	for _, broker := range c.snapshot.Brokers {
+		if _, ok := idsMap[broker.ID]; !ok && len(idsMap) > 0 {
+			continue
+		}
+		brokerInfos = append(brokerInfos, copyBrokerInfo(broker))
+	}
+
+	return brokerInfos, nil
+}
+
+// GetControllerID gets ID of the controller broker at the time of the snapshot.
+func (c *SnapshotAdminClient) GetControllerID(ctx context.Context) (int, error) {
+	return c.snapshot.ControllerID, nil
+}
+
+// GetBrokerIDs get the IDs of all brokers in the cluster.
+func (c *SnapshotAdminClient) GetBrokerIDs(ctx context.Context) ([]int, error) {
+	return BrokerIDs(c.snapshot.Brokers), nil
+}
+
+// GetConnector returns nil since snapshot-based clients can't connect to the cluster.
+func (c *SnapshotAdminClient) GetConnector() *Connector {
+	return nil
+}
+
+// GetTopics gets full information about each topic in the cluster.
+func (c *SnapshotAdminClient) GetTopics(
+	ctx context.Context,
+	names []string,
+	detailed bool,
+) ([]TopicInfo, error) {
+	topicInfos := []TopicInfo{}
+
+	if len(names) == 0 {
+		for _, topic := range c.snapshot.Topics {
+			topicInfos = append(topicInfos, copyTopicInfo(topic))
+		}
+		return topicInfos, nil
+	}
+
+	for _, name := range names {
+		topic, ok := c.topics[name]
+		if !ok {
+			log.Debugf("Skipping over topic %s because it does not exist", name)
+			continue
+		}
+		topicInfos = append(topicInfos, copyTopicInfo(topic))
+	}
+
+	return topicInfos, nil
+}
+
+// GetTopicNames gets just the names of each topic in the cluster.
+func (c *SnapshotAdminClient) GetTopicNames(ctx context.Context) ([]string, error) {
+	topicNames := []string{}
+	for _, topic := range c.snapshot.Topics {
+		topicNames = append(topicNames, topic.Name)
+	}
+	return topicNames, nil
+}
+
+// GetTopic gets the details of a single topic in the cluster.
+func (c *SnapshotAdminClient) GetTopic(
+	ctx context.Context,
+	name string,
+	detailed bool,
+) (TopicInfo, error) {
+	topic, ok := c.topics[name]
+	if !ok {
+		return TopicInfo{}, ErrTopicDoesNotExist
+	}
+	return copyTopicInfo(topic), nil
+}
+
+// GetACLs returns ErrNotInSnapshot since ACLs aren't included in snapshots.
+func (c *SnapshotAdminClient) GetACLs(
+	ctx context.Context,
+	filter kafka.ACLFilter,
+) ([]ACLInfo, error) {
+	return nil, ErrNotInSnapshot
+}
+
+// GetAllTopicsMetadata builds the metadata response for all topics in the snapshot.
+func (c *SnapshotAdminClient) GetAllTopicsMetadata(
+	ctx context.Context,
+) (*kafka.MetadataResponse, error) {
+	brokers := map[int]kafka.Broker{}
+	resp := &kafka.MetadataResponse{
+		ClusterID: c.snapshot.ClusterID,
+	}
+
+	for _, broker := range c.snapshot.Brokers {
+		brokers[broker.ID] = kafka.Broker{
+			Host: broker.Host,
+			Port: int(broker.Port),
+			ID:   broker.ID,
+			Rack: broker.Rack,
+		}
+		resp.Brokers = append(resp.Brokers, brokers[broker.ID])
+	}
+	resp.Controller = brokers[c.snapshot.ControllerID]
+
+	// Brokers that aren't in the snapshot (e.g., because they were down) only have IDs
+	toBrokers := func(ids []int) []kafka.Broker {
+		results := []kafka.Broker{}
+		for _, id := range ids {
+			broker, ok := brokers[id]
+			if !ok {
+				broker = kafka.Broker{ID: id}
+			}
+			results = append(results, broker)
+		}
+		return results
+	}
+
+	for _, topic := range c.snapshot.Topics {
+		partitions := []kafka.Partition{}
+		for _, partition := range topic.Partitions {
+			partitions = append(
+				partitions,
+				kafka.Partition{
+					Topic:    topic.Name,
+					ID:       partition.ID,
+					Leader:   toBrokers([]int{partition.Leader})[0],
+					Replicas: toBrokers(partition.Replicas),
+					Isr:      toBrokers(partition.ISR),
+				},
+			)
+		}
+
+		resp.Topics = append(
+			resp.Topics,
+			kafka.Topic{
+				Name:       topic.Name,
+				Partitions: partitions,
+			},
+		)
+	}
+
+	return resp, nil
+}
+
+// GetReplicaSizes gets the on-disk size of each partition replica in the argument brokers. It
+// returns an error if the snapshot was taken without sizes.
+func (c *SnapshotAdminClient) GetReplicaSizes(
+	ctx context.Context,
+	brokerIDs []int,
+	topics []string,
+) ([]ReplicaSize, error) {
+	if len(c.snapshot.ReplicaSizes) == 0 {
+		return nil, errors.New("Snapshot does not include replica sizes")
+	}
+
+	brokersMap := map[int]struct{}{}
+	for _, brokerID := range brokerIDs {
+		brokersMap[brokerID] = struct{}{}
+	}
+	topicsMap := map[string]struct{}{}
+	for _, topic := range topics {
+		topicsMap[topic] = struct{}{}
+	}
+
+	replicaSizes := []ReplicaSize{}
+	for _, replicaSize := range c.snapshot.ReplicaSizes {
+		if _, ok := brokersMap[replicaSize.Broker]; !ok {
+			continue
+		}
+		if _, ok := topicsMap[replicaSize.Topic]; !ok && len(topicsMap) > 0 {
+			continue
+		}
+		replicaSizes = append(replicaSizes, replicaSize)
+	}
+
+	return replicaSizes, nil
+}
+
+// GetGroupOffsets gets the consumer group offsets in the snapshot, optionally filtered to a
+// single group and/or topic. It returns an error if the snapshot was taken without group
+// offsets.
+func (c *SnapshotAdminClient) GetGroupOffsets(
+	ctx context.Context,
+	groupID string,
+	topic string,
+) ([]GroupOffset, error) {
+	if len(c.snapshot.GroupOffsets) == 0 {
+		return nil, errors.New("Snapshot does not include group offsets")
+	}
+
+	groupOffsets := []GroupOffset{}
+	for _, groupOffset := range c.snapshot.GroupOffsets {
+		if groupID != "" && groupOffset.GroupID != groupID {
+			continue
+		}
+		if topic != "" && groupOffset.Topic != topic {
+			continue
+		}
+		groupOffsets = append(groupOffsets, groupOffset)
+	}
+
+	return groupOffsets, nil
+}
+
+// GetUsers returns ErrNotInSnapshot since users aren't included in snapshots.
+func (c *SnapshotAdminClient) GetUsers(
+	ctx context.Context,
+	names []string,
+) ([]UserInfo, error) {
+	return nil, ErrNotInSnapshot
+}
+
+// UpdateTopicConfig returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) UpdateTopicConfig(
+	ctx context.Context,
+	name string,
+	configEntries []kafka.ConfigEntry,
+	overwrite bool,
+) ([]string, error) {
+	return nil, ErrSnapshotReadOnly
+}
+
+// UpdateBrokerConfig returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) UpdateBrokerConfig(
+	ctx context.Context,
+	id int,
+	configEntries []kafka.ConfigEntry,
+	overwrite bool,
+) ([]string, error) {
+	return nil, ErrSnapshotReadOnly
+}
+
+// CreateTopic returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) CreateTopic(
+	ctx context.Context,
+	config kafka.TopicConfig,
+) error {
+	return ErrSnapshotReadOnly
+}
+
+// CreateACLs returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) CreateACLs(
+	ctx context.Context,
+	acls []kafka.ACLEntry,
+) error {
+	return ErrSnapshotReadOnly
+}
+
+// DeleteACLs returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) DeleteACLs(
+	ctx context.Context,
+	filters []kafka.DeleteACLsFilter,
+) (*kafka.DeleteACLsResponse, error) {
+	return nil, ErrSnapshotReadOnly
+}
+
+// UpsertUser returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) UpsertUser(
+	ctx context.Context,
+	user kafka.UserScramCredentialsUpsertion,
+) error {
+	return ErrSnapshotReadOnly
+}
+
+// AssignPartitions returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) AssignPartitions(
+	ctx context.Context,
+	topic string,
+	assignments []PartitionAssignment,
+) error {
+	return ErrSnapshotReadOnly
+}
+
+// AddPartitions returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) AddPartitions(
+	ctx context.Context,
+	topic string,
+	newAssignments []PartitionAssignment,
+) error {
+	return ErrSnapshotReadOnly
+}
+
+// RunLeaderElection returns ErrSnapshotReadOnly.
+func (c *SnapshotAdminClient) RunLeaderElection(
+	ctx context.Context,
+	topic string,
+	partitions []int,
+) error {
+	return ErrSnapshotReadOnly
+}
+
+// AcquireLock is a no-op since nothing else can change a snapshot.
+func (c *SnapshotAdminClient) AcquireLock(ctx context.Context, path string) (
+	zk.Lock,
+	error,
+) {
+	return nil, nil
+}
+
+// LockHeld always returns false since nothing else can change a snapshot.
+func (c *SnapshotAdminClient) LockHeld(ctx context.Context, path string) (bool, error) {
+	return false, nil
+}
+
+// GetSupportedFeatures gets the features supported by the cluster for this client. Applies
+// are reported as supported so that they can be dry-run against the snapshot.
+func (c *SnapshotAdminClient) GetSupportedFeatures() SupportedFeatures {
+	return SupportedFeatures{
+		Reads:                true,
+		Applies:              true,
+		DynamicBrokerConfigs: true,
+	}
+}
+
+// Close closes the client.
+func (c *SnapshotAdminClient) Close() error {
+	return nil
+}
+
+func copyBrokerInfo(broker BrokerInfo) BrokerInfo {
+	broker.Endpoints = slices.Clone(broker.Endpoints)
+	broker.Tags = slices.Clone(broker.Tags)
+	broker.Config = copyConfig(broker.Config)
+	return broker
+}
+
+func copyTopicInfo(topic TopicInfo) TopicInfo {
+	topic.Config = copyConfig(topic.Config)
+
+	partitions := []PartitionInfo{}
+	for _, partition := range topic.Partitions {
+		partition.Replicas = util.CopyInts(partition.Replicas)
+		partition.ISR = util.CopyInts(partition.ISR)
+		partitions = append(partitions, partition)
+	}
+	topic.Partitions = partitions
+
+	return topic
+}
+
+func copyConfig(config map[string]string) map[string]string {
+	if config == nil {
+		return nil
+	}
+
+	copied := map[string]string{}
+	for key, value := range config {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,255 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotAdminClient(t *testing.T) {
+	ctx := context.Background()
+	snapshot := testSnapshot()
+	snapshotPath := writeTestSnapshot(t, snapshot)
+
+	client, err := NewSnapshotAdminClient(
+		SnapshotAdminClientConfig{
+			Path:              snapshotPath,
+			ExpectedClusterID: "test-cluster",
+		},
+	)
+	require.NoError(t, err)
+
+	clusterID, err := client.GetClusterID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "test-cluster", clusterID)
+
+	controllerID, err := client.GetControllerID(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, controllerID)
+
+	brokerIDs, err := client.GetBrokerIDs(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, brokerIDs)
+
+	brokers, err := client.GetBrokers(ctx, []int{3, 1})
+	require.NoError(t, err)
+	assert.Equal(t, []BrokerInfo{snapshot.Brokers[0], snapshot.Brokers[2]}, brokers)
+
+	// Changes to the results don't affect the snapshot
+	brokers[0].Config["leader.replication.throttled.rate"] = "1"
+	brokers, err = client.GetBrokers(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Brokers, brokers)
+
+	topicNames, err := client.GetTopicNames(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"topic1", "topic2"}, topicNames)
+
+	topics, err := client.GetTopics(ctx, []string{"topic2", "non-existent-topic"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []TopicInfo{snapshot.Topics[1]}, topics)
+
+	topic, err := client.GetTopic(ctx, "topic1", true)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.Topics[0], topic)
+
+	_, err = client.GetTopic(ctx, "non-existent-topic", true)
+	assert.Equal(t, ErrTopicDoesNotExist, err)
+
+	replicaSizes, err := client.GetReplicaSizes(ctx, []int{2, 3}, []string{"topic1"})
+	require.NoError(t, err)
+	assert.Equal(t, []ReplicaSize{snapshot.ReplicaSizes[1]}, replicaSizes)
+
+	groupOffsets, err := client.GetGroupOffsets(ctx, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.GroupOffsets, groupOffsets)
+	groupOffsets, err = client.GetGroupOffsets(ctx, "group1", "topic1")
+	require.NoError(t, err)
+	assert.Equal(t, []GroupOffset{snapshot.GroupOffsets[0]}, groupOffsets)
+	groupOffsets, err = client.GetGroupOffsets(ctx, "", "topic1")
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]GroupOffset{snapshot.GroupOffsets[0], snapshot.GroupOffsets[2]},
+		groupOffsets,
+	)
+
+	metadata, err := client.GetAllTopicsMetadata(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, metadata.Controller.ID)
+	assert.Equal(t, 3, len(metadata.Brokers))
+	assert.Equal(t, 2, len(metadata.Topics))
+	assert.Equal(
+		t,
+		[]kafka.Broker{
+			{ID: 2, Host: "broker2", Port: 9092, Rack: "rack2"},
+			{ID: 4},
+		},
+		metadata.Topics[1].Partitions[0].Replicas,
+	)
+
+	_, err = client.GetACLs(ctx, kafka.ACLFilter{})
+	assert.Equal(t, ErrNotInSnapshot, err)
+	_, err = client.UpdateTopicConfig(ctx, "topic1", nil, true)
+	assert.Equal(t, ErrSnapshotReadOnly, err)
+	assert.Equal(
+		t,
+		ErrSnapshotReadOnly,
+		client.AssignPartitions(ctx, "topic1", []PartitionAssignment{{ID: 0, Replicas: []int{2, 3}}}),
+	)
+	assert.Equal(t, ErrSnapshotReadOnly, client.RunLeaderElection(ctx, "topic1", []int{0}))
+	assert.Nil(t, client.GetConnector())
+}
+
+func TestSnapshotAdminClientErrors(t *testing.T) {
+	snapshot := testSnapshot()
+
+	_, err := NewSnapshotAdminClient(
+		SnapshotAdminClientConfig{
+			Path:              writeTestSnapshot(t, snapshot),
+			ExpectedClusterID: "other-cluster",
+		},
+	)
+	assert.Error(t, err)
+
+	snapshot.Version = SnapshotVersion + 1
+	_, err = NewSnapshotAdminClient(
+		SnapshotAdminClientConfig{
+			Path: writeTestSnapshot(t, snapshot),
+		},
+	)
+	assert.Error(t, err)
+
+	snapshot = testSnapshot()
+	snapshot.ReplicaSizes = nil
+	client, err := NewSnapshotAdminClient(
+		SnapshotAdminClientConfig{
+			Path: writeTestSnapshot(t, snapshot),
+		},
+	)
+	require.NoError(t, err)
+	_, err = client.GetReplicaSizes(context.Background(), []int{1, 2, 3}, nil)
+	assert.Error(t, err)
+
+	snapshot = testSnapshot()
+	snapshot.GroupOffsets = nil
+	client, err = NewSnapshotAdminClient(
+		SnapshotAdminClientConfig{
+			Path: writeTestSnapshot(t, snapshot),
+		},
+	)
+	require.NoError(t, err)
+	_, err = client.GetGroupOffsets(context.Background(), "", "")
+	assert.Error(t, err)
+}
+
+func TestNewSnapshot(t *testing.T) {
+	ctx := context.Background()
+	snapshot := testSnapshot()
+
+	client, err := NewSnapshotAdminClient(
+		SnapshotAdminClientConfig{
+			Path: writeTestSnapshot(t, snapshot),
+		},
+	)
+	require.NoError(t, err)
+
+	newSnapshot, err := NewSnapshot(ctx, client, true)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.ClusterID, newSnapshot.ClusterID)
+	assert.Equal(t, snapshot.ControllerID, newSnapshot.ControllerID)
+	assert.Equal(t, snapshot.Brokers, newSnapshot.Brokers)
+	assert.Equal(t, snapshot.Topics, newSnapshot.Topics)
+	assert.Equal(t, snapshot.ReplicaSizes, newSnapshot.ReplicaSizes)
+
+	newSnapshot, err = NewSnapshot(ctx, client, false)
+	require.NoError(t, err)
+	assert.Nil(t, newSnapshot.ReplicaSizes)
+}
+
+func testSnapshot() Snapshot {
+	return Snapshot{
+		Version:      SnapshotVersion,
+		CreatedAt:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		ClusterID:    "test-cluster",
+		ControllerID: 2,
+		Brokers: []BrokerInfo{
+			{
+				ID:   1,
+				Host: "broker1",
+				Port: 9092,
+				Rack: "rack1",
+				Config: map[string]string{
+					"leader.replication.throttled.rate": "1234",
+				},
+			},
+			{
+				ID:   2,
+				Host: "broker2",
+				Port: 9092,
+				Rack: "rack2",
+			},
+			{
+				ID:   3,
+				Host: "broker3",
+				Port: 9092,
+				Rack: "rack3",
+			},
+		},
+		Topics: []TopicInfo{
+			{
+				Name: "topic1",
+				Config: map[string]string{
+					"retention.ms": "3600000",
+				},
+				Partitions: []PartitionInfo{
+					{
+						Topic:    "topic1",
+						ID:       0,
+						Leader:   1,
+						Replicas: []int{1, 2},
+						ISR:      []int{1, 2},
+					},
+				},
+			},
+			{
+				Name: "topic2",
+				Partitions: []PartitionInfo{
+					{
+						Topic:    "topic2",
+						ID:       0,
+						Leader:   2,
+						Replicas: []int{2, 4},
+						ISR:      []int{2},
+					},
+				},
+			},
+		},
+		ReplicaSizes: []ReplicaSize{
+			{Topic: "topic1", Partition: 0, Broker: 1, LogDir: "/data", Size: 100},
+			{Topic: "topic1", Partition: 0, Broker: 2, LogDir: "/data", Size: 100},
+			{Topic: "topic2", Partition: 0, Broker: 2, LogDir: "/data", Size: 50},
+		},
+		GroupOffsets: []GroupOffset{
+			{GroupID: "group1", Coordinator: 1, Topic: "topic1", Partition: 0, Offset: 10, NewestOffset: 15},
+			{GroupID: "group1", Coordinator: 1, Topic: "topic2", Partition: 0, Offset: 5, NewestOffset: 5},
+			{GroupID: "group2", Coordinator: 2, Topic: "topic1", Partition: 0, Offset: 12, NewestOffset: 15},
+		},
+	}
+}
+
+func writeTestSnapshot(t *testing.T, snapshot Snapshot) string {
+	contents, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(snapshotPath, contents, 0644))
+	return snapshotPath
+}
@@ -47,6 +47,10 @@ type BrokerInfo struct {
 	Version          int               `json:"version"`
 	Timestamp        time.Time         `json:"timestamp"`
 	Config           map[string]string `json:"config"`
+
+	// Tags are set from the cluster config instead of Kafka. They're used to restrict the
+	// brokers that topic replicas can be placed on.
+	Tags []string `json:"tags,omitempty"`
 }
 
 // TopicInfo represents the information stored about a topic in zookeeper.
@@ -77,6 +81,18 @@ type PartitionAssignment struct {
 	Replicas []int `json:"replicas"`
 }
 
+// ReplicaSize stores the on-disk size of a single partition replica, as reported
+// by the broker that hosts it.
+type ReplicaSize struct {
+	Topic     string `json:"topic"`
+	Partition int    `json:"partition"`
+	Broker    int    `json:"broker"`
+	LogDir    string `json:"logDir"`
+	Size      int64  `json:"size"`
+	OffsetLag int64  `json:"offsetLag"`
+	IsFuture  bool   `json:"isFuture"`
+}
+
 // PartitionInfo represents the information stored about an ACL
 // in zookeeper.
 type ACLInfo struct {
@@ -1367,3 +1383,47 @@ func GetAllTopicNamesFromMetadata(
 
 	return topicsSet
 }
+
+// TopicSizes returns the total size of each topic across all of its replicas. Future
+// replicas (i.e., ones that are in the process of being moved between log dirs) are
+// not included.
+func TopicSizes(replicaSizes []ReplicaSize) map[string]int64 {
+	topicSizes := map[string]int64{}
+
+	for _, replicaSize := range replicaSizes {
+		if replicaSize.IsFuture {
+			continue
+		}
+		topicSizes[replicaSize.Topic] += replicaSize.Size
+	}
+
+	return topicSizes
+}
+
+// PartitionSizes returns the size of each partition in the argument topic. Since
+// replicas can differ slightly in size, the largest replica is used.
+func PartitionSizes(replicaSizes []ReplicaSize, topic string) map[int]int64 {
+	partitionSizes := map[int]int64{}
+
+	for _, replicaSize := range replicaSizes {
+		if replicaSize.IsFuture || replicaSize.Topic != topic {
+			continue
+		}
+		if replicaSize.Size > partitionSizes[replicaSize.Partition] {
+			partitionSizes[replicaSize.Partition] = replicaSize.Size
+		}
+	}
+
+	return partitionSizes
+}
+
+// BrokerSizes returns the total size of all replicas on each broker.
+func BrokerSizes(replicaSizes []ReplicaSize) map[int]int64 {
+	brokerSizes := map[int]int64{}
+
+	for _, replicaSize := range replicaSizes {
+		brokerSizes[replicaSize.Broker] += replicaSize.Size
+	}
+
+	return brokerSizes
+}
@@ -618,3 +618,39 @@ func TestDiffHelpers(t *testing.T) {
 		NewLeaderPartitions(curr, desired),
 	)
 }
+
+func TestReplicaSizeHelpers(t *testing.T) {
+	replicaSizes := []ReplicaSize{
+		{Topic: "topic1", Partition: 0, Broker: 1, Size: 100},
+		{Topic: "topic1", Partition: 0, Broker: 2, Size: 110},
+		{Topic: "topic1", Partition: 1, Broker: 2, Size: 50},
+		{Topic: "topic1", Partition: 1, Broker: 3, Size: 20, IsFuture: true},
+		{Topic: "topic2", Partition: 0, Broker: 3, Size: 500},
+	}
+
+	assert.Equal(
+		t,
+		map[string]int64{
+			"topic1": 260,
+			"topic2": 500,
+		},
+		TopicSizes(replicaSizes),
+	)
+	assert.Equal(
+		t,
+		map[int]int64{
+			0: 110,
+			1: 50,
+		},
+		PartitionSizes(replicaSizes, "topic1"),
+	)
+	assert.Equal(
+		t,
+		map[int]int64{
+			1: 100,
+			2: 160,
+			3: 520,
+		},
+		BrokerSizes(replicaSizes),
+	)
+}
@@ -466,6 +466,16 @@ func (c *ZKAdminClient) DeleteACLs(
 	return nil, errors.New("ACLs not yet supported with zk access mode; omit zk addresses to fix.")
 }
 
+// GetReplicaSizes gets the on-disk size of each partition replica in the argument brokers.
+// Log dir information isn't stored in zookeeper, so this uses the broker APIs.
+func (c *ZKAdminClient) GetReplicaSizes(
+	ctx context.Context,
+	brokerIDs []int,
+	topics []string,
+) ([]ReplicaSize, error) {
+	return getReplicaSizes(ctx, c.Connector, brokerIDs, topics)
+}
+
 func (c *ZKAdminClient) GetUsers(
 	ctx context.Context,
 	names []string,
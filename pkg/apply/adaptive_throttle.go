@@ -0,0 +1,158 @@
+package apply
+
+import (
+	"fmt"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+// throttleSignals are the inputs used to adjust an adaptive throttle after each poll
+// of a migration batch.
+type throttleSignals struct {
+	// newURPs is the number of under-replicated partitions in other topics, excluding the
+	// ones that were already under-replicated when the batch started.
+	newURPs int
+
+	// isrShrinks is the number of partitions in other topics whose ISRs shrank since the
+	// previous poll.
+	isrShrinks int
+
+	// progressed indicates whether any partitions in the batch finished since the
+	// previous poll.
+	progressed bool
+}
+
+// adaptiveThrottle adjusts the broker throttle rate during a migration. The rate is lowered
+// when other topics become unhealthy, raised when the cluster is healthy but the batch isn't
+// making progress, and otherwise kept the same.
+type adaptiveThrottle struct {
+	minBytes     int64
+	maxBytes     int64
+	stepFraction float64
+	currBytes    int64
+
+	// State from the current batch
+	baselineURPs map[topicPartition]struct{}
+	prevISRSizes map[topicPartition]int
+	prevNotReady int
+}
+
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+func newAdaptiveThrottle(
+	adaptiveConfig config.AdaptiveThrottleConfig,
+	initialBytes int64,
+) *adaptiveThrottle {
+	stepPercent := adaptiveConfig.StepPercent
+	if stepPercent <= 0 {
+		stepPercent = 20
+	}
+
+	a := &adaptiveThrottle{
+		minBytes:     adaptiveConfig.MinThrottleMB * 1000000,
+		maxBytes:     adaptiveConfig.MaxThrottleMB * 1000000,
+		stepFraction: float64(stepPercent) / 100.0,
+	}
+	a.currBytes = a.clamp(initialBytes)
+
+	return a
+}
+
+// startBatch resets the per-batch state based on the current state of the other topics
+// in the cluster.
+func (a *adaptiveThrottle) startBatch(topics []admin.TopicInfo, topicName string, batchSize int) {
+	a.baselineURPs = map[topicPartition]struct{}{}
+	a.prevISRSizes = map[topicPartition]int{}
+	a.prevNotReady = batchSize
+
+	for _, topic := range topics {
+		if topic.Name == topicName {
+			continue
+		}
+		for _, partition := range topic.Partitions {
+			key := topicPartition{topic: topic.Name, partition: partition.ID}
+			if len(partition.ISR) < len(partition.Replicas) {
+				a.baselineURPs[key] = struct{}{}
+			}
+			a.prevISRSizes[key] = len(partition.ISR)
+		}
+	}
+}
+
+// signals computes the throttle signals from the current state of the other topics in the
+// cluster and the number of partitions in the batch that aren't done yet.
+func (a *adaptiveThrottle) signals(
+	topics []admin.TopicInfo,
+	topicName string,
+	notReady int,
+) throttleSignals {
+	signals := throttleSignals{
+		progressed: notReady < a.prevNotReady,
+	}
+	a.prevNotReady = notReady
+
+	isrSizes := map[topicPartition]int{}
+
+	for _, topic := range topics {
+		if topic.Name == topicName {
+			continue
+		}
+		for _, partition := range topic.Partitions {
+			key := topicPartition{topic: topic.Name, partition: partition.ID}
+			isrSizes[key] = len(partition.ISR)
+
+			if len(partition.ISR) < len(partition.Replicas) {
+				if _, ok := a.baselineURPs[key]; !ok {
+					signals.newURPs++
+				}
+			}
+			if prevSize, ok := a.prevISRSizes[key]; ok && len(partition.ISR) < prevSize {
+				signals.isrShrinks++
+			}
+		}
+	}
+	a.prevISRSizes = isrSizes
+
+	return signals
+}
+
+// next returns the new throttle rate based on the argument signals, along with the reason
+// for the change. The reason is empty if the rate is unchanged.
+func (a *adaptiveThrottle) next(signals throttleSignals) (int64, string) {
+	var newBytes int64
+	var reason string
+
+	if signals.newURPs > 0 || signals.isrShrinks > 0 {
+		newBytes = a.clamp(int64(float64(a.currBytes) * (1.0 - a.stepFraction)))
+		reason = fmt.Sprintf(
+			"%d new under-replicated partition(s) and %d ISR shrink(s) in other topics",
+			signals.newURPs,
+			signals.isrShrinks,
+		)
+	} else if !signals.progressed {
+		newBytes = a.clamp(int64(float64(a.currBytes) * (1.0 + a.stepFraction)))
+		reason = "cluster is healthy but no partitions finished since the last check"
+	} else {
+		return a.currBytes, ""
+	}
+
+	if newBytes == a.currBytes {
+		return a.currBytes, ""
+	}
+
+	a.currBytes = newBytes
+	return newBytes, reason
+}
+
+func (a *adaptiveThrottle) clamp(bytes int64) int64 {
+	if bytes < a.minBytes {
+		return a.minBytes
+	} else if bytes > a.maxBytes {
+		return a.maxBytes
+	}
+	return bytes
+}
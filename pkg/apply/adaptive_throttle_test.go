@@ -0,0 +1,150 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveThrottleNext(t *testing.T) {
+	throttle := newAdaptiveThrottle(
+		config.AdaptiveThrottleConfig{
+			MinThrottleMB: 10,
+			MaxThrottleMB: 50,
+			StepPercent:   50,
+		},
+		120000000,
+	)
+
+	// Initial value is clamped to the max
+	assert.Equal(t, int64(50000000), throttle.currBytes)
+
+	type testCase struct {
+		description   string
+		signals       throttleSignals
+		expectedBytes int64
+		expectChange  bool
+	}
+
+	testCases := []testCase{
+		{
+			description:   "healthy and progressing",
+			signals:       throttleSignals{progressed: true},
+			expectedBytes: 50000000,
+			expectChange:  false,
+		},
+		{
+			description:   "new URPs",
+			signals:       throttleSignals{newURPs: 2, progressed: true},
+			expectedBytes: 25000000,
+			expectChange:  true,
+		},
+		{
+			description:   "ISR shrinks",
+			signals:       throttleSignals{isrShrinks: 1},
+			expectedBytes: 12500000,
+			expectChange:  true,
+		},
+		{
+			description:   "ISR shrinks at min",
+			signals:       throttleSignals{isrShrinks: 1},
+			expectedBytes: 10000000,
+			expectChange:  true,
+		},
+		{
+			description:   "ISR shrinks below min",
+			signals:       throttleSignals{isrShrinks: 1},
+			expectedBytes: 10000000,
+			expectChange:  false,
+		},
+		{
+			description:   "healthy but stalled",
+			signals:       throttleSignals{},
+			expectedBytes: 15000000,
+			expectChange:  true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		newBytes, reason := throttle.next(testCase.signals)
+		assert.Equal(t, testCase.expectedBytes, newBytes, testCase.description)
+		assert.Equal(t, testCase.expectChange, reason != "", testCase.description)
+	}
+}
+
+func TestAdaptiveThrottleSignals(t *testing.T) {
+	throttle := newAdaptiveThrottle(
+		config.AdaptiveThrottleConfig{
+			MinThrottleMB: 10,
+			MaxThrottleMB: 50,
+		},
+		20000000,
+	)
+	assert.Equal(t, 0.2, throttle.stepFraction)
+
+	throttle.startBatch(
+		[]admin.TopicInfo{
+			{
+				Name: "migrating-topic",
+				Partitions: []admin.PartitionInfo{
+					{ID: 0, Replicas: []int{1, 2, 3}, ISR: []int{1, 2}},
+				},
+			},
+			{
+				Name: "other-topic",
+				Partitions: []admin.PartitionInfo{
+					{ID: 0, Replicas: []int{1, 2, 3}, ISR: []int{1, 2}},
+					{ID: 1, Replicas: []int{1, 2, 3}, ISR: []int{1, 2, 3}},
+					{ID: 2, Replicas: []int{1, 2, 3}, ISR: []int{1, 2, 3}},
+				},
+			},
+		},
+		"migrating-topic",
+		3,
+	)
+
+	// The migrating topic and the existing URP are ignored
+	signals := throttle.signals(
+		[]admin.TopicInfo{
+			{
+				Name: "migrating-topic",
+				Partitions: []admin.PartitionInfo{
+					{ID: 0, Replicas: []int{1, 2, 3, 4}, ISR: []int{1}},
+				},
+			},
+			{
+				Name: "other-topic",
+				Partitions: []admin.PartitionInfo{
+					{ID: 0, Replicas: []int{1, 2, 3}, ISR: []int{1, 2}},
+					{ID: 1, Replicas: []int{1, 2, 3}, ISR: []int{1, 2, 3}},
+					{ID: 2, Replicas: []int{1, 2, 3}, ISR: []int{1, 2, 3}},
+				},
+			},
+		},
+		"migrating-topic",
+		2,
+	)
+	assert.Equal(t, throttleSignals{progressed: true}, signals)
+
+	signals = throttle.signals(
+		[]admin.TopicInfo{
+			{
+				Name: "other-topic",
+				Partitions: []admin.PartitionInfo{
+					{ID: 0, Replicas: []int{1, 2, 3}, ISR: []int{1}},
+					{ID: 1, Replicas: []int{1, 2, 3}, ISR: []int{1, 3}},
+					{ID: 2, Replicas: []int{1, 2, 3}, ISR: []int{1, 2, 3}},
+				},
+			},
+		},
+		"migrating-topic",
+		2,
+	)
+	assert.Equal(
+		t,
+		throttleSignals{newURPs: 1, isrShrinks: 2, progressed: false},
+		signals,
+	)
+}
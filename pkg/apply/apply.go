@@ -87,6 +87,9 @@ type UpdateChangesTracker struct {
 	// tracks changes in partition count
 	NumPartitions *IntValueChanges `json:"numPartitions"`
 
+	// tracks changes in replication factor
+	ReplicationFactor *IntValueChanges `json:"replicationFactor"`
+
 	// tracks changes in replica assignments
 	ReplicaAssignments *[]ReplicaAssignmentChanges `json:"replicaAssignments"`
 
@@ -106,7 +109,7 @@ func (changes *UpdateChangesTracker) mergeReplicaAssignments(
 	desiredAssignments []admin.PartitionAssignment,
 ) {
 	// when creating a new topic, updatePlacements is called with UpdateChangesTracker == nil
-	if changes == nil {
+	if changes == nil || changes.ReplicaAssignments == nil {
 		return
 	}
 
@@ -119,9 +122,61 @@ func (changes *UpdateChangesTracker) mergeReplicaAssignments(
 	}
 }
 
+// trackReplicaAssignments records the current partition assignments in the argument topic
+// so that later replica moves can be merged in. It's a no-op if the assignments are already
+// being tracked.
+func (changes *UpdateChangesTracker) trackReplicaAssignments(topicInfo admin.TopicInfo) {
+	if changes == nil || changes.ReplicaAssignments != nil {
+		return
+	}
+
+	assignmentChanges := make([]ReplicaAssignmentChanges, 0)
+	for _, assignment := range topicInfo.ToAssignments() {
+		assignmentChanges = append(assignmentChanges, ReplicaAssignmentChanges{
+			Partition:       assignment.ID,
+			CurrentReplicas: assignment.Replicas,
+			UpdatedReplicas: nil,
+		})
+	}
+	changes.ReplicaAssignments = &assignmentChanges
+}
+
 // used as a Union type of NewChangesTracker and UpdateChangesTracker
 type NewOrUpdatedChanges interface{}
 
+// nonNilChanges converts typed nil pointers to a nil interface so that they're omitted
+// when serialized.
+func nonNilChanges(changes NewOrUpdatedChanges) NewOrUpdatedChanges {
+	switch typedChanges := changes.(type) {
+	case *NewChangesTracker:
+		if typedChanges == nil {
+			return nil
+		}
+	case *UpdateChangesTracker:
+		if typedChanges == nil {
+			return nil
+		}
+	}
+	return changes
+}
+
+// RebalanceStrategy is a string type that stores what a rebalance evens out across the brokers.
+type RebalanceStrategy string
+
+const (
+	// RebalanceStrategyCount balances the number of replicas on each broker.
+	RebalanceStrategyCount RebalanceStrategy = "count"
+
+	// RebalanceStrategyDisk balances the bytes on each broker and log dir.
+	RebalanceStrategyDisk RebalanceStrategy = "disk"
+)
+
+// AllRebalanceStrategies contains all of the valid rebalance strategies.
+var AllRebalanceStrategies = []RebalanceStrategy{
+	RebalanceStrategyCount,
+	RebalanceStrategyDisk,
+}
+
 // TopicApplierConfig contains the configuration for a TopicApplier struct.
 type TopicApplierConfig struct {
 	BrokerThrottleMBsOverride  int
@@ -138,6 +193,33 @@ type TopicApplierConfig struct {
 	Destructive                bool
 	SleepLoopDuration          time.Duration
 	TopicConfig                config.TopicConfig
+
+	// MigrationStateDir is the directory where the progress of placement migrations is
+	// recorded. If blank, then progress isn't recorded and migrations can't be resumed.
+	MigrationStateDir string
+
+	// Resume indicates whether to continue an interrupted migration instead of starting
+	// a new one.
+	Resume bool
+
+	// HistoryDir is the directory where the state of topics before they're changed is
+	// recorded so that the changes can be rolled back. If blank, then no history is kept.
+	HistoryDir string
+
+	// Events, if set, receives structured progress events.
+	Events *EventWriter
+
+	// Scheduler, if set, coordinates this apply with the migrations of other topics running
+	// at the same time. It holds the cluster lock and manages the broker throttles.
+	Scheduler *MigrationScheduler
+
+	// RebalanceBy is what rebalances even out across the brokers. If blank, then replica counts
+	// are used.
+	RebalanceBy RebalanceStrategy
+
+	// DiskTolerance is the fraction that the bytes on a broker or log dir can be above the mean
+	// in disk rebalances. If zero, then rebalancers.DefaultDiskTolerance is used.
+	DiskTolerance float64
 }
 
 // TopicApplier executes an "apply" run on a topic by comparing the actual
@@ -151,9 +233,18 @@ type TopicApplier struct {
 	// Pull out some fields for easier access
 	clusterConfig config.ClusterConfig
 	maxBatchSize  int
+	batchBytes    int64
 	throttleBytes int64
 	topicConfig   config.TopicConfig
 	topicName     string
+
+	// migrationState tracks the progress of the current placement migration; nil if
+	// no migration is running or progress isn't being recorded.
+	migrationState *MigrationState
+
+	// adaptiveThrottle adjusts throttleBytes during migrations; nil if adaptive throttles
+	// aren't enabled.
+	adaptiveThrottle *adaptiveThrottle
 }
 
 // NewTopicApplier creates and returns a new TopicApplier instance.
@@ -173,6 +264,7 @@ func NewTopicApplier(
 	if err != nil {
 		return nil, err
 	}
+	brokers = applierConfig.ClusterConfig.Spec.BrokerTags.TagBrokers(brokers)
 
 	var maxBatchSize int
 	if applierConfig.PartitionBatchSizeOverride > 0 {
@@ -195,15 +287,28 @@ func NewTopicApplier(
 		throttleBytes = 120000000
 	}
 
+	// Adaptive throttles start from the value above, clamped to the configured bounds
+	var throttle *adaptiveThrottle
+	if adaptiveConfig := applierConfig.TopicConfig.Spec.MigrationConfig.AdaptiveThrottle; adaptiveConfig != nil {
+		if applierConfig.Scheduler != nil {
+			log.Warnf("Adaptive throttles aren't supported when migrating topics in parallel; ignoring")
+		} else {
+			throttle = newAdaptiveThrottle(*adaptiveConfig, throttleBytes)
+			throttleBytes = throttle.currBytes
+		}
+	}
+
 	return &TopicApplier{
-		adminClient:   adminClient,
-		config:        applierConfig,
-		brokers:       brokers,
-		clusterConfig: applierConfig.ClusterConfig,
-		maxBatchSize:  maxBatchSize,
-		throttleBytes: throttleBytes,
-		topicConfig:   applierConfig.TopicConfig,
-		topicName:     applierConfig.TopicConfig.Meta.Name,
+		adminClient:      adminClient,
+		config:           applierConfig,
+		brokers:          brokers,
+		clusterConfig:    applierConfig.ClusterConfig,
+		maxBatchSize:     maxBatchSize,
+		batchBytes:       applierConfig.TopicConfig.Spec.MigrationConfig.BatchBytes,
+		throttleBytes:    throttleBytes,
+		topicConfig:      applierConfig.TopicConfig,
+		topicName:        applierConfig.TopicConfig.Meta.Name,
+		adaptiveThrottle: throttle,
 	}, nil
 }
 
@@ -224,6 +329,7 @@ func ensureChangesOccurred(updateChanges *UpdateChangesTracker) (*UpdateChangesT
 
 	// if nothing actually changed, report changes as nil
 	if (updateChanges.NumPartitions == nil &&
+		updateChanges.ReplicationFactor == nil &&
 		updateChanges.NewConfigEntries == nil &&
 		updateChanges.UpdatedConfigEntries == nil &&
 		len(updateChanges.MissingKeys) == 0 &&
@@ -244,10 +350,13 @@ func ensureChangesOccurred(updateChanges *UpdateChangesTracker) (*UpdateChangesT
 //     b. Update the placement in accordance with the configured strategy
 //  5. If exists:
 //     a. Check retention and update if needed
-//     b. Check replication factor (can't be updated by topicctl)
+//     b. Check replication factor and update/migrate if needed
 //     c. Check partition count and extend if needed
 //     d. Check partition placement and update/migrate if needed
 //     e. Check partition leaders and update if needed
+//
+// Any hooks in the cluster config are run before settings are updated, before and after each
+// batch of partition reassignments, and after the topic has been changed.
 func (t *TopicApplier) Apply(ctx context.Context) (NewOrUpdatedChanges, error) {
 	log.Info("Validating configs...")
 	brokerRacks := admin.DistinctRacks(t.brokers)
@@ -270,22 +379,117 @@ func (t *TopicApplier) Apply(ctx context.Context) (NewOrUpdatedChanges, error) {
 		// if the topic doesn't exist, create it
 		if err == admin.ErrTopicDoesNotExist {
 			newTopicChanges, err := t.applyNewTopic(ctx)
+			if newTopicChanges == nil {
+				t.emitSummary(nil, err)
+				return nil, err
+			}
 			if err != nil {
 				msg := err.Error()
 				newTopicChanges.ErrorMessage = &msg
 			}
+			t.runHooks(ctx, config.HookEventPostApply, newTopicChanges, nil, err)
+			t.emitSummary(newTopicChanges, err)
 			return newTopicChanges, err
 		}
 		return nil, err
 	}
 
+	// record the current state of the topic so that the changes can be rolled back
+	revision, err := t.recordRevision(topicInfo)
+	if err != nil {
+		return nil, err
+	}
+
 	// if the topic does exist, update it
 	updatedTopic, err := t.applyExistingTopic(ctx, topicInfo)
+	t.cleanupRevision(ctx, revision)
+
 	if err != nil {
+		if updatedTopic == nil {
+			t.emitSummary(nil, err)
+			return nil, err
+		}
 		msg := err.Error()
     updatedTopic.ErrorMessage = &msg
 	}
-	return ensureChangesOccurred(updatedTopic), err
+
+	updatedTopic = ensureChangesOccurred(updatedTopic)
+
+	if updatedTopic != nil {
+		t.runHooks(ctx, config.HookEventPostApply, updatedTopic, nil, err)
+	}
+	t.emitSummary(updatedTopic, err)
+
+	return updatedTopic, err
+}
+
+// emitSummary emits the events at the end of an apply: an error event if the apply failed,
+// followed by a summary of the changes.
+func (t *TopicApplier) emitSummary(changes NewOrUpdatedChanges, err error) {
+	if err != nil {
+		t.emitEvent(
+			Event{
+				Type:  EventTypeError,
+				Error: err.Error(),
+			},
+		)
+	}
+	t.emitEvent(
+		Event{
+			Type:    EventTypeSummary,
+			Changes: changes,
+		},
+	)
+}
+
+// recordRevision saves the current state of the topic to the history directory before any
+// changes are made. It returns nil if history isn't being kept.
+func (t *TopicApplier) recordRevision(topicInfo admin.TopicInfo) (*TopicRevision, error) {
+	if t.config.DryRun || t.config.HistoryDir == "" {
+		return nil, nil
+	}
+
+	revision, err := NewTopicRevision(
+		t.config.HistoryDir,
+		t.clusterConfig.Meta.Name,
+		t.clusterConfig.Meta.Environment,
+		t.clusterConfig.Meta.Region,
+		topicInfo,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := revision.Save(); err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// cleanupRevision removes the argument revision if the topic still matches it, i.e. if the
+// apply was declined, failed, or didn't need to change anything, so that a later rollback
+// doesn't restore a state that was never left.
+func (t *TopicApplier) cleanupRevision(ctx context.Context, revision *TopicRevision) {
+	if revision == nil {
+		return
+	}
+
+	topicInfo, err := t.adminClient.GetTopic(ctx, t.topicName, true)
+	if err != nil {
+		log.Warnf("Error checking whether topic %s was changed: %+v", t.topicName, err)
+	} else if revision.Matches(topicInfo) {
+		// Nothing changed, so there's nothing to roll back
+		if err := revision.Delete(); err != nil {
+			log.Warnf("Error removing unused topic revision: %+v", err)
+		}
+		return
+	}
+
+	log.Infof(
+		"Recorded the previous state of topic %s as revision %d",
+		t.topicName,
+		revision.Revision,
+	)
 }
 
 func (t *TopicApplier) applyNewTopic(ctx context.Context) (*NewChangesTracker, error) {
@@ -345,6 +549,17 @@ func (t *TopicApplier) applyExistingTopic(
 ) (*UpdateChangesTracker, error) {
 	log.Infof("Updating existing topic '%s'", t.topicName)
 
+	resumed, err := t.resumeMigration(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if resumed {
+		topicInfo, err = t.adminClient.GetTopic(ctx, t.topicName, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if err := t.checkExistingState(ctx, topicInfo); err != nil {
 		return nil, err
 	}
@@ -360,7 +575,11 @@ func (t *TopicApplier) applyExistingTopic(
 		return changes, err
 	}
 
-	if err := t.updateReplication(ctx, topicInfo); err != nil {
+	// record current partition assignments before we start any replication changes or
+	// rebalances
+	changes.trackReplicaAssignments(topicInfo)
+
+	if err := t.updateReplication(ctx, topicInfo, changes); err != nil {
 		return changes, err
 	}
 
@@ -372,18 +591,6 @@ func (t *TopicApplier) applyExistingTopic(
 		return changes, err
 	}
 
-	// record current partition assignments before we start any rebalances
-	assignments := topicInfo.ToAssignments()
-	assignmentChanges := make([]ReplicaAssignmentChanges, 0)
-	for _, assignment := range assignments {
-		assignmentChanges = append(assignmentChanges, ReplicaAssignmentChanges{
-			Partition:       assignment.ID,
-			CurrentReplicas: assignment.Replicas,
-			UpdatedReplicas: nil,
-		})
-	}
-	changes.ReplicaAssignments = &assignmentChanges
-
 	if err := t.updatePlacement(
 		ctx,
 		t.maxBatchSize,
@@ -426,12 +633,19 @@ func (t *TopicApplier) checkExistingState(
 ) error {
 	log.Infof("Checking the existing state of the cluster, topic, and throttles...")
 
-	lockHeld, err := t.clusterLockHeld(ctx)
-	if err != nil {
-		return err
-	}
-	if lockHeld {
-		log.Warnf("The cluster lock is currently held, partition migrations might be locked")
+	// When running with a scheduler, the cluster lock is held by this process; the scheduler
+	// keeps track of the throttles that the other migrations in the process are using
+	lockHeld := false
+
+	if t.config.Scheduler == nil {
+		var err error
+		lockHeld, err = t.clusterLockHeld(ctx)
+		if err != nil {
+			return err
+		}
+		if lockHeld {
+			log.Warnf("The cluster lock is currently held, partition migrations might be locked")
+		}
 	}
 
 	outOfSync := topicInfo.OutOfSyncPartitions(nil)
@@ -503,7 +717,15 @@ func (t *TopicApplier) checkExistingState(
 						log.Info("Skipping removal")
 						return nil
 					}
-					if err := t.removeThottles(ctx, false, throttledBrokers); err != nil {
+					if t.config.Scheduler != nil {
+						// Don't remove throttles that other migrations are using
+						if err := t.config.Scheduler.removeUnusedThrottles(
+							ctx,
+							throttledBrokers,
+						); err != nil {
+							return err
+						}
+					} else if err := t.removeThottles(ctx, false, throttledBrokers); err != nil {
 						return err
 					}
 				}
@@ -519,6 +741,191 @@ func (t *TopicApplier) checkExistingState(
 	return nil
 }
 
+// resumeMigration continues a placement migration that was interrupted in a previous run.
+// It waits for the in-progress batch to finish, removes the throttles that were left behind,
+// and then runs the remaining batches with their original target assignments. It returns
+// true if an interrupted migration was found and completed.
+func (t *TopicApplier) resumeMigration(ctx context.Context) (bool, error) {
+	if t.config.MigrationStateDir == "" {
+		if t.config.Resume {
+			return false, errors.New("Cannot resume migrations without a migration state directory")
+		}
+		return false, nil
+	}
+
+	path := t.migrationStatePath()
+	state, err := LoadMigrationState(path)
+	if err != nil {
+		return false, err
+	}
+	if state == nil {
+		if t.config.Resume {
+			log.Infof("No interrupted migration found for topic %s in %s", t.topicName, path)
+		}
+		return false, nil
+	}
+
+	log.Infof(
+		"Found interrupted migration for topic %s started at %s; %d of %d batches completed",
+		t.topicName,
+		state.StartedAt.Format(time.RFC3339),
+		state.CompletedBatches,
+		state.NumBatches(),
+	)
+
+	if !t.config.Resume {
+		log.Warnf(
+			"Ignoring the interrupted migration for topic %s; re-run with --resume to continue it or remove %s to start over",
+			t.topicName,
+			path,
+		)
+		return false, nil
+	}
+	if t.config.DryRun {
+		log.Infof("Skipping resume because dryRun is set to true")
+		return false, nil
+	}
+
+	lock, lockPath, err := t.acquireClusterLock(ctx)
+	if err != nil {
+		return false, err
+	}
+	if lock != nil {
+		defer func() {
+			log.Infof("Releasing cluster lock: %s", lockPath)
+			lock.Unlock()
+		}()
+	}
+
+	origRemaining, targetRemaining := state.RemainingAssignments()
+
+	// The batch that was running when the migration was interrupted might still be in
+	// progress; wait for it to finish before touching the throttles.
+	inFlightStart, inFlightEnd := state.batchRange(state.CompletedBatches)
+	inFlight := inFlightEnd - inFlightStart
+
+	if state.BatchSize <= 0 && len(state.BatchEnds) == 0 {
+		state.BatchSize = len(targetRemaining)
+	}
+	if err := t.waitForAssignments(
+		ctx,
+		origRemaining[:inFlight],
+		targetRemaining[:inFlight],
+	); err != nil {
+		return false, err
+	}
+
+	if state.ThrottledTopic || len(state.ThrottledBrokers) > 0 {
+		orphanedBrokers := state.ThrottledBrokers
+		if t.config.Scheduler != nil {
+			// Don't remove throttles that other migrations are using
+			orphanedBrokers = t.config.Scheduler.unusedBrokers(orphanedBrokers)
+		}
+
+		log.Infof("Removing throttles left behind by the interrupted migration")
+		if err := t.removeThottles(ctx, state.ThrottledTopic, orphanedBrokers); err != nil {
+			return false, err
+		}
+		if err := state.setThrottles(false, nil); err != nil {
+			return false, err
+		}
+	}
+
+	topicInfo, err := t.adminClient.GetTopic(ctx, t.topicName, true)
+	if err != nil {
+		return false, err
+	}
+	currAssignments := topicInfo.ToAssignments()
+	desiredAssignments := admin.CopyAssignments(currAssignments)
+
+	for a, target := range targetRemaining {
+		if target.ID >= len(currAssignments) {
+			return false, fmt.Errorf(
+				"Partition %d in interrupted migration no longer exists; remove %s and re-run apply",
+				target.ID,
+				path,
+			)
+		}
+
+		currReplicas := currAssignments[target.ID].Replicas
+		if reflect.DeepEqual(currReplicas, target.Replicas) {
+			continue
+		}
+		if !reflect.DeepEqual(currReplicas, origRemaining[a].Replicas) {
+			return false, fmt.Errorf(
+				"Replicas for partition %d (%+v) match neither the original (%+v) nor the target (%+v) in the interrupted migration; remove %s and re-run apply",
+				target.ID,
+				currReplicas,
+				origRemaining[a].Replicas,
+				target.Replicas,
+				path,
+			)
+		}
+		desiredAssignments[target.ID] = target
+	}
+
+	if len(admin.AssignmentsToUpdate(currAssignments, desiredAssignments)) == 0 {
+		log.Infof("All partitions in the interrupted migration have been moved")
+		return true, state.Delete()
+	}
+
+	if err := t.updatePlacementRunner(
+		ctx,
+		currAssignments,
+		desiredAssignments,
+		state.BatchSize,
+		false,
+		nil,
+	); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// waitForAssignments waits until each of the argument partitions is fully in-sync and has
+// either its original or its target replicas.
+func (t *TopicApplier) waitForAssignments(
+	ctx context.Context,
+	origAssignments []admin.PartitionAssignment,
+	targetAssignments []admin.PartitionAssignment,
+) error {
+	for {
+		topicInfo, err := t.adminClient.GetTopic(ctx, t.topicName, true)
+		if err != nil {
+			return err
+		}
+
+		notReady := []admin.PartitionInfo{}
+
+		for a, target := range targetAssignments {
+			if target.ID >= len(topicInfo.Partitions) {
+				continue
+			}
+			partitionInfo := topicInfo.Partitions[target.ID]
+
+			if !util.SameElements(partitionInfo.Replicas, partitionInfo.ISR) ||
+				(!reflect.DeepEqual(partitionInfo.Replicas, target.Replicas) &&
+					!reflect.DeepEqual(partitionInfo.Replicas, origAssignments[a].Replicas)) {
+				notReady = append(notReady, partitionInfo)
+			}
+		}
+
+		if len(notReady) == 0 {
+			return nil
+		}
+
+		log.Infof(
+			"Waiting for %d partition(s) from the interrupted batch to finish moving:\n%s",
+			len(notReady),
+			admin.FormatTopicPartitions(notReady, t.brokers),
+		)
+		if err := interruptableSleep(ctx, t.config.SleepLoopDuration); err != nil {
+			return err
+		}
+	}
+}
+
 func (t *TopicApplier) updateSettings(
 	ctx context.Context,
 	topicInfo admin.TopicInfo,
@@ -628,6 +1035,10 @@ func (t *TopicApplier) updateSettings(
 		}
 		log.Infof("OK, updating")
 
+		if err := t.runHooks(ctx, config.HookEventPreSettings, changes, nil, nil); err != nil {
+			return err
+		}
+
 		_, err = t.adminClient.UpdateTopicConfig(
 			ctx,
 			t.topicName,
@@ -645,18 +1056,94 @@ func (t *TopicApplier) updateSettings(
 func (t *TopicApplier) updateReplication(
 	ctx context.Context,
 	topicInfo admin.TopicInfo,
+	changes *UpdateChangesTracker,
 ) error {
 	log.Infof("Checking replication...")
 
-	currReplication := topicInfo.MaxISR()
-	if currReplication != t.topicConfig.Spec.ReplicationFactor {
+	currReplication := topicInfo.MaxReplication()
+	if currReplication == t.topicConfig.Spec.ReplicationFactor {
+		return nil
+	}
+
+	if !topicInfo.AllReplicasInSync() {
 		return fmt.Errorf(
-			"Replication in topic config (%d) is not equal to observed max ISR (%d); this cannot be resolved by topicctl",
+			"Replication in topic config (%d) is not equal to observed replication (%d), but replicas are not in-sync; please try again later",
 			t.topicConfig.Spec.ReplicationFactor,
 			currReplication,
 		)
 	}
 
+	lock, path, err := t.acquireClusterLock(ctx)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		defer func() {
+			log.Infof("Releasing cluster lock: %s", path)
+			lock.Unlock()
+		}()
+	}
+
+	return t.updateReplicationHelper(ctx, changes)
+}
+
+func (t *TopicApplier) updateReplicationHelper(
+	ctx context.Context,
+	changes *UpdateChangesTracker,
+) error {
+	topicInfo, err := t.adminClient.GetTopic(ctx, t.topicName, true)
+	if err != nil {
+		return err
+	}
+	currAssignments := topicInfo.ToAssignments()
+
+	// note current and updated replication factors to be added to UpdateChangesTracker
+	// after applying
+	replicationChanges := &IntValueChanges{
+		Current: topicInfo.MaxReplication(),
+		Updated: t.topicConfig.Spec.ReplicationFactor,
+	}
+
+	log.Infof(
+		"Trying to change replication from %d to %d consistent with '%s' strategy",
+		replicationChanges.Current,
+		replicationChanges.Updated,
+		t.topicConfig.Spec.PlacementConfig.Strategy,
+	)
+
+	picker, err := t.getPicker(ctx)
+	if err != nil {
+		return err
+	}
+
+	assigner := assigners.NewReplicationAssigner(
+		t.placementBrokers(),
+		t.topicConfig.Spec.PlacementConfig,
+		t.topicConfig.Spec.ReplicationFactor,
+		picker,
+	)
+	desiredAssignments, err := assigner.Assign(t.topicName, currAssignments)
+	if err != nil {
+		return err
+	}
+
+	batchSize := t.maxBatchSize
+	if batchSize <= 0 {
+		batchSize = len(currAssignments)
+	}
+
+	if err := t.updatePlacementRunner(
+		ctx,
+		currAssignments,
+		desiredAssignments,
+		batchSize,
+		false,
+		changes,
+	); err != nil {
+		return err
+	}
+	changes.ReplicationFactor = replicationChanges
+
 	return nil
 }
 
@@ -739,16 +1226,22 @@ func (t *TopicApplier) updatePartitionsHelper(
 		}
 	case config.PlacementStrategyInRack:
 		extender = extenders.NewBalancedExtender(
-			t.brokers,
+			t.placementBrokers(),
 			true,
 			picker,
 		)
 	case config.PlacementStrategyBalancedLeaders, config.PlacementStrategyAny:
 		extender = extenders.NewBalancedExtender(
-			t.brokers,
+			t.placementBrokers(),
 			false,
 			picker,
 		)
+	case config.PlacementStrategyLeaderRack:
+		extender = extenders.NewLeaderRackExtender(
+			t.placementBrokers(),
+			t.topicConfig.Spec.PlacementConfig.PreferredLeaderRacks,
+			picker,
+		)
 	default:
 		return fmt.Errorf("Cannot extend using strategy %s", desiredPlacement)
 	}
@@ -856,9 +1349,11 @@ func (t *TopicApplier) updatePlacement(
 	}
 
 	switch desiredPlacement {
-	case config.PlacementStrategyStatic,
+	case config.PlacementStrategyAny,
+		config.PlacementStrategyStatic,
 		config.PlacementStrategyStaticInRack,
-		config.PlacementStrategyBalancedLeaders:
+		config.PlacementStrategyBalancedLeaders,
+		config.PlacementStrategyLeaderRack:
 		return t.updatePlacementHelper(
 			ctx,
 			desiredPlacement,
@@ -871,7 +1366,7 @@ func (t *TopicApplier) updatePlacement(
 		// block this, but we should at least warn the user before continuing.
 		result, err = assigners.EvaluateAssignments(
 			currAssignments,
-			t.brokers,
+			t.placementBrokers(),
 			config.TopicPlacementConfig{
 				Strategy: config.PlacementStrategyBalancedLeaders,
 			},
@@ -920,12 +1415,10 @@ func (t *TopicApplier) updateBalance(
 	}
 	currAssignments := topicInfo.ToAssignments()
 
-	// TODO: Make these parameters configurable?
-	rebalancer := rebalancers.NewFrequencyRebalancer(
-		t.brokers,
-		pickers.NewRandomizedPicker(),
-		t.topicConfig.Spec.PlacementConfig,
-	)
+	rebalancer, err := t.getRebalancer(ctx)
+	if err != nil {
+		return err
+	}
 	desiredAssignments, err := rebalancer.Rebalance(
 		t.topicName,
 		currAssignments,
@@ -953,6 +1446,44 @@ func (t *TopicApplier) updateBalance(
 	)
 }
 
+func (t *TopicApplier) getRebalancer(ctx context.Context) (rebalancers.Rebalancer, error) {
+	switch t.config.RebalanceBy {
+	case "", RebalanceStrategyCount:
+		// TODO: Make these parameters configurable?
+		return rebalancers.NewFrequencyRebalancer(
+			t.placementBrokers(),
+			pickers.NewRandomizedPicker(),
+			t.topicConfig.Spec.PlacementConfig,
+			t.brokerWeights(),
+		), nil
+	case RebalanceStrategyDisk:
+		// The sizes of all topics are needed since they all take up space on the brokers
+		replicaSizes, err := t.adminClient.GetReplicaSizes(
+			ctx,
+			admin.BrokerIDs(t.brokers),
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("Error getting replica sizes for disk rebalance: %+v", err)
+		}
+
+		tolerance := t.config.DiskTolerance
+		if tolerance == 0 {
+			tolerance = rebalancers.DefaultDiskTolerance
+		}
+
+		return rebalancers.NewDiskRebalancer(
+			t.placementBrokers(),
+			t.topicConfig.Spec.PlacementConfig,
+			replicaSizes,
+			t.brokerWeights(),
+			tolerance,
+		), nil
+	default:
+		return nil, fmt.Errorf("Unrecognized rebalance strategy: %s", t.config.RebalanceBy)
+	}
+}
+
 func (t *TopicApplier) updatePlacementHelper(
 	ctx context.Context,
 	desiredPlacement config.PlacementStrategy,
@@ -975,13 +1506,28 @@ func (t *TopicApplier) updatePlacementHelper(
 		return err
 	}
 
+	placementConfig := t.topicConfig.Spec.PlacementConfig
+	placementBrokers := t.placementBrokers()
+
 	switch desiredPlacement {
+	case config.PlacementStrategyAny:
+		// The "any" strategy is always satisfied, so we only get here if the placement
+		// constraints aren't; the constrained assigner below moves the affected replicas.
+		if !placementConfig.HasConstraints() {
+			return fmt.Errorf("Cannot update using strategy %s", desiredPlacement)
+		}
 	case config.PlacementStrategyBalancedLeaders:
-		assigner = assigners.NewBalancedLeaderAssigner(t.brokers, picker)
+		assigner = assigners.NewBalancedLeaderAssigner(placementBrokers, picker)
 	case config.PlacementStrategyInRack:
-		assigner = assigners.NewSingleRackAssigner(t.brokers, picker)
+		assigner = assigners.NewSingleRackAssigner(placementBrokers, picker)
 	case config.PlacementStrategyCrossRack:
-		assigner = assigners.NewCrossRackAssigner(t.brokers, picker)
+		assigner = assigners.NewCrossRackAssigner(placementBrokers, picker)
+	case config.PlacementStrategyLeaderRack:
+		assigner = assigners.NewLeaderRackAssigner(
+			placementBrokers,
+			placementConfig.PreferredLeaderRacks,
+			picker,
+		)
 	case config.PlacementStrategyStatic:
 		assigner = &assigners.StaticAssigner{
 			Assignments: admin.ReplicasToAssignments(
@@ -990,7 +1536,7 @@ func (t *TopicApplier) updatePlacementHelper(
 		}
 	case config.PlacementStrategyStaticInRack:
 		assigner = assigners.NewStaticSingleRackAssigner(
-			t.brokers,
+			placementBrokers,
 			t.topicConfig.Spec.PlacementConfig.StaticRackAssignments,
 			picker,
 		)
@@ -998,6 +1544,15 @@ func (t *TopicApplier) updatePlacementHelper(
 		return fmt.Errorf("Cannot update using strategy %s", desiredPlacement)
 	}
 
+	if placementConfig.HasConstraints() {
+		assigner = assigners.NewConstrainedAssigner(
+			assigner,
+			t.brokers,
+			placementConfig,
+			picker,
+		)
+	}
+
 	desiredAssignments, err := assigner.Assign(t.topicName, currAssignments)
 	if err != nil {
 		return err
@@ -1039,11 +1594,81 @@ func (t *TopicApplier) updatePlacementRunner(
 		),
 	)
 
-	log.Infof(
-		"They will be applied in batches of %d partitions each, with a throttle of %d bytes/sec (%d MB/sec)",
+	assignmentsToUpdate := admin.AssignmentsToUpdate(
+		currAssignments,
+		desiredAssignments,
+	)
+
+	currDiffAssignments := []admin.PartitionAssignment{}
+
+	for _, diff := range assignmentsToUpdate {
+		currDiffAssignments = append(
+			currDiffAssignments,
+			currAssignments[diff.ID],
+		)
+	}
+
+	// New topics don't have any data to move, so they're only batched by partition count
+	var batchBytes int64
+	var partitionSizes map[int]int64
+	if t.batchBytes > 0 && !newTopic {
+		batchBytes = t.batchBytes
+
+		brokerIDs := []int{}
+		for _, broker := range t.brokers {
+			brokerIDs = append(brokerIDs, broker.ID)
+		}
+		replicaSizes, err := t.adminClient.GetReplicaSizes(
+			ctx,
+			brokerIDs,
+			[]string{t.topicName},
+		)
+		if err != nil {
+			return fmt.Errorf("Error getting partition sizes for batching: %+v", err)
+		}
+		partitionSizes = admin.PartitionSizes(replicaSizes, t.topicName)
+	}
+
+	batches := batchAssignments(
+		currDiffAssignments,
+		assignmentsToUpdate,
 		batchSize,
+		batchBytes,
+		partitionSizes,
 		t.throttleBytes,
-		t.throttleBytes/1000000,
+	)
+	numRounds := len(batches)
+
+	if batchBytes > 0 {
+		log.Infof(
+			"They will be applied in %d batches of up to %s each, with a throttle of %d bytes/sec (%d MB/sec)",
+			numRounds,
+			util.PrettyBytes(batchBytes),
+			t.throttleBytes,
+			t.throttleBytes/1000000,
+		)
+		log.Infof(
+			"Here are the estimated bytes and durations of the batches:\n%s",
+			formatMigrationBatches(batches, assignmentsToUpdate),
+		)
+	} else {
+		log.Infof(
+			"They will be applied in batches of %d partitions each, with a throttle of %d bytes/sec (%d MB/sec)",
+			batchSize,
+			t.throttleBytes,
+			t.throttleBytes/1000000,
+		)
+	}
+
+	t.emitEvent(
+		Event{
+			Type:              EventTypePlanComputed,
+			BatchSize:         batchSize,
+			NumRounds:         numRounds,
+			CurrAssignments:   currAssignments,
+			TargetAssignments: desiredAssignments,
+			ThrottleBytes:     t.throttleBytes,
+		},
 	)
 
 	if t.config.DryRun {
@@ -1061,28 +1686,39 @@ func (t *TopicApplier) updatePlacementRunner(
 		return errors.New("Stopping because of user response")
 	}
 
-	assignmentsToUpdate := admin.AssignmentsToUpdate(
-		currAssignments,
-		desiredAssignments,
-	)
-
-	currDiffAssignments := []admin.PartitionAssignment{}
+	if !newTopic && t.config.MigrationStateDir != "" {
+		t.migrationState = &MigrationState{
+			Version:           MigrationStateVersion,
+			Cluster:           t.clusterConfig.Meta.Name,
+			Environment:       t.clusterConfig.Meta.Environment,
+			Region:            t.clusterConfig.Meta.Region,
+			Topic:             t.topicName,
+			StartedAt:         time.Now().UTC(),
+			CurrAssignments:   admin.CopyAssignments(currDiffAssignments),
+			TargetAssignments: admin.CopyAssignments(assignmentsToUpdate),
+			BatchSize:         batchSize,
+			ThrottledBrokers:  []int{},
+			path:              t.migrationStatePath(),
+		}
+		if batchBytes > 0 {
+			for _, batch := range batches {
+				t.migrationState.BatchEnds = append(t.migrationState.BatchEnds, batch.end)
+			}
+		}
+		defer func() {
+			t.migrationState = nil
+		}()
 
-	for _, diff := range assignmentsToUpdate {
-		currDiffAssignments = append(
-			currDiffAssignments,
-			currAssignments[diff.ID],
-		)
+		log.Infof("Recording migration progress in %s", t.migrationState.path)
+		if err := t.migrationState.Save(); err != nil {
+			return err
+		}
 	}
 
-	numRounds := (len(assignmentsToUpdate) + batchSize - 1) / batchSize // Ceil() with integer math
 	highlighter := color.New(color.FgYellow, color.Bold).SprintfFunc()
-	for i, round := 0, 1; i < len(assignmentsToUpdate); i, round = i+batchSize, round+1 {
-		end := i + batchSize
-
-		if end > len(assignmentsToUpdate) {
-			end = len(assignmentsToUpdate)
-		}
+	for b, migrationBatch := range batches {
+		round := b + 1
+		i, end := migrationBatch.start, migrationBatch.end
 
 		var roundLabel string // "x of y" used to mark progress in balancing rounds
 		roundLabel = highlighter("%d of %d", round, numRounds)
@@ -1114,6 +1750,30 @@ func (t *TopicApplier) updatePlacementRunner(
 			)
 		}
 
+		batch := &HookBatch{
+			Round:             round,
+			NumRounds:         numRounds,
+			CurrAssignments:   currDiffAssignments[i:end],
+			TargetAssignments: assignmentsToUpdate[i:end],
+		}
+		if err := t.runHooks(ctx, config.HookEventPreBatch, changes, batch, nil); err != nil {
+			if showProgress {
+				stopChan <- true
+			}
+			return err
+		}
+
+		t.emitEvent(
+			Event{
+				Type:              EventTypeBatchStarted,
+				Round:             round,
+				NumRounds:         numRounds,
+				CurrAssignments:   currDiffAssignments[i:end],
+				TargetAssignments: assignmentsToUpdate[i:end],
+			},
+		)
+		batchStartTime := time.Now()
+
 		err := t.updatePartitionsIteration(
 			ctx,
 			currDiffAssignments[i:end],
@@ -1131,6 +1791,22 @@ func (t *TopicApplier) updatePlacementRunner(
 		// add updated replica assignments to changes tracker
 		changes.mergeReplicaAssignments(assignmentsToUpdate[i:end])
 
+		t.emitEvent(
+			Event{
+				Type:              EventTypeBatchFinished,
+				Round:             round,
+				NumRounds:         numRounds,
+				CurrAssignments:   currDiffAssignments[i:end],
+				TargetAssignments: assignmentsToUpdate[i:end],
+				DurationSeconds:   time.Since(batchStartTime).Seconds(),
+			},
+		)
+		t.runHooks(ctx, config.HookEventPostBatch, changes, batch, nil)
+
+		if err := t.migrationState.completeBatch(); err != nil {
+			return err
+		}
+
 		if t.config.AutoContinueRebalance {
 			log.Infof("Autocontinuing to next round")
 		} else {
@@ -1146,6 +1822,10 @@ func (t *TopicApplier) updatePlacementRunner(
 		}
 	}
 
+	if err := t.migrationState.Delete(); err != nil {
+		return err
+	}
+
 	topicInfo, err := t.adminClient.GetTopic(ctx, t.topicName, true)
 	if err != nil {
 		return err
@@ -1173,16 +1853,54 @@ func (t *TopicApplier) updatePartitionsIteration(
 
 	log.Infof("Starting update iteration for partition(s) %+v", idsToUpdate)
 
+	if t.config.Scheduler != nil && len(currAssignments) > 0 && !newTopic {
+		releaseMoves, err := t.config.Scheduler.acquireMoves(
+			ctx,
+			t.topicName,
+			BrokerMoves(currAssignments, assignmentsToUpdate),
+		)
+		if err != nil {
+			return err
+		}
+		defer releaseMoves()
+	}
+
 	throttledTopic, throttledBrokers, err := t.applyThrottles(
 		ctx,
 		currAssignments,
 		assignmentsToUpdate,
 		newTopic,
 	)
+	if stateErr := t.migrationState.setThrottles(throttledTopic, throttledBrokers); stateErr != nil {
+		log.Warnf("Error recording throttles in migration state: %+v", stateErr)
+	}
 	if err != nil {
 		return err
 	}
 
+	// Release the shared broker throttles on every exit path so that the reference counts in
+	// the scheduler don't leak when the migration fails
+	releasedBrokers := t.config.Scheduler == nil
+	defer func() {
+		if releasedBrokers {
+			return
+		}
+		if err := t.config.Scheduler.unthrottleBrokers(ctx, throttledBrokers); err != nil {
+			log.Warnf("Error releasing broker throttles: %+v", err)
+		}
+	}()
+
+	adaptive := t.adaptiveThrottle != nil && len(throttledBrokers) > 0
+	if adaptive {
+		topics, err := t.adminClient.GetTopics(ctx, nil, true)
+		if err != nil {
+			log.Warnf("Error getting topics, not adapting throttles for this batch: %+v", err)
+			adaptive = false
+		} else {
+			t.adaptiveThrottle.startBatch(topics, t.topicName, len(assignmentsToUpdate))
+		}
+	}
+
 	if len(currAssignments) > 0 {
 		err = t.adminClient.AssignPartitions(
 			ctx,
@@ -1268,6 +1986,12 @@ outerLoop:
 				admin.FormatTopicPartitions(notReady, t.brokers),
 			)
 
+			if adaptive {
+				if err := t.adjustThrottle(ctx, throttledBrokers, len(notReady)); err != nil {
+					log.Warnf("Error adjusting throttles: %+v", err)
+				}
+			}
+
 			var roundString string // convert to " (round x of y)" if roundLabel is present
 			if roundLabel != "" {
 				roundString = fmt.Sprintf(" (current round %s, %+v elapsed)", roundLabel, time.Now().Sub(roundStartTime))
@@ -1281,7 +2005,18 @@ outerLoop:
 	}
 
 	// Only remove throttles if apply was successful
-	return t.removeThottles(ctx, throttledTopic, throttledBrokers)
+	if t.config.Scheduler != nil {
+		if err := t.removeThottles(ctx, throttledTopic, nil); err != nil {
+			return err
+		}
+		releasedBrokers = true
+		if err := t.config.Scheduler.unthrottleBrokers(ctx, throttledBrokers); err != nil {
+			return err
+		}
+	} else if err := t.removeThottles(ctx, throttledTopic, throttledBrokers); err != nil {
+		return err
+	}
+	return t.migrationState.setThrottles(false, nil)
 }
 
 func (t *TopicApplier) applyThrottles(
@@ -1331,6 +2066,30 @@ func (t *TopicApplier) applyThrottles(
 
 	throttledBrokers := []int{}
 
+	if t.config.Scheduler != nil {
+		for _, brokerThrottle := range brokerThrottles {
+			throttledBrokers = append(throttledBrokers, brokerThrottle.Broker)
+		}
+		log.Infof(
+			"Applying shared throttles (%d MB/sec) to brokers %+v",
+			t.config.Scheduler.config.ThrottleBytes/1000000,
+			throttledBrokers,
+		)
+		if err := t.config.Scheduler.throttleBrokers(ctx, throttledBrokers); err != nil {
+			return throttledTopic, nil, err
+		}
+		if throttledTopic {
+			// The scheduler emits the events for the broker throttles that it changes
+			t.emitEvent(
+				Event{
+					Type:           EventTypeThrottleApplied,
+					ThrottledTopic: true,
+				},
+			)
+		}
+		return throttledTopic, throttledBrokers, nil
+	}
+
 	for _, brokerThrottle := range brokerThrottles {
 		log.Debugf("Applying throttle to broker %d", brokerThrottle.Broker)
 		updatedKeys, err := t.adminClient.UpdateBrokerConfig(
@@ -1354,9 +2113,73 @@ func (t *TopicApplier) applyThrottles(
 	}
 	log.Infof("Applied throttles to brokers %+v", throttledBrokers)
 
+	if throttledTopic || len(throttledBrokers) > 0 {
+		t.emitEvent(
+			Event{
+				Type:           EventTypeThrottleApplied,
+				ThrottledTopic: throttledTopic,
+				Brokers:        throttledBrokers,
+				ThrottleBytes:  t.throttleBytes,
+			},
+		)
+	}
+
 	return throttledTopic, throttledBrokers, nil
 }
 
+// adjustThrottle updates the throttle rate on the argument brokers based on the current
+// state of the cluster and the number of partitions in the batch that aren't done yet.
+func (t *TopicApplier) adjustThrottle(
+	ctx context.Context,
+	throttledBrokers []int,
+	notReady int,
+) error {
+	topics, err := t.adminClient.GetTopics(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+
+	signals := t.adaptiveThrottle.signals(topics, t.topicName, notReady)
+	newBytes, reason := t.adaptiveThrottle.next(signals)
+	if reason == "" {
+		return nil
+	}
+
+	log.Infof(
+		"Changing broker throttle from %d MB/sec to %d MB/sec on brokers %+v because %s",
+		t.throttleBytes/1000000,
+		newBytes/1000000,
+		throttledBrokers,
+		reason,
+	)
+	t.throttleBytes = newBytes
+
+	for _, broker := range throttledBrokers {
+		brokerThrottle := admin.BrokerThrottle{
+			Broker:        broker,
+			ThrottleBytes: newBytes,
+		}
+		if _, err := t.adminClient.UpdateBrokerConfig(
+			ctx,
+			broker,
+			brokerThrottle.ConfigEntries(),
+			true,
+		); err != nil {
+			return err
+		}
+	}
+
+	t.emitEvent(
+		Event{
+			Type:          EventTypeThrottleApplied,
+			Brokers:       throttledBrokers,
+			ThrottleBytes: newBytes,
+		},
+	)
+
+	return nil
+}
+
 func (t *TopicApplier) removeThottles(
 	ctx context.Context,
 	throttledTopic bool,
@@ -1419,6 +2242,16 @@ func (t *TopicApplier) removeThottles(
 	}
 	log.Infof("Removed throttles from brokers %+v", throttledBrokers)
 
+	if throttledTopic || len(throttledBrokers) > 0 {
+		t.emitEvent(
+			Event{
+				Type:           EventTypeThrottleRemoved,
+				ThrottledTopic: throttledTopic,
+				Brokers:        throttledBrokers,
+			},
+		)
+	}
+
 	return err
 }
 
@@ -1513,6 +2346,12 @@ func (t *TopicApplier) updateLeadersIteration(
 	if err != nil {
 		return err
 	}
+	t.emitEvent(
+		Event{
+			Type:       EventTypeLeaderElection,
+			Partitions: electionPartitions,
+		},
+	)
 
 	checkTimer := time.NewTicker(t.config.SleepLoopDuration)
 	defer checkTimer.Stop()
@@ -1555,7 +2394,7 @@ outerLoop:
 }
 
 func (t *TopicApplier) acquireClusterLock(ctx context.Context) (zk.Lock, string, error) {
-	if t.config.DryRun || t.clusterConfig.Spec.ZKLockPath == "" {
+	if t.config.DryRun || t.clusterConfig.Spec.ZKLockPath == "" || t.config.Scheduler != nil {
 		return nil, "", nil
 	}
 
@@ -1569,7 +2408,7 @@ func (t *TopicApplier) acquireClusterLock(ctx context.Context) (zk.Lock, string,
 }
 
 func (t *TopicApplier) acquireTopicLock(ctx context.Context) (zk.Lock, string, error) {
-	if t.config.DryRun || t.clusterConfig.Spec.ZKLockPath == "" {
+	if t.config.DryRun || t.clusterConfig.Spec.ZKLockPath == "" || t.config.Scheduler != nil {
 		return nil, "", nil
 	}
 
@@ -1587,14 +2426,21 @@ func (t *TopicApplier) clusterLockHeld(ctx context.Context) (bool, error) {
 }
 
 func (t *TopicApplier) clusterLockPath() string {
-	return filepath.Join(
+	return clusterLockPath(
 		t.clusterConfig.Spec.ZKLockPath,
-		fmt.Sprintf(
-			"%s-%s-%s",
-			t.topicConfig.Meta.Cluster,
-			t.topicConfig.Meta.Environment,
-			t.topicConfig.Meta.Region,
-		),
+		t.topicConfig.Meta.Cluster,
+		t.topicConfig.Meta.Environment,
+		t.topicConfig.Meta.Region,
+	)
+}
+
+func (t *TopicApplier) migrationStatePath() string {
+	return MigrationStatePath(
+		t.config.MigrationStateDir,
+		t.clusterConfig.Meta.Name,
+		t.clusterConfig.Meta.Environment,
+		t.clusterConfig.Meta.Region,
+		t.topicName,
 	)
 }
 
@@ -1649,9 +2495,25 @@ func (t *TopicApplier) getPicker(ctx context.Context) (pickers.Picker, error) {
 		)
 	}
 
+	if brokerWeights := t.brokerWeights(); brokerWeights != nil {
+		picker = pickers.NewWeightedPicker(picker, brokerWeights)
+	}
+
 	return picker, nil
 }
 
+// placementBrokers returns the brokers that the topic's replicas can be placed on, based on
+// the constraints in its placement config.
+func (t *TopicApplier) placementBrokers() []admin.BrokerInfo {
+	return t.topicConfig.Spec.PlacementConfig.AllowedBrokers(t.brokers)
+}
+
+// brokerWeights returns the weights of the brokers in the cluster, or nil if they're all
+// weighted equally.
+func (t *TopicApplier) brokerWeights() map[int]float64 {
+	return t.clusterConfig.Spec.BrokerWeights.Weights(t.brokers)
+}
+
 func interruptableSleep(ctx context.Context, duration time.Duration) error {
 	log.Infof("Sleeping for %s", duration.String())
 
@@ -1665,3 +2527,15 @@ func interruptableSleep(ctx context.Context, duration time.Duration) error {
 		return nil
 	}
 }
+
+func clusterLockPath(zkLockPath string, cluster string, environment string, region string) string {
+	return filepath.Join(
+		zkLockPath,
+		fmt.Sprintf(
+			"%s-%s-%s",
+			cluster,
+			environment,
+			region,
+		),
+	)
+}
@@ -123,13 +123,6 @@ func TestApplyBasicUpdates(t *testing.T) {
 	assert.Equal(t, "27000000", topicInfo.Config[admin.RetentionKey])
 	assert.Equal(t, "delete", topicInfo.Config["cleanup.policy"])
 
-	// Updating replication factor not allowed
-	applier.topicConfig.Spec.Partitions = 9
-	applier.topicConfig.Spec.ReplicationFactor = 3
-	_, err = applier.Apply(ctx)
-	require.NotNil(t, err)
-	applier.topicConfig.Spec.ReplicationFactor = 2
-
 	// Settings are not deleted if Destructive is false. They are
 	// if it is true
 	delete(applier.topicConfig.Spec.Settings, "cleanup.policy")
@@ -156,6 +149,115 @@ func TestApplyBasicUpdates(t *testing.T) {
 	assert.Equal(t, changes.(*UpdateChangesTracker).MissingKeys, []string{"cleanup.policy"})
 }
 
+func TestApplyReplicationUpdates(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	topicName := util.RandomString("apply-topic-replication-", 6)
+	topicConfig := config.TopicConfig{
+		Meta: config.ResourceMeta{
+			Name:        topicName,
+			Cluster:     "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+		Spec: config.TopicSpec{
+			Partitions:        6,
+			ReplicationFactor: 2,
+			RetentionMinutes:  500,
+			PlacementConfig: config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyCrossRack,
+				Picker:   config.PickerMethodLowestIndex,
+			},
+			MigrationConfig: &config.TopicMigrationConfig{
+				PartitionBatchSize: 3,
+			},
+		},
+	}
+
+	applier := testApplier(ctx, t, topicConfig)
+	defer applier.adminClient.Close()
+
+	_, err := applier.Apply(ctx)
+	require.NoError(t, err)
+
+	// Increase replication
+	applier.topicConfig.Spec.ReplicationFactor = 3
+	changes, err := applier.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&IntValueChanges{Current: 2, Updated: 3},
+		changes.(*UpdateChangesTracker).ReplicationFactor,
+	)
+	require.NotNil(t, changes.(*UpdateChangesTracker).ReplicaAssignments)
+	for _, assignment := range *changes.(*UpdateChangesTracker).ReplicaAssignments {
+		assert.Equal(t, 2, len(assignment.CurrentReplicas))
+		assert.Equal(t, 3, len(assignment.UpdatedReplicas))
+	}
+
+	topicInfo, err := applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	for _, partition := range topicInfo.Partitions {
+		assert.Equal(t, 3, len(partition.Replicas))
+	}
+	assert.Equal(t, 3, topicInfo.MaxISR())
+
+	// Decrease replication
+	applier.topicConfig.Spec.ReplicationFactor = 2
+	changes, err = applier.Apply(ctx)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		&IntValueChanges{Current: 3, Updated: 2},
+		changes.(*UpdateChangesTracker).ReplicationFactor,
+	)
+
+	topicInfo, err = applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	for _, partition := range topicInfo.Partitions {
+		assert.Equal(t, 2, len(partition.Replicas))
+	}
+}
+
+func TestTrackReplicaAssignments(t *testing.T) {
+	topicInfo := admin.TopicInfo{
+		Name: "test-topic",
+		Partitions: []admin.PartitionInfo{
+			{ID: 0, Leader: 1, Replicas: []int{1, 2}},
+			{ID: 1, Leader: 2, Replicas: []int{2, 3}},
+		},
+	}
+
+	changes := &UpdateChangesTracker{}
+	changes.trackReplicaAssignments(topicInfo)
+
+	// Replica moves from the replication update are merged in
+	changes.mergeReplicaAssignments(
+		[]admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{1, 2, 3}},
+			{ID: 1, Replicas: []int{2, 3, 1}},
+		},
+	)
+
+	// Tracking again, e.g. before the placement update, keeps the merged moves
+	changes.trackReplicaAssignments(topicInfo)
+	changes.mergeReplicaAssignments(
+		[]admin.PartitionAssignment{
+			{ID: 1, Replicas: []int{2, 3, 4}},
+		},
+	)
+
+	assert.Equal(
+		t,
+		&[]ReplicaAssignmentChanges{
+			{Partition: 0, CurrentReplicas: []int{1, 2}, UpdatedReplicas: []int{1, 2, 3}},
+			{Partition: 1, CurrentReplicas: []int{2, 3}, UpdatedReplicas: []int{2, 3, 4}},
+		},
+		changes.ReplicaAssignments,
+	)
+}
+
 func TestApplyNoop(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
@@ -714,6 +816,212 @@ func TestApplyExistingThrottles(t *testing.T) {
 	assert.Equal(t, 0, len(admin.ThrottledBrokerIDs(brokers)))
 }
 
+func TestApplyResume(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	topicName := util.RandomString("apply-topic-resume-", 6)
+	topicConfig := config.TopicConfig{
+		Meta: config.ResourceMeta{
+			Name:        topicName,
+			Cluster:     "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+		Spec: config.TopicSpec{
+			Partitions:        6,
+			ReplicationFactor: 2,
+			RetentionMinutes:  500,
+			PlacementConfig: config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyAny,
+				Picker:   config.PickerMethodLowestIndex,
+			},
+			MigrationConfig: &config.TopicMigrationConfig{
+				PartitionBatchSize: 2,
+			},
+		},
+	}
+
+	applier := testApplier(ctx, t, topicConfig)
+	defer applier.adminClient.Close()
+	applier.config.MigrationStateDir = t.TempDir()
+
+	_, err := applier.Apply(ctx)
+	require.NoError(t, err)
+
+	topicInfo, err := applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	origAssignments := topicInfo.ToAssignments()
+
+	// Simulate a migration that was interrupted after its first batch, leaving a broker
+	// throttle behind
+	targetAssignments := admin.CopyAssignments(origAssignments)
+	for _, assignment := range targetAssignments {
+		assignment.Replicas[0], assignment.Replicas[1] = assignment.Replicas[1], assignment.Replicas[0]
+	}
+
+	_, err = applier.adminClient.UpdateBrokerConfig(
+		ctx,
+		1,
+		[]kafka.ConfigEntry{
+			{
+				ConfigName:  admin.LeaderThrottledKey,
+				ConfigValue: "2000000",
+			},
+		},
+		true,
+	)
+	require.NoError(t, err)
+
+	state := &MigrationState{
+		Version:           MigrationStateVersion,
+		Cluster:           "test-cluster",
+		Environment:       "test-environment",
+		Region:            "test-region",
+		Topic:             topicName,
+		CurrAssignments:   origAssignments,
+		TargetAssignments: targetAssignments,
+		BatchSize:         2,
+		CompletedBatches:  1,
+		ThrottledBrokers:  []int{1},
+		path:              applier.migrationStatePath(),
+	}
+	require.NoError(t, state.Save())
+
+	// Without resume, apply only warns about the interrupted migration and leaves its state
+	// alone
+	_, err = applier.Apply(ctx)
+	require.NoError(t, err)
+	state, err = LoadMigrationState(applier.migrationStatePath())
+	require.NoError(t, err)
+	require.NotNil(t, state)
+
+	applier.config.Resume = true
+	_, err = applier.Apply(ctx)
+	require.NoError(t, err)
+
+	topicInfo, err = applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		append(
+			admin.CopyAssignments(origAssignments[0:2]),
+			targetAssignments[2:]...,
+		),
+		topicInfo.ToAssignments(),
+	)
+
+	brokers, err := applier.adminClient.GetBrokers(ctx, []int{1})
+	require.NoError(t, err)
+	_, present := brokers[0].Config[admin.LeaderThrottledKey]
+	assert.False(t, present)
+
+	state, err = LoadMigrationState(applier.migrationStatePath())
+	require.NoError(t, err)
+	assert.Nil(t, state)
+}
+
+func TestApplyRollback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	topicName := util.RandomString("apply-topic-rollback-", 6)
+	topicConfig := config.TopicConfig{
+		Meta: config.ResourceMeta{
+			Name:        topicName,
+			Cluster:     "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+		Spec: config.TopicSpec{
+			Partitions:        3,
+			ReplicationFactor: 2,
+			RetentionMinutes:  500,
+			Settings: config.TopicSettings{
+				"cleanup.policy": "delete",
+			},
+			PlacementConfig: config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyStatic,
+				StaticAssignments: [][]int{
+					{1, 2},
+					{2, 3},
+					{3, 4},
+				},
+			},
+		},
+	}
+
+	historyDir := t.TempDir()
+	topicDir := TopicHistoryDir(
+		historyDir,
+		"test-cluster",
+		"test-environment",
+		"test-region",
+		topicName,
+	)
+
+	applier := testApplier(ctx, t, topicConfig)
+	defer applier.adminClient.Close()
+	applier.config.HistoryDir = historyDir
+
+	_, err := applier.Apply(ctx)
+	require.NoError(t, err)
+
+	// Creating a topic doesn't record a revision
+	revisions, err := ListTopicRevisions(topicDir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(revisions))
+
+	topicConfig.Spec.RetentionMinutes = 400
+	topicConfig.Spec.Settings["max.message.bytes"] = 5542880
+	topicConfig.Spec.PlacementConfig.StaticAssignments = [][]int{
+		{2, 1},
+		{3, 4},
+		{4, 1},
+	}
+	applier = testApplier(ctx, t, topicConfig)
+	defer applier.adminClient.Close()
+	applier.config.HistoryDir = historyDir
+
+	_, err = applier.Apply(ctx)
+	require.NoError(t, err)
+
+	// Applying again doesn't change anything, so no revision is recorded
+	_, err = applier.Apply(ctx)
+	require.NoError(t, err)
+
+	revisions, err = ListTopicRevisions(topicDir)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(revisions))
+
+	topicInfo, err := applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+
+	rollbackConfig, err := RollbackTopicConfig(applier.clusterConfig, revisions[0], topicInfo)
+	require.NoError(t, err)
+
+	applier = testApplier(ctx, t, rollbackConfig)
+	defer applier.adminClient.Close()
+	applier.config.Destructive = true
+	applier.config.HistoryDir = historyDir
+
+	_, err = applier.Apply(ctx)
+	require.NoError(t, err)
+
+	topicInfo, err = applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	assert.Equal(t, revisions[0].Assignments, topicInfo.ToAssignments())
+	assert.Equal(t, "30000000", topicInfo.Config[admin.RetentionKey])
+	assert.Equal(t, "delete", topicInfo.Config["cleanup.policy"])
+	_, present := topicInfo.Config["max.message.bytes"]
+	assert.False(t, present)
+
+	// The rollback itself is recorded so that it can be reverted
+	revisions, err = ListTopicRevisions(topicDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(revisions))
+}
+
 func TestApplyDryRun(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 	defer cancel()
@@ -0,0 +1,121 @@
+package assigners
+
+import (
+	"fmt"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+// ConstrainedAssigner is an Assigner that wraps another one so that replicas are only placed
+// on the brokers allowed by the constraints in a placement config (i.e., the required and
+// avoided tags and the excluded brokers). The algorithm is:
+//
+//	for each partition:
+//	  for each replica:
+//	    if replica isn't on an allowed broker:
+//	      use picker to replace it with an allowed broker that isn't in the partition
+//
+//	run the wrapped assigner on the result
+//
+// The wrapped assigner should be created with just the allowed brokers so that it keeps the
+// replicas on them. If it doesn't, e.g. because it's a static assigner with a disallowed
+// broker, then an error is returned. If the wrapped assigner is nil, then only the replicas on
+// disallowed brokers are changed.
+type ConstrainedAssigner struct {
+	assigner        Assigner
+	brokers         []admin.BrokerInfo
+	placementConfig config.TopicPlacementConfig
+	picker          pickers.Picker
+}
+
+var _ Assigner = (*ConstrainedAssigner)(nil)
+
+// NewConstrainedAssigner creates and returns a ConstrainedAssigner instance. The brokers
+// should include all of the brokers in the cluster, with their tags set.
+func NewConstrainedAssigner(
+	assigner Assigner,
+	brokers []admin.BrokerInfo,
+	placementConfig config.TopicPlacementConfig,
+	picker pickers.Picker,
+) *ConstrainedAssigner {
+	return &ConstrainedAssigner{
+		assigner:        assigner,
+		brokers:         brokers,
+		placementConfig: placementConfig,
+		picker:          picker,
+	}
+}
+
+// Assign returns a new partition assignment according to the assigner-specific logic.
+func (c *ConstrainedAssigner) Assign(
+	topic string,
+	curr []admin.PartitionAssignment,
+) ([]admin.PartitionAssignment, error) {
+	if err := admin.CheckAssignments(curr); err != nil {
+		return nil, err
+	}
+
+	allowedBrokers := c.placementConfig.AllowedBrokers(c.brokers)
+	if len(allowedBrokers) < len(curr[0].Replicas) {
+		return nil, fmt.Errorf(
+			"Only %d brokers satisfy the placement constraints for topic %s, need at least %d",
+			len(allowedBrokers),
+			topic,
+			len(curr[0].Replicas),
+		)
+	}
+
+	allowed := map[int]struct{}{}
+	for _, broker := range allowedBrokers {
+		allowed[broker.ID] = struct{}{}
+	}
+
+	desired := admin.CopyAssignments(curr)
+
+	// First, null-out any replicas that aren't on allowed brokers
+	for _, assignment := range desired {
+		for r, replica := range assignment.Replicas {
+			if _, ok := allowed[replica]; !ok {
+				assignment.Replicas[r] = -1
+			}
+		}
+	}
+
+	// Then, replace them with allowed brokers
+	brokerChoices := admin.BrokerIDs(allowedBrokers)
+
+	for p, assignment := range desired {
+		for r, replica := range assignment.Replicas {
+			if replica != -1 {
+				continue
+			}
+			if err := c.picker.PickNew(topic, brokerChoices, desired, p, r); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if c.assigner != nil {
+		var err error
+		desired, err = c.assigner.Assign(topic, desired)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if violations := ConstraintViolations(
+		desired,
+		c.brokers,
+		c.placementConfig,
+	); len(violations) > 0 {
+		return nil, fmt.Errorf(
+			"Partitions %+v of topic %s don't satisfy the placement constraints",
+			violations,
+			topic,
+		)
+	}
+
+	return desired, nil
+}
@@ -0,0 +1,99 @@
+package assigners
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+func TestConstrainedAssigner(t *testing.T) {
+	brokers := testBrokers(6, 3)
+	for b := range brokers {
+		if brokers[b].ID <= 3 {
+			brokers[b].Tags = []string{"ssd"}
+		}
+	}
+
+	placementConfig := config.TopicPlacementConfig{
+		Strategy:    config.PlacementStrategyAny,
+		RequireTags: []string{"ssd"},
+	}
+	picker := pickers.NewLowestIndexPicker()
+
+	testCases := []struct {
+		assigner Assigner
+		testCase assignerTestCase
+	}{
+		{
+			testCase: assignerTestCase{
+				description: "Replicas moved to allowed brokers",
+				curr: [][]int{
+					{1, 4},
+					{2, 3},
+					{5, 6},
+				},
+				expected: [][]int{
+					{1, 2},
+					{2, 3},
+					{3, 1},
+				},
+			},
+		},
+		{
+			assigner: NewCrossRackAssigner(placementConfig.AllowedBrokers(brokers), picker),
+			testCase: assignerTestCase{
+				description: "Wrapped assigner run after constraints",
+				curr: [][]int{
+					{1, 4},
+					{2, 5},
+					{3, 6},
+				},
+				expected: [][]int{
+					{1, 2},
+					{2, 1},
+					{3, 1},
+				},
+			},
+		},
+		{
+			assigner: &StaticAssigner{
+				Assignments: admin.ReplicasToAssignments(
+					[][]int{
+						{1, 2},
+						{2, 4},
+					},
+				),
+			},
+			testCase: assignerTestCase{
+				description: "Wrapped assigner uses disallowed broker",
+				curr: [][]int{
+					{1, 2},
+					{2, 3},
+				},
+				err: errors.New("partitions don't satisfy the placement constraints"),
+			},
+		},
+		{
+			testCase: assignerTestCase{
+				description: "Not enough allowed brokers",
+				curr: [][]int{
+					{1, 2, 3, 4},
+				},
+				err: errors.New("not enough brokers satisfy the placement constraints"),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assigner := NewConstrainedAssigner(
+			testCase.assigner,
+			brokers,
+			placementConfig,
+			picker,
+		)
+		testCase.testCase.evaluate(t, assigner)
+	}
+}
@@ -3,6 +3,7 @@ package assigners
 import (
 	"fmt"
 	"reflect"
+	"slices"
 
 	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/config"
@@ -10,7 +11,8 @@ import (
 )
 
 // EvaluateAssignments determines whether the given assignments are consistent
-// with the provided placement strategy.
+// with the provided placement strategy and constraints. If the placement config has
+// constraints, then the strategy is evaluated with just the brokers that are allowed by them.
 func EvaluateAssignments(
 	assignments []admin.PartitionAssignment,
 	brokers []admin.BrokerInfo,
@@ -20,6 +22,13 @@ func EvaluateAssignments(
 		return false, err
 	}
 
+	if placementConfig.HasConstraints() {
+		if len(ConstraintViolations(assignments, brokers, placementConfig)) > 0 {
+			return false, nil
+		}
+		brokers = placementConfig.AllowedBrokers(brokers)
+	}
+
 	minRacks, maxRacks, leaderRackCounts := minMaxRacks(assignments, brokers)
 	balanced := balancedLeaders(leaderRackCounts)
 
@@ -73,6 +82,12 @@ func EvaluateAssignments(
 			}
 		}
 		return true, nil
+	case config.PlacementStrategyLeaderRack:
+		return leaderRackBalanced(
+			assignments,
+			brokers,
+			placementConfig.PreferredLeaderRacks,
+		), nil
 	default:
 		return false, fmt.Errorf(
 			"Unrecognized placementStrategy: %s",
@@ -81,6 +96,73 @@ func EvaluateAssignments(
 	}
 }
 
+// ConstraintViolations returns the IDs of the partitions that have replicas on brokers that
+// aren't allowed by the constraints in the argument placement config. Replicas on brokers that
+// aren't in the argument brokers are also considered violations.
+func ConstraintViolations(
+	assignments []admin.PartitionAssignment,
+	brokers []admin.BrokerInfo,
+	placementConfig config.TopicPlacementConfig,
+) []int {
+	allowed := map[int]bool{}
+	for _, broker := range brokers {
+		allowed[broker.ID] = placementConfig.BrokerAllowed(broker)
+	}
+
+	violations := []int{}
+	for _, assignment := range assignments {
+		for _, replica := range assignment.Replicas {
+			if !allowed[replica] {
+				violations = append(violations, assignment.ID)
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// leaderRackBalanced returns whether every leader is in one of the preferred racks, the
+// leaders are spread evenly across the preferred racks (within one), and the replicas in each
+// partition are all in different racks.
+func leaderRackBalanced(
+	assignments []admin.PartitionAssignment,
+	brokers []admin.BrokerInfo,
+	preferredRacks []string,
+) bool {
+	brokerRacks := admin.BrokerRacks(brokers)
+	leaderRacks := LeaderRacks(preferredRacks, brokers)
+
+	leaderCounts := map[string]int{}
+	for _, rack := range leaderRacks {
+		leaderCounts[rack] = 0
+	}
+
+	for _, assignment := range assignments {
+		leaderRack := brokerRacks[assignment.Replicas[0]]
+		if !slices.Contains(leaderRacks, leaderRack) {
+			return false
+		}
+		leaderCounts[leaderRack]++
+
+		if len(assignment.Replicas) != len(assignment.DistinctRacks(brokerRacks)) {
+			return false
+		}
+	}
+
+	minCount, maxCount := len(assignments), 0
+	for _, count := range leaderCounts {
+		if count < minCount {
+			minCount = count
+		}
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+
+	return maxCount-minCount <= 1
+}
+
 func balancedLeaders(leaderRackCounts map[string]int) bool {
 	var minCount, maxCount int
 	first := true
@@ -171,3 +171,170 @@ func TestEvaluateAssignmentsNonStatic(t *testing.T) {
 		}
 	}
 }
+
+func TestEvaluateAssignmentsConstraints(t *testing.T) {
+	brokers := testBrokers(6, 3)
+	for b := range brokers {
+		if brokers[b].ID <= 3 {
+			brokers[b].Tags = []string{"ssd"}
+		}
+	}
+
+	type evaluateTestCase struct {
+		description        string
+		replicaSlices      [][]int
+		placementConfig    config.TopicPlacementConfig
+		expectedResult     bool
+		expectedViolations []int
+	}
+
+	testCases := []evaluateTestCase{
+		{
+			description: "Required tags satisfied",
+			replicaSlices: [][]int{
+				{1, 2},
+				{2, 3},
+				{3, 1},
+			},
+			placementConfig: config.TopicPlacementConfig{
+				Strategy:    config.PlacementStrategyAny,
+				RequireTags: []string{"ssd"},
+			},
+			expectedResult:     true,
+			expectedViolations: []int{},
+		},
+		{
+			description: "Required tags not satisfied",
+			replicaSlices: [][]int{
+				{1, 4},
+				{2, 3},
+				{5, 6},
+			},
+			placementConfig: config.TopicPlacementConfig{
+				Strategy:    config.PlacementStrategyAny,
+				RequireTags: []string{"ssd"},
+			},
+			expectedResult:     false,
+			expectedViolations: []int{0, 2},
+		},
+		{
+			description: "Avoided tags not satisfied",
+			replicaSlices: [][]int{
+				{4, 5},
+				{5, 6},
+				{6, 1},
+			},
+			placementConfig: config.TopicPlacementConfig{
+				Strategy:  config.PlacementStrategyAny,
+				AvoidTags: []string{"ssd"},
+			},
+			expectedResult:     false,
+			expectedViolations: []int{2},
+		},
+		{
+			description: "Balanced leaders among allowed brokers",
+			// Broker 3 and 6 are the only ones in zone3, so leaders only need to be balanced
+			// between zone1 and zone2
+			replicaSlices: [][]int{
+				{1, 2},
+				{2, 1},
+			},
+			placementConfig: config.TopicPlacementConfig{
+				Strategy:       config.PlacementStrategyBalancedLeaders,
+				ExcludeBrokers: []int{3, 6},
+			},
+			expectedResult:     true,
+			expectedViolations: []int{},
+		},
+		{
+			description: "Excluded broker",
+			replicaSlices: [][]int{
+				{1, 2},
+				{2, 3},
+			},
+			placementConfig: config.TopicPlacementConfig{
+				Strategy:       config.PlacementStrategyAny,
+				ExcludeBrokers: []int{3, 6},
+			},
+			expectedResult:     false,
+			expectedViolations: []int{1},
+		},
+	}
+
+	for _, testCase := range testCases {
+		assignments := admin.ReplicasToAssignments(testCase.replicaSlices)
+
+		result, err := EvaluateAssignments(assignments, brokers, testCase.placementConfig)
+		assert.NoError(t, err, testCase.description)
+		assert.Equal(t, testCase.expectedResult, result, testCase.description)
+		assert.Equal(
+			t,
+			testCase.expectedViolations,
+			ConstraintViolations(assignments, brokers, testCase.placementConfig),
+			testCase.description,
+		)
+	}
+}
+
+func TestEvaluateAssignmentsLeaderRack(t *testing.T) {
+	brokers := testBrokers(9, 3)
+	placementConfig := config.TopicPlacementConfig{
+		Strategy:             config.PlacementStrategyLeaderRack,
+		PreferredLeaderRacks: []string{"zone1", "zone2"},
+	}
+
+	type evaluateTestCase struct {
+		description    string
+		replicaSlices  [][]int
+		expectedResult bool
+	}
+
+	testCases := []evaluateTestCase{
+		{
+			description: "Leaders balanced across preferred racks",
+			replicaSlices: [][]int{
+				{1, 2, 3},
+				{2, 3, 1},
+				{4, 6, 5},
+				{5, 4, 9},
+			},
+			expectedResult: true,
+		},
+		{
+			description: "Leader outside of preferred racks",
+			replicaSlices: [][]int{
+				{1, 2, 3},
+				{3, 2, 1},
+			},
+			expectedResult: false,
+		},
+		{
+			description: "Leaders not balanced",
+			replicaSlices: [][]int{
+				{1, 2, 3},
+				{4, 3, 2},
+				{7, 2, 3},
+				{2, 1, 3},
+			},
+			expectedResult: false,
+		},
+		{
+			description: "Replicas in the same rack",
+			replicaSlices: [][]int{
+				{1, 4, 3},
+				{2, 3, 1},
+			},
+			expectedResult: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		result, err := EvaluateAssignments(
+			admin.ReplicasToAssignments(testCase.replicaSlices),
+			brokers,
+			placementConfig,
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedResult, result, testCase.description)
+	}
+}
@@ -0,0 +1,245 @@
+package assigners
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+)
+
+// LeaderRackAssigner is an assigner that ensures that the leader of each partition is in
+// one of a list of preferred racks and that the followers are in different racks than the
+// leader and each other. The algorithm is:
+//
+//	for each partition:
+//	  if leader isn't in a preferred rack, or its rack already has its share of the leaders:
+//	    set the leader rack to the preferred rack with the fewest leaders
+//	    swap the leader with a follower in that rack if possible, otherwise use picker to
+//	      replace it with a broker in that rack
+//
+// then:
+//
+//	for each partition:
+//	  for each follower:
+//	    if follower is in the same rack as the leader or an earlier follower:
+//	      change follower to a placeholder (-1)
+//
+//	for each partition:
+//	  for each follower:
+//	    if follower is set to placeholder:
+//	      use picker to replace it with a broker in the unused rack with the fewest replicas
+//	        in the follower's position
+//
+// The leaders are spread evenly across the preferred racks. If the number of partitions isn't a
+// multiple of the number of preferred racks, then some of the racks get one extra leader.
+type LeaderRackAssigner struct {
+	brokers        []admin.BrokerInfo
+	brokerRacks    map[int]string
+	brokersPerRack map[string][]int
+	racks          []string
+	preferredRacks []string
+	picker         pickers.Picker
+}
+
+var _ Assigner = (*LeaderRackAssigner)(nil)
+
+// NewLeaderRackAssigner creates and returns a LeaderRackAssigner instance.
+func NewLeaderRackAssigner(
+	brokers []admin.BrokerInfo,
+	preferredRacks []string,
+	picker pickers.Picker,
+) *LeaderRackAssigner {
+	return &LeaderRackAssigner{
+		brokers:        brokers,
+		brokerRacks:    admin.BrokerRacks(brokers),
+		brokersPerRack: admin.BrokersPerRack(brokers),
+		racks:          admin.DistinctRacks(brokers),
+		preferredRacks: preferredRacks,
+		picker:         picker,
+	}
+}
+
+// Assign returns a new partition assignment according to the assigner-specific logic.
+func (l *LeaderRackAssigner) Assign(
+	topic string,
+	curr []admin.PartitionAssignment,
+) ([]admin.PartitionAssignment, error) {
+	if err := admin.CheckAssignments(curr); err != nil {
+		return nil, err
+	}
+
+	// Check to make sure that the number of racks is >= number of replicas.
+	// Otherwise, we won't be able to find a feasible assignment.
+	if len(l.racks) < len(curr[0].Replicas) {
+		return nil, fmt.Errorf("Do not have enough racks for leader-rack placement")
+	}
+
+	leaderRacks := LeaderRacks(l.preferredRacks, l.brokers)
+	if len(leaderRacks) == 0 {
+		return nil, fmt.Errorf(
+			"None of the preferred leader racks %+v have any brokers",
+			l.preferredRacks,
+		)
+	}
+
+	desired := admin.CopyAssignments(curr)
+
+	// First, keep the leaders that are in preferred racks, up to each rack's share
+	minLeaders := len(desired) / len(leaderRacks)
+	extraLeaders := len(desired) % len(leaderRacks)
+
+	leaderCounts := map[string]int{}
+	toMove := []int{}
+
+	for index, assignment := range desired {
+		leaderRack := l.brokerRacks[assignment.Replicas[0]]
+		if !slices.Contains(leaderRacks, leaderRack) {
+			toMove = append(toMove, index)
+		} else if leaderCounts[leaderRack] < minLeaders {
+			leaderCounts[leaderRack]++
+		} else if leaderCounts[leaderRack] == minLeaders && extraLeaders > 0 {
+			leaderCounts[leaderRack]++
+			extraLeaders--
+		} else {
+			toMove = append(toMove, index)
+		}
+	}
+
+	// Then, move the remaining leaders to the preferred racks with the fewest leaders
+	for _, index := range toMove {
+		targetRack := FewestLeadersRack(leaderRacks, leaderCounts)
+		leaderCounts[targetRack]++
+
+		replicas := desired[index].Replicas
+		swapped := false
+
+		for r := 1; r < len(replicas); r++ {
+			if l.brokerRacks[replicas[r]] == targetRack {
+				replicas[0], replicas[r] = replicas[r], replicas[0]
+				swapped = true
+				break
+			}
+		}
+
+		if !swapped {
+			replicas[0] = -1
+			err := l.picker.PickNew(
+				topic,
+				l.brokersPerRack[targetRack],
+				desired,
+				index,
+				0,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Next, null-out any followers that are in the same rack as an earlier replica
+	for _, assignment := range desired {
+		usedRacks := map[string]struct{}{
+			l.brokerRacks[assignment.Replicas[0]]: {},
+		}
+
+		for r := 1; r < len(assignment.Replicas); r++ {
+			replicaRack := l.brokerRacks[assignment.Replicas[r]]
+			if _, used := usedRacks[replicaRack]; used {
+				assignment.Replicas[r] = -1
+			} else {
+				usedRacks[replicaRack] = struct{}{}
+			}
+		}
+	}
+
+	// Finally, replace all of the placeholders with brokers from unused racks
+	for index, assignment := range desired {
+		for r := 1; r < len(assignment.Replicas); r++ {
+			if assignment.Replicas[r] != -1 {
+				continue
+			}
+
+			targetRack := FollowerRack(desired, index, r, l.racks, l.brokerRacks)
+			err := l.picker.PickNew(
+				topic,
+				l.brokersPerRack[targetRack],
+				desired,
+				index,
+				r,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return desired, nil
+}
+
+// LeaderRacks returns the racks in the argument preferred list that have brokers, in the
+// same order.
+func LeaderRacks(preferredRacks []string, brokers []admin.BrokerInfo) []string {
+	brokersPerRack := admin.BrokersPerRack(brokers)
+	leaderRacks := []string{}
+
+	for _, rack := range preferredRacks {
+		if len(brokersPerRack[rack]) > 0 && !slices.Contains(leaderRacks, rack) {
+			leaderRacks = append(leaderRacks, rack)
+		}
+	}
+
+	return leaderRacks
+}
+
+// FewestLeadersRack returns the rack in the argument list with the lowest count. Ties are
+// broken by the order of the list.
+func FewestLeadersRack(leaderRacks []string, leaderCounts map[string]int) string {
+	targetRack := leaderRacks[0]
+
+	for _, rack := range leaderRacks[1:] {
+		if leaderCounts[rack] < leaderCounts[targetRack] {
+			targetRack = rack
+		}
+	}
+
+	return targetRack
+}
+
+// FollowerRack returns the rack that the follower at the argument partition and index should
+// be placed in. This is the rack, among the ones not used by the other replicas in the
+// partition, that has the fewest replicas at the same index across all partitions. Ties are
+// broken by rack name.
+func FollowerRack(
+	assignments []admin.PartitionAssignment,
+	partition int,
+	index int,
+	racks []string,
+	brokerRacks map[int]string,
+) string {
+	usedRacks := map[string]struct{}{}
+	for r, replica := range assignments[partition].Replicas {
+		if r != index && replica != -1 {
+			usedRacks[brokerRacks[replica]] = struct{}{}
+		}
+	}
+
+	indexCounts := map[string]int{}
+	for _, assignment := range assignments {
+		if replica := assignment.Replicas[index]; replica != -1 {
+			indexCounts[brokerRacks[replica]]++
+		}
+	}
+
+	targetRack := ""
+	for _, rack := range racks {
+		if _, used := usedRacks[rack]; used {
+			continue
+		}
+		if targetRack == "" || indexCounts[rack] < indexCounts[targetRack] {
+			targetRack = rack
+		}
+	}
+
+	return targetRack
+}
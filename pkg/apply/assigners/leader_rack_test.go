@@ -0,0 +1,131 @@
+package assigners
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+func TestLeaderRackAssigner(t *testing.T) {
+	brokers := testBrokers(9, 3)
+	preferredRacks := []string{"zone1"}
+	assigner := NewLeaderRackAssigner(
+		brokers,
+		preferredRacks,
+		pickers.NewLowestIndexPicker(),
+	)
+	checker := func(result []admin.PartitionAssignment) bool {
+		ok, _ := EvaluateAssignments(
+			result,
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy:             config.PlacementStrategyLeaderRack,
+				PreferredLeaderRacks: preferredRacks,
+			},
+		)
+		return ok
+	}
+
+	testCases := []assignerTestCase{
+		{
+			description: "Already leader rack",
+			curr: [][]int{
+				{1, 2, 3},
+				{4, 5, 6},
+				{7, 8, 9},
+			},
+			expected: [][]int{
+				{1, 2, 3},
+				{4, 5, 6},
+				{7, 8, 9},
+			},
+			checker: checker,
+		},
+		{
+			description: "Leaders swapped with followers",
+			curr: [][]int{
+				{1, 2, 3},
+				{5, 4, 6},
+				{9, 8, 7},
+			},
+			expected: [][]int{
+				{1, 2, 3},
+				{4, 5, 6},
+				{7, 8, 9},
+			},
+			checker: checker,
+		},
+		{
+			description: "Leaders and followers replaced",
+			curr: [][]int{
+				{1, 4, 3},
+				{2, 5, 6},
+				{3, 6, 9},
+			},
+			expected: [][]int{
+				{1, 2, 3},
+				{4, 5, 6},
+				{7, 6, 2},
+			},
+			checker: checker,
+		},
+		{
+			description: "Not enough racks",
+			curr: [][]int{
+				{1, 2, 3, 4},
+			},
+			err: errors.New("Do not have enough racks for leader-rack placement"),
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(t, assigner)
+	}
+}
+
+func TestLeaderRackAssignerMultipleRacks(t *testing.T) {
+	brokers := testBrokers(9, 3)
+	preferredRacks := []string{"zone1", "zone2"}
+	assigner := NewLeaderRackAssigner(
+		brokers,
+		preferredRacks,
+		pickers.NewLowestIndexPicker(),
+	)
+	checker := func(result []admin.PartitionAssignment) bool {
+		ok, _ := EvaluateAssignments(
+			result,
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy:             config.PlacementStrategyLeaderRack,
+				PreferredLeaderRacks: preferredRacks,
+			},
+		)
+		return ok
+	}
+
+	testCases := []assignerTestCase{
+		{
+			description: "Leaders spread across preferred racks",
+			curr: [][]int{
+				{1, 2},
+				{4, 5},
+				{7, 8},
+				{3, 6},
+			},
+			expected: [][]int{
+				{1, 2},
+				{4, 5},
+				{8, 7},
+				{2, 6},
+			},
+			checker: checker,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(t, assigner)
+	}
+}
@@ -0,0 +1,253 @@
+package assigners
+
+import (
+	"fmt"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+// ReplicationAssigner is an assigner that changes the replication factor of the partitions
+// in a topic. The algorithm is:
+//
+//	if increasing replication:
+//	  for each partition:
+//	    add placeholder (-1) replicas to the end until the desired replication is reached
+//	  for each new replica index:
+//	    for each partition:
+//	      use picker to replace the placeholder with a broker consistent with the placement
+//	      strategy (e.g., same rack as the leader for in-rack, a rack not already used by
+//	      the partition for cross-rack)
+//
+//	if decreasing replication:
+//	  for each partition:
+//	    while there are too many replicas:
+//	      use picker to sort the non-leader replicas, preferring ones on brokers that hold
+//	      the most replicas in the topic, and remove the first one
+//
+// Leaders are never changed. For the static strategies, the static assignments are used
+// directly since they already determine the replication.
+type ReplicationAssigner struct {
+	brokers           []admin.BrokerInfo
+	brokerRacks       map[int]string
+	brokersPerRack    map[string][]int
+	placementConfig   config.TopicPlacementConfig
+	replicationFactor int
+	picker            pickers.Picker
+}
+
+var _ Assigner = (*ReplicationAssigner)(nil)
+
+// NewReplicationAssigner creates and returns a ReplicationAssigner instance.
+func NewReplicationAssigner(
+	brokers []admin.BrokerInfo,
+	placementConfig config.TopicPlacementConfig,
+	replicationFactor int,
+	picker pickers.Picker,
+) *ReplicationAssigner {
+	return &ReplicationAssigner{
+		brokers:           brokers,
+		brokerRacks:       admin.BrokerRacks(brokers),
+		brokersPerRack:    admin.BrokersPerRack(brokers),
+		placementConfig:   placementConfig,
+		replicationFactor: replicationFactor,
+		picker:            picker,
+	}
+}
+
+// Assign returns a new partition assignment according to the assigner-specific logic.
+func (r *ReplicationAssigner) Assign(
+	topic string,
+	curr []admin.PartitionAssignment,
+) ([]admin.PartitionAssignment, error) {
+	if err := admin.CheckAssignments(curr); err != nil {
+		return nil, err
+	}
+	if r.replicationFactor <= 0 {
+		return nil, fmt.Errorf("Invalid replication factor: %d", r.replicationFactor)
+	}
+	if r.replicationFactor > len(r.brokers) {
+		return nil, fmt.Errorf(
+			"Replication factor (%d) is larger than the number of brokers (%d)",
+			r.replicationFactor,
+			len(r.brokers),
+		)
+	}
+
+	switch r.placementConfig.Strategy {
+	case config.PlacementStrategyStatic:
+		assigner := &StaticAssigner{
+			Assignments: admin.ReplicasToAssignments(
+				r.placementConfig.StaticAssignments,
+			),
+		}
+		return assigner.Assign(topic, curr)
+	case config.PlacementStrategyCrossRack, config.PlacementStrategyLeaderRack:
+		if r.replicationFactor > len(r.brokersPerRack) {
+			return nil, fmt.Errorf(
+				"Do not have enough racks for %s placement",
+				r.placementConfig.Strategy,
+			)
+		}
+	}
+
+	currReplication := len(curr[0].Replicas)
+
+	if r.replicationFactor > currReplication {
+		return r.addReplicas(topic, curr)
+	} else if r.replicationFactor < currReplication {
+		return r.removeReplicas(topic, curr)
+	}
+
+	return admin.CopyAssignments(curr), nil
+}
+
+func (r *ReplicationAssigner) addReplicas(
+	topic string,
+	curr []admin.PartitionAssignment,
+) ([]admin.PartitionAssignment, error) {
+	desired := admin.CopyAssignments(curr)
+	currReplication := len(curr[0].Replicas)
+
+	for p := range desired {
+		for i := currReplication; i < r.replicationFactor; i++ {
+			desired[p].Replicas = append(desired[p].Replicas, -1)
+		}
+	}
+
+	for index := currReplication; index < r.replicationFactor; index++ {
+		for p := range desired {
+			brokerChoices, err := r.brokerChoices(desired[p])
+			if err != nil {
+				return nil, err
+			}
+
+			if err := r.picker.PickNew(
+				topic,
+				brokerChoices,
+				desired,
+				p,
+				index,
+			); err != nil {
+				return nil, fmt.Errorf(
+					"Could not pick replica %d for partition %d: %+v",
+					index,
+					p,
+					err,
+				)
+			}
+		}
+	}
+
+	return desired, nil
+}
+
+func (r *ReplicationAssigner) removeReplicas(
+	topic string,
+	curr []admin.PartitionAssignment,
+) ([]admin.PartitionAssignment, error) {
+	desired := admin.CopyAssignments(curr)
+
+	for p := range desired {
+		for len(desired[p].Replicas) > r.replicationFactor {
+			// Build a slice with one single-replica assignment for each replica in the topic
+			// on the brokers that this partition could drop. Sorting these by frequency gives
+			// us the broker in the partition that's used most across the topic.
+			candidates := []admin.PartitionAssignment{}
+			candidateBrokers := map[int]struct{}{}
+
+			for _, replica := range desired[p].Replicas[1:] {
+				candidateBrokers[replica] = struct{}{}
+			}
+
+			for _, assignment := range desired {
+				for _, replica := range assignment.Replicas {
+					if _, ok := candidateBrokers[replica]; ok {
+						candidates = append(
+							candidates,
+							admin.PartitionAssignment{
+								ID:       len(candidates),
+								Replicas: []int{replica},
+							},
+						)
+					}
+				}
+			}
+
+			candidateIDs := []int{}
+			for _, candidate := range candidates {
+				candidateIDs = append(candidateIDs, candidate.ID)
+			}
+
+			if err := r.picker.SortRemovals(
+				topic,
+				candidateIDs,
+				candidates,
+				0,
+			); err != nil {
+				return nil, fmt.Errorf(
+					"Could not pick replica to remove for partition %d: %+v",
+					p,
+					err,
+				)
+			}
+
+			toRemove := candidates[candidateIDs[0]].Replicas[0]
+			replicas := []int{}
+			for _, replica := range desired[p].Replicas {
+				if replica != toRemove {
+					replicas = append(replicas, replica)
+				}
+			}
+			desired[p].Replicas = replicas
+		}
+	}
+
+	return desired, nil
+}
+
+// brokerChoices returns the brokers that can be used for a new replica in the argument
+// partition given the placement strategy and the replicas that have already been chosen.
+func (r *ReplicationAssigner) brokerChoices(
+	assignment admin.PartitionAssignment,
+) ([]int, error) {
+	switch r.placementConfig.Strategy {
+	case config.PlacementStrategyInRack:
+		return r.brokersPerRack[r.brokerRacks[assignment.Replicas[0]]], nil
+	case config.PlacementStrategyStaticInRack:
+		if assignment.ID >= len(r.placementConfig.StaticRackAssignments) {
+			return nil, fmt.Errorf(
+				"No static rack assignment for partition %d",
+				assignment.ID,
+			)
+		}
+		return r.brokersPerRack[r.placementConfig.StaticRackAssignments[assignment.ID]], nil
+	case config.PlacementStrategyCrossRack, config.PlacementStrategyLeaderRack:
+		usedRacks := map[string]struct{}{}
+		for _, replica := range assignment.Replicas {
+			if replica >= 0 {
+				usedRacks[r.brokerRacks[replica]] = struct{}{}
+			}
+		}
+
+		choices := []int{}
+		for _, broker := range r.brokers {
+			if _, used := usedRacks[broker.Rack]; !used {
+				choices = append(choices, broker.ID)
+			}
+		}
+		return choices, nil
+	case config.PlacementStrategyAny, config.PlacementStrategyBalancedLeaders:
+		choices := []int{}
+		for _, broker := range r.brokers {
+			choices = append(choices, broker.ID)
+		}
+		return choices, nil
+	default:
+		return nil, fmt.Errorf(
+			"Cannot change replication using strategy %s",
+			r.placementConfig.Strategy,
+		)
+	}
+}
@@ -0,0 +1,259 @@
+package assigners
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+func TestReplicationAssignerIncrease(t *testing.T) {
+	brokers := testBrokers(6, 3)
+
+	crossRackChecker := func(result []admin.PartitionAssignment) bool {
+		ok, _ := EvaluateAssignments(
+			result,
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyCrossRack,
+			},
+		)
+		return ok
+	}
+
+	testCases := []assignerTestCase{
+		{
+			description: "Any strategy, from 2 to 3",
+			curr: [][]int{
+				{1, 4},
+				{2, 5},
+				{3, 6},
+				{4, 1},
+				{5, 2},
+				{6, 3},
+			},
+			expected: [][]int{
+				{1, 4, 2},
+				{2, 5, 1},
+				{3, 6, 4},
+				{4, 1, 3},
+				{5, 2, 6},
+				{6, 3, 5},
+			},
+		},
+		{
+			description: "Same replication",
+			curr: [][]int{
+				{1, 4},
+				{2, 5},
+			},
+			expected: [][]int{
+				{1, 4},
+				{2, 5},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(
+			t,
+			NewReplicationAssigner(
+				brokers,
+				config.TopicPlacementConfig{
+					Strategy: config.PlacementStrategyAny,
+				},
+				len(testCase.expected[0]),
+				pickers.NewLowestIndexPicker(),
+			),
+		)
+	}
+
+	crossRackCase := assignerTestCase{
+		description: "Cross-rack strategy, from 2 to 3",
+		curr: [][]int{
+			{1, 2},
+			{2, 3},
+			{3, 4},
+			{4, 5},
+			{5, 6},
+			{6, 1},
+		},
+		expected: [][]int{
+			{1, 2, 3},
+			{2, 3, 1},
+			{3, 4, 2},
+			{4, 5, 6},
+			{5, 6, 4},
+			{6, 1, 5},
+		},
+		checker: crossRackChecker,
+	}
+	crossRackCase.evaluate(
+		t,
+		NewReplicationAssigner(
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyCrossRack,
+			},
+			3,
+			pickers.NewLowestIndexPicker(),
+		),
+	)
+
+	inRackCase := assignerTestCase{
+		description: "In-rack strategy, from 1 to 2",
+		curr: [][]int{
+			{1},
+			{2},
+			{3},
+			{4},
+		},
+		expected: [][]int{
+			{1, 4},
+			{2, 5},
+			{3, 6},
+			{4, 1},
+		},
+		checker: func(result []admin.PartitionAssignment) bool {
+			ok, _ := EvaluateAssignments(
+				result,
+				brokers,
+				config.TopicPlacementConfig{
+					Strategy: config.PlacementStrategyInRack,
+				},
+			)
+			return ok
+		},
+	}
+	inRackCase.evaluate(
+		t,
+		NewReplicationAssigner(
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyInRack,
+			},
+			2,
+			pickers.NewLowestIndexPicker(),
+		),
+	)
+
+	infeasibleCase := assignerTestCase{
+		description: "In-rack strategy without enough brokers in rack",
+		curr: [][]int{
+			{1, 4},
+			{2, 5},
+		},
+		err: pickers.ErrNoFeasibleChoice,
+	}
+	infeasibleCase.evaluate(
+		t,
+		NewReplicationAssigner(
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyInRack,
+			},
+			3,
+			pickers.NewLowestIndexPicker(),
+		),
+	)
+}
+
+func TestReplicationAssignerDecrease(t *testing.T) {
+	brokers := testBrokers(6, 3)
+
+	testCases := []assignerTestCase{
+		{
+			description: "From 3 to 2",
+			curr: [][]int{
+				{1, 2, 3},
+				{2, 3, 4},
+				{3, 4, 5},
+				{4, 5, 6},
+				{5, 6, 1},
+				{6, 1, 2},
+			},
+			expected: [][]int{
+				{1, 3},
+				{2, 4},
+				{3, 5},
+				{4, 6},
+				{5, 6},
+				{6, 2},
+			},
+		},
+		{
+			description: "Drops replicas on most-used brokers first, picker breaks ties",
+			curr: [][]int{
+				{1, 2, 6},
+				{2, 3, 6},
+				{3, 4, 6},
+				{4, 5, 1},
+			},
+			expected: [][]int{
+				{1, 2},
+				{2, 6},
+				{3, 6},
+				{4, 5},
+			},
+		},
+		{
+			description: "From 3 to 1 keeps leaders",
+			curr: [][]int{
+				{1, 2, 3},
+				{2, 3, 4},
+				{3, 4, 5},
+			},
+			expected: [][]int{
+				{1},
+				{2},
+				{3},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(
+			t,
+			NewReplicationAssigner(
+				brokers,
+				config.TopicPlacementConfig{
+					Strategy: config.PlacementStrategyCrossRack,
+				},
+				len(testCase.expected[0]),
+				pickers.NewLowestIndexPicker(),
+			),
+		)
+	}
+}
+
+func TestReplicationAssignerStatic(t *testing.T) {
+	brokers := testBrokers(6, 3)
+
+	testCase := assignerTestCase{
+		description: "Static assignments are used directly",
+		curr: [][]int{
+			{1, 2},
+			{2, 3},
+		},
+		expected: [][]int{
+			{1, 2, 3},
+			{2, 3, 4},
+		},
+	}
+	testCase.evaluate(
+		t,
+		NewReplicationAssigner(
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyStatic,
+				StaticAssignments: [][]int{
+					{1, 2, 3},
+					{2, 3, 4},
+				},
+			},
+			3,
+			pickers.NewLowestIndexPicker(),
+		),
+	)
+}
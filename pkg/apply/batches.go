@@ -0,0 +1,149 @@
+package apply
+
+import (
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+)
+
+// migrationBatch is a range of partitions, in the order that they're updated, that are
+// reassigned together.
+type migrationBatch struct {
+	start int
+	end   int
+
+	// bytes is the estimated number of bytes that need to be copied to new replicas in
+	// this batch; only set if partition sizes are known.
+	bytes int64
+
+	// duration is the estimated time that it takes to copy the bytes at the throttle
+	// rate; only set if partition sizes are known.
+	duration time.Duration
+}
+
+// batchAssignments splits the partitions to update into batches of at most batchSize
+// partitions each. If batchBytes is positive, then partitions are also packed, in order,
+// into batches that copy at most batchBytes to new replicas. A partition that is larger
+// than batchBytes on its own is put in a batch by itself. The bytes and durations of the
+// batches are estimated if partitionSizes is non-nil.
+func batchAssignments(
+	currAssignments []admin.PartitionAssignment,
+	targetAssignments []admin.PartitionAssignment,
+	batchSize int,
+	batchBytes int64,
+	partitionSizes map[int]int64,
+	throttleBytes int64,
+) []migrationBatch {
+	if batchSize <= 0 {
+		batchSize = len(targetAssignments)
+	}
+
+	batches := []migrationBatch{}
+	var currBytes int64
+
+	for i := range targetAssignments {
+		partitionBytes := movedBytes(currAssignments[i], targetAssignments[i], partitionSizes)
+
+		if len(batches) == 0 {
+			batches = append(batches, migrationBatch{start: i, end: i})
+		} else {
+			last := batches[len(batches)-1]
+			if last.end-last.start >= batchSize ||
+				(batchBytes > 0 && currBytes+partitionBytes > batchBytes) {
+				batches = append(batches, migrationBatch{start: i, end: i})
+				currBytes = 0
+			}
+		}
+
+		batches[len(batches)-1].end++
+		currBytes += partitionBytes
+	}
+
+	if partitionSizes != nil {
+		for b := range batches {
+			batch := &batches[b]
+			batch.bytes, batch.duration = estimateBatch(
+				currAssignments[batch.start:batch.end],
+				targetAssignments[batch.start:batch.end],
+				partitionSizes,
+				throttleBytes,
+			)
+		}
+	}
+
+	return batches
+}
+
+// movedBytes returns the number of bytes that need to be copied to move a partition from
+// its current to its target replicas.
+func movedBytes(
+	currAssignment admin.PartitionAssignment,
+	targetAssignment admin.PartitionAssignment,
+	partitionSizes map[int]int64,
+) int64 {
+	return partitionSizes[targetAssignment.ID] *
+		int64(newReplicas(currAssignment, targetAssignment))
+}
+
+// newReplicas returns the number of replicas in the target assignment that aren't in the
+// current one.
+func newReplicas(
+	currAssignment admin.PartitionAssignment,
+	targetAssignment admin.PartitionAssignment,
+) int {
+	count := 0
+	for _, replica := range targetAssignment.Replicas {
+		if currAssignment.Index(replica) == -1 {
+			count++
+		}
+	}
+	return count
+}
+
+// estimateBatch returns the total number of bytes copied in a batch and an estimate of how
+// long the copies take. Throttles limit the replication traffic in and out of each broker,
+// so the estimate is based on the busiest broker; the copies for each new replica are read
+// from the current leader.
+func estimateBatch(
+	currAssignments []admin.PartitionAssignment,
+	targetAssignments []admin.PartitionAssignment,
+	partitionSizes map[int]int64,
+	throttleBytes int64,
+) (int64, time.Duration) {
+	var totalBytes int64
+	inBytes := map[int]int64{}
+	outBytes := map[int]int64{}
+
+	for i, target := range targetAssignments {
+		curr := currAssignments[i]
+		size := partitionSizes[target.ID]
+
+		for _, replica := range target.Replicas {
+			if curr.Index(replica) != -1 {
+				continue
+			}
+			totalBytes += size
+			inBytes[replica] += size
+			if len(curr.Replicas) > 0 {
+				outBytes[curr.Replicas[0]] += size
+			}
+		}
+	}
+
+	if throttleBytes <= 0 {
+		return totalBytes, 0
+	}
+
+	var maxBrokerBytes int64
+	for _, brokerBytes := range []map[int]int64{inBytes, outBytes} {
+		for _, bytes := range brokerBytes {
+			if bytes > maxBrokerBytes {
+				maxBrokerBytes = bytes
+			}
+		}
+	}
+
+	return totalBytes, time.Duration(
+		float64(maxBrokerBytes) / float64(throttleBytes) * float64(time.Second),
+	)
+}
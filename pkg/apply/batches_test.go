@@ -0,0 +1,115 @@
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchAssignments(t *testing.T) {
+	currAssignments := []admin.PartitionAssignment{
+		{ID: 0, Replicas: []int{1, 2}},
+		{ID: 1, Replicas: []int{2, 3}},
+		{ID: 2, Replicas: []int{3, 1}},
+		{ID: 3, Replicas: []int{1, 2}},
+		{ID: 4, Replicas: []int{2, 3}},
+	}
+	targetAssignments := []admin.PartitionAssignment{
+		{ID: 0, Replicas: []int{4, 2}},
+		{ID: 1, Replicas: []int{2, 4}},
+		{ID: 2, Replicas: []int{4, 5}},
+		{ID: 3, Replicas: []int{2, 1}},
+		{ID: 4, Replicas: []int{2, 5}},
+	}
+	partitionSizes := map[int]int64{
+		0: 100,
+		1: 200,
+		2: 500,
+		3: 1000,
+		4: 50,
+	}
+
+	type testCase struct {
+		description    string
+		batchSize      int
+		batchBytes     int64
+		partitionSizes map[int]int64
+		expRanges      [][]int
+		expBytes       []int64
+	}
+
+	testCases := []testCase{
+		{
+			description: "partition count only",
+			batchSize:   2,
+			expRanges:   [][]int{{0, 2}, {2, 4}, {4, 5}},
+		},
+		{
+			description: "all at once",
+			batchSize:   0,
+			expRanges:   [][]int{{0, 5}},
+		},
+		{
+			description:    "bytes",
+			batchBytes:     400,
+			partitionSizes: partitionSizes,
+			// Partition 2 copies 1000 bytes on its own, and partition 3 doesn't copy anything
+			expRanges: [][]int{{0, 2}, {2, 3}, {3, 5}},
+			expBytes:  []int64{300, 1000, 50},
+		},
+		{
+			description:    "bytes and partition count",
+			batchSize:      1,
+			batchBytes:     400,
+			partitionSizes: partitionSizes,
+			expRanges:      [][]int{{0, 1}, {1, 2}, {2, 3}, {3, 4}, {4, 5}},
+			expBytes:       []int64{100, 200, 1000, 0, 50},
+		},
+	}
+
+	for _, testCase := range testCases {
+		batches := batchAssignments(
+			currAssignments,
+			targetAssignments,
+			testCase.batchSize,
+			testCase.batchBytes,
+			testCase.partitionSizes,
+			100,
+		)
+
+		ranges := [][]int{}
+		bytes := []int64{}
+		for _, batch := range batches {
+			ranges = append(ranges, []int{batch.start, batch.end})
+			bytes = append(bytes, batch.bytes)
+		}
+		assert.Equal(t, testCase.expRanges, ranges, testCase.description)
+		if testCase.expBytes != nil {
+			assert.Equal(t, testCase.expBytes, bytes, testCase.description)
+		}
+	}
+}
+
+func TestEstimateBatch(t *testing.T) {
+	totalBytes, duration := estimateBatch(
+		[]admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{1, 2}},
+			{ID: 1, Replicas: []int{1, 3}},
+		},
+		[]admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{4, 2}},
+			{ID: 1, Replicas: []int{5, 6}},
+		},
+		map[int]int64{
+			0: 1000,
+			1: 2000,
+		},
+		1000,
+	)
+
+	// Broker 1 leads both partitions, so it sends 1000 + 2 * 2000 bytes
+	assert.Equal(t, int64(5000), totalBytes)
+	assert.Equal(t, 5*time.Second, duration)
+}
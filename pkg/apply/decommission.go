@@ -0,0 +1,236 @@
+package apply
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+// BrokerReplicaCounts returns the number of replicas on each broker across all of the argument
+// topics.
+func BrokerReplicaCounts(topics []admin.TopicInfo) map[int]int {
+	replicaCounts := map[int]int{}
+
+	for _, topic := range topics {
+		for _, partition := range topic.Partitions {
+			for _, replica := range partition.Replicas {
+				replicaCounts[replica]++
+			}
+		}
+	}
+
+	return replicaCounts
+}
+
+// DecommissionAssignments returns the target assignments for a topic that move all of its
+// replicas off of the argument brokers. Each replica is replaced in place, so the other replicas
+// in the partition and their order aren't changed.
+//
+// Replacements respect the rack layout of the topic. If every partition of a topic with more
+// than one replica is in a single rack, then replacements are made within the same rack.
+// Otherwise, a replacement can't reduce the number of distinct racks in the partition.
+// Among the brokers that satisfy these constraints, brokers in the same rack as the replaced
+// one are preferred, followed by brokers in racks that the partition doesn't use yet, followed
+// by the ones with the fewest replicas in the argument replica counts. The counts are updated
+// with the replacements so that they can be shared across topics.
+func DecommissionAssignments(
+	topicInfo admin.TopicInfo,
+	brokers []admin.BrokerInfo,
+	brokerIDs []int,
+	replicaCounts map[int]int,
+) ([]admin.PartitionAssignment, error) {
+	brokerRacks := admin.BrokerRacks(brokers)
+	currAssignments := topicInfo.ToAssignments()
+	inRack := inRackAssignments(currAssignments, brokerRacks)
+
+	candidates := []admin.BrokerInfo{}
+	for _, broker := range brokers {
+		if !slices.Contains(brokerIDs, broker.ID) {
+			candidates = append(candidates, broker)
+		}
+	}
+
+	targetAssignments := admin.CopyAssignments(currAssignments)
+
+	for _, assignment := range targetAssignments {
+		for r, replica := range assignment.Replicas {
+			if !slices.Contains(brokerIDs, replica) {
+				continue
+			}
+
+			replacementRack := ""
+			if inRack {
+				replacementRack = brokerRacks[replica]
+			}
+			replacement := pickReplacement(
+				assignment,
+				r,
+				candidates,
+				brokerRacks,
+				replicaCounts,
+				replacementRack,
+				len(assignment.DistinctRacks(brokerRacks)),
+			)
+
+			if replacement == -1 {
+				return nil, fmt.Errorf(
+					"Could not find a replacement for broker %d in partition %d of topic %s that keeps its rack placement",
+					replica,
+					assignment.ID,
+					topicInfo.Name,
+				)
+			}
+
+			assignment.Replicas[r] = replacement
+			replicaCounts[replica]--
+			replicaCounts[replacement]++
+		}
+	}
+
+	return targetAssignments, nil
+}
+
+// inRackAssignments returns whether every partition has all of its replicas in a single rack.
+// Topics with a single replica per partition are never considered in-rack since there's no
+// way to tell.
+func inRackAssignments(
+	assignments []admin.PartitionAssignment,
+	brokerRacks map[int]string,
+) bool {
+	if len(assignments) == 0 || len(assignments[0].Replicas) < 2 {
+		return false
+	}
+
+	for _, assignment := range assignments {
+		if len(assignment.DistinctRacks(brokerRacks)) != 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// ReassignTopicConfig returns a topic config that, when applied with a TopicApplier, moves
+// the argument topic to the target assignments. The settings are taken from the topic itself so
+// that only the replicas change. The assignments are expressed as static placements so that the
+// migration goes through the usual throttled, batched path.
+func ReassignTopicConfig(
+	clusterConfig config.ClusterConfig,
+	topicInfo admin.TopicInfo,
+	targetAssignments []admin.PartitionAssignment,
+	description string,
+) config.TopicConfig {
+	topicConfig := config.TopicConfigFromTopicInfo(clusterConfig, topicInfo)
+	topicConfig.Meta.Description = description
+	topicConfig.Spec.PlacementConfig = StaticPlacementConfig(targetAssignments)
+	delete(topicConfig.Spec.Settings, admin.LeaderReplicasThrottledKey)
+	delete(topicConfig.Spec.Settings, admin.FollowerReplicasThrottledKey)
+	topicConfig.SetDefaults()
+
+	return topicConfig
+}
+
+// StaticPlacementConfig returns a placement config that puts the replicas of a topic on
+// exactly the argument target assignments.
+func StaticPlacementConfig(
+	targetAssignments []admin.PartitionAssignment,
+) config.TopicPlacementConfig {
+	staticAssignments := [][]int{}
+	for _, assignment := range targetAssignments {
+		staticAssignments = append(staticAssignments, slices.Clone(assignment.Replicas))
+	}
+
+	return config.TopicPlacementConfig{
+		Strategy:          config.PlacementStrategyStatic,
+		StaticAssignments: staticAssignments,
+	}
+}
+
+// ReplaceBrokerAssignments returns the target assignments for a topic that substitute the
+// to broker for the from broker in every partition, keeping the replica positions. None of
+// the other replicas are changed.
+func ReplaceBrokerAssignments(
+	topicInfo admin.TopicInfo,
+	fromBrokerID int,
+	toBrokerID int,
+) ([]admin.PartitionAssignment, error) {
+	targetAssignments := topicInfo.ToAssignments()
+
+	for _, assignment := range targetAssignments {
+		index := assignment.Index(fromBrokerID)
+		if index == -1 {
+			continue
+		}
+		if assignment.Index(toBrokerID) != -1 {
+			return nil, fmt.Errorf(
+				"Partition %d of topic %s already has a replica on broker %d",
+				assignment.ID,
+				topicInfo.Name,
+				toBrokerID,
+			)
+		}
+		assignment.Replicas[index] = toBrokerID
+	}
+
+	return targetAssignments, nil
+}
+
+// BrokerDecommissionStatus summarizes what's left on a broker that's being decommissioned.
+type BrokerDecommissionStatus struct {
+	Broker   int
+	Rack     string
+	Replicas int
+	Leaders  int
+
+	// Partitions are the topic partitions that still have replicas on the broker, formatted as
+	// [topic]/[partition].
+	Partitions []string
+}
+
+// Safe returns whether the broker holds no replicas or leaders and can be shut down.
+func (s BrokerDecommissionStatus) Safe() bool {
+	return s.Replicas == 0 && s.Leaders == 0
+}
+
+// BrokerDecommissionStatuses returns the status of each of the argument brokers based on the
+// current state of the argument topics.
+func BrokerDecommissionStatuses(
+	topics []admin.TopicInfo,
+	brokers []admin.BrokerInfo,
+	brokerIDs []int,
+) []BrokerDecommissionStatus {
+	brokerRacks := admin.BrokerRacks(brokers)
+	statuses := []BrokerDecommissionStatus{}
+
+	sortedIDs := slices.Clone(brokerIDs)
+	sort.Ints(sortedIDs)
+
+	for _, brokerID := range sortedIDs {
+		status := BrokerDecommissionStatus{
+			Broker:     brokerID,
+			Rack:       brokerRacks[brokerID],
+			Partitions: []string{},
+		}
+
+		for _, topic := range topics {
+			for _, partition := range topic.Partitions {
+				if partition.Leader == brokerID {
+					status.Leaders++
+				}
+				if slices.Contains(partition.Replicas, brokerID) {
+					status.Replicas++
+					status.Partitions = append(
+						status.Partitions,
+						fmt.Sprintf("%s/%d", topic.Name, partition.ID),
+					)
+				}
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
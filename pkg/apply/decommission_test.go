@@ -0,0 +1,265 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDecommissionBrokers() []admin.BrokerInfo {
+	return []admin.BrokerInfo{
+		{ID: 1, Rack: "rack1"},
+		{ID: 2, Rack: "rack1"},
+		{ID: 3, Rack: "rack2"},
+		{ID: 4, Rack: "rack2"},
+		{ID: 5, Rack: "rack3"},
+		{ID: 6, Rack: "rack3"},
+		{ID: 7, Rack: "rack3"},
+	}
+}
+
+func testTopicInfo(name string, replicas ...[]int) admin.TopicInfo {
+	topicInfo := admin.TopicInfo{Name: name}
+	for p, partitionReplicas := range replicas {
+		topicInfo.Partitions = append(
+			topicInfo.Partitions,
+			admin.PartitionInfo{
+				Topic:    name,
+				ID:       p,
+				Leader:   partitionReplicas[0],
+				Replicas: partitionReplicas,
+				ISR:      partitionReplicas,
+			},
+		)
+	}
+	return topicInfo
+}
+
+func TestDecommissionAssignments(t *testing.T) {
+	type testCase struct {
+		description   string
+		topicInfo     admin.TopicInfo
+		brokerIDs     []int
+		replicaCounts map[int]int
+		expected      [][]int
+		expectedErr   bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "cross-rack replicas stay in distinct racks",
+			topicInfo:   testTopicInfo("topic", []int{1, 3, 5}, []int{3, 5, 2}),
+			brokerIDs:   []int{1},
+			expected:    [][]int{{2, 3, 5}, {3, 5, 2}},
+		},
+		{
+			description: "other racks are used if the rack has no other brokers",
+			topicInfo:   testTopicInfo("topic", []int{1, 3}, []int{2, 4}),
+			brokerIDs:   []int{1, 2},
+			expected:    [][]int{{5, 3}, {6, 4}},
+		},
+		{
+			description:   "least-used brokers are preferred",
+			topicInfo:     testTopicInfo("topic", []int{1, 3}, []int{1, 4}),
+			brokerIDs:     []int{1, 2},
+			replicaCounts: map[int]int{5: 1},
+			expected:      [][]int{{6, 3}, {7, 4}},
+		},
+		{
+			description: "in-rack topics stay in-rack",
+			topicInfo:   testTopicInfo("topic", []int{3, 4}, []int{5, 6}),
+			brokerIDs:   []int{6},
+			expected:    [][]int{{3, 4}, {5, 7}},
+		},
+		{
+			description: "in-rack topics need another broker in the rack",
+			topicInfo:   testTopicInfo("topic", []int{1, 2}, []int{2, 1}),
+			brokerIDs:   []int{1},
+			expectedErr: true,
+		},
+		{
+			description: "single replicas prefer the same rack",
+			topicInfo:   testTopicInfo("topic", []int{3}, []int{1}),
+			brokerIDs:   []int{3},
+			expected:    [][]int{{4}, {1}},
+		},
+		{
+			description: "racks can't be lost",
+			topicInfo:   testTopicInfo("topic", []int{1, 3, 5}),
+			brokerIDs:   []int{1, 2},
+			expectedErr: true,
+		},
+		{
+			description: "nothing to move",
+			topicInfo:   testTopicInfo("topic", []int{3, 5}),
+			brokerIDs:   []int{1},
+			expected:    [][]int{{3, 5}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		replicaCounts := testCase.replicaCounts
+		if replicaCounts == nil {
+			replicaCounts = map[int]int{}
+		}
+
+		assignments, err := DecommissionAssignments(
+			testCase.topicInfo,
+			testDecommissionBrokers(),
+			testCase.brokerIDs,
+			replicaCounts,
+		)
+		if testCase.expectedErr {
+			assert.Error(t, err, testCase.description)
+			continue
+		}
+		require.NoError(t, err, testCase.description)
+
+		replicas, err := admin.AssignmentsToReplicas(assignments)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, replicas, testCase.description)
+	}
+}
+
+func TestDecommissionAssignmentsSharedCounts(t *testing.T) {
+	brokers := []admin.BrokerInfo{
+		{ID: 1, Rack: "rack1"},
+		{ID: 2, Rack: "rack1"},
+		{ID: 3, Rack: "rack1"},
+	}
+	topics := []admin.TopicInfo{
+		testTopicInfo("topic1", []int{1}, []int{2}),
+		testTopicInfo("topic2", []int{1}, []int{3}),
+	}
+
+	replicaCounts := BrokerReplicaCounts(topics)
+	assert.Equal(t, map[int]int{1: 2, 2: 1, 3: 1}, replicaCounts)
+
+	// Replicas are spread across the remaining brokers, across topics
+	assignments1, err := DecommissionAssignments(topics[0], brokers, []int{1}, replicaCounts)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, assignments1[0].Replicas)
+
+	assignments2, err := DecommissionAssignments(topics[1], brokers, []int{1}, replicaCounts)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, assignments2[0].Replicas)
+
+	assert.Equal(t, map[int]int{1: 0, 2: 2, 3: 2}, replicaCounts)
+}
+
+func TestReassignTopicConfig(t *testing.T) {
+	clusterConfig := config.ClusterConfig{
+		Meta: config.ClusterMeta{
+			Name:        "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+	}
+	topicInfo := testTopicInfo("test-topic", []int{1, 2}, []int{2, 3})
+	topicInfo.Config = map[string]string{
+		"retention.ms":                     "3600000",
+		"cleanup.policy":                   "compact",
+		admin.LeaderReplicasThrottledKey:   "0:1",
+		admin.FollowerReplicasThrottledKey: "0:3",
+	}
+
+	topicConfig := ReassignTopicConfig(
+		clusterConfig,
+		topicInfo,
+		[]admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{4, 2}},
+			{ID: 1, Replicas: []int{2, 3}},
+		},
+		"Broker decommission",
+	)
+	assert.Equal(t, "test-topic", topicConfig.Meta.Name)
+	assert.Equal(t, "Broker decommission", topicConfig.Meta.Description)
+	assert.Equal(t, 2, topicConfig.Spec.Partitions)
+	assert.Equal(t, 2, topicConfig.Spec.ReplicationFactor)
+	assert.Equal(t, 60, topicConfig.Spec.RetentionMinutes)
+	assert.Equal(
+		t,
+		config.TopicSettings{"cleanup.policy": "compact"},
+		topicConfig.Spec.Settings,
+	)
+	assert.Equal(t, config.PlacementStrategyStatic, topicConfig.Spec.PlacementConfig.Strategy)
+	assert.Equal(
+		t,
+		[][]int{{4, 2}, {2, 3}},
+		topicConfig.Spec.PlacementConfig.StaticAssignments,
+	)
+}
+
+func TestStaticPlacementConfig(t *testing.T) {
+	targetAssignments := []admin.PartitionAssignment{
+		{ID: 0, Replicas: []int{4, 2}},
+		{ID: 1, Replicas: []int{2, 3}},
+	}
+
+	placementConfig := StaticPlacementConfig(targetAssignments)
+	assert.Equal(
+		t,
+		config.TopicPlacementConfig{
+			Strategy:          config.PlacementStrategyStatic,
+			StaticAssignments: [][]int{{4, 2}, {2, 3}},
+		},
+		placementConfig,
+	)
+
+	// The assignments are copied
+	targetAssignments[0].Replicas[0] = 5
+	assert.Equal(t, []int{4, 2}, placementConfig.StaticAssignments[0])
+}
+
+func TestReplaceBrokerAssignments(t *testing.T) {
+	assignments, err := ReplaceBrokerAssignments(
+		testTopicInfo("topic", []int{7, 1, 2}, []int{1, 7, 3}, []int{1, 2, 3}),
+		7,
+		12,
+	)
+	require.NoError(t, err)
+	replicas, err := admin.AssignmentsToReplicas(assignments)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{12, 1, 2}, {1, 12, 3}, {1, 2, 3}}, replicas)
+
+	_, err = ReplaceBrokerAssignments(
+		testTopicInfo("topic", []int{7, 1}, []int{12, 7}),
+		7,
+		12,
+	)
+	assert.Error(t, err)
+}
+
+func TestBrokerDecommissionStatuses(t *testing.T) {
+	topics := []admin.TopicInfo{
+		testTopicInfo("topic1", []int{1, 3}, []int{3, 5}),
+		testTopicInfo("topic2", []int{5, 1}),
+	}
+
+	statuses := BrokerDecommissionStatuses(topics, testDecommissionBrokers(), []int{2, 1})
+	assert.Equal(
+		t,
+		[]BrokerDecommissionStatus{
+			{
+				Broker:     1,
+				Rack:       "rack1",
+				Replicas:   2,
+				Leaders:    1,
+				Partitions: []string{"topic1/0", "topic2/0"},
+			},
+			{
+				Broker:     2,
+				Rack:       "rack1",
+				Replicas:   0,
+				Leaders:    0,
+				Partitions: []string{},
+			},
+		},
+		statuses,
+	)
+	assert.False(t, statuses[0].Safe())
+	assert.True(t, statuses[1].Safe())
+}
@@ -0,0 +1,671 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/util"
+	"github.com/segmentio/topicctl/pkg/zk"
+	log "github.com/sirupsen/logrus"
+)
+
+// BrokerDrainStateVersion is the version of the broker drain state file format.
+const BrokerDrainStateVersion = 1
+
+// BrokerDrainState records the replica orderings of the partitions that were changed when
+// draining the leadership off of a broker, so that the preferred leaders can be restored
+// after the broker is back.
+type BrokerDrainState struct {
+	Version     int       `json:"version"`
+	Cluster     string    `json:"cluster"`
+	Environment string    `json:"environment"`
+	Region      string    `json:"region"`
+	Broker      int       `json:"broker"`
+	DrainedAt   time.Time `json:"drainedAt"`
+
+	// Partitions are the partitions whose replicas were reordered.
+	Partitions []DrainedPartition `json:"partitions"`
+
+	path string
+}
+
+// DrainedPartition stores the replica orderings of a partition before and after a drain.
+type DrainedPartition struct {
+	Topic            string `json:"topic"`
+	Partition        int    `json:"partition"`
+	OriginalReplicas []int  `json:"originalReplicas"`
+	DrainedReplicas  []int  `json:"drainedReplicas"`
+}
+
+// BrokerDrainStatePath returns the path of the drain state file for a broker.
+func BrokerDrainStatePath(
+	stateDir string,
+	cluster string,
+	environment string,
+	region string,
+	brokerID int,
+) string {
+	return filepath.Join(
+		stateDir,
+		fmt.Sprintf("%s-%s-%s-broker-%d.json", cluster, environment, region, brokerID),
+	)
+}
+
+// LoadBrokerDrainState loads the drain state at the argument path. It returns nil if there
+// is no state file.
+func LoadBrokerDrainState(path string) (*BrokerDrainState, error) {
+	state := &BrokerDrainState{}
+	found, err := readJSONFile(path, state)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading broker drain state file %s: %+v", path, err)
+	} else if !found {
+		return nil, nil
+	}
+	if state.Version != BrokerDrainStateVersion {
+		return nil, fmt.Errorf(
+			"Unsupported broker drain state version %d in %s (expected %d)",
+			state.Version,
+			path,
+			BrokerDrainStateVersion,
+		)
+	}
+	state.path = path
+
+	return state, nil
+}
+
+// Save writes the drain state to its file; nil states aren't written.
+func (s *BrokerDrainState) Save() error {
+	if s == nil {
+		return nil
+	}
+	return writeJSONFileAtomic(s.path, s)
+}
+
+// Delete removes the file of the drain state, e.g. once the broker has been restored.
+func (s *BrokerDrainState) Delete() error {
+	if s == nil {
+		return nil
+	}
+	return removeFile(s.path)
+}
+
+// BrokerMaintenanceConfig contains the configuration for draining and restoring the
+// leadership of a broker.
+type BrokerMaintenanceConfig struct {
+	ClusterConfig     config.ClusterConfig
+	BrokerID          int
+	DryRun            bool
+	SkipConfirm       bool
+	SleepLoopDuration time.Duration
+
+	// StateDir is the directory where the original replica orderings of drained brokers
+	// are recorded.
+	StateDir string
+}
+
+func (c BrokerMaintenanceConfig) statePath(brokerID int) string {
+	return BrokerDrainStatePath(
+		c.StateDir,
+		c.ClusterConfig.Meta.Name,
+		c.ClusterConfig.Meta.Environment,
+		c.ClusterConfig.Meta.Region,
+		brokerID,
+	)
+}
+
+// drainedBrokers returns the IDs of the other brokers in the cluster that are currently
+// drained.
+func (c BrokerMaintenanceConfig) drainedBrokers() ([]int, error) {
+	prefix := fmt.Sprintf(
+		"%s-%s-%s-broker-",
+		c.ClusterConfig.Meta.Name,
+		c.ClusterConfig.Meta.Environment,
+		c.ClusterConfig.Meta.Region,
+	)
+	paths, err := filepath.Glob(filepath.Join(c.StateDir, prefix+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	brokerIDs := []int{}
+	for _, path := range paths {
+		var brokerID int
+		if _, err := fmt.Sscanf(
+			strings.TrimPrefix(filepath.Base(path), prefix),
+			"%d.json",
+			&brokerID,
+		); err != nil {
+			continue
+		}
+		if brokerID != c.BrokerID {
+			brokerIDs = append(brokerIDs, brokerID)
+		}
+	}
+	sort.Ints(brokerIDs)
+
+	return brokerIDs, nil
+}
+
+// DrainBroker moves the leadership of every partition whose preferred leader is the argument
+// broker to another in-sync replica. It does this by reordering the replicas so that the
+// broker isn't first and then running leader elections; no data is moved. Brokers that are
+// already drained are avoided as new leaders if possible. The original orderings are
+// recorded in a state file so that they can be put back with RestoreBroker.
+func DrainBroker(
+	ctx context.Context,
+	adminClient admin.Client,
+	maintenanceConfig BrokerMaintenanceConfig,
+) error {
+	brokerID := maintenanceConfig.BrokerID
+	if maintenanceConfig.StateDir == "" {
+		return errors.New("A state directory is required to drain brokers")
+	}
+
+	path := maintenanceConfig.statePath(brokerID)
+	existingState, err := LoadBrokerDrainState(path)
+	if err != nil {
+		return err
+	}
+	if existingState != nil {
+		return fmt.Errorf(
+			"Broker %d was already drained at %s; restore it before draining it again, or remove %s",
+			brokerID,
+			existingState.DrainedAt.Format(time.RFC3339),
+			path,
+		)
+	}
+
+	if err := checkBrokerExists(ctx, adminClient, brokerID); err != nil {
+		return err
+	}
+
+	drainedBrokers, err := maintenanceConfig.drainedBrokers()
+	if err != nil {
+		return err
+	}
+	if len(drainedBrokers) > 0 {
+		log.Infof("Avoiding brokers that are already drained as new leaders: %+v", drainedBrokers)
+	}
+
+	topics, err := adminClient.GetTopics(ctx, nil, true)
+	if err != nil {
+		return err
+	}
+
+	state := &BrokerDrainState{
+		Version:     BrokerDrainStateVersion,
+		Cluster:     maintenanceConfig.ClusterConfig.Meta.Name,
+		Environment: maintenanceConfig.ClusterConfig.Meta.Environment,
+		Region:      maintenanceConfig.ClusterConfig.Meta.Region,
+		Broker:      brokerID,
+		DrainedAt:   time.Now().UTC(),
+		Partitions:  []DrainedPartition{},
+		path:        path,
+	}
+	elections := map[string][]int{}
+	stuck := []admin.PartitionInfo{}
+
+	for _, topic := range topics {
+		for _, partition := range topic.Partitions {
+			if len(partition.Replicas) == 0 {
+				continue
+			}
+
+			if partition.Replicas[0] != brokerID {
+				// The broker might still be the leader if the preferred leader isn't; an
+				// election moves leadership back to the preferred leader if it's in-sync.
+				if partition.Leader == brokerID &&
+					slices.Contains(partition.ISR, partition.Replicas[0]) {
+					elections[topic.Name] = append(elections[topic.Name], partition.ID)
+				} else if partition.Leader == brokerID {
+					stuck = append(stuck, partition)
+				}
+				continue
+			}
+
+			drainedReplicas := drainReplicas(partition, brokerID, drainedBrokers)
+			if drainedReplicas == nil {
+				stuck = append(stuck, partition)
+				continue
+			}
+
+			state.Partitions = append(
+				state.Partitions,
+				DrainedPartition{
+					Topic:            topic.Name,
+					Partition:        partition.ID,
+					OriginalReplicas: util.CopyInts(partition.Replicas),
+					DrainedReplicas:  drainedReplicas,
+				},
+			)
+			elections[topic.Name] = append(elections[topic.Name], partition.ID)
+		}
+	}
+
+	if len(stuck) > 0 {
+		log.Warnf(
+			"Leadership of %d partition(s) can't be moved off of broker %d because no other replicas are in-sync: %s",
+			len(stuck),
+			brokerID,
+			formatPartitionIDs(stuck),
+		)
+	}
+	if len(elections) == 0 {
+		log.Infof("Broker %d doesn't lead any partitions that can be moved", brokerID)
+		return nil
+	}
+
+	log.Infof(
+		"Here are the proposed replica reorderings for draining broker %d:\n%s",
+		brokerID,
+		FormatDrainedPartitions(state.Partitions),
+	)
+
+	if maintenanceConfig.DryRun {
+		log.Infof("Skipping update because dryRun is set to true")
+		return nil
+	}
+
+	ok, _ := util.Confirm(
+		fmt.Sprintf("OK to drain leadership from broker %d?", brokerID),
+		maintenanceConfig.SkipConfirm,
+	)
+	if !ok {
+		return errors.New("Stopping because of user response")
+	}
+
+	lock, lockPath, err := acquireMaintenanceLock(ctx, adminClient, maintenanceConfig)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		defer func() {
+			log.Infof("Releasing cluster lock: %s", lockPath)
+			lock.Unlock()
+		}()
+	}
+
+	// Record the original orderings before changing anything so that an interrupted drain
+	// can still be restored.
+	log.Infof("Recording original replica orderings in %s", path)
+	if err := state.Save(); err != nil {
+		return err
+	}
+
+	if err := reorderReplicas(ctx, adminClient, state.Partitions, false); err != nil {
+		return err
+	}
+
+	return runElectionsAndWait(
+		ctx,
+		adminClient,
+		elections,
+		func(partition admin.PartitionInfo) bool {
+			return partition.Leader != brokerID
+		},
+		maintenanceConfig.SleepLoopDuration,
+	)
+}
+
+// RestoreBroker puts back the replica orderings that were changed by DrainBroker and runs
+// leader elections so that the broker leads its preferred partitions again. The broker must
+// be in-sync for all of these partitions. Partitions whose replicas have changed since the
+// drain are skipped.
+func RestoreBroker(
+	ctx context.Context,
+	adminClient admin.Client,
+	maintenanceConfig BrokerMaintenanceConfig,
+) error {
+	brokerID := maintenanceConfig.BrokerID
+	path := maintenanceConfig.statePath(brokerID)
+
+	state, err := LoadBrokerDrainState(path)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("No drain state found for broker %d in %s", brokerID, path)
+	}
+
+	log.Infof(
+		"Found drain of broker %d from %s with %d reordered partition(s)",
+		brokerID,
+		state.DrainedAt.Format(time.RFC3339),
+		len(state.Partitions),
+	)
+
+	topicNames := []string{}
+	drainedByTopic := map[string][]DrainedPartition{}
+	for _, partition := range state.Partitions {
+		if _, ok := drainedByTopic[partition.Topic]; !ok {
+			topicNames = append(topicNames, partition.Topic)
+		}
+		drainedByTopic[partition.Topic] = append(drainedByTopic[partition.Topic], partition)
+	}
+
+	toRestore := []DrainedPartition{}
+	elections := map[string][]int{}
+	notInSync := []admin.PartitionInfo{}
+
+	for _, topicName := range topicNames {
+		topicInfo, err := adminClient.GetTopic(ctx, topicName, true)
+		if err == admin.ErrTopicDoesNotExist {
+			log.Warnf("Topic %s no longer exists; skipping it", topicName)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		for _, drained := range drainedByTopic[topicName] {
+			if drained.Partition >= len(topicInfo.Partitions) {
+				log.Warnf("Partition %d in topic %s no longer exists; skipping it", drained.Partition, topicName)
+				continue
+			}
+			partition := topicInfo.Partitions[drained.Partition]
+
+			if !util.SameElements(partition.Replicas, drained.OriginalReplicas) {
+				log.Warnf(
+					"Replicas for partition %d in topic %s (%+v) have changed since the drain; skipping it",
+					drained.Partition,
+					topicName,
+					partition.Replicas,
+				)
+				continue
+			}
+			if !slices.Contains(partition.ISR, brokerID) {
+				notInSync = append(notInSync, partition)
+				continue
+			}
+
+			if !reflect.DeepEqual(partition.Replicas, drained.OriginalReplicas) {
+				toRestore = append(toRestore, drained)
+			}
+			if partition.Leader != drained.OriginalReplicas[0] {
+				elections[topicName] = append(elections[topicName], drained.Partition)
+			}
+		}
+	}
+
+	if len(notInSync) > 0 {
+		return fmt.Errorf(
+			"Broker %d isn't in-sync yet for %d partition(s): %s; wait for it to catch up and re-run restore",
+			brokerID,
+			len(notInSync),
+			formatPartitionIDs(notInSync),
+		)
+	}
+
+	if len(toRestore) == 0 && len(elections) == 0 {
+		log.Infof("All preferred leaders for broker %d are already restored", brokerID)
+		if maintenanceConfig.DryRun {
+			return nil
+		}
+		return state.Delete()
+	}
+
+	log.Infof(
+		"Here are the proposed replica reorderings for restoring broker %d:\n%s",
+		brokerID,
+		FormatDrainedPartitions(toRestore),
+	)
+
+	if maintenanceConfig.DryRun {
+		log.Infof("Skipping update because dryRun is set to true")
+		return nil
+	}
+
+	ok, _ := util.Confirm(
+		fmt.Sprintf("OK to restore leadership to broker %d?", brokerID),
+		maintenanceConfig.SkipConfirm,
+	)
+	if !ok {
+		return errors.New("Stopping because of user response")
+	}
+
+	lock, lockPath, err := acquireMaintenanceLock(ctx, adminClient, maintenanceConfig)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		defer func() {
+			log.Infof("Releasing cluster lock: %s", lockPath)
+			lock.Unlock()
+		}()
+	}
+
+	if err := reorderReplicas(ctx, adminClient, toRestore, true); err != nil {
+		return err
+	}
+
+	expectedLeaders := map[string]map[int]int{}
+	for _, partition := range state.Partitions {
+		if _, ok := expectedLeaders[partition.Topic]; !ok {
+			expectedLeaders[partition.Topic] = map[int]int{}
+		}
+		expectedLeaders[partition.Topic][partition.Partition] = partition.OriginalReplicas[0]
+	}
+
+	if err := runElectionsAndWait(
+		ctx,
+		adminClient,
+		elections,
+		func(partition admin.PartitionInfo) bool {
+			return partition.Leader == expectedLeaders[partition.Topic][partition.ID]
+		},
+		maintenanceConfig.SleepLoopDuration,
+	); err != nil {
+		return err
+	}
+
+	return state.Delete()
+}
+
+// drainReplicas returns a new ordering of the partition's replicas with an in-sync replica
+// other than the argument broker first. Replicas on avoided brokers are only used if there's
+// no other choice. It returns nil if there are no other in-sync replicas.
+func drainReplicas(
+	partition admin.PartitionInfo,
+	brokerID int,
+	avoidBrokers []int,
+) []int {
+	newLeader := -1
+	for _, allowAvoided := range []bool{false, true} {
+		for _, replica := range partition.Replicas {
+			if replica == brokerID || !slices.Contains(partition.ISR, replica) {
+				continue
+			}
+			if !allowAvoided && slices.Contains(avoidBrokers, replica) {
+				continue
+			}
+			newLeader = replica
+			break
+		}
+		if newLeader != -1 {
+			break
+		}
+	}
+	if newLeader == -1 {
+		return nil
+	}
+
+	replicas := []int{newLeader}
+	for _, replica := range partition.Replicas {
+		if replica != newLeader {
+			replicas = append(replicas, replica)
+		}
+	}
+	return replicas
+}
+
+func checkBrokerExists(ctx context.Context, adminClient admin.Client, brokerID int) error {
+	brokerIDs, err := adminClient.GetBrokerIDs(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(brokerIDs, brokerID) {
+		return fmt.Errorf("Broker %d isn't in the cluster (brokers: %+v)", brokerID, brokerIDs)
+	}
+	return nil
+}
+
+// reorderReplicas sets the replicas of each argument partition to either the original or the
+// drained ordering. Since the replica sets don't change, no data is moved.
+func reorderReplicas(
+	ctx context.Context,
+	adminClient admin.Client,
+	partitions []DrainedPartition,
+	original bool,
+) error {
+	topicNames := []string{}
+	assignments := map[string][]admin.PartitionAssignment{}
+
+	for _, partition := range partitions {
+		if _, ok := assignments[partition.Topic]; !ok {
+			topicNames = append(topicNames, partition.Topic)
+		}
+
+		replicas := partition.DrainedReplicas
+		if original {
+			replicas = partition.OriginalReplicas
+		}
+		assignments[partition.Topic] = append(
+			assignments[partition.Topic],
+			admin.PartitionAssignment{
+				ID:       partition.Partition,
+				Replicas: util.CopyInts(replicas),
+			},
+		)
+	}
+
+	for _, topicName := range topicNames {
+		log.Infof("Reordering replicas for %d partition(s) in topic %s", len(assignments[topicName]), topicName)
+		if err := adminClient.AssignPartitions(ctx, topicName, assignments[topicName]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runElectionsAndWait runs leader elections for the argument partitions and then waits until
+// the leader of each one satisfies the argument function. Elections are retried for the
+// partitions that don't, e.g. because a reordering hadn't finished yet.
+func runElectionsAndWait(
+	ctx context.Context,
+	adminClient admin.Client,
+	elections map[string][]int,
+	leaderOK func(partition admin.PartitionInfo) bool,
+	sleepLoopDuration time.Duration,
+) error {
+	topicNames := []string{}
+	for topicName := range elections {
+		topicNames = append(topicNames, topicName)
+	}
+	sort.Strings(topicNames)
+
+	remaining := elections
+
+	checkTimer := time.NewTicker(sleepLoopDuration)
+	defer checkTimer.Stop()
+
+	for {
+		for _, topicName := range topicNames {
+			partitionIDs := remaining[topicName]
+			if len(partitionIDs) == 0 {
+				continue
+			}
+
+			log.Infof("Running leader elections in topic %s for partitions %+v", topicName, partitionIDs)
+			if err := adminClient.RunLeaderElection(ctx, topicName, partitionIDs); err != nil {
+				log.Warnf("Error running leader elections in topic %s: %+v", topicName, err)
+			}
+		}
+
+		select {
+		case <-checkTimer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		log.Info("Checking if leaders have been updated...")
+		nextRemaining := map[string][]int{}
+		numRemaining := 0
+		wrongLeaders := []admin.PartitionInfo{}
+
+		for _, topicName := range topicNames {
+			if len(remaining[topicName]) == 0 {
+				continue
+			}
+
+			topicInfo, err := adminClient.GetTopic(ctx, topicName, true)
+			if err != nil {
+				return err
+			}
+			for _, partitionID := range remaining[topicName] {
+				if partitionID >= len(topicInfo.Partitions) {
+					continue
+				}
+				partition := topicInfo.Partitions[partitionID]
+				if !leaderOK(partition) {
+					nextRemaining[topicName] = append(nextRemaining[topicName], partitionID)
+					wrongLeaders = append(wrongLeaders, partition)
+					numRemaining++
+				}
+			}
+		}
+
+		if numRemaining == 0 {
+			log.Infof("Leaders look good")
+			return nil
+		}
+		log.Infof(
+			"%d partition(s) still have the wrong leaders: %s",
+			numRemaining,
+			formatPartitionIDs(wrongLeaders),
+		)
+		log.Infof("Sleeping for %s", sleepLoopDuration.String())
+		remaining = nextRemaining
+	}
+}
+
+func acquireMaintenanceLock(
+	ctx context.Context,
+	adminClient admin.Client,
+	maintenanceConfig BrokerMaintenanceConfig,
+) (zk.Lock, string, error) {
+	if maintenanceConfig.DryRun || maintenanceConfig.ClusterConfig.Spec.ZKLockPath == "" {
+		return nil, "", nil
+	}
+
+	lockPath := clusterLockPath(
+		maintenanceConfig.ClusterConfig.Spec.ZKLockPath,
+		maintenanceConfig.ClusterConfig.Meta.Name,
+		maintenanceConfig.ClusterConfig.Meta.Environment,
+		maintenanceConfig.ClusterConfig.Meta.Region,
+	)
+	log.Infof("Acquiring cluster lock: %s", lockPath)
+	lockCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	lock, err := adminClient.AcquireLock(lockCtx, lockPath)
+	return lock, lockPath, err
+}
+
+func formatPartitionIDs(partitions []admin.PartitionInfo) string {
+	partitionStrs := []string{}
+	for _, partition := range partitions {
+		partitionStrs = append(partitionStrs, fmt.Sprintf("%s/%d", partition.Topic, partition.ID))
+	}
+	partitionsStr, _ := util.TruncateStringSuffix(strings.Join(partitionStrs, ", "), 500)
+	return partitionsStr
+}
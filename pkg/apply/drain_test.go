@@ -0,0 +1,143 @@
+package apply
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDrainReplicas(t *testing.T) {
+	type testCase struct {
+		description  string
+		partition    admin.PartitionInfo
+		avoidBrokers []int
+		expected     []int
+	}
+
+	testCases := []testCase{
+		{
+			description: "first in-sync replica becomes leader",
+			partition: admin.PartitionInfo{
+				Replicas: []int{1, 2, 3},
+				ISR:      []int{1, 2, 3},
+			},
+			expected: []int{2, 1, 3},
+		},
+		{
+			description: "out-of-sync replicas are skipped",
+			partition: admin.PartitionInfo{
+				Replicas: []int{1, 2, 3},
+				ISR:      []int{3, 1},
+			},
+			expected: []int{3, 1, 2},
+		},
+		{
+			description: "drained brokers are avoided",
+			partition: admin.PartitionInfo{
+				Replicas: []int{1, 2, 3},
+				ISR:      []int{1, 2, 3},
+			},
+			avoidBrokers: []int{2},
+			expected:     []int{3, 1, 2},
+		},
+		{
+			description: "drained brokers are used if there's no other choice",
+			partition: admin.PartitionInfo{
+				Replicas: []int{1, 2, 3},
+				ISR:      []int{1, 2},
+			},
+			avoidBrokers: []int{2},
+			expected:     []int{2, 1, 3},
+		},
+		{
+			description: "no other in-sync replicas",
+			partition: admin.PartitionInfo{
+				Replicas: []int{1, 2},
+				ISR:      []int{1},
+			},
+			expected: nil,
+		},
+		{
+			description: "single replica",
+			partition: admin.PartitionInfo{
+				Replicas: []int{1},
+				ISR:      []int{1},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(
+			t,
+			testCase.expected,
+			drainReplicas(testCase.partition, 1, testCase.avoidBrokers),
+			testCase.description,
+		)
+	}
+}
+
+func TestBrokerDrainStateDrainedBrokers(t *testing.T) {
+	stateDir := t.TempDir()
+	maintenanceConfig := BrokerMaintenanceConfig{
+		ClusterConfig: config.ClusterConfig{
+			Meta: config.ClusterMeta{
+				Name:        "test-cluster",
+				Environment: "test-environment",
+				Region:      "test-region",
+			},
+		},
+		BrokerID: 1,
+		StateDir: stateDir,
+	}
+
+	path := maintenanceConfig.statePath(1)
+	assert.Equal(
+		t,
+		filepath.Join(stateDir, "test-cluster-test-environment-test-region-broker-1.json"),
+		path,
+	)
+
+	drainedBrokers, err := maintenanceConfig.drainedBrokers()
+	require.NoError(t, err)
+	assert.Equal(t, []int{}, drainedBrokers)
+
+	state := &BrokerDrainState{
+		Version:   BrokerDrainStateVersion,
+		Cluster:   "test-cluster",
+		Broker:    1,
+		DrainedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Partitions: []DrainedPartition{
+			{
+				Topic:            "test-topic",
+				Partition:        2,
+				OriginalReplicas: []int{1, 2, 3},
+				DrainedReplicas:  []int{2, 1, 3},
+			},
+		},
+		path: path,
+	}
+	require.NoError(t, state.Save())
+
+	loadedState, err := LoadBrokerDrainState(path)
+	require.NoError(t, err)
+	assert.Equal(t, state, loadedState)
+
+	// Drained brokers only include the other brokers in the same cluster
+	for _, otherPath := range []string{
+		BrokerDrainStatePath(stateDir, "test-cluster", "test-environment", "test-region", 3),
+		BrokerDrainStatePath(stateDir, "other-cluster", "test-environment", "test-region", 4),
+	} {
+		otherState := *state
+		otherState.path = otherPath
+		require.NoError(t, otherState.Save())
+	}
+	drainedBrokers, err = maintenanceConfig.drainedBrokers()
+	require.NoError(t, err)
+	assert.Equal(t, []int{3}, drainedBrokers)
+}
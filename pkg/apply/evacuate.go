@@ -0,0 +1,365 @@
+package apply
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// RackEvacuationStateVersion is the version of the rack evacuation state file format.
+const RackEvacuationStateVersion = 1
+
+// RackEvacuationState records the replica assignments of the topics that were changed when
+// evacuating a rack, so that they can be put back after the rack is available again.
+type RackEvacuationState struct {
+	Version     int       `json:"version"`
+	Cluster     string    `json:"cluster"`
+	Environment string    `json:"environment"`
+	Region      string    `json:"region"`
+	Rack        string    `json:"rack"`
+	Brokers     []int     `json:"brokers"`
+	EvacuatedAt time.Time `json:"evacuatedAt"`
+
+	// Topics are the topics whose replicas were moved.
+	Topics []EvacuatedTopic `json:"topics"`
+
+	path string
+}
+
+// EvacuatedTopic stores the replica assignments of a topic before and after a rack evacuation.
+type EvacuatedTopic struct {
+	Topic                string                      `json:"topic"`
+	OriginalAssignments  []admin.PartitionAssignment `json:"originalAssignments"`
+	EvacuatedAssignments []admin.PartitionAssignment `json:"evacuatedAssignments"`
+}
+
+// RackEvacuationStatePath returns the path of the evacuation state file for a rack.
+func RackEvacuationStatePath(
+	stateDir string,
+	cluster string,
+	environment string,
+	region string,
+	rack string,
+) string {
+	return filepath.Join(
+		stateDir,
+		fmt.Sprintf("%s-%s-%s-rack-%s.json", cluster, environment, region, rack),
+	)
+}
+
+// NewRackEvacuationState returns an empty evacuation state that's saved to the argument path.
+func NewRackEvacuationState(
+	path string,
+	cluster string,
+	environment string,
+	region string,
+	rack string,
+	brokers []int,
+) *RackEvacuationState {
+	return &RackEvacuationState{
+		Version:     RackEvacuationStateVersion,
+		Cluster:     cluster,
+		Environment: environment,
+		Region:      region,
+		Rack:        rack,
+		Brokers:     brokers,
+		EvacuatedAt: time.Now().UTC(),
+		Topics:      []EvacuatedTopic{},
+		path:        path,
+	}
+}
+
+// LoadRackEvacuationState loads the evacuation state at the argument path. It returns nil if
+// there is no state file.
+func LoadRackEvacuationState(path string) (*RackEvacuationState, error) {
+	state := &RackEvacuationState{}
+	found, err := readJSONFile(path, state)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading rack evacuation state file %s: %+v", path, err)
+	} else if !found {
+		return nil, nil
+	}
+	if state.Version != RackEvacuationStateVersion {
+		return nil, fmt.Errorf(
+			"Unsupported rack evacuation state version %d in %s (expected %d)",
+			state.Version,
+			path,
+			RackEvacuationStateVersion,
+		)
+	}
+	state.path = path
+
+	return state, nil
+}
+
+// Save writes the evacuation state to its file, e.g. after each topic has been evacuated.
+func (s *RackEvacuationState) Save() error {
+	if s == nil {
+		return nil
+	}
+	return writeJSONFileAtomic(s.path, s)
+}
+
+// Delete removes the file of the evacuation state once the rack has been restored.
+func (s *RackEvacuationState) Delete() error {
+	if s == nil {
+		return nil
+	}
+	return removeFile(s.path)
+}
+
+// RestoreAssignments returns the assignments that put the argument topic back to its original
+// assignments. Partitions that aren't on their evacuated assignments anymore, e.g. because
+// they were moved by something else, keep their current replicas, as do partitions that were
+// added after the evacuation.
+func (t EvacuatedTopic) RestoreAssignments(
+	currAssignments []admin.PartitionAssignment,
+) []admin.PartitionAssignment {
+	targetAssignments := admin.CopyAssignments(currAssignments)
+
+	for a, assignment := range targetAssignments {
+		if assignment.ID >= len(t.OriginalAssignments) ||
+			assignment.ID >= len(t.EvacuatedAssignments) {
+			continue
+		}
+		if !reflect.DeepEqual(
+			assignment.Replicas,
+			t.EvacuatedAssignments[assignment.ID].Replicas,
+		) {
+			log.Warnf(
+				"Replicas for partition %d in topic %s (%+v) have changed since the evacuation; keeping them",
+				assignment.ID,
+				t.Topic,
+				assignment.Replicas,
+			)
+			continue
+		}
+		targetAssignments[a].Replicas = util.CopyInts(
+			t.OriginalAssignments[assignment.ID].Replicas,
+		)
+	}
+
+	return targetAssignments
+}
+
+// EvacuateRackAssignments returns the target assignments for a topic that move all of its
+// replicas off of the brokers in the argument rack, keeping the topic's rack placement with
+// the remaining racks.
+//
+// If every partition of a topic with more than one replica is in a single rack, then the
+// partitions in the evacuated rack are moved, as a whole, to the remaining rack with the
+// fewest replicas per broker that has enough brokers. Otherwise, each replica in the rack is
+// replaced in place, preferring racks that the partition doesn't use yet, and each partition
+// keeps as many distinct racks as it had, up to the number of remaining racks. Among the
+// brokers that satisfy these constraints, the ones with the fewest replicas in the argument
+// counts are preferred; the counts are updated with the replacements so that they can be
+// shared across topics.
+func EvacuateRackAssignments(
+	topicInfo admin.TopicInfo,
+	brokers []admin.BrokerInfo,
+	rack string,
+	replicaCounts map[int]int,
+) ([]admin.PartitionAssignment, error) {
+	brokerRacks := admin.BrokerRacks(brokers)
+	currAssignments := topicInfo.ToAssignments()
+
+	candidates := []admin.BrokerInfo{}
+	for _, broker := range brokers {
+		if broker.Rack != rack {
+			candidates = append(candidates, broker)
+		}
+	}
+	remainingRacks := len(admin.DistinctRacks(candidates))
+
+	if inRackAssignments(currAssignments, brokerRacks) {
+		return evacuateInRackAssignments(
+			topicInfo.Name,
+			currAssignments,
+			candidates,
+			brokerRacks,
+			rack,
+			replicaCounts,
+		)
+	}
+
+	targetAssignments := admin.CopyAssignments(currAssignments)
+
+	for _, assignment := range targetAssignments {
+		minRacks := len(assignment.DistinctRacks(brokerRacks))
+		if minRacks > remainingRacks {
+			minRacks = remainingRacks
+		}
+
+		for r, replica := range assignment.Replicas {
+			if brokerRacks[replica] != rack {
+				continue
+			}
+
+			replacement := pickReplacement(
+				assignment,
+				r,
+				candidates,
+				brokerRacks,
+				replicaCounts,
+				"",
+				0,
+			)
+			if replacement == -1 {
+				return nil, fmt.Errorf(
+					"Could not find a replacement for broker %d in partition %d of topic %s outside of rack %s",
+					replica,
+					assignment.ID,
+					topicInfo.Name,
+					rack,
+				)
+			}
+
+			assignment.Replicas[r] = replacement
+			replicaCounts[replica]--
+			replicaCounts[replacement]++
+		}
+
+		if len(assignment.DistinctRacks(brokerRacks)) < minRacks {
+			return nil, fmt.Errorf(
+				"Could not keep partition %d of topic %s in %d racks without rack %s",
+				assignment.ID,
+				topicInfo.Name,
+				minRacks,
+				rack,
+			)
+		}
+	}
+
+	return targetAssignments, nil
+}
+
+func evacuateInRackAssignments(
+	topicName string,
+	currAssignments []admin.PartitionAssignment,
+	candidates []admin.BrokerInfo,
+	brokerRacks map[int]string,
+	rack string,
+	replicaCounts map[int]int,
+) ([]admin.PartitionAssignment, error) {
+	brokersPerRack := admin.BrokersPerRack(candidates)
+	racks := admin.DistinctRacks(candidates)
+	targetAssignments := admin.CopyAssignments(currAssignments)
+
+	for _, assignment := range targetAssignments {
+		if brokerRacks[assignment.Replicas[0]] != rack {
+			continue
+		}
+
+		// Pick the rack with the fewest replicas per broker
+		targetRack := ""
+		var targetUsage float64
+		for _, candidateRack := range racks {
+			rackBrokers := brokersPerRack[candidateRack]
+			if len(rackBrokers) < len(assignment.Replicas) {
+				continue
+			}
+
+			rackReplicas := 0
+			for _, brokerID := range rackBrokers {
+				rackReplicas += replicaCounts[brokerID]
+			}
+			usage := float64(rackReplicas) / float64(len(rackBrokers))
+			if targetRack == "" || usage < targetUsage {
+				targetRack = candidateRack
+				targetUsage = usage
+			}
+		}
+		if targetRack == "" {
+			return nil, fmt.Errorf(
+				"Could not find another rack with %d brokers for partition %d of topic %s",
+				len(assignment.Replicas),
+				assignment.ID,
+				topicName,
+			)
+		}
+
+		// Then, the least-used brokers in that rack
+		rackBrokers := slices.Clone(brokersPerRack[targetRack])
+		sort.Slice(rackBrokers, func(a, b int) bool {
+			countA := replicaCounts[rackBrokers[a]]
+			countB := replicaCounts[rackBrokers[b]]
+			return countA < countB || (countA == countB && rackBrokers[a] < rackBrokers[b])
+		})
+
+		for r, replica := range assignment.Replicas {
+			replicaCounts[replica]--
+			replicaCounts[rackBrokers[r]]++
+			assignment.Replicas[r] = rackBrokers[r]
+		}
+	}
+
+	return targetAssignments, nil
+}
+
+// pickReplacement returns the broker that should replace the replica at the argument index of
+// the assignment, or -1 if there's none. Candidates can't already be in the partition, must be
+// in the argument rack if it's set, and must leave the partition with at least minRacks
+// distinct racks. Brokers in the same rack as the replaced one are preferred, followed by
+// brokers in racks that the partition doesn't use yet, followed by the ones with the fewest
+// replicas in the argument counts.
+func pickReplacement(
+	assignment admin.PartitionAssignment,
+	index int,
+	candidates []admin.BrokerInfo,
+	brokerRacks map[int]string,
+	replicaCounts map[int]int,
+	rack string,
+	minRacks int,
+) int {
+	replica := assignment.Replicas[index]
+	otherRacks := map[string]struct{}{}
+	for r, otherReplica := range assignment.Replicas {
+		if r != index {
+			otherRacks[brokerRacks[otherReplica]] = struct{}{}
+		}
+	}
+
+	replacement := -1
+	var replacementScore []int
+
+	for _, candidate := range candidates {
+		if assignment.Index(candidate.ID) != -1 {
+			continue
+		}
+		if rack != "" && candidate.Rack != rack {
+			continue
+		}
+
+		_, usedRack := otherRacks[candidate.Rack]
+		numRacks := len(otherRacks)
+		if !usedRack {
+			numRacks++
+		}
+		if numRacks < minRacks {
+			continue
+		}
+
+		sameRack := 1
+		if candidate.Rack == brokerRacks[replica] {
+			sameRack = 0
+		}
+		newRack := 0
+		if usedRack {
+			newRack = 1
+		}
+		score := []int{sameRack, newRack, replicaCounts[candidate.ID], candidate.ID}
+		if replacement == -1 || slices.Compare(score, replacementScore) < 0 {
+			replacement = candidate.ID
+			replacementScore = score
+		}
+	}
+
+	return replacement
+}
@@ -0,0 +1,165 @@
+package apply
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvacuateRackAssignments(t *testing.T) {
+	type testCase struct {
+		description   string
+		topicInfo     admin.TopicInfo
+		rack          string
+		replicaCounts map[int]int
+		expected      [][]int
+		expectedErr   bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "cross-rack replicas move to unused racks",
+			topicInfo:   testTopicInfo("topic", []int{1, 3}, []int{4, 2}, []int{5, 3}),
+			rack:        "rack1",
+			expected:    [][]int{{5, 3}, {4, 6}, {5, 3}},
+		},
+		{
+			description: "cross-rack replicas keep as many racks as possible",
+			topicInfo:   testTopicInfo("topic", []int{1, 3, 5}, []int{4, 6, 2}),
+			rack:        "rack1",
+			expected:    [][]int{{4, 3, 5}, {4, 6, 3}},
+		},
+		{
+			description:   "least-used brokers are preferred",
+			topicInfo:     testTopicInfo("topic", []int{3, 1}),
+			rack:          "rack1",
+			replicaCounts: map[int]int{5: 3, 6: 1},
+			expected:      [][]int{{3, 7}},
+		},
+		{
+			description: "in-rack partitions move to another rack together",
+			topicInfo:   testTopicInfo("topic", []int{1, 2}, []int{3, 4}),
+			rack:        "rack1",
+			replicaCounts: map[int]int{
+				3: 5,
+				4: 5,
+			},
+			expected: [][]int{{5, 6}, {3, 4}},
+		},
+		{
+			description: "in-rack partitions need a rack with enough brokers",
+			topicInfo:   testTopicInfo("topic", []int{5, 6, 7}),
+			rack:        "rack3",
+			expectedErr: true,
+		},
+		{
+			description: "nothing to move",
+			topicInfo:   testTopicInfo("topic", []int{3, 5}),
+			rack:        "rack1",
+			expected:    [][]int{{3, 5}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		replicaCounts := testCase.replicaCounts
+		if replicaCounts == nil {
+			replicaCounts = map[int]int{}
+		}
+
+		assignments, err := EvacuateRackAssignments(
+			testCase.topicInfo,
+			testDecommissionBrokers(),
+			testCase.rack,
+			replicaCounts,
+		)
+		if testCase.expectedErr {
+			assert.Error(t, err, testCase.description)
+			continue
+		}
+		require.NoError(t, err, testCase.description)
+
+		replicas, err := admin.AssignmentsToReplicas(assignments)
+		require.NoError(t, err)
+		assert.Equal(t, testCase.expected, replicas, testCase.description)
+	}
+}
+
+func TestRackEvacuationStateSaveLoad(t *testing.T) {
+	stateDir := t.TempDir()
+	path := RackEvacuationStatePath(
+		stateDir,
+		"test-cluster",
+		"test-environment",
+		"test-region",
+		"rack1",
+	)
+	assert.Equal(
+		t,
+		filepath.Join(stateDir, "test-cluster-test-environment-test-region-rack-rack1.json"),
+		path,
+	)
+
+	state := NewRackEvacuationState(
+		path,
+		"test-cluster",
+		"test-environment",
+		"test-region",
+		"rack1",
+		[]int{1, 2},
+	)
+	state.Topics = append(
+		state.Topics,
+		EvacuatedTopic{
+			Topic: "test-topic",
+			OriginalAssignments: []admin.PartitionAssignment{
+				{ID: 0, Replicas: []int{1, 3}},
+			},
+			EvacuatedAssignments: []admin.PartitionAssignment{
+				{ID: 0, Replicas: []int{5, 3}},
+			},
+		},
+	)
+	require.NoError(t, state.Save())
+
+	loadedState, err := LoadRackEvacuationState(path)
+	require.NoError(t, err)
+	assert.Equal(t, state.Topics, loadedState.Topics)
+	assert.Equal(t, state.Brokers, loadedState.Brokers)
+	assert.True(t, state.EvacuatedAt.Equal(loadedState.EvacuatedAt))
+}
+
+func TestEvacuatedTopicRestoreAssignments(t *testing.T) {
+	evacuatedTopic := EvacuatedTopic{
+		Topic: "test-topic",
+		OriginalAssignments: []admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{1, 3}},
+			{ID: 1, Replicas: []int{3, 2}},
+		},
+		EvacuatedAssignments: []admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{5, 3}},
+			{ID: 1, Replicas: []int{3, 6}},
+		},
+	}
+
+	assert.Equal(
+		t,
+		[]admin.PartitionAssignment{
+			// Restored
+			{ID: 0, Replicas: []int{1, 3}},
+			// Moved since the evacuation
+			{ID: 1, Replicas: []int{4, 6}},
+			// Added since the evacuation
+			{ID: 2, Replicas: []int{5, 4}},
+		},
+		evacuatedTopic.RestoreAssignments(
+			[]admin.PartitionAssignment{
+				{ID: 0, Replicas: []int{5, 3}},
+				{ID: 1, Replicas: []int{4, 6}},
+				{ID: 2, Replicas: []int{5, 4}},
+			},
+		),
+	)
+}
@@ -0,0 +1,141 @@
+package apply
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	log "github.com/sirupsen/logrus"
+)
+
+// EventType is the type of an apply or rebalance progress event.
+type EventType string
+
+const (
+	// EventTypePlanComputed is emitted when the partition reassignments for a topic have been
+	// computed, before any of them are applied.
+	EventTypePlanComputed EventType = "planComputed"
+
+	// EventTypeBatchStarted is emitted before each batch of partition reassignments.
+	EventTypeBatchStarted EventType = "batchStarted"
+
+	// EventTypeBatchFinished is emitted after each batch of partition reassignments finishes.
+	EventTypeBatchFinished EventType = "batchFinished"
+
+	// EventTypeThrottleApplied is emitted when topic or broker throttles are applied or their
+	// rate is changed.
+	EventTypeThrottleApplied EventType = "throttleApplied"
+
+	// EventTypeThrottleRemoved is emitted when topic or broker throttles are removed.
+	EventTypeThrottleRemoved EventType = "throttleRemoved"
+
+	// EventTypeLeaderElection is emitted when leader elections are run for partitions.
+	EventTypeLeaderElection EventType = "leaderElection"
+
+	// EventTypeError is emitted when an apply or rebalance of a topic fails.
+	EventTypeError EventType = "error"
+
+	// EventTypeSummary is emitted at the end of an apply of a topic, and at the end of a
+	// rebalance of all topics.
+	EventTypeSummary EventType = "summary"
+)
+
+// Event is a structured progress event from an apply or rebalance run. Only the fields that
+// are relevant to the event type are set.
+type Event struct {
+	Type        EventType `json:"type"`
+	Time        time.Time `json:"time"`
+	Cluster     string    `json:"cluster"`
+	Environment string    `json:"environment"`
+	Region      string    `json:"region"`
+	Topic       string    `json:"topic,omitempty"`
+
+	// Round and NumRounds are set for batch events.
+	Round     int `json:"round,omitempty"`
+	NumRounds int `json:"numRounds,omitempty"`
+
+	// BatchSize is set for planComputed events.
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// CurrAssignments and TargetAssignments are set for planComputed and batch events.
+	CurrAssignments   []admin.PartitionAssignment `json:"currAssignments,omitempty"`
+	TargetAssignments []admin.PartitionAssignment `json:"targetAssignments,omitempty"`
+
+	// DurationSeconds is the time that a batch took, set for batchFinished events.
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+
+	// ThrottledTopic, Brokers, and ThrottleBytes are set for throttle events.
+	ThrottledTopic bool  `json:"throttledTopic,omitempty"`
+	Brokers        []int `json:"brokers,omitempty"`
+	ThrottleBytes  int64 `json:"throttleBytes,omitempty"`
+
+	// Partitions are set for leaderElection events.
+	Partitions []int `json:"partitions,omitempty"`
+
+	// Error is set for error events.
+	Error string `json:"error,omitempty"`
+
+	// Changes are the changes made to the topic, set for summary events of a topic.
+	Changes NewOrUpdatedChanges `json:"changes,omitempty"`
+
+	// SuccessTopics, ErrorTopics, and TopicErrors are set for rebalance summary events.
+	SuccessTopics int               `json:"successTopics,omitempty"`
+	ErrorTopics   int               `json:"errorTopics,omitempty"`
+	TopicErrors   map[string]string `json:"topicErrors,omitempty"`
+}
+
+// EventWriter writes events as newline-delimited JSON. It's safe to use from multiple
+// goroutines.
+type EventWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewEventWriter returns a new EventWriter that writes to the argument writer.
+func NewEventWriter(writer io.Writer) *EventWriter {
+	return &EventWriter{
+		writer: writer,
+	}
+}
+
+// Emit writes an event. The event time is set to the current time if it's not already set.
+// Write errors are logged but not returned so that they don't interrupt the run. It's a
+// no-op if the writer is nil.
+func (e *EventWriter) Emit(event Event) {
+	if e == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	contents, err := json.Marshal(event)
+	if err != nil {
+		log.Warnf("Error marshalling %s event: %+v", event.Type, err)
+		return
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if _, err := e.writer.Write(append(contents, '\n')); err != nil {
+		log.Warnf("Error writing %s event: %+v", event.Type, err)
+	}
+}
+
+// emitEvent fills in the cluster and topic fields of an event and then emits it.
+func (t *TopicApplier) emitEvent(event Event) {
+	if t.config.Events == nil {
+		return
+	}
+
+	event.Cluster = t.clusterConfig.Meta.Name
+	event.Environment = t.clusterConfig.Meta.Environment
+	event.Region = t.clusterConfig.Meta.Region
+	event.Topic = t.topicName
+	event.Changes = nonNilChanges(event.Changes)
+
+	t.config.Events.Emit(event)
+}
@@ -0,0 +1,123 @@
+package apply
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer := NewEventWriter(buf)
+
+	applier := &TopicApplier{
+		clusterConfig: config.ClusterConfig{
+			Meta: config.ClusterMeta{
+				Name:        "test-cluster",
+				Environment: "test-env",
+				Region:      "test-region",
+			},
+		},
+		topicName: "test-topic",
+		config: TopicApplierConfig{
+			Events: writer,
+		},
+	}
+
+	applier.emitEvent(
+		Event{
+			Type:      EventTypeBatchStarted,
+			Round:     1,
+			NumRounds: 3,
+			TargetAssignments: []admin.PartitionAssignment{
+				{
+					ID:       0,
+					Replicas: []int{1, 2},
+				},
+			},
+		},
+	)
+
+	fixedTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	writer.Emit(
+		Event{
+			Type:          EventTypeSummary,
+			Time:          fixedTime,
+			Cluster:       "test-cluster",
+			SuccessTopics: 2,
+			ErrorTopics:   1,
+			TopicErrors: map[string]string{
+				"bad-topic": "timed out",
+			},
+		},
+	)
+
+	// Emitting to a nil writer is a no-op
+	var nilWriter *EventWriter
+	nilWriter.Emit(Event{Type: EventTypeError})
+	(&TopicApplier{}).emitEvent(Event{Type: EventTypeError})
+
+	events := []map[string]interface{}{}
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, 2, len(events))
+
+	assert.Equal(t, "batchStarted", events[0]["type"])
+	assert.Equal(t, "test-cluster", events[0]["cluster"])
+	assert.Equal(t, "test-env", events[0]["environment"])
+	assert.Equal(t, "test-region", events[0]["region"])
+	assert.Equal(t, "test-topic", events[0]["topic"])
+	assert.Equal(t, float64(1), events[0]["round"])
+	assert.Equal(t, float64(3), events[0]["numRounds"])
+	assert.NotEmpty(t, events[0]["time"])
+	assert.NotContains(t, events[0], "changes")
+	assert.NotContains(t, events[0], "error")
+
+	assert.Equal(t, "summary", events[1]["type"])
+	assert.Equal(t, "2024-01-02T03:04:05Z", events[1]["time"])
+	assert.Equal(t, float64(2), events[1]["successTopics"])
+	assert.Equal(
+		t,
+		map[string]interface{}{"bad-topic": "timed out"},
+		events[1]["topicErrors"],
+	)
+}
+
+func TestEventWriterConcurrent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	writer := NewEventWriter(buf)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(round int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				writer.Emit(Event{Type: EventTypeBatchFinished, Round: round})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	numLines := 0
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		event := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		numLines++
+	}
+	assert.Equal(t, 200, numLines)
+}
@@ -0,0 +1,126 @@
+package extenders
+
+import (
+	"fmt"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/assigners"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+)
+
+// LeaderRackExtender adds extra partition assignments for topics that use the leader-rack
+// strategy. The current algorithm is:
+//
+//	for each new partition:
+//	  set the leader rack to the preferred rack with the fewest leaders
+//	  choose the leader using the picker
+//	  for each follower:
+//	    set the rack to the unused rack with the fewest replicas in the follower's position
+//	    pick the follower using the picker
+type LeaderRackExtender struct {
+	brokers        []admin.BrokerInfo
+	preferredRacks []string
+	picker         pickers.Picker
+	racks          []string
+	brokerRacks    map[int]string
+	brokersPerRack map[string][]int
+}
+
+var _ Extender = (*LeaderRackExtender)(nil)
+
+// NewLeaderRackExtender returns a new LeaderRackExtender instance.
+func NewLeaderRackExtender(
+	brokers []admin.BrokerInfo,
+	preferredRacks []string,
+	picker pickers.Picker,
+) *LeaderRackExtender {
+	return &LeaderRackExtender{
+		brokers:        brokers,
+		preferredRacks: preferredRacks,
+		picker:         picker,
+		racks:          admin.DistinctRacks(brokers),
+		brokerRacks:    admin.BrokerRacks(brokers),
+		brokersPerRack: admin.BrokersPerRack(brokers),
+	}
+}
+
+// Extend returns partition assignments for the extension of the argument topic.
+func (l *LeaderRackExtender) Extend(
+	topic string,
+	curr []admin.PartitionAssignment,
+	extraPartitions int,
+) ([]admin.PartitionAssignment, error) {
+	if len(l.racks) < len(curr[0].Replicas) {
+		return nil, fmt.Errorf("Do not have enough racks for leader-rack placement")
+	}
+
+	leaderRacks := assigners.LeaderRacks(l.preferredRacks, l.brokers)
+	if len(leaderRacks) == 0 {
+		return nil, fmt.Errorf(
+			"None of the preferred leader racks %+v have any brokers",
+			l.preferredRacks,
+		)
+	}
+
+	desired := admin.CopyAssignments(curr)
+
+	leaderCounts := map[string]int{}
+	for _, assignment := range desired {
+		leaderCounts[l.brokerRacks[assignment.Replicas[0]]]++
+	}
+
+	for i := 0; i < extraPartitions; i++ {
+		partitionID := i + len(curr)
+		nextAssignment := admin.PartitionAssignment{
+			ID:       partitionID,
+			Replicas: []int{},
+		}
+
+		// Put in placeholders for replicas
+		for j := 0; j < len(curr[0].Replicas); j++ {
+			nextAssignment.Replicas = append(
+				nextAssignment.Replicas,
+				-1,
+			)
+		}
+
+		desired = append(desired, nextAssignment)
+
+		leaderRack := assigners.FewestLeadersRack(leaderRacks, leaderCounts)
+		leaderCounts[leaderRack]++
+
+		err := l.picker.PickNew(
+			topic,
+			l.brokersPerRack[leaderRack],
+			desired,
+			partitionID,
+			0,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for j := 1; j < len(curr[0].Replicas); j++ {
+			followerRack := assigners.FollowerRack(
+				desired,
+				partitionID,
+				j,
+				l.racks,
+				l.brokerRacks,
+			)
+
+			err := l.picker.PickNew(
+				topic,
+				l.brokersPerRack[followerRack],
+				desired,
+				partitionID,
+				j,
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return desired, nil
+}
@@ -0,0 +1,56 @@
+package extenders
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/assigners"
+	"github.com/segmentio/topicctl/pkg/apply/pickers"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+func TestLeaderRackExtender(t *testing.T) {
+	brokers := testBrokers(9, 3)
+	preferredRacks := []string{"zone1", "zone2"}
+	extender := NewLeaderRackExtender(
+		brokers,
+		preferredRacks,
+		pickers.NewLowestIndexPicker(),
+	)
+	checker := func(result []admin.PartitionAssignment) bool {
+		ok, _ := assigners.EvaluateAssignments(
+			result,
+			brokers,
+			config.TopicPlacementConfig{
+				Strategy:             config.PlacementStrategyLeaderRack,
+				PreferredLeaderRacks: preferredRacks,
+			},
+		)
+		return ok
+	}
+
+	testCases := []extenderTestCase{
+		{
+			description: "Add partitions",
+			topic:       "test-topic",
+			curr: [][]int{
+				{1, 2, 3},
+				{2, 3, 1},
+			},
+			extraPartitions: 4,
+			expected: [][]int{
+				{1, 2, 3},
+				{2, 3, 1},
+				{4, 5, 6},
+				{5, 1, 9},
+				{7, 6, 2},
+				{8, 4, 3},
+			},
+			checker: checker,
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(t, extender)
+	}
+}
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/util"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -225,3 +229,358 @@ func timeSuffix(msStr string) string {
 
 	return fmt.Sprintf(" (%d min)", msInt/60000)
 }
+
+// FormatTopicPlan generates a table that summarizes the steps in a topic plan.
+func FormatTopicPlan(topicPlan TopicPlan) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"Step",
+			"Type",
+			"Details",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for s, step := range topicPlan.Steps {
+		details := []string{}
+
+		if step.Broker != nil {
+			details = append(details, fmt.Sprintf("broker %d", *step.Broker))
+		}
+		for _, entry := range step.ConfigEntries {
+			if entry.Value == "" {
+				details = append(details, fmt.Sprintf("remove %s", entry.Name))
+			} else {
+				details = append(details, fmt.Sprintf("%s=%s", entry.Name, entry.Value))
+			}
+		}
+		for _, assignment := range step.Assignments {
+			details = append(
+				details,
+				fmt.Sprintf("partition %d: %+v", assignment.ID, assignment.Replicas),
+			)
+		}
+		if len(step.Partitions) > 0 {
+			details = append(details, fmt.Sprintf("partitions %+v", step.Partitions))
+		}
+
+		table.Append(
+			[]string{
+				fmt.Sprintf("%d", s+1),
+				string(step.Type),
+				strings.Join(details, "\n"),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// FormatTopicRevisions generates a table that summarizes the recorded revisions of a topic.
+func FormatTopicRevisions(revisions []TopicRevision) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"Revision",
+			"Created At",
+			"Partitions",
+			"Replication",
+			"Config Keys",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for _, revision := range revisions {
+		var replication int
+		if len(revision.Assignments) > 0 {
+			replication = len(revision.Assignments[0].Replicas)
+		}
+
+		table.Append(
+			[]string{
+				fmt.Sprintf("%d", revision.Revision),
+				revision.CreatedAt.Format(time.RFC3339),
+				fmt.Sprintf("%d", len(revision.Assignments)),
+				fmt.Sprintf("%d", replication),
+				fmt.Sprintf("%d", len(revision.Config)),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// formatMigrationBatches generates a table that shows the partitions in each batch of a
+// migration, along with the estimated bytes that are copied and how long that takes.
+func formatMigrationBatches(
+	batches []migrationBatch,
+	targetAssignments []admin.PartitionAssignment,
+) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"Batch",
+			"Partitions",
+			"Est. Bytes",
+			"Est. Duration",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for b, batch := range batches {
+		partitionIDs := []int{}
+		for _, assignment := range targetAssignments[batch.start:batch.end] {
+			partitionIDs = append(partitionIDs, assignment.ID)
+		}
+
+		partitionsStr, _ := util.TruncateStringSuffix(fmt.Sprintf("%+v", partitionIDs), 60)
+
+		table.Append(
+			[]string{
+				fmt.Sprintf("%d", b+1),
+				partitionsStr,
+				util.PrettyBytes(batch.bytes),
+				util.PrettyDuration(batch.duration),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// FormatDrainedPartitions generates a table that shows the original and drained replica
+// orderings of the partitions reordered when draining a broker.
+func FormatDrainedPartitions(partitions []DrainedPartition) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"Topic",
+			"Partition",
+			"Original Replicas",
+			"Drained Replicas",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for _, partition := range partitions {
+		table.Append(
+			[]string{
+				partition.Topic,
+				fmt.Sprintf("%d", partition.Partition),
+				fmt.Sprintf("%+v", partition.OriginalReplicas),
+				fmt.Sprintf("%+v", partition.DrainedReplicas),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// FormatBrokerDecommissionStatuses generates a table that shows whether each broker being
+// decommissioned is safe to shut down.
+func FormatBrokerDecommissionStatuses(statuses []BrokerDecommissionStatus) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"Broker",
+			"Rack",
+			"Replicas",
+			"Leaders",
+			"Remaining Partitions",
+			"Status",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for _, status := range statuses {
+		var statusStr string
+		if status.Safe() {
+			statusStr = color.GreenString("Safe to shut down")
+		} else {
+			statusStr = color.RedString("NOT safe to shut down")
+		}
+
+		partitionsStr, _ := util.TruncateStringSuffix(
+			strings.Join(status.Partitions, ", "),
+			60,
+		)
+
+		table.Append(
+			[]string{
+				fmt.Sprintf("%d", status.Broker),
+				status.Rack,
+				fmt.Sprintf("%d", status.Replicas),
+				fmt.Sprintf("%d", status.Leaders),
+				partitionsStr,
+				statusStr,
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// FormatGlobalBrokerBalances generates a table that shows the replica and leader counts of
+// each broker before and after a global rebalance.
+func FormatGlobalBrokerBalances(balances []GlobalBrokerBalance) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+	table.SetHeader(
+		[]string{
+			"Broker",
+			"Rack",
+			"Weight",
+			"Curr Replicas",
+			"Target Replicas",
+			"Curr Leaders",
+			"Target Leaders",
+		},
+	)
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for _, balance := range balances {
+		table.Append(
+			[]string{
+				fmt.Sprintf("%d", balance.Broker),
+				balance.Rack,
+				strconv.FormatFloat(balance.Weight, 'f', -1, 64),
+				fmt.Sprintf("%d", balance.CurrReplicas),
+				formatCountDiff(balance.CurrReplicas, balance.TargetReplicas),
+				fmt.Sprintf("%d", balance.CurrLeaders),
+				formatCountDiff(balance.CurrLeaders, balance.TargetLeaders),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+func formatCountDiff(curr int, target int) string {
+	switch {
+	case target > curr:
+		return fmt.Sprintf("%d %s", target, color.GreenString("(+%d)", target-curr))
+	case target < curr:
+		return fmt.Sprintf("%d %s", target, color.RedString("(%d)", target-curr))
+	default:
+		return fmt.Sprintf("%d", target)
+	}
+}
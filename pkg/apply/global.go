@@ -0,0 +1,559 @@
+package apply
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/assigners"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// globalEpsilon is the minimum improvement in the imbalance score for a move to be made.
+const globalEpsilon = 1e-9
+
+// GlobalTopic is a topic that's considered when optimizing the balance of the whole cluster.
+type GlobalTopic struct {
+	// Info is the current state of the topic in the cluster.
+	Info admin.TopicInfo
+
+	// Managed is whether the topic has a config that the optimizer can move it according to.
+	// Topics that aren't managed are never moved, but still count towards the broker totals.
+	Managed bool
+
+	// PlacementConfig is the placement config of the topic. It's ignored if Managed is false.
+	PlacementConfig config.TopicPlacementConfig
+
+	// PartitionSizes are the sizes of the partitions in bytes. They're used to prefer moving
+	// smaller partitions; missing partitions are treated as empty.
+	PartitionSizes map[int]int64
+}
+
+// GlobalOptimizerConfig stores the cluster-level inputs to OptimizeGlobal.
+type GlobalOptimizerConfig struct {
+	// Brokers are all of the brokers in the cluster, with their tags set.
+	Brokers []admin.BrokerInfo
+
+	// BrokerWeights are the relative capacities of the brokers. Brokers that aren't included
+	// have a weight of 1.
+	BrokerWeights map[int]float64
+
+	// MaxMoves is the maximum number of replica moves to make; 0 means no limit.
+	MaxMoves int
+}
+
+// GlobalPlan is a cluster-wide plan for balancing replicas and leaders across brokers.
+type GlobalPlan struct {
+	// Topics are the topics that have changes, sorted by name.
+	Topics []GlobalTopicPlan
+
+	// Brokers are the replica and leader counts of each broker before and after the plan.
+	Brokers []GlobalBrokerBalance
+
+	// ReplicaMoves is the number of replicas that are moved to new brokers.
+	ReplicaMoves int
+
+	// LeaderChanges is the number of partitions that get new leaders.
+	LeaderChanges int
+
+	// BytesMoved is the total size of the replicas that are moved.
+	BytesMoved int64
+}
+
+// GlobalTopicPlan stores the current and target assignments for a topic in a GlobalPlan.
+type GlobalTopicPlan struct {
+	Topic             string
+	CurrAssignments   []admin.PartitionAssignment
+	TargetAssignments []admin.PartitionAssignment
+	ReplicaMoves      int
+	BytesMoved        int64
+}
+
+// GlobalBrokerBalance stores the counts for a single broker in a GlobalPlan. Leaders are
+// counted by the first replica in each partition (i.e., the preferred leader).
+type GlobalBrokerBalance struct {
+	Broker         int
+	Rack           string
+	Weight         float64
+	CurrReplicas   int
+	TargetReplicas int
+	CurrLeaders    int
+	TargetLeaders  int
+}
+
+type globalTopicState struct {
+	topic       GlobalTopic
+	assignments []admin.PartitionAssignment
+	allowed     map[int]struct{}
+
+	replicaCounts map[int]int
+	leaderCounts  map[int]int
+}
+
+type globalOptimizer struct {
+	brokerIDs   []int
+	brokerRacks map[int]string
+	weights     map[int]float64
+	topics      []*globalTopicState
+
+	replicaCounts map[int]int
+	leaderCounts  map[int]int
+}
+
+type globalMove struct {
+	topic     int
+	partition int
+	index     int
+	to        int
+	gain      float64
+	size      int64
+}
+
+// OptimizeGlobal returns a plan that balances the replicas and leaders of all of the argument
+// topics across the brokers in the cluster, in proportion to the broker weights. Each topic is
+// otherwise balanced on its own, so the cluster can be skewed in total even when every topic is
+// balanced; this evens out the totals by choosing which brokers get the extra replicas and
+// leaders in each topic.
+//
+// The optimizer uses a local search. At each step, it makes the replica move that most reduces
+// the sum over brokers of count^2 / weight for both replicas and leaders, breaking ties by
+// choosing the smallest partition so that as few bytes as possible are moved. A move is only
+// considered if:
+//
+//   - the topic is managed and its current assignments satisfy its placement config
+//   - the new broker is allowed by the topic's placement constraints
+//   - the new broker's rack keeps the topic's placement strategy satisfied
+//   - the topic is at least as balanced between the two brokers after the move
+//
+// Once no replica moves help, it swaps leaders with followers in the same way, which doesn't
+// move any data. Topics with static placements are never changed.
+func OptimizeGlobal(
+	topics []GlobalTopic,
+	optimizerConfig GlobalOptimizerConfig,
+) (GlobalPlan, error) {
+	if len(optimizerConfig.Brokers) == 0 {
+		return GlobalPlan{}, errors.New("Cannot optimize a cluster without any brokers")
+	}
+
+	optimizer := newGlobalOptimizer(topics, optimizerConfig)
+	currReplicaCounts := copyCounts(optimizer.replicaCounts)
+	currLeaderCounts := copyCounts(optimizer.leaderCounts)
+
+	for moves := 0; optimizerConfig.MaxMoves <= 0 || moves < optimizerConfig.MaxMoves; moves++ {
+		move, ok := optimizer.bestReplicaMove()
+		if !ok {
+			break
+		}
+		optimizer.applyReplicaMove(move)
+	}
+
+	for {
+		move, ok := optimizer.bestLeaderSwap()
+		if !ok {
+			break
+		}
+		optimizer.applyLeaderSwap(move)
+	}
+
+	plan := GlobalPlan{
+		Topics:  []GlobalTopicPlan{},
+		Brokers: []GlobalBrokerBalance{},
+	}
+
+	for _, state := range optimizer.topics {
+		currAssignments := state.topic.Info.ToAssignments()
+		diffs := admin.AssignmentsToUpdate(currAssignments, state.assignments)
+		if len(diffs) == 0 {
+			continue
+		}
+
+		topicPlan := GlobalTopicPlan{
+			Topic:             state.topic.Info.Name,
+			CurrAssignments:   currAssignments,
+			TargetAssignments: state.assignments,
+		}
+
+		for p, assignment := range state.assignments {
+			if assignment.Replicas[0] != currAssignments[p].Replicas[0] {
+				plan.LeaderChanges++
+			}
+			for _, replica := range assignment.Replicas {
+				if currAssignments[p].Index(replica) == -1 {
+					topicPlan.ReplicaMoves++
+					topicPlan.BytesMoved += state.topic.PartitionSizes[assignment.ID]
+				}
+			}
+		}
+
+		plan.ReplicaMoves += topicPlan.ReplicaMoves
+		plan.BytesMoved += topicPlan.BytesMoved
+		plan.Topics = append(plan.Topics, topicPlan)
+	}
+
+	brokerRacks := admin.BrokerRacks(optimizerConfig.Brokers)
+	for _, brokerID := range optimizer.brokerIDs {
+		plan.Brokers = append(
+			plan.Brokers,
+			GlobalBrokerBalance{
+				Broker:         brokerID,
+				Rack:           brokerRacks[brokerID],
+				Weight:         optimizer.weights[brokerID],
+				CurrReplicas:   currReplicaCounts[brokerID],
+				TargetReplicas: optimizer.replicaCounts[brokerID],
+				CurrLeaders:    currLeaderCounts[brokerID],
+				TargetLeaders:  optimizer.leaderCounts[brokerID],
+			},
+		)
+	}
+
+	return plan, nil
+}
+
+func newGlobalOptimizer(
+	topics []GlobalTopic,
+	optimizerConfig GlobalOptimizerConfig,
+) *globalOptimizer {
+	optimizer := &globalOptimizer{
+		brokerIDs:     admin.BrokerIDs(optimizerConfig.Brokers),
+		brokerRacks:   admin.BrokerRacks(optimizerConfig.Brokers),
+		weights:       map[int]float64{},
+		topics:        []*globalTopicState{},
+		replicaCounts: map[int]int{},
+		leaderCounts:  map[int]int{},
+	}
+	sort.Ints(optimizer.brokerIDs)
+
+	for _, brokerID := range optimizer.brokerIDs {
+		optimizer.weights[brokerID] = 1.0
+		if weight, ok := optimizerConfig.BrokerWeights[brokerID]; ok && weight > 0 {
+			optimizer.weights[brokerID] = weight
+		}
+		optimizer.replicaCounts[brokerID] = 0
+		optimizer.leaderCounts[brokerID] = 0
+	}
+
+	sortedTopics := make([]GlobalTopic, len(topics))
+	copy(sortedTopics, topics)
+	sort.Slice(sortedTopics, func(a, b int) bool {
+		return sortedTopics[a].Info.Name < sortedTopics[b].Info.Name
+	})
+
+	for _, topic := range sortedTopics {
+		state := &globalTopicState{
+			topic:         topic,
+			assignments:   topic.Info.ToAssignments(),
+			allowed:       map[int]struct{}{},
+			replicaCounts: map[int]int{},
+			leaderCounts:  map[int]int{},
+		}
+
+		for _, assignment := range state.assignments {
+			for r, replica := range assignment.Replicas {
+				state.replicaCounts[replica]++
+				optimizer.replicaCounts[replica]++
+				if r == 0 {
+					state.leaderCounts[replica]++
+					optimizer.leaderCounts[replica]++
+				}
+			}
+		}
+
+		if topic.Managed && globalMovable(topic, state.assignments, optimizerConfig.Brokers) {
+			for _, broker := range topic.PlacementConfig.AllowedBrokers(optimizerConfig.Brokers) {
+				state.allowed[broker.ID] = struct{}{}
+			}
+		}
+
+		optimizer.topics = append(optimizer.topics, state)
+	}
+
+	return optimizer
+}
+
+// globalMovable returns whether the argument topic can be changed by the optimizer.
+func globalMovable(
+	topic GlobalTopic,
+	assignments []admin.PartitionAssignment,
+	brokers []admin.BrokerInfo,
+) bool {
+	if len(assignments) == 0 ||
+		topic.PlacementConfig.Strategy == config.PlacementStrategyStatic {
+		return false
+	}
+
+	ok, err := assigners.EvaluateAssignments(assignments, brokers, topic.PlacementConfig)
+	if err != nil || !ok {
+		log.Warnf(
+			"Topic %s doesn't satisfy its placement config, so it won't be moved; apply or rebalance it on its own first",
+			topic.Info.Name,
+		)
+		return false
+	}
+
+	return true
+}
+
+// bestReplicaMove returns the replica move that most reduces the imbalance, or false if none
+// of them do.
+func (o *globalOptimizer) bestReplicaMove() (globalMove, bool) {
+	var best globalMove
+	found := false
+
+	for t, state := range o.topics {
+		if len(state.allowed) == 0 {
+			continue
+		}
+
+		for p, assignment := range state.assignments {
+			size := state.topic.PartitionSizes[assignment.ID]
+
+			for r, from := range assignment.Replicas {
+				for _, to := range o.brokerIDs {
+					if !o.replicaMoveAllowed(state, assignment, r, to) {
+						continue
+					}
+
+					gain := o.gain(o.replicaCounts, from, to)
+					if r == 0 {
+						gain += o.gain(o.leaderCounts, from, to)
+					}
+					if gain < globalEpsilon {
+						continue
+					}
+
+					if !found ||
+						gain > best.gain+globalEpsilon ||
+						(gain > best.gain-globalEpsilon && size < best.size) {
+						best = globalMove{
+							topic:     t,
+							partition: p,
+							index:     r,
+							to:        to,
+							gain:      gain,
+							size:      size,
+						}
+						found = true
+					}
+				}
+			}
+		}
+	}
+
+	return best, found
+}
+
+func (o *globalOptimizer) replicaMoveAllowed(
+	state *globalTopicState,
+	assignment admin.PartitionAssignment,
+	index int,
+	to int,
+) bool {
+	from := assignment.Replicas[index]
+
+	if _, ok := state.allowed[to]; !ok {
+		return false
+	}
+	if assignment.Index(to) != -1 {
+		return false
+	}
+	if !globalRackAllowed(
+		state.topic.PlacementConfig.Strategy,
+		assignment.Replicas,
+		index,
+		to,
+		o.brokerRacks,
+	) {
+		return false
+	}
+	if !o.balanceKept(state.replicaCounts, from, to) {
+		return false
+	}
+	if index == 0 && !o.balanceKept(state.leaderCounts, from, to) {
+		return false
+	}
+
+	return true
+}
+
+func (o *globalOptimizer) applyReplicaMove(move globalMove) {
+	state := o.topics[move.topic]
+	replicas := state.assignments[move.partition].Replicas
+	from := replicas[move.index]
+
+	replicas[move.index] = move.to
+	state.replicaCounts[from]--
+	state.replicaCounts[move.to]++
+	o.replicaCounts[from]--
+	o.replicaCounts[move.to]++
+
+	if move.index == 0 {
+		state.leaderCounts[from]--
+		state.leaderCounts[move.to]++
+		o.leaderCounts[from]--
+		o.leaderCounts[move.to]++
+	}
+}
+
+// bestLeaderSwap returns the swap of a leader with one of its followers that most reduces the
+// leader imbalance, or false if none of them do. The index in the returned move is the index of
+// the follower.
+func (o *globalOptimizer) bestLeaderSwap() (globalMove, bool) {
+	var best globalMove
+	found := false
+
+	for t, state := range o.topics {
+		if len(state.allowed) == 0 {
+			continue
+		}
+
+		for p, assignment := range state.assignments {
+			from := assignment.Replicas[0]
+
+			for r := 1; r < len(assignment.Replicas); r++ {
+				to := assignment.Replicas[r]
+				if !globalSwapAllowed(
+					state.topic.PlacementConfig.Strategy,
+					assignment.Replicas,
+					r,
+					o.brokerRacks,
+				) {
+					continue
+				}
+				if !o.balanceKept(state.leaderCounts, from, to) {
+					continue
+				}
+
+				gain := o.gain(o.leaderCounts, from, to)
+				if gain < globalEpsilon {
+					continue
+				}
+
+				if !found || gain > best.gain+globalEpsilon {
+					best = globalMove{
+						topic:     t,
+						partition: p,
+						index:     r,
+						to:        to,
+						gain:      gain,
+					}
+					found = true
+				}
+			}
+		}
+	}
+
+	return best, found
+}
+
+func (o *globalOptimizer) applyLeaderSwap(move globalMove) {
+	state := o.topics[move.topic]
+	replicas := state.assignments[move.partition].Replicas
+	from := replicas[0]
+
+	replicas[0], replicas[move.index] = replicas[move.index], replicas[0]
+	state.leaderCounts[from]--
+	state.leaderCounts[move.to]++
+	o.leaderCounts[from]--
+	o.leaderCounts[move.to]++
+}
+
+// gain returns how much moving one unit of the argument counts from one broker to another
+// reduces the sum of count^2 / weight across all brokers.
+func (o *globalOptimizer) gain(counts map[int]int, from int, to int) float64 {
+	return float64(2*counts[from]-1)/o.weight(from) - float64(2*counts[to]+1)/o.weight(to)
+}
+
+// balanceKept returns whether moving one unit of the argument topic counts from one broker to
+// another leaves the weighted counts on the two brokers at least as even as before.
+func (o *globalOptimizer) balanceKept(counts map[int]int, from int, to int) bool {
+	fromBefore := float64(counts[from]) / o.weight(from)
+	toBefore := float64(counts[to]) / o.weight(to)
+	fromAfter := float64(counts[from]-1) / o.weight(from)
+	toAfter := float64(counts[to]+1) / o.weight(to)
+
+	maxBefore := fromBefore
+	if toBefore > maxBefore {
+		maxBefore = toBefore
+	}
+	maxAfter := fromAfter
+	if toAfter > maxAfter {
+		maxAfter = toAfter
+	}
+
+	return maxAfter < maxBefore+globalEpsilon
+}
+
+func (o *globalOptimizer) weight(brokerID int) float64 {
+	if weight, ok := o.weights[brokerID]; ok {
+		return weight
+	}
+	return 1.0
+}
+
+// globalRackAllowed returns whether the replica at the argument index can be moved to the
+// argument broker without breaking the rack requirements of the argument placement strategy.
+func globalRackAllowed(
+	strategy config.PlacementStrategy,
+	replicas []int,
+	index int,
+	to int,
+	brokerRacks map[int]string,
+) bool {
+	fromRack := brokerRacks[replicas[index]]
+	toRack := brokerRacks[to]
+
+	switch strategy {
+	case config.PlacementStrategyAny:
+		return true
+	case config.PlacementStrategyBalancedLeaders:
+		// Only the leader racks matter
+		return index != 0 || fromRack == toRack
+	case config.PlacementStrategyInRack, config.PlacementStrategyStaticInRack:
+		return fromRack == toRack
+	case config.PlacementStrategyCrossRack, config.PlacementStrategyLeaderRack:
+		if fromRack == toRack {
+			return true
+		}
+		if strategy == config.PlacementStrategyLeaderRack && index == 0 {
+			return false
+		}
+		for r, replica := range replicas {
+			if r != index && brokerRacks[replica] == toRack {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// globalSwapAllowed returns whether the leader can be swapped with the follower at the argument
+// index without breaking the rack requirements of the argument placement strategy.
+func globalSwapAllowed(
+	strategy config.PlacementStrategy,
+	replicas []int,
+	index int,
+	brokerRacks map[int]string,
+) bool {
+	switch strategy {
+	case config.PlacementStrategyAny,
+		config.PlacementStrategyInRack,
+		config.PlacementStrategyStaticInRack,
+		config.PlacementStrategyCrossRack:
+		return true
+	case config.PlacementStrategyBalancedLeaders, config.PlacementStrategyLeaderRack:
+		return brokerRacks[replicas[0]] == brokerRacks[replicas[index]]
+	default:
+		return false
+	}
+}
+
+func copyCounts(counts map[int]int) map[int]int {
+	copied := map[int]int{}
+	for key, value := range counts {
+		copied[key] = value
+	}
+	return copied
+}
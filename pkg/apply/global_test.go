@@ -0,0 +1,306 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptimizeGlobal(t *testing.T) {
+	anyPlacement := config.TopicPlacementConfig{
+		Strategy: config.PlacementStrategyAny,
+	}
+	crossRackPlacement := config.TopicPlacementConfig{
+		Strategy: config.PlacementStrategyCrossRack,
+	}
+
+	type testCase struct {
+		description        string
+		brokers            []admin.BrokerInfo
+		brokerWeights      map[int]float64
+		topics             []GlobalTopic
+		expected           map[string][][]int
+		expectedBytesMoved int64
+	}
+
+	testCases := []testCase{
+		{
+			description: "single-partition topics are spread out",
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+				{ID: 3, Rack: "rack3"},
+			},
+			topics: []GlobalTopic{
+				{
+					Info:            testTopicInfo("topic-a", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+					PartitionSizes:  map[int]int64{0: 100},
+				},
+				{
+					Info:            testTopicInfo("topic-b", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+					PartitionSizes:  map[int]int64{0: 100},
+				},
+				{
+					Info:            testTopicInfo("topic-c", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+					PartitionSizes:  map[int]int64{0: 10},
+				},
+			},
+			// The smallest topic is moved first
+			expected: map[string][][]int{
+				"topic-a": {{3}},
+				"topic-c": {{2}},
+			},
+			expectedBytesMoved: 110,
+		},
+		{
+			description: "unmanaged and static topics count but aren't moved",
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+			},
+			topics: []GlobalTopic{
+				{
+					Info: testTopicInfo("topic-a", []int{1}, []int{1}),
+				},
+				{
+					Info:    testTopicInfo("topic-b", []int{1}),
+					Managed: true,
+					PlacementConfig: config.TopicPlacementConfig{
+						Strategy:          config.PlacementStrategyStatic,
+						StaticAssignments: [][]int{{1}},
+					},
+				},
+				{
+					Info:            testTopicInfo("topic-c", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+			},
+			expected: map[string][][]int{
+				"topic-c": {{2}},
+			},
+		},
+		{
+			description: "topics stay balanced",
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+			},
+			topics: []GlobalTopic{
+				{
+					Info: testTopicInfo("topic-a", []int{1}, []int{1}),
+				},
+				{
+					Info:            testTopicInfo("topic-b", []int{1}, []int{2}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+			},
+			expected: map[string][][]int{},
+		},
+		{
+			description: "cross-rack topics stay cross-rack",
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack1"},
+				{ID: 3, Rack: "rack2"},
+				{ID: 4, Rack: "rack2"},
+			},
+			topics: []GlobalTopic{
+				{
+					Info:            testTopicInfo("topic-a", []int{1, 3}),
+					Managed:         true,
+					PlacementConfig: crossRackPlacement,
+				},
+				{
+					Info:            testTopicInfo("topic-b", []int{1, 3}),
+					Managed:         true,
+					PlacementConfig: crossRackPlacement,
+				},
+			},
+			expected: map[string][][]int{
+				"topic-a": {{2, 4}},
+			},
+		},
+		{
+			description: "brokers are filled in proportion to their weights",
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+			},
+			brokerWeights: map[int]float64{2: 3.0},
+			topics: []GlobalTopic{
+				{
+					Info:            testTopicInfo("topic-a", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+				{
+					Info:            testTopicInfo("topic-b", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+				{
+					Info:            testTopicInfo("topic-c", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+				{
+					Info:            testTopicInfo("topic-d", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+			},
+			expected: map[string][][]int{
+				"topic-a": {{2}},
+				"topic-b": {{2}},
+				"topic-c": {{2}},
+			},
+		},
+		{
+			description: "placement constraints are respected",
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+				{ID: 3, Rack: "rack3"},
+			},
+			topics: []GlobalTopic{
+				{
+					Info:    testTopicInfo("topic-a", []int{1}),
+					Managed: true,
+					PlacementConfig: config.TopicPlacementConfig{
+						Strategy:       config.PlacementStrategyAny,
+						ExcludeBrokers: []int{2},
+					},
+				},
+				{
+					Info:            testTopicInfo("topic-b", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+				{
+					Info:            testTopicInfo("topic-c", []int{1}),
+					Managed:         true,
+					PlacementConfig: anyPlacement,
+				},
+			},
+			expected: map[string][][]int{
+				"topic-a": {{3}},
+				"topic-b": {{2}},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		plan, err := OptimizeGlobal(
+			testCase.topics,
+			GlobalOptimizerConfig{
+				Brokers:       testCase.brokers,
+				BrokerWeights: testCase.brokerWeights,
+			},
+		)
+		require.NoError(t, err, testCase.description)
+
+		result := map[string][][]int{}
+		for _, topicPlan := range plan.Topics {
+			replicas, err := admin.AssignmentsToReplicas(topicPlan.TargetAssignments)
+			require.NoError(t, err)
+			result[topicPlan.Topic] = replicas
+		}
+		assert.Equal(t, testCase.expected, result, testCase.description)
+		assert.Equal(
+			t,
+			testCase.expectedBytesMoved,
+			plan.BytesMoved,
+			testCase.description,
+		)
+	}
+}
+
+func TestOptimizeGlobalMaxMoves(t *testing.T) {
+	topics := []GlobalTopic{}
+	for _, name := range []string{"topic-a", "topic-b", "topic-c", "topic-d"} {
+		topics = append(
+			topics,
+			GlobalTopic{
+				Info:    testTopicInfo(name, []int{1}),
+				Managed: true,
+				PlacementConfig: config.TopicPlacementConfig{
+					Strategy: config.PlacementStrategyAny,
+				},
+			},
+		)
+	}
+
+	plan, err := OptimizeGlobal(
+		topics,
+		GlobalOptimizerConfig{
+			Brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+				{ID: 3, Rack: "rack3"},
+				{ID: 4, Rack: "rack4"},
+			},
+			MaxMoves: 2,
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 2, plan.ReplicaMoves)
+	assert.Equal(t, 2, plan.LeaderChanges)
+	assert.Equal(
+		t,
+		[]GlobalBrokerBalance{
+			{Broker: 1, Rack: "rack1", Weight: 1, CurrReplicas: 4, TargetReplicas: 2, CurrLeaders: 4, TargetLeaders: 2},
+			{Broker: 2, Rack: "rack2", Weight: 1, CurrReplicas: 0, TargetReplicas: 1, CurrLeaders: 0, TargetLeaders: 1},
+			{Broker: 3, Rack: "rack3", Weight: 1, CurrReplicas: 0, TargetReplicas: 1, CurrLeaders: 0, TargetLeaders: 1},
+			{Broker: 4, Rack: "rack4", Weight: 1, CurrReplicas: 0, TargetReplicas: 0, CurrLeaders: 0, TargetLeaders: 0},
+		},
+		plan.Brokers,
+	)
+}
+
+func TestOptimizeGlobalLeaderSwaps(t *testing.T) {
+	plan, err := OptimizeGlobal(
+		[]GlobalTopic{
+			{
+				Info:    testTopicInfo("topic-a", []int{1, 2}),
+				Managed: true,
+				PlacementConfig: config.TopicPlacementConfig{
+					Strategy: config.PlacementStrategyAny,
+				},
+			},
+			{
+				Info:    testTopicInfo("topic-b", []int{1, 2}),
+				Managed: true,
+				PlacementConfig: config.TopicPlacementConfig{
+					Strategy: config.PlacementStrategyAny,
+				},
+			},
+		},
+		GlobalOptimizerConfig{
+			Brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "rack1"},
+				{ID: 2, Rack: "rack2"},
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(plan.Topics))
+	assert.Equal(t, "topic-a", plan.Topics[0].Topic)
+	assert.Equal(
+		t,
+		[]admin.PartitionAssignment{{ID: 0, Replicas: []int{2, 1}}},
+		plan.Topics[0].TargetAssignments,
+	)
+	assert.Equal(t, 0, plan.ReplicaMoves)
+	assert.Equal(t, 1, plan.LeaderChanges)
+}
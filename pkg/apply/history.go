@@ -0,0 +1,212 @@
+package apply
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+)
+
+// TopicRevisionVersion is the version of the topic revision file format.
+const TopicRevisionVersion = 1
+
+// TopicRevision records the state of a topic before it was changed by an apply run. Revisions
+// are stored in a local history directory and are used by rollback to restore the previous
+// settings and replica assignments of the topic.
+type TopicRevision struct {
+	Version     int       `json:"version"`
+	Revision    int       `json:"revision"`
+	Cluster     string    `json:"cluster"`
+	Environment string    `json:"environment"`
+	Region      string    `json:"region"`
+	Topic       string    `json:"topic"`
+	CreatedAt   time.Time `json:"createdAt"`
+
+	// Config is the topic config map before the change, not including replication throttles.
+	Config map[string]string `json:"config"`
+
+	// Assignments are the replica assignments before the change.
+	Assignments []admin.PartitionAssignment `json:"assignments"`
+
+	path string
+}
+
+// TopicHistoryDir returns the directory in which the revisions of a topic are stored.
+func TopicHistoryDir(
+	historyDir string,
+	cluster string,
+	environment string,
+	region string,
+	topic string,
+) string {
+	return filepath.Join(
+		historyDir,
+		fmt.Sprintf("%s-%s-%s", cluster, environment, region),
+		topic,
+	)
+}
+
+// NewTopicRevision creates a new revision from the current state of a topic. The revision
+// is numbered after the latest one in the history directory, but isn't saved until Save is
+// called.
+func NewTopicRevision(
+	historyDir string,
+	cluster string,
+	environment string,
+	region string,
+	topicInfo admin.TopicInfo,
+) (*TopicRevision, error) {
+	topicDir := TopicHistoryDir(historyDir, cluster, environment, region, topicInfo.Name)
+
+	revisions, err := ListTopicRevisions(topicDir)
+	if err != nil {
+		return nil, err
+	}
+	revisionNum := 1
+	if len(revisions) > 0 {
+		revisionNum = revisions[len(revisions)-1].Revision + 1
+	}
+
+	return &TopicRevision{
+		Version:     TopicRevisionVersion,
+		Revision:    revisionNum,
+		Cluster:     cluster,
+		Environment: environment,
+		Region:      region,
+		Topic:       topicInfo.Name,
+		CreatedAt:   time.Now().UTC(),
+		Config:      revisionConfig(topicInfo),
+		Assignments: topicInfo.ToAssignments(),
+		path:        filepath.Join(topicDir, fmt.Sprintf("%d.json", revisionNum)),
+	}, nil
+}
+
+// Matches returns whether the argument topic has the same config, not including replication
+// throttles, and replica assignments as the revision.
+func (r *TopicRevision) Matches(topicInfo admin.TopicInfo) bool {
+	configMap := revisionConfig(topicInfo)
+	if len(configMap) != len(r.Config) {
+		return false
+	}
+	for key, value := range configMap {
+		if currValue, ok := r.Config[key]; !ok || currValue != value {
+			return false
+		}
+	}
+
+	return reflect.DeepEqual(r.Assignments, topicInfo.ToAssignments())
+}
+
+// revisionConfig returns the config of the argument topic without replication throttles.
+func revisionConfig(topicInfo admin.TopicInfo) map[string]string {
+	configMap := map[string]string{}
+	for key, value := range topicInfo.Config {
+		if key == admin.LeaderReplicasThrottledKey || key == admin.FollowerReplicasThrottledKey {
+			continue
+		}
+		configMap[key] = value
+	}
+	return configMap
+}
+
+// ListTopicRevisions returns all of the revisions in the argument topic history directory,
+// sorted by revision number. It returns an empty slice if the directory doesn't exist.
+func ListTopicRevisions(topicDir string) ([]TopicRevision, error) {
+	entries, err := os.ReadDir(topicDir)
+	if os.IsNotExist(err) {
+		return []TopicRevision{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	revisions := []TopicRevision{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if _, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json")); err != nil {
+			continue
+		}
+
+		revision, err := LoadTopicRevision(filepath.Join(topicDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	sort.Slice(revisions, func(a, b int) bool {
+		return revisions[a].Revision < revisions[b].Revision
+	})
+
+	return revisions, nil
+}
+
+// GetTopicRevision returns the revision with the argument number in the topic history
+// directory. If revisionNum is 0, then the latest revision is returned.
+func GetTopicRevision(topicDir string, revisionNum int) (TopicRevision, error) {
+	revisions, err := ListTopicRevisions(topicDir)
+	if err != nil {
+		return TopicRevision{}, err
+	}
+	if len(revisions) == 0 {
+		return TopicRevision{}, fmt.Errorf("No revisions found in %s", topicDir)
+	}
+
+	if revisionNum == 0 {
+		return revisions[len(revisions)-1], nil
+	}
+	for _, revision := range revisions {
+		if revision.Revision == revisionNum {
+			return revision, nil
+		}
+	}
+
+	return TopicRevision{}, fmt.Errorf("Revision %d not found in %s", revisionNum, topicDir)
+}
+
+// LoadTopicRevision loads the topic revision at the argument path.
+func LoadTopicRevision(path string) (TopicRevision, error) {
+	revision := TopicRevision{}
+
+	found, err := readJSONFile(path, &revision)
+	if err != nil {
+		return revision, fmt.Errorf("Error reading topic revision file %s: %+v", path, err)
+	} else if !found {
+		return revision, fmt.Errorf("Topic revision file %s does not exist", path)
+	}
+	if revision.Version != TopicRevisionVersion {
+		return revision, fmt.Errorf(
+			"Unsupported topic revision version %d in %s (expected %d)",
+			revision.Version,
+			path,
+			TopicRevisionVersion,
+		)
+	}
+	revision.path = path
+
+	return revision, nil
+}
+
+// Save writes the revision to disk. It's a no-op if the revision is nil.
+func (r *TopicRevision) Save() error {
+	if r == nil {
+		return nil
+	}
+	return writeJSONFileAtomic(r.path, r)
+}
+
+// Delete removes the revision from disk. It's a no-op if the revision is nil.
+func (r *TopicRevision) Delete() error {
+	if r == nil {
+		return nil
+	}
+	return removeFile(r.path)
+}
@@ -0,0 +1,164 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopicRevisionSaveLoad(t *testing.T) {
+	historyDir := t.TempDir()
+	topicDir := TopicHistoryDir(
+		historyDir,
+		"test-cluster",
+		"test-environment",
+		"test-region",
+		"test-topic",
+	)
+
+	revisions, err := ListTopicRevisions(topicDir)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(revisions))
+
+	_, err = GetTopicRevision(topicDir, 0)
+	assert.Error(t, err)
+
+	topicInfo := admin.TopicInfo{
+		Name: "test-topic",
+		Config: map[string]string{
+			"retention.ms":                     "3600000",
+			"cleanup.policy":                   "delete",
+			admin.LeaderReplicasThrottledKey:   "0:1,1:2",
+			admin.FollowerReplicasThrottledKey: "0:3",
+		},
+		Partitions: []admin.PartitionInfo{
+			{
+				ID:       0,
+				Leader:   1,
+				Replicas: []int{1, 2},
+			},
+			{
+				ID:       1,
+				Leader:   2,
+				Replicas: []int{2, 3},
+			},
+		},
+	}
+
+	for i := 1; i <= 3; i++ {
+		revision, err := NewTopicRevision(
+			historyDir,
+			"test-cluster",
+			"test-environment",
+			"test-region",
+			topicInfo,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, i, revision.Revision)
+		require.NoError(t, revision.Save())
+
+		topicInfo.Config["retention.ms"] = "7200000"
+	}
+
+	// Other files in the directory are ignored
+	require.NoError(
+		t,
+		os.WriteFile(filepath.Join(topicDir, "notes.txt"), []byte("notes"), 0644),
+	)
+
+	revisions, err = ListTopicRevisions(topicDir)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(revisions))
+	assert.Equal(t, 1, revisions[0].Revision)
+	assert.Equal(t, 3, revisions[2].Revision)
+
+	revision, err := GetTopicRevision(topicDir, 1)
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		map[string]string{
+			"retention.ms":   "3600000",
+			"cleanup.policy": "delete",
+		},
+		revision.Config,
+	)
+	assert.Equal(
+		t,
+		[]admin.PartitionAssignment{
+			{
+				ID:       0,
+				Replicas: []int{1, 2},
+			},
+			{
+				ID:       1,
+				Replicas: []int{2, 3},
+			},
+		},
+		revision.Assignments,
+	)
+
+	latest, err := GetTopicRevision(topicDir, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, latest.Revision)
+	assert.Equal(t, "7200000", latest.Config["retention.ms"])
+
+	_, err = GetTopicRevision(topicDir, 4)
+	assert.Error(t, err)
+
+	require.NoError(t, latest.Delete())
+	revisions, err = ListTopicRevisions(topicDir)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(revisions))
+
+	// Deleting a missing or nil revision is a no-op
+	require.NoError(t, latest.Delete())
+	var nilRevision *TopicRevision
+	require.NoError(t, nilRevision.Delete())
+	require.NoError(t, nilRevision.Save())
+}
+
+func TestTopicRevisionMatches(t *testing.T) {
+	topicInfo := admin.TopicInfo{
+		Name: "test-topic",
+		Config: map[string]string{
+			"retention.ms": "3600000",
+		},
+		Partitions: []admin.PartitionInfo{
+			{
+				ID:       0,
+				Leader:   1,
+				Replicas: []int{1, 2},
+			},
+		},
+	}
+
+	revision, err := NewTopicRevision(
+		t.TempDir(),
+		"test-cluster",
+		"test-environment",
+		"test-region",
+		topicInfo,
+	)
+	require.NoError(t, err)
+	assert.True(t, revision.Matches(topicInfo))
+
+	// Throttles and leaders are ignored
+	topicInfo.Config[admin.LeaderReplicasThrottledKey] = "0:1"
+	topicInfo.Partitions[0].Leader = 2
+	assert.True(t, revision.Matches(topicInfo))
+
+	topicInfo.Config["cleanup.policy"] = "compact"
+	assert.False(t, revision.Matches(topicInfo))
+	delete(topicInfo.Config, "cleanup.policy")
+
+	topicInfo.Config["retention.ms"] = "7200000"
+	assert.False(t, revision.Matches(topicInfo))
+	topicInfo.Config["retention.ms"] = "3600000"
+
+	topicInfo.Partitions[0].Replicas = []int{2, 1}
+	assert.False(t, revision.Matches(topicInfo))
+}
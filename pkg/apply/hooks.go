@@ -0,0 +1,175 @@
+package apply
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// HookPayload is the JSON payload that's sent to apply hooks.
+type HookPayload struct {
+	Event       config.HookEvent `json:"event"`
+	Time        time.Time        `json:"time"`
+	Cluster     string           `json:"cluster"`
+	Environment string           `json:"environment"`
+	Region      string           `json:"region"`
+	Topic       string           `json:"topic"`
+
+	// Batch is set for the preBatch and postBatch events.
+	Batch *HookBatch `json:"batch,omitempty"`
+
+	// Changes are the changes made or about to be made to the topic so far. These are
+	// either a NewChangesTracker or an UpdateChangesTracker.
+	Changes NewOrUpdatedChanges `json:"changes,omitempty"`
+
+	// Error is set in postApply payloads if the apply failed.
+	Error string `json:"error,omitempty"`
+}
+
+// HookBatch describes a batch of partition reassignments in a hook payload.
+type HookBatch struct {
+	Round             int                         `json:"round"`
+	NumRounds         int                         `json:"numRounds"`
+	CurrAssignments   []admin.PartitionAssignment `json:"currAssignments"`
+	TargetAssignments []admin.PartitionAssignment `json:"targetAssignments"`
+}
+
+// RunHooks runs the hooks in the cluster config that are configured for the payload's event,
+// in order. If a hook for a pre-change event fails, then the remaining hooks are skipped and
+// the error is returned so that the change can be stopped. Failures for other events are
+// logged and otherwise ignored.
+func RunHooks(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	payload HookPayload,
+) error {
+	contents, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for _, hook := range clusterConfig.Spec.Hooks {
+		if !hook.RunsFor(payload.Event) {
+			continue
+		}
+
+		log.Infof("Running %s hook %s", payload.Event, hook.Name)
+		if err := runHook(ctx, clusterConfig, hook, payload.Event, contents); err != nil {
+			if payload.Event.IsPre() {
+				return fmt.Errorf("Hook %s failed: %+v", hook.Name, err)
+			}
+			log.Warnf("Hook %s failed: %+v", hook.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func runHook(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	hook config.HookConfig,
+	event config.HookEvent,
+	contents []byte,
+) error {
+	timeout, err := hook.GetTimeout()
+	if err != nil {
+		return err
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if hook.URL != "" {
+		return runWebhook(hookCtx, hook, contents)
+	}
+
+	command := clusterConfig.HookCommand(hook)
+	cmd := exec.CommandContext(hookCtx, command[0], command[1:]...)
+	cmd.Stdin = bytes.NewReader(contents)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("TOPICCTL_HOOK_EVENT=%s", event))
+
+	output, err := cmd.CombinedOutput()
+	if len(output) > 0 {
+		log.Debugf("Output from hook %s:\n%s", hook.Name, output)
+	}
+	if err != nil {
+		return fmt.Errorf("%+v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+func runWebhook(ctx context.Context, hook config.HookConfig, contents []byte) error {
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		hook.URL,
+		bytes.NewReader(contents),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf(
+			"Webhook returned status %d: %s",
+			resp.StatusCode,
+			strings.TrimSpace(string(body)),
+		)
+	}
+
+	return nil
+}
+
+// runHooks runs the cluster's hooks for an event in this apply. Hooks aren't run in
+// dry-run mode.
+func (t *TopicApplier) runHooks(
+	ctx context.Context,
+	event config.HookEvent,
+	changes NewOrUpdatedChanges,
+	batch *HookBatch,
+	applyErr error,
+) error {
+	if t.config.DryRun || len(t.clusterConfig.Spec.Hooks) == 0 {
+		return nil
+	}
+
+	payload := HookPayload{
+		Event:       event,
+		Time:        time.Now().UTC(),
+		Cluster:     t.clusterConfig.Meta.Name,
+		Environment: t.clusterConfig.Meta.Environment,
+		Region:      t.clusterConfig.Meta.Region,
+		Topic:       t.topicName,
+		Batch:       batch,
+		Changes:     nonNilChanges(changes),
+	}
+
+	if applyErr != nil {
+		payload.Error = applyErr.Error()
+	}
+
+	return RunHooks(ctx, t.clusterConfig, payload)
+}
@@ -0,0 +1,178 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunHooksWebhook(t *testing.T) {
+	ctx := context.Background()
+
+	var lock sync.Mutex
+	payloads := []HookPayload{}
+	headers := []string{}
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			payload := HookPayload{}
+			require.NoError(t, json.Unmarshal(body, &payload))
+			payloads = append(payloads, payload)
+			headers = append(headers, r.Header.Get("X-Test-Header"))
+
+			if r.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write([]byte("paused consumers not found"))
+			}
+		}),
+	)
+	defer server.Close()
+
+	clusterConfig := config.ClusterConfig{
+		Spec: config.ClusterSpec{
+			Hooks: []config.HookConfig{
+				{
+					Name: "all-events",
+					URL:  server.URL + "/ok",
+					Headers: map[string]string{
+						"X-Test-Header": "test-value",
+					},
+				},
+				{
+					Name:   "fail-batches",
+					URL:    server.URL + "/fail",
+					Events: []config.HookEvent{config.HookEventPreBatch, config.HookEventPostBatch},
+				},
+			},
+		},
+	}
+
+	batch := &HookBatch{
+		Round:     1,
+		NumRounds: 2,
+		CurrAssignments: []admin.PartitionAssignment{
+			{
+				ID:       0,
+				Replicas: []int{1, 2},
+			},
+		},
+		TargetAssignments: []admin.PartitionAssignment{
+			{
+				ID:       0,
+				Replicas: []int{3, 2},
+			},
+		},
+	}
+
+	// Failures of pre-change hooks are returned
+	err := RunHooks(
+		ctx,
+		clusterConfig,
+		HookPayload{
+			Event: config.HookEventPreBatch,
+			Topic: "test-topic",
+			Batch: batch,
+		},
+	)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "paused consumers not found")
+
+	// Failures of other hooks are ignored
+	err = RunHooks(
+		ctx,
+		clusterConfig,
+		HookPayload{
+			Event: config.HookEventPostBatch,
+			Topic: "test-topic",
+			Batch: batch,
+		},
+	)
+	require.NoError(t, err)
+
+	// Hooks that aren't configured for the event aren't run
+	err = RunHooks(
+		ctx,
+		clusterConfig,
+		HookPayload{
+			Event: config.HookEventPreSettings,
+			Topic: "test-topic",
+		},
+	)
+	require.NoError(t, err)
+
+	require.Equal(t, 5, len(payloads))
+	assert.Equal(t, config.HookEventPreBatch, payloads[0].Event)
+	assert.Equal(t, "test-topic", payloads[0].Topic)
+	assert.Equal(t, batch, payloads[0].Batch)
+	assert.Equal(t, config.HookEventPostBatch, payloads[2].Event)
+	assert.Equal(t, config.HookEventPreSettings, payloads[4].Event)
+	assert.Equal(t, []string{"test-value", "", "test-value", "", "test-value"}, headers)
+}
+
+func TestRunHooksCommand(t *testing.T) {
+	ctx := context.Background()
+	tempDir := t.TempDir()
+	outputPath := filepath.Join(tempDir, "payload.json")
+
+	clusterConfig := config.ClusterConfig{
+		Spec: config.ClusterSpec{
+			Hooks: []config.HookConfig{
+				{
+					Name: "write-payload",
+					Command: []string{
+						"sh",
+						"-c",
+						`cat > "$0" && test "$TOPICCTL_HOOK_EVENT" = preSettings`,
+						outputPath,
+					},
+				},
+			},
+		},
+	}
+
+	err := RunHooks(
+		ctx,
+		clusterConfig,
+		HookPayload{
+			Event:   config.HookEventPreSettings,
+			Cluster: "test-cluster",
+			Topic:   "test-topic",
+		},
+	)
+	require.NoError(t, err)
+
+	contents, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	payload := HookPayload{}
+	require.NoError(t, json.Unmarshal(contents, &payload))
+	assert.Equal(t, "test-cluster", payload.Cluster)
+	assert.Equal(t, "test-topic", payload.Topic)
+
+	// The command exits non-zero for other events
+	err = RunHooks(
+		ctx,
+		clusterConfig,
+		HookPayload{
+			Event: config.HookEventPreBatch,
+			Topic: "test-topic",
+		},
+	)
+	require.Error(t, err)
+}
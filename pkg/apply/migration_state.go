@@ -0,0 +1,178 @@
+package apply
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/util"
+)
+
+// MigrationStateVersion is the version of the migration state file format.
+const MigrationStateVersion = 1
+
+// MigrationState records the progress of a placement migration for a single topic so that
+// the migration can be resumed if the apply process is interrupted. It's stored in a local
+// file (and not in the zk lock node) because lock nodes are ephemeral and are removed when
+// the process that created them exits.
+type MigrationState struct {
+	Version     int       `json:"version"`
+	Cluster     string    `json:"cluster"`
+	Environment string    `json:"environment"`
+	Region      string    `json:"region"`
+	Topic       string    `json:"topic"`
+	StartedAt   time.Time `json:"startedAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// CurrAssignments are the assignments, before the migration, of the partitions that are
+	// being moved.
+	CurrAssignments []admin.PartitionAssignment `json:"currAssignments"`
+
+	// TargetAssignments are the desired assignments of the partitions that are being moved,
+	// in the order in which they're applied.
+	TargetAssignments []admin.PartitionAssignment `json:"targetAssignments"`
+
+	BatchSize        int `json:"batchSize"`
+	CompletedBatches int `json:"completedBatches"`
+
+	// BatchEnds are the end indices, in TargetAssignments, of each batch. They're set when
+	// batches can have different sizes, e.g. when they're packed by bytes; otherwise, each
+	// batch has BatchSize partitions.
+	BatchEnds []int `json:"batchEnds,omitempty"`
+
+	// ThrottledTopic and ThrottledBrokers record the throttles applied for the current
+	// batch; these are cleared after the batch completes.
+	ThrottledTopic   bool  `json:"throttledTopic"`
+	ThrottledBrokers []int `json:"throttledBrokers"`
+
+	path string
+}
+
+// MigrationStatePath returns the path of the migration state file for a topic.
+func MigrationStatePath(
+	stateDir string,
+	cluster string,
+	environment string,
+	region string,
+	topic string,
+) string {
+	return filepath.Join(
+		stateDir,
+		fmt.Sprintf("%s-%s-%s-%s.json", cluster, environment, region, topic),
+	)
+}
+
+// LoadMigrationState loads the migration state at the argument path. It returns nil if
+// there is no state file.
+func LoadMigrationState(path string) (*MigrationState, error) {
+	state := &MigrationState{}
+	found, err := readJSONFile(path, state)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading migration state file %s: %+v", path, err)
+	} else if !found {
+		return nil, nil
+	}
+	if state.Version != MigrationStateVersion {
+		return nil, fmt.Errorf(
+			"Unsupported migration state version %d in %s (expected %d)",
+			state.Version,
+			path,
+			MigrationStateVersion,
+		)
+	}
+	state.path = path
+
+	return state, nil
+}
+
+// NumBatches returns the total number of batches in the migration.
+func (m *MigrationState) NumBatches() int {
+	if len(m.BatchEnds) > 0 {
+		return len(m.BatchEnds)
+	}
+	if m.BatchSize <= 0 {
+		return 0
+	}
+	return (len(m.TargetAssignments) + m.BatchSize - 1) / m.BatchSize
+}
+
+// RemainingAssignments returns the current and target assignments for the partitions in
+// batches that haven't been completed yet.
+func (m *MigrationState) RemainingAssignments() (
+	[]admin.PartitionAssignment,
+	[]admin.PartitionAssignment,
+) {
+	start, _ := m.batchRange(m.CompletedBatches)
+
+	return admin.CopyAssignments(m.CurrAssignments[start:]),
+		admin.CopyAssignments(m.TargetAssignments[start:])
+}
+
+// batchRange returns the start and end indices, in TargetAssignments, of the argument
+// (zero-indexed) batch.
+func (m *MigrationState) batchRange(batch int) (int, int) {
+	var start, end int
+
+	if len(m.BatchEnds) > 0 {
+		if batch > 0 && batch <= len(m.BatchEnds) {
+			start = m.BatchEnds[batch-1]
+		} else if batch > len(m.BatchEnds) {
+			start = len(m.TargetAssignments)
+		}
+		end = len(m.TargetAssignments)
+		if batch < len(m.BatchEnds) {
+			end = m.BatchEnds[batch]
+		}
+	} else {
+		batchSize := m.BatchSize
+		if batchSize <= 0 {
+			batchSize = len(m.TargetAssignments)
+		}
+		start = batch * batchSize
+		end = start + batchSize
+	}
+
+	if start > len(m.TargetAssignments) {
+		start = len(m.TargetAssignments)
+	}
+	if end > len(m.TargetAssignments) {
+		end = len(m.TargetAssignments)
+	}
+	return start, end
+}
+
+// Save writes the state to disk. It's a no-op if the state is nil.
+func (m *MigrationState) Save() error {
+	if m == nil {
+		return nil
+	}
+	m.UpdatedAt = time.Now().UTC()
+
+	return writeJSONFileAtomic(m.path, m)
+}
+
+// Delete removes the state file from disk. It's a no-op if the state is nil.
+func (m *MigrationState) Delete() error {
+	if m == nil {
+		return nil
+	}
+	return removeFile(m.path)
+}
+
+func (m *MigrationState) setThrottles(throttledTopic bool, throttledBrokers []int) error {
+	if m == nil {
+		return nil
+	}
+	m.ThrottledTopic = throttledTopic
+	m.ThrottledBrokers = util.CopyInts(throttledBrokers)
+	return m.Save()
+}
+
+func (m *MigrationState) completeBatch() error {
+	if m == nil {
+		return nil
+	}
+	m.CompletedBatches++
+	return m.Save()
+}
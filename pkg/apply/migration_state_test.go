@@ -0,0 +1,124 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationStateSaveLoad(t *testing.T) {
+	stateDir := t.TempDir()
+	path := MigrationStatePath(
+		filepath.Join(stateDir, "migrations"),
+		"test-cluster",
+		"test-environment",
+		"test-region",
+		"test-topic",
+	)
+	assert.Equal(
+		t,
+		filepath.Join(
+			stateDir,
+			"migrations",
+			"test-cluster-test-environment-test-region-test-topic.json",
+		),
+		path,
+	)
+
+	state, err := LoadMigrationState(path)
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	// Operations on nil states are no-ops
+	require.NoError(t, state.Save())
+	require.NoError(t, state.completeBatch())
+	require.NoError(t, state.Delete())
+
+	state = &MigrationState{
+		Version: MigrationStateVersion,
+		Topic:   "test-topic",
+		CurrAssignments: []admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{1, 2}},
+			{ID: 2, Replicas: []int{3, 4}},
+			{ID: 3, Replicas: []int{5, 6}},
+		},
+		TargetAssignments: []admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{2, 1}},
+			{ID: 2, Replicas: []int{3, 5}},
+			{ID: 3, Replicas: []int{5, 4}},
+		},
+		BatchSize: 2,
+		path:      path,
+	}
+	assert.Equal(t, 2, state.NumBatches())
+
+	require.NoError(t, state.setThrottles(true, []int{3, 4, 5}))
+	require.NoError(t, state.completeBatch())
+
+	loadedState, err := LoadMigrationState(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, loadedState.CompletedBatches)
+	assert.True(t, loadedState.ThrottledTopic)
+	assert.Equal(t, []int{3, 4, 5}, loadedState.ThrottledBrokers)
+
+	currRemaining, targetRemaining := loadedState.RemainingAssignments()
+	assert.Equal(
+		t,
+		[]admin.PartitionAssignment{{ID: 3, Replicas: []int{5, 6}}},
+		currRemaining,
+	)
+	assert.Equal(
+		t,
+		[]admin.PartitionAssignment{{ID: 3, Replicas: []int{5, 4}}},
+		targetRemaining,
+	)
+
+	require.NoError(t, loadedState.Delete())
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestMigrationStateBatchEnds(t *testing.T) {
+	state := &MigrationState{
+		Version: MigrationStateVersion,
+		Topic:   "test-topic",
+		CurrAssignments: []admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{1, 2}},
+			{ID: 1, Replicas: []int{2, 3}},
+			{ID: 2, Replicas: []int{3, 4}},
+			{ID: 3, Replicas: []int{4, 5}},
+		},
+		TargetAssignments: []admin.PartitionAssignment{
+			{ID: 0, Replicas: []int{2, 1}},
+			{ID: 1, Replicas: []int{2, 4}},
+			{ID: 2, Replicas: []int{3, 5}},
+			{ID: 3, Replicas: []int{4, 1}},
+		},
+		BatchEnds: []int{1, 3, 4},
+	}
+	assert.Equal(t, 3, state.NumBatches())
+
+	start, end := state.batchRange(0)
+	assert.Equal(t, []int{0, 1}, []int{start, end})
+	start, end = state.batchRange(1)
+	assert.Equal(t, []int{1, 3}, []int{start, end})
+	start, end = state.batchRange(3)
+	assert.Equal(t, []int{4, 4}, []int{start, end})
+
+	state.CompletedBatches = 1
+	currRemaining, targetRemaining := state.RemainingAssignments()
+	assert.Equal(t, state.CurrAssignments[1:], currRemaining)
+	assert.Equal(t, state.TargetAssignments[1:], targetRemaining)
+}
+
+func TestMigrationStateBadVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"version": 100}`), 0644))
+
+	_, err := LoadMigrationState(path)
+	assert.Error(t, err)
+}
@@ -92,7 +92,7 @@ func (c *ClusterUsePicker) ScoreBroker(
 	partition int,
 	index int,
 ) int {
-	return c.brokerCountsByPosition[index][brokerID]
+	return c.positionCount(index, brokerID)
 }
 
 func (c *ClusterUsePicker) keySorter(index int, asc bool) util.KeySorter {
@@ -101,13 +101,21 @@ func (c *ClusterUsePicker) keySorter(index int, asc bool) util.KeySorter {
 
 		sort.Slice(keys, func(a, b int) bool {
 			if asc {
-				return c.brokerCountsByPosition[index][keys[a]] <
-					c.brokerCountsByPosition[index][keys[b]]
+				return c.positionCount(index, keys[a]) < c.positionCount(index, keys[b])
 			}
-			return c.brokerCountsByPosition[index][keys[a]] >
-				c.brokerCountsByPosition[index][keys[b]]
+			return c.positionCount(index, keys[a]) > c.positionCount(index, keys[b])
 		})
 
 		return keys
 	}
 }
+
+// positionCount returns the number of times that the argument broker is used at the
+// argument index across the cluster. The index can be beyond the max replication of the
+// other topics, e.g. if the replication of the current topic is being increased.
+func (c *ClusterUsePicker) positionCount(index int, brokerID int) int {
+	if index >= len(c.brokerCountsByPosition) {
+		return 0
+	}
+	return c.brokerCountsByPosition[index][brokerID]
+}
@@ -222,4 +222,9 @@ func TestClusterUsePickerScoreBroker(t *testing.T) {
 	picker := NewClusterUsePicker(brokers, topics)
 	score := picker.ScoreBroker("test-topic3", 3, 1, 0)
 	assert.Equal(t, 2, score)
+
+	// Indices beyond the max replication in the cluster (e.g., when increasing
+	// the replication of a topic) have no usage
+	score = picker.ScoreBroker("test-topic3", 3, 1, 3)
+	assert.Equal(t, 0, score)
 }
@@ -0,0 +1,124 @@
+package pickers
+
+import (
+	"math"
+	"sort"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+)
+
+// weightEpsilon is the tolerance used when comparing weighted counts.
+const weightEpsilon = 1e-9
+
+// WeightedPicker is a picker that wraps another one so that brokers are chosen in proportion
+// to their weights instead of evenly. Among the feasible choices, only the brokers whose
+// weighted counts would be the lowest after the pick are passed to the underlying picker, which
+// then breaks the ties.
+type WeightedPicker struct {
+	picker  Picker
+	weights map[int]float64
+}
+
+var _ Picker = (*WeightedPicker)(nil)
+
+// NewWeightedPicker returns a new WeightedPicker instance. Brokers that aren't in the
+// argument weights have a weight of 1.
+func NewWeightedPicker(picker Picker, weights map[int]float64) *WeightedPicker {
+	return &WeightedPicker{
+		picker:  picker,
+		weights: weights,
+	}
+}
+
+// PickNew updates the replica for the argument partition and index, using the choices in
+// brokerChoices.
+func (w *WeightedPicker) PickNew(
+	topic string,
+	brokerChoices []int,
+	curr []admin.PartitionAssignment,
+	partition int,
+	index int,
+) error {
+	brokerCounts := map[int]int{}
+	for _, assignment := range curr {
+		brokerCounts[assignment.Replicas[index]]++
+	}
+
+	feasibleChoices := []int{}
+	minScore := math.Inf(1)
+
+	for _, choice := range brokerChoices {
+		if curr[partition].Index(choice) != -1 {
+			continue
+		}
+		feasibleChoices = append(feasibleChoices, choice)
+
+		// Score by the weighted count after this broker is picked
+		score := float64(brokerCounts[choice]+1) / w.weight(choice)
+		if score < minScore {
+			minScore = score
+		}
+	}
+
+	if len(feasibleChoices) == 0 {
+		return ErrNoFeasibleChoice
+	}
+
+	lowestChoices := []int{}
+	for _, choice := range feasibleChoices {
+		score := float64(brokerCounts[choice]+1) / w.weight(choice)
+		if score < minScore+weightEpsilon {
+			lowestChoices = append(lowestChoices, choice)
+		}
+	}
+
+	return w.picker.PickNew(topic, lowestChoices, curr, partition, index)
+}
+
+// SortRemovals sorts the argument partitions in order of priority for removing the broker
+// at the argument index.
+func (w *WeightedPicker) SortRemovals(
+	topic string,
+	partitionChoices []int,
+	curr []admin.PartitionAssignment,
+	index int,
+) error {
+	if err := w.picker.SortRemovals(topic, partitionChoices, curr, index); err != nil {
+		return err
+	}
+
+	brokerCounts := map[int]int{}
+	for _, partition := range partitionChoices {
+		brokerCounts[curr[partition].Replicas[index]]++
+	}
+
+	// Re-sort by weighted count descending, keeping the underlying picker's order for ties
+	sort.SliceStable(partitionChoices, func(a, b int) bool {
+		aReplica := curr[partitionChoices[a]].Replicas[index]
+		bReplica := curr[partitionChoices[b]].Replicas[index]
+
+		aScore := float64(brokerCounts[aReplica]) / w.weight(aReplica)
+		bScore := float64(brokerCounts[bReplica]) / w.weight(bReplica)
+
+		return aScore > bScore+weightEpsilon
+	})
+
+	return nil
+}
+
+// ScoreBroker returns an integer score for the given broker at the provided partition and index.
+func (w *WeightedPicker) ScoreBroker(
+	topic string,
+	brokerID int,
+	partition int,
+	index int,
+) int {
+	return w.picker.ScoreBroker(topic, brokerID, partition, index)
+}
+
+func (w *WeightedPicker) weight(brokerID int) float64 {
+	if weight, ok := w.weights[brokerID]; ok {
+		return weight
+	}
+	return 1.0
+}
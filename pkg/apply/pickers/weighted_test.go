@@ -0,0 +1,137 @@
+package pickers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedPickerPickNew(t *testing.T) {
+	testCases := []struct {
+		weights  map[int]float64
+		testCase pickNewTestCase
+	}{
+		{
+			weights: map[int]float64{3: 2.0},
+			testCase: pickNewTestCase{
+				description:   "Heavier broker picked with same count",
+				topic:         "test-topic",
+				brokerChoices: []int{1, 3},
+				curr: [][]int{
+					{1, 2},
+					{2, 3},
+					{3, 1},
+					{-1, 2},
+				},
+				partition:      3,
+				index:          0,
+				expectedChoice: 3,
+			},
+		},
+		{
+			weights: map[int]float64{1: 2.0, 3: 2.0},
+			testCase: pickNewTestCase{
+				description:   "Ties broken by underlying picker",
+				topic:         "test-topic",
+				brokerChoices: []int{1, 3},
+				curr: [][]int{
+					{1, 2},
+					{2, 3},
+					{3, 1},
+					{-1, 2},
+				},
+				partition:      3,
+				index:          0,
+				expectedChoice: 1,
+			},
+		},
+		{
+			weights: map[int]float64{3: 3.0},
+			testCase: pickNewTestCase{
+				description:   "Heavier broker picked with higher count",
+				topic:         "test-topic",
+				brokerChoices: []int{1, 3},
+				curr: [][]int{
+					{1, 2},
+					{3, 2},
+					{3, 1},
+					{-1, 2},
+				},
+				partition:      3,
+				index:          0,
+				expectedChoice: 3,
+			},
+		},
+		{
+			weights: map[int]float64{1: 0.25},
+			testCase: pickNewTestCase{
+				description:   "Lighter broker skipped with lower count",
+				topic:         "test-topic",
+				brokerChoices: []int{1, 3},
+				curr: [][]int{
+					{2, 1},
+					{3, 2},
+					{3, 1},
+					{-1, 2},
+				},
+				partition:      3,
+				index:          0,
+				expectedChoice: 3,
+			},
+		},
+		{
+			weights: map[int]float64{2: 2.0},
+			testCase: pickNewTestCase{
+				description:   "Not feasible",
+				topic:         "test-topic",
+				brokerChoices: []int{2},
+				curr: [][]int{
+					{1, 2},
+					{-1, 2},
+				},
+				partition:   1,
+				index:       0,
+				expectedErr: true,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		picker := NewWeightedPicker(NewLowestIndexPicker(), testCase.weights)
+		testCase.testCase.evaluate(t, picker)
+	}
+}
+
+func TestWeightedPickerSortRemovals(t *testing.T) {
+	picker := NewWeightedPicker(NewLowestIndexPicker(), map[int]float64{1: 2.0})
+
+	testCases := []sortRemovalsTestCase{
+		{
+			description:      "Weighted sort",
+			topic:            "test-topic",
+			partitionChoices: []int{0, 1, 2, 3, 4},
+			curr: [][]int{
+				{1, 5},
+				{1, 4},
+				{2, 5},
+				{2, 4},
+				{3, 5},
+			},
+			index:            0,
+			expectedOrdering: []int{2, 3, 0, 1, 4},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(t, picker)
+	}
+}
+
+func TestWeightedPickerScoreBroker(t *testing.T) {
+	picker := NewWeightedPicker(NewRandomizedPicker(), map[int]float64{2: 2.0})
+	assert.Equal(
+		t,
+		NewRandomizedPicker().ScoreBroker("test-topic", 2, 3, 4),
+		picker.ScoreBroker("test-topic", 2, 3, 4),
+	)
+}
@@ -0,0 +1,649 @@
+package apply
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// PlanVersion is the version of the plan file format. It should be incremented whenever
+// the format changes in a backwards-incompatible way.
+const PlanVersion = 1
+
+// PlanStepType is the type of a step in a topic plan.
+type PlanStepType string
+
+const (
+	PlanStepCreateTopic        PlanStepType = "createTopic"
+	PlanStepUpdateTopicConfig  PlanStepType = "updateTopicConfig"
+	PlanStepUpdateBrokerConfig PlanStepType = "updateBrokerConfig"
+	PlanStepAddPartitions      PlanStepType = "addPartitions"
+	PlanStepAssignPartitions   PlanStepType = "assignPartitions"
+	PlanStepRunLeaderElection  PlanStepType = "runLeaderElection"
+)
+
+// Plan is a set of topic changes that were computed ahead of time by running the apply
+// logic against the current state of a cluster. Plans are written to disk by the "plan"
+// subcommand and then executed via "apply --plan".
+type Plan struct {
+	Version           int         `json:"version"`
+	CreatedAt         time.Time   `json:"createdAt"`
+	Cluster           string      `json:"cluster"`
+	Environment       string      `json:"environment"`
+	Region            string      `json:"region"`
+	ClusterConfigPath string      `json:"clusterConfigPath"`
+	Topics            []TopicPlan `json:"topics"`
+}
+
+// TopicPlan contains the steps to apply for a single topic.
+type TopicPlan struct {
+	Topic string `json:"topic"`
+
+	// Fingerprint is a hash of the cluster state (brokers, broker throttles, and topic
+	// config and placement) that the plan was computed against.
+	Fingerprint string `json:"fingerprint"`
+
+	// ThrottleBytes is the broker throttle rate that was used when computing the plan;
+	// the actual throttle config changes are included in the steps.
+	ThrottleBytes int64 `json:"throttleBytes"`
+
+	Steps []PlanStep `json:"steps"`
+}
+
+// PlanStep is a single admin operation in a topic plan. Each assignPartitions or
+// addPartitions step corresponds to one batch in the apply process.
+type PlanStep struct {
+	Type PlanStepType `json:"type"`
+
+	// Broker is the broker to update; only set for updateBrokerConfig steps.
+	Broker *int `json:"broker,omitempty"`
+
+	// ConfigEntries are the topic or broker config entries to set. Entries with empty
+	// values are removed.
+	ConfigEntries []NewConfigEntry `json:"configEntries,omitempty"`
+	Overwrite     bool             `json:"overwrite,omitempty"`
+
+	// Assignments are the replica assignments for createTopic, addPartitions, and
+	// assignPartitions steps.
+	Assignments []admin.PartitionAssignment `json:"assignments,omitempty"`
+
+	// Partitions are the partitions to run leader elections for.
+	Partitions []int `json:"partitions,omitempty"`
+}
+
+// NewPlan creates a new, empty plan for the cluster in the argument config.
+func NewPlan(clusterConfig config.ClusterConfig, clusterConfigPath string) Plan {
+	return Plan{
+		Version:           PlanVersion,
+		CreatedAt:         time.Now().UTC(),
+		Cluster:           clusterConfig.Meta.Name,
+		Environment:       clusterConfig.Meta.Environment,
+		Region:            clusterConfig.Meta.Region,
+		ClusterConfigPath: clusterConfigPath,
+		Topics:            []TopicPlan{},
+	}
+}
+
+// LoadPlanFile loads a plan from a JSON file on disk.
+func LoadPlanFile(path string) (Plan, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{}
+	if err := json.Unmarshal(contents, &plan); err != nil {
+		return Plan{}, fmt.Errorf("Error parsing plan file %s: %+v", path, err)
+	}
+
+	return plan, nil
+}
+
+// WritePlanFile writes a plan to disk in JSON format.
+func WritePlanFile(plan Plan, path string) error {
+	contents, err := plan.ToJSON()
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, contents, 0644)
+}
+
+// ToJSON returns the indented JSON representation of the plan.
+func (p Plan) ToJSON() ([]byte, error) {
+	contents, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append(contents, '\n'), nil
+}
+
+// Validate checks that the plan has a supported version and was computed for the
+// cluster in the argument config.
+func (p Plan) Validate(clusterConfig config.ClusterConfig) error {
+	if p.Version != PlanVersion {
+		return fmt.Errorf(
+			"Unsupported plan version %d (expected %d); please re-run plan",
+			p.Version,
+			PlanVersion,
+		)
+	}
+
+	if p.Cluster != clusterConfig.Meta.Name ||
+		p.Environment != clusterConfig.Meta.Environment ||
+		p.Region != clusterConfig.Meta.Region {
+		return fmt.Errorf(
+			"Plan was created for cluster %s-%s-%s, but cluster config is for %s-%s-%s",
+			p.Cluster,
+			p.Environment,
+			p.Region,
+			clusterConfig.Meta.Name,
+			clusterConfig.Meta.Environment,
+			clusterConfig.Meta.Region,
+		)
+	}
+
+	topicsSeen := map[string]struct{}{}
+	for _, topicPlan := range p.Topics {
+		if _, ok := topicsSeen[topicPlan.Topic]; ok {
+			return fmt.Errorf("Topic %s appears multiple times in plan", topicPlan.Topic)
+		}
+		topicsSeen[topicPlan.Topic] = struct{}{}
+
+		for s, step := range topicPlan.Steps {
+			if err := step.validate(); err != nil {
+				return fmt.Errorf(
+					"Invalid step %d for topic %s: %+v",
+					s,
+					topicPlan.Topic,
+					err,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s PlanStep) validate() error {
+	switch s.Type {
+	case PlanStepCreateTopic, PlanStepAddPartitions, PlanStepAssignPartitions:
+		if len(s.Assignments) == 0 {
+			return errors.New("Assignments must be set")
+		}
+		return admin.CheckAssignments(s.Assignments)
+	case PlanStepUpdateTopicConfig:
+		if len(s.ConfigEntries) == 0 {
+			return errors.New("ConfigEntries must be set")
+		}
+	case PlanStepUpdateBrokerConfig:
+		if s.Broker == nil {
+			return errors.New("Broker must be set")
+		}
+		if len(s.ConfigEntries) == 0 {
+			return errors.New("ConfigEntries must be set")
+		}
+	case PlanStepRunLeaderElection:
+		if len(s.Partitions) == 0 {
+			return errors.New("Partitions must be set")
+		}
+	default:
+		return fmt.Errorf("Unrecognized step type: %s", s.Type)
+	}
+
+	return nil
+}
+
+// PlanTopic computes the plan for a single topic. It runs the same logic as a regular
+// apply, but against a simulated copy of the topic so that no changes are made to the
+// cluster. The settings that affect the plan (throttles, batch sizes, rebalancing,
+// brokers to remove, etc.) are taken from the argument applier config.
+func PlanTopic(
+	ctx context.Context,
+	adminClient admin.Client,
+	applierConfig TopicApplierConfig,
+) (TopicPlan, error) {
+	topicName := applierConfig.TopicConfig.Meta.Name
+
+	fingerprint, err := TopicFingerprint(ctx, adminClient, topicName)
+	if err != nil {
+		return TopicPlan{}, err
+	}
+
+	planningClient, err := newPlanningClient(ctx, adminClient, topicName)
+	if err != nil {
+		return TopicPlan{}, err
+	}
+
+	applierConfig.DryRun = false
+	applierConfig.SkipConfirm = true
+	applierConfig.AutoContinueRebalance = true
+	applierConfig.JsonOutput = false
+	applierConfig.SleepLoopDuration = time.Millisecond
+	applierConfig.MigrationStateDir = ""
+	applierConfig.Resume = false
+	applierConfig.HistoryDir = ""
+	applierConfig.Scheduler = nil
+	applierConfig.Events = nil
+
+	// Hooks are only run when changes are actually made
+	applierConfig.ClusterConfig.Spec.Hooks = nil
+
+	applier, err := NewTopicApplier(ctx, planningClient, applierConfig)
+	if err != nil {
+		return TopicPlan{}, err
+	}
+
+	// Plans use fixed throttles since they can't observe the cluster during the migration
+	applier.adaptiveThrottle = nil
+
+	log.Infof("Simulating apply for topic %s", topicName)
+	if _, err := applier.Apply(ctx); err != nil {
+		return TopicPlan{}, err
+	}
+
+	return TopicPlan{
+		Topic:         topicName,
+		Fingerprint:   fingerprint,
+		ThrottleBytes: applier.throttleBytes,
+		Steps:         planningClient.steps,
+	}, nil
+}
+
+type fingerprintState struct {
+	Brokers []fingerprintBroker `json:"brokers"`
+	Topic   *fingerprintTopic   `json:"topic"`
+}
+
+type fingerprintBroker struct {
+	ID        int    `json:"id"`
+	Rack      string `json:"rack"`
+	Throttles string `json:"throttles"`
+}
+
+type fingerprintTopic struct {
+	Config     map[string]string      `json:"config"`
+	Partitions []fingerprintPartition `json:"partitions"`
+}
+
+type fingerprintPartition struct {
+	ID       int   `json:"id"`
+	Replicas []int `json:"replicas"`
+}
+
+// TopicFingerprint returns a hash of the cluster state that a topic plan depends on. This
+// includes the brokers and their racks and throttles, and the topic's config and replicas.
+// Leaders and ISRs are excluded since they can change without any admin action.
+func TopicFingerprint(
+	ctx context.Context,
+	adminClient admin.Client,
+	topicName string,
+) (string, error) {
+	brokers, err := adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+
+	state := fingerprintState{
+		Brokers: []fingerprintBroker{},
+	}
+
+	for _, broker := range brokers {
+		state.Brokers = append(
+			state.Brokers,
+			fingerprintBroker{
+				ID:   broker.ID,
+				Rack: broker.Rack,
+				Throttles: fmt.Sprintf(
+					"%s/%s",
+					broker.Config[admin.LeaderThrottledKey],
+					broker.Config[admin.FollowerThrottledKey],
+				),
+			},
+		)
+	}
+
+	topicInfo, err := adminClient.GetTopic(ctx, topicName, true)
+	if err == nil {
+		state.Topic = &fingerprintTopic{
+			Config:     topicInfo.Config,
+			Partitions: []fingerprintPartition{},
+		}
+		for _, partition := range topicInfo.Partitions {
+			state.Topic.Partitions = append(
+				state.Topic.Partitions,
+				fingerprintPartition{
+					ID:       partition.ID,
+					Replicas: partition.Replicas,
+				},
+			)
+		}
+	} else if err != admin.ErrTopicDoesNotExist {
+		return "", err
+	}
+
+	// Map keys are sorted by the json encoder, so this is deterministic
+	contents, err := json.Marshal(state)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(contents)
+
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// checkTopicFingerprint returns an error if the cluster state that the argument plan
+// depends on has changed since the plan was created.
+func checkTopicFingerprint(
+	ctx context.Context,
+	adminClient admin.Client,
+	topicPlan TopicPlan,
+) error {
+	fingerprint, err := TopicFingerprint(ctx, adminClient, topicPlan.Topic)
+	if err != nil {
+		return err
+	}
+	if fingerprint != topicPlan.Fingerprint {
+		return fmt.Errorf(
+			"Cluster state for topic %s has changed since the plan was created; please re-run plan",
+			topicPlan.Topic,
+		)
+	}
+	return nil
+}
+
+// PlanExecutorConfig contains the configuration for executing a plan.
+type PlanExecutorConfig struct {
+	ClusterConfig     config.ClusterConfig
+	DryRun            bool
+	SkipConfirm       bool
+	SleepLoopDuration time.Duration
+
+	// HistoryDir is the directory where the state of topics before they're changed is
+	// recorded. If blank, then no history is kept.
+	HistoryDir string
+}
+
+// ExecuteTopicPlan runs the steps in a topic plan exactly as they were computed. It refuses
+// to make any changes if the cluster state has changed since the plan was created.
+func ExecuteTopicPlan(
+	ctx context.Context,
+	adminClient admin.Client,
+	topicPlan TopicPlan,
+	executorConfig PlanExecutorConfig,
+) error {
+	if err := checkTopicFingerprint(ctx, adminClient, topicPlan); err != nil {
+		return err
+	}
+
+	if len(topicPlan.Steps) == 0 {
+		log.Infof("No changes planned for topic %s", topicPlan.Topic)
+		return nil
+	}
+
+	log.Infof(
+		"Plan for topic %s:\n%s",
+		topicPlan.Topic,
+		FormatTopicPlan(topicPlan),
+	)
+
+	if executorConfig.DryRun {
+		log.Infof("Skipping update because dryRun is set to true")
+		return nil
+	}
+
+	ok, _ := util.Confirm("OK to apply?", executorConfig.SkipConfirm)
+	if !ok {
+		return errors.New("Stopping because of user response")
+	}
+
+	if executorConfig.ClusterConfig.Spec.ZKLockPath != "" {
+		lockPath := clusterLockPath(
+			executorConfig.ClusterConfig.Spec.ZKLockPath,
+			executorConfig.ClusterConfig.Meta.Name,
+			executorConfig.ClusterConfig.Meta.Environment,
+			executorConfig.ClusterConfig.Meta.Region,
+		)
+		log.Infof("Acquiring cluster lock: %s", lockPath)
+		lockCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		lock, err := adminClient.AcquireLock(lockCtx, lockPath)
+		if err != nil {
+			return err
+		}
+		if lock != nil {
+			defer func() {
+				log.Infof("Releasing cluster lock: %s", lockPath)
+				lock.Unlock()
+			}()
+		}
+
+		// Check again in case the cluster was changed by someone else while we were waiting
+		// for the lock
+		if err := checkTopicFingerprint(ctx, adminClient, topicPlan); err != nil {
+			return err
+		}
+	}
+
+	if executorConfig.HistoryDir != "" && topicPlan.Steps[0].Type != PlanStepCreateTopic {
+		topicInfo, err := adminClient.GetTopic(ctx, topicPlan.Topic, false)
+		if err != nil {
+			return err
+		}
+		revision, err := NewTopicRevision(
+			executorConfig.HistoryDir,
+			executorConfig.ClusterConfig.Meta.Name,
+			executorConfig.ClusterConfig.Meta.Environment,
+			executorConfig.ClusterConfig.Meta.Region,
+			topicInfo,
+		)
+		if err != nil {
+			return err
+		}
+		if err := revision.Save(); err != nil {
+			return err
+		}
+		log.Infof(
+			"Recorded the previous state of topic %s as revision %d",
+			topicPlan.Topic,
+			revision.Revision,
+		)
+	}
+
+	for s, step := range topicPlan.Steps {
+		log.Infof(
+			"Running step %d/%d for topic %s: %s",
+			s+1,
+			len(topicPlan.Steps),
+			topicPlan.Topic,
+			step.Type,
+		)
+		if err := executePlanStep(
+			ctx,
+			adminClient,
+			topicPlan.Topic,
+			step,
+			executorConfig.SleepLoopDuration,
+		); err != nil {
+			return fmt.Errorf(
+				"Error running step %d (%s) for topic %s: %+v",
+				s+1,
+				step.Type,
+				topicPlan.Topic,
+				err,
+			)
+		}
+	}
+
+	return nil
+}
+
+func executePlanStep(
+	ctx context.Context,
+	adminClient admin.Client,
+	topic string,
+	step PlanStep,
+	sleepLoopDuration time.Duration,
+) error {
+	switch step.Type {
+	case PlanStepCreateTopic:
+		replicaAssignments := []kafka.ReplicaAssignment{}
+		for _, assignment := range step.Assignments {
+			replicaAssignments = append(
+				replicaAssignments,
+				kafka.ReplicaAssignment{
+					Partition: assignment.ID,
+					Replicas:  assignment.Replicas,
+				},
+			)
+		}
+
+		if err := adminClient.CreateTopic(
+			ctx,
+			kafka.TopicConfig{
+				Topic:              topic,
+				NumPartitions:      -1,
+				ReplicationFactor:  -1,
+				ReplicaAssignments: replicaAssignments,
+				ConfigEntries:      toKafkaConfigEntries(step.ConfigEntries),
+			},
+		); err != nil {
+			return err
+		}
+		return waitForAssignments(ctx, adminClient, topic, step.Assignments, sleepLoopDuration)
+	case PlanStepUpdateTopicConfig:
+		_, err := adminClient.UpdateTopicConfig(
+			ctx,
+			topic,
+			toKafkaConfigEntries(step.ConfigEntries),
+			step.Overwrite,
+		)
+		return err
+	case PlanStepUpdateBrokerConfig:
+		_, err := adminClient.UpdateBrokerConfig(
+			ctx,
+			*step.Broker,
+			toKafkaConfigEntries(step.ConfigEntries),
+			step.Overwrite,
+		)
+		return err
+	case PlanStepAddPartitions:
+		if err := adminClient.AddPartitions(ctx, topic, step.Assignments); err != nil {
+			return err
+		}
+		return waitForAssignments(ctx, adminClient, topic, step.Assignments, sleepLoopDuration)
+	case PlanStepAssignPartitions:
+		if err := adminClient.AssignPartitions(ctx, topic, step.Assignments); err != nil {
+			return err
+		}
+		return waitForAssignments(ctx, adminClient, topic, step.Assignments, sleepLoopDuration)
+	case PlanStepRunLeaderElection:
+		if err := adminClient.RunLeaderElection(ctx, topic, step.Partitions); err != nil {
+			return err
+		}
+		return waitForTopic(
+			ctx,
+			adminClient,
+			topic,
+			sleepLoopDuration,
+			func(topicInfo admin.TopicInfo) []admin.PartitionInfo {
+				return topicInfo.WrongLeaderPartitions(step.Partitions)
+			},
+		)
+	default:
+		return fmt.Errorf("Unrecognized step type: %s", step.Type)
+	}
+}
+
+func waitForAssignments(
+	ctx context.Context,
+	adminClient admin.Client,
+	topic string,
+	assignments []admin.PartitionAssignment,
+	sleepLoopDuration time.Duration,
+) error {
+	return waitForTopic(
+		ctx,
+		adminClient,
+		topic,
+		sleepLoopDuration,
+		func(topicInfo admin.TopicInfo) []admin.PartitionInfo {
+			notReady := []admin.PartitionInfo{}
+
+			for _, assignment := range assignments {
+				if assignment.ID >= len(topicInfo.Partitions) {
+					notReady = append(
+						notReady,
+						admin.PartitionInfo{
+							Topic: topic,
+							ID:    assignment.ID,
+						},
+					)
+					continue
+				}
+
+				partitionInfo := topicInfo.Partitions[assignment.ID]
+				if !util.SameElements(partitionInfo.Replicas, partitionInfo.ISR) ||
+					!reflect.DeepEqual(partitionInfo.Replicas, assignment.Replicas) {
+					notReady = append(notReady, partitionInfo)
+				}
+			}
+
+			return notReady
+		},
+	)
+}
+
+// waitForTopic polls the argument topic until the notReady function returns no partitions.
+func waitForTopic(
+	ctx context.Context,
+	adminClient admin.Client,
+	topic string,
+	sleepLoopDuration time.Duration,
+	notReady func(topicInfo admin.TopicInfo) []admin.PartitionInfo,
+) error {
+	checkTimer := time.NewTicker(sleepLoopDuration)
+	defer checkTimer.Stop()
+
+	for {
+		select {
+		case <-checkTimer.C:
+			topicInfo, err := adminClient.GetTopic(ctx, topic, true)
+			if err == admin.ErrTopicDoesNotExist {
+				log.Infof("Topic %s not visible yet", topic)
+				continue
+			} else if err != nil {
+				return err
+			}
+
+			partitions := notReady(topicInfo)
+			if len(partitions) == 0 {
+				return nil
+			}
+
+			log.Infof(
+				"%d partition(s) in topic %s are not ready yet: %+v; sleeping for %s",
+				len(partitions),
+				topic,
+				admin.PartitionIDs(partitions),
+				sleepLoopDuration.String(),
+			)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
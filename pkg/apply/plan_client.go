@@ -0,0 +1,484 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/util"
+	"github.com/segmentio/topicctl/pkg/zk"
+)
+
+// planningClient is an admin.Client that simulates the changes made by a TopicApplier
+// instead of making them. Reads are passed through to the underlying client, except for
+// the planned topic, whose state is kept in memory and updated as changes are "made".
+// Each change is recorded as a PlanStep so that it can be replayed later.
+type planningClient struct {
+	admin.Client
+
+	topicName     string
+	topicInfo     *admin.TopicInfo
+	brokerIDs     []int
+	brokerConfigs map[int]map[string]string
+	steps         []PlanStep
+}
+
+var _ admin.Client = (*planningClient)(nil)
+
+func newPlanningClient(
+	ctx context.Context,
+	client admin.Client,
+	topicName string,
+) (*planningClient, error) {
+	planningClient := &planningClient{
+		Client:        client,
+		topicName:     topicName,
+		brokerConfigs: map[int]map[string]string{},
+		steps:         []PlanStep{},
+	}
+
+	topicInfo, err := client.GetTopic(ctx, topicName, true)
+	if err == nil {
+		planningClient.topicInfo = &topicInfo
+	} else if err != admin.ErrTopicDoesNotExist {
+		return nil, err
+	}
+
+	brokers, err := client.GetBrokers(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, broker := range brokers {
+		planningClient.brokerIDs = append(planningClient.brokerIDs, broker.ID)
+
+		brokerConfig := map[string]string{}
+		for key, value := range broker.Config {
+			brokerConfig[key] = value
+		}
+		planningClient.brokerConfigs[broker.ID] = brokerConfig
+	}
+	sort.Ints(planningClient.brokerIDs)
+
+	return planningClient, nil
+}
+
+// GetBrokers gets information about the brokers in the cluster, using the simulated broker
+// configs so that planned throttle changes are visible to later steps.
+func (p *planningClient) GetBrokers(ctx context.Context, ids []int) ([]admin.BrokerInfo, error) {
+	brokers, err := p.Client.GetBrokers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for b, broker := range brokers {
+		brokerConfig, ok := p.brokerConfigs[broker.ID]
+		if !ok {
+			continue
+		}
+
+		brokers[b].Config = map[string]string{}
+		for key, value := range brokerConfig {
+			brokers[b].Config[key] = value
+		}
+	}
+
+	return brokers, nil
+}
+
+// GetTopic gets the details of a single topic, using the simulated state for the planned topic.
+func (p *planningClient) GetTopic(
+	ctx context.Context,
+	name string,
+	detailed bool,
+) (admin.TopicInfo, error) {
+	if name != p.topicName {
+		return p.Client.GetTopic(ctx, name, detailed)
+	}
+	if p.topicInfo == nil {
+		return admin.TopicInfo{}, admin.ErrTopicDoesNotExist
+	}
+	return copyTopicInfo(*p.topicInfo), nil
+}
+
+// GetTopics gets information about each topic, using the simulated state for the planned topic.
+func (p *planningClient) GetTopics(
+	ctx context.Context,
+	names []string,
+	detailed bool,
+) ([]admin.TopicInfo, error) {
+	topicInfos, err := p.Client.GetTopics(ctx, names, detailed)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []admin.TopicInfo{}
+	for _, topicInfo := range topicInfos {
+		if topicInfo.Name != p.topicName {
+			results = append(results, topicInfo)
+		}
+	}
+	if p.topicInfo != nil && (len(names) == 0 || containsString(names, p.topicName)) {
+		results = append(results, copyTopicInfo(*p.topicInfo))
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		return results[a].Name < results[b].Name
+	})
+
+	return results, nil
+}
+
+// GetTopicNames gets the names of each topic, using the simulated state for the planned topic.
+func (p *planningClient) GetTopicNames(ctx context.Context) ([]string, error) {
+	names, err := p.Client.GetTopicNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []string{}
+	for _, name := range names {
+		if name != p.topicName {
+			results = append(results, name)
+		}
+	}
+	if p.topicInfo != nil {
+		results = append(results, p.topicName)
+	}
+	sort.Strings(results)
+
+	return results, nil
+}
+
+// UpdateTopicConfig records a topic config update and applies it to the simulated topic.
+func (p *planningClient) UpdateTopicConfig(
+	ctx context.Context,
+	name string,
+	configEntries []kafka.ConfigEntry,
+	overwrite bool,
+) ([]string, error) {
+	if err := p.checkTopic(name); err != nil {
+		return nil, err
+	}
+
+	p.steps = append(
+		p.steps,
+		PlanStep{
+			Type:          PlanStepUpdateTopicConfig,
+			ConfigEntries: toNewConfigEntries(configEntries),
+			Overwrite:     overwrite,
+		},
+	)
+
+	if p.topicInfo.Config == nil {
+		p.topicInfo.Config = map[string]string{}
+	}
+	return updateConfigMap(p.topicInfo.Config, configEntries, true), nil
+}
+
+// UpdateBrokerConfig records a broker config update and applies it to the simulated broker
+// configs.
+func (p *planningClient) UpdateBrokerConfig(
+	ctx context.Context,
+	id int,
+	configEntries []kafka.ConfigEntry,
+	overwrite bool,
+) ([]string, error) {
+	brokerConfig, ok := p.brokerConfigs[id]
+	if !ok {
+		return nil, fmt.Errorf("Broker %d not found", id)
+	}
+
+	broker := id
+	p.steps = append(
+		p.steps,
+		PlanStep{
+			Type:          PlanStepUpdateBrokerConfig,
+			Broker:        &broker,
+			ConfigEntries: toNewConfigEntries(configEntries),
+			Overwrite:     overwrite,
+		},
+	)
+
+	return updateConfigMap(brokerConfig, configEntries, overwrite), nil
+}
+
+// CreateTopic records a topic creation and creates the simulated topic. If the argument
+// config doesn't have explicit replica assignments, then the replicas are assigned
+// round-robin across the brokers; these assignments are recorded so that the topic is
+// created identically when the plan is executed.
+func (p *planningClient) CreateTopic(
+	ctx context.Context,
+	config kafka.TopicConfig,
+) error {
+	if config.Topic != p.topicName {
+		return fmt.Errorf("Cannot create topic %s in plan for %s", config.Topic, p.topicName)
+	}
+	if p.topicInfo != nil {
+		return fmt.Errorf("Topic %s already exists", config.Topic)
+	}
+
+	assignments := []admin.PartitionAssignment{}
+
+	if len(config.ReplicaAssignments) > 0 {
+		for _, replicaAssignment := range config.ReplicaAssignments {
+			assignments = append(
+				assignments,
+				admin.PartitionAssignment{
+					ID:       replicaAssignment.Partition,
+					Replicas: util.CopyInts(replicaAssignment.Replicas),
+				},
+			)
+		}
+	} else {
+		if config.ReplicationFactor > len(p.brokerIDs) {
+			return fmt.Errorf(
+				"Replication factor (%d) is larger than the number of brokers (%d)",
+				config.ReplicationFactor,
+				len(p.brokerIDs),
+			)
+		}
+
+		for partition := 0; partition < config.NumPartitions; partition++ {
+			replicas := []int{}
+			for r := 0; r < config.ReplicationFactor; r++ {
+				replicas = append(
+					replicas,
+					p.brokerIDs[(partition+r)%len(p.brokerIDs)],
+				)
+			}
+			assignments = append(
+				assignments,
+				admin.PartitionAssignment{
+					ID:       partition,
+					Replicas: replicas,
+				},
+			)
+		}
+	}
+
+	p.steps = append(
+		p.steps,
+		PlanStep{
+			Type:          PlanStepCreateTopic,
+			ConfigEntries: toNewConfigEntries(config.ConfigEntries),
+			Assignments:   admin.CopyAssignments(assignments),
+		},
+	)
+
+	p.topicInfo = &admin.TopicInfo{
+		Name:   p.topicName,
+		Config: map[string]string{},
+	}
+	updateConfigMap(p.topicInfo.Config, config.ConfigEntries, true)
+	p.addPartitions(assignments)
+
+	return nil
+}
+
+// AssignPartitions records a partition reassignment and applies it to the simulated topic.
+func (p *planningClient) AssignPartitions(
+	ctx context.Context,
+	topic string,
+	assignments []admin.PartitionAssignment,
+) error {
+	if err := p.checkTopic(topic); err != nil {
+		return err
+	}
+
+	p.steps = append(
+		p.steps,
+		PlanStep{
+			Type:        PlanStepAssignPartitions,
+			Assignments: admin.CopyAssignments(assignments),
+		},
+	)
+
+	for _, assignment := range assignments {
+		if assignment.ID >= len(p.topicInfo.Partitions) {
+			return fmt.Errorf("Partition %d does not exist", assignment.ID)
+		}
+		partition := &p.topicInfo.Partitions[assignment.ID]
+
+		// The current leader stays the leader if it's still a replica
+		if len(assignment.Replicas) > 0 && assignment.Index(partition.Leader) == -1 {
+			partition.Leader = assignment.Replicas[0]
+		}
+		partition.Replicas = util.CopyInts(assignment.Replicas)
+		partition.ISR = util.CopyInts(assignment.Replicas)
+	}
+
+	return nil
+}
+
+// AddPartitions records the addition of partitions and applies it to the simulated topic.
+func (p *planningClient) AddPartitions(
+	ctx context.Context,
+	topic string,
+	newAssignments []admin.PartitionAssignment,
+) error {
+	if err := p.checkTopic(topic); err != nil {
+		return err
+	}
+
+	p.steps = append(
+		p.steps,
+		PlanStep{
+			Type:        PlanStepAddPartitions,
+			Assignments: admin.CopyAssignments(newAssignments),
+		},
+	)
+	p.addPartitions(newAssignments)
+
+	return nil
+}
+
+// RunLeaderElection records a leader election and applies it to the simulated topic.
+func (p *planningClient) RunLeaderElection(
+	ctx context.Context,
+	topic string,
+	partitions []int,
+) error {
+	if err := p.checkTopic(topic); err != nil {
+		return err
+	}
+
+	p.steps = append(
+		p.steps,
+		PlanStep{
+			Type:       PlanStepRunLeaderElection,
+			Partitions: util.CopyInts(partitions),
+		},
+	)
+
+	for _, partition := range partitions {
+		if partition >= len(p.topicInfo.Partitions) {
+			return fmt.Errorf("Partition %d does not exist", partition)
+		}
+		p.topicInfo.Partitions[partition].Leader = p.topicInfo.Partitions[partition].Replicas[0]
+	}
+
+	return nil
+}
+
+// AcquireLock is a no-op since no changes are made to the cluster while planning.
+func (p *planningClient) AcquireLock(ctx context.Context, path string) (zk.Lock, error) {
+	return nil, nil
+}
+
+// Close is a no-op; the underlying client should be closed by its owner.
+func (p *planningClient) Close() error {
+	return nil
+}
+
+func (p *planningClient) checkTopic(name string) error {
+	if name != p.topicName {
+		return fmt.Errorf("Cannot update topic %s in plan for %s", name, p.topicName)
+	}
+	if p.topicInfo == nil {
+		return admin.ErrTopicDoesNotExist
+	}
+	return nil
+}
+
+func (p *planningClient) addPartitions(assignments []admin.PartitionAssignment) {
+	for _, assignment := range assignments {
+		p.topicInfo.Partitions = append(
+			p.topicInfo.Partitions,
+			admin.PartitionInfo{
+				Topic:    p.topicName,
+				ID:       assignment.ID,
+				Leader:   assignment.Replicas[0],
+				Replicas: util.CopyInts(assignment.Replicas),
+				ISR:      util.CopyInts(assignment.Replicas),
+			},
+		)
+	}
+}
+
+// updateConfigMap applies the argument config entries to a config map, treating empty values
+// as deletions. It returns the keys that were updated.
+func updateConfigMap(
+	configMap map[string]string,
+	configEntries []kafka.ConfigEntry,
+	overwrite bool,
+) []string {
+	updated := []string{}
+
+	for _, entry := range configEntries {
+		if currValue := configMap[entry.ConfigName]; !overwrite && currValue != "" {
+			continue
+		}
+
+		if entry.ConfigValue == "" {
+			delete(configMap, entry.ConfigName)
+		} else {
+			configMap[entry.ConfigName] = entry.ConfigValue
+		}
+		updated = append(updated, entry.ConfigName)
+	}
+
+	return updated
+}
+
+func toNewConfigEntries(configEntries []kafka.ConfigEntry) []NewConfigEntry {
+	newConfigEntries := []NewConfigEntry{}
+
+	for _, entry := range configEntries {
+		newConfigEntries = append(
+			newConfigEntries,
+			NewConfigEntry{
+				Name:  entry.ConfigName,
+				Value: entry.ConfigValue,
+			},
+		)
+	}
+
+	return newConfigEntries
+}
+
+func toKafkaConfigEntries(newConfigEntries []NewConfigEntry) []kafka.ConfigEntry {
+	configEntries := []kafka.ConfigEntry{}
+
+	for _, entry := range newConfigEntries {
+		configEntries = append(
+			configEntries,
+			kafka.ConfigEntry{
+				ConfigName:  entry.Name,
+				ConfigValue: entry.Value,
+			},
+		)
+	}
+
+	return configEntries
+}
+
+func copyTopicInfo(topicInfo admin.TopicInfo) admin.TopicInfo {
+	copied := admin.TopicInfo{
+		Name:       topicInfo.Name,
+		Config:     map[string]string{},
+		Partitions: []admin.PartitionInfo{},
+		Version:    topicInfo.Version,
+	}
+
+	for key, value := range topicInfo.Config {
+		copied.Config[key] = value
+	}
+	for _, partition := range topicInfo.Partitions {
+		partition.Replicas = util.CopyInts(partition.Replicas)
+		partition.ISR = util.CopyInts(partition.ISR)
+		copied.Partitions = append(copied.Partitions, partition)
+	}
+
+	return copied
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,384 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanAndExecute(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	topicName := util.RandomString("plan-topic-", 6)
+	topicConfig := config.TopicConfig{
+		Meta: config.ResourceMeta{
+			Name:        topicName,
+			Cluster:     "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+		Spec: config.TopicSpec{
+			Partitions:        6,
+			ReplicationFactor: 2,
+			RetentionMinutes:  500,
+			PlacementConfig: config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyAny,
+				Picker:   config.PickerMethodLowestIndex,
+			},
+			MigrationConfig: &config.TopicMigrationConfig{
+				PartitionBatchSize: 3,
+			},
+		},
+	}
+
+	applier := testApplier(ctx, t, topicConfig)
+	defer applier.adminClient.Close()
+
+	// Plan the creation of the topic; nothing should change in the cluster
+	topicPlan, err := PlanTopic(ctx, applier.adminClient, applier.config)
+	require.NoError(t, err)
+	require.Greater(t, len(topicPlan.Steps), 0)
+	assert.Equal(t, PlanStepCreateTopic, topicPlan.Steps[0].Type)
+	assert.Equal(t, 6, len(topicPlan.Steps[0].Assignments))
+
+	_, err = applier.adminClient.GetTopic(ctx, topicName, false)
+	assert.Equal(t, admin.ErrTopicDoesNotExist, err)
+
+	plan := NewPlan(applier.clusterConfig, "")
+	plan.Topics = append(plan.Topics, topicPlan)
+
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	require.NoError(t, WritePlanFile(plan, planPath))
+	plan, err = LoadPlanFile(planPath)
+	require.NoError(t, err)
+	require.NoError(t, plan.Validate(applier.clusterConfig))
+
+	executorConfig := PlanExecutorConfig{
+		ClusterConfig:     applier.clusterConfig,
+		SkipConfirm:       true,
+		SleepLoopDuration: 500 * time.Millisecond,
+	}
+	require.NoError(
+		t,
+		ExecuteTopicPlan(ctx, applier.adminClient, plan.Topics[0], executorConfig),
+	)
+
+	topicInfo, err := applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	assert.Equal(t, 6, len(topicInfo.Partitions))
+	assert.Equal(t, "30000000", topicInfo.Config[admin.RetentionKey])
+
+	// Plan a placement change; the executed plan should match the simulated assignments
+	applier.topicConfig.Spec.PlacementConfig.Strategy = config.PlacementStrategyInRack
+	applier.config.TopicConfig = applier.topicConfig
+	topicPlan, err = PlanTopic(ctx, applier.adminClient, applier.config)
+	require.NoError(t, err)
+
+	expectedAssignments := topicInfo.ToAssignments()
+	for _, step := range topicPlan.Steps {
+		if step.Type == PlanStepAssignPartitions {
+			for _, assignment := range step.Assignments {
+				expectedAssignments[assignment.ID] = assignment
+			}
+		}
+	}
+
+	// Plans are rejected if the cluster changes after they're created
+	_, err = applier.adminClient.UpdateTopicConfig(
+		ctx,
+		topicName,
+		[]kafka.ConfigEntry{
+			{
+				ConfigName:  "cleanup.policy",
+				ConfigValue: "compact",
+			},
+		},
+		true,
+	)
+	require.NoError(t, err)
+	err = ExecuteTopicPlan(ctx, applier.adminClient, topicPlan, executorConfig)
+	require.Error(t, err)
+
+	topicPlan, err = PlanTopic(ctx, applier.adminClient, applier.config)
+	require.NoError(t, err)
+	require.NoError(
+		t,
+		ExecuteTopicPlan(ctx, applier.adminClient, topicPlan, executorConfig),
+	)
+
+	topicInfo, err = applier.adminClient.GetTopic(ctx, topicName, true)
+	require.NoError(t, err)
+	assert.Equal(t, expectedAssignments, topicInfo.ToAssignments())
+	assert.True(t, topicInfo.AllLeadersCorrect())
+	assert.False(t, topicInfo.IsThrottled())
+}
+
+func TestPlanValidate(t *testing.T) {
+	clusterConfig := config.ClusterConfig{
+		Meta: config.ClusterMeta{
+			Name:        "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+	}
+	broker := 1
+
+	plan := NewPlan(clusterConfig, "")
+	plan.Topics = []TopicPlan{
+		{
+			Topic: "topic1",
+			Steps: []PlanStep{
+				{
+					Type: PlanStepUpdateBrokerConfig,
+					ConfigEntries: []NewConfigEntry{
+						{
+							Name:  admin.LeaderThrottledKey,
+							Value: "1000000",
+						},
+					},
+					Broker: &broker,
+				},
+				{
+					Type: PlanStepAssignPartitions,
+					Assignments: []admin.PartitionAssignment{
+						{
+							ID:       0,
+							Replicas: []int{1, 2},
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.NoError(t, plan.Validate(clusterConfig))
+
+	otherClusterConfig := clusterConfig
+	otherClusterConfig.Meta.Name = "other-cluster"
+	assert.Error(t, plan.Validate(otherClusterConfig))
+
+	badVersion := plan
+	badVersion.Version = PlanVersion + 1
+	assert.Error(t, badVersion.Validate(clusterConfig))
+
+	badSteps := NewPlan(clusterConfig, "")
+	badSteps.Topics = []TopicPlan{
+		{
+			Topic: "topic1",
+			Steps: []PlanStep{
+				{
+					Type: PlanStepUpdateBrokerConfig,
+					ConfigEntries: []NewConfigEntry{
+						{
+							Name:  admin.LeaderThrottledKey,
+							Value: "1000000",
+						},
+					},
+				},
+			},
+		},
+	}
+	assert.Error(t, badSteps.Validate(clusterConfig))
+
+	badSteps.Topics[0].Steps = []PlanStep{
+		{
+			Type: PlanStepAssignPartitions,
+			Assignments: []admin.PartitionAssignment{
+				{
+					ID:       0,
+					Replicas: []int{1, 1},
+				},
+			},
+		},
+	}
+	assert.Error(t, badSteps.Validate(clusterConfig))
+
+	badSteps.Topics[0].Steps = []PlanStep{
+		{
+			Type: "unknownType",
+		},
+	}
+	assert.Error(t, badSteps.Validate(clusterConfig))
+}
+
+func TestTopicFingerprint(t *testing.T) {
+	ctx := context.Background()
+	snapshot := testPlanSnapshot()
+
+	fingerprint, err := TopicFingerprint(ctx, testSnapshotClient(t, snapshot), "test-topic")
+	require.NoError(t, err)
+
+	// Leader and ISR changes don't affect the fingerprint
+	snapshot.Topics[0].Partitions[0].Leader = 2
+	snapshot.Topics[0].Partitions[0].ISR = []int{2}
+	newFingerprint, err := TopicFingerprint(ctx, testSnapshotClient(t, snapshot), "test-topic")
+	require.NoError(t, err)
+	assert.Equal(t, fingerprint, newFingerprint)
+
+	// Replica, config, and throttle changes do
+	snapshot = testPlanSnapshot()
+	snapshot.Topics[0].Partitions[0].Replicas = []int{1, 3}
+	newFingerprint, err = TopicFingerprint(ctx, testSnapshotClient(t, snapshot), "test-topic")
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, newFingerprint)
+
+	snapshot = testPlanSnapshot()
+	snapshot.Topics[0].Config[admin.RetentionKey] = "1000"
+	newFingerprint, err = TopicFingerprint(ctx, testSnapshotClient(t, snapshot), "test-topic")
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, newFingerprint)
+
+	snapshot = testPlanSnapshot()
+	snapshot.Brokers[0].Config = map[string]string{admin.LeaderThrottledKey: "1000"}
+	newFingerprint, err = TopicFingerprint(ctx, testSnapshotClient(t, snapshot), "test-topic")
+	require.NoError(t, err)
+	assert.NotEqual(t, fingerprint, newFingerprint)
+}
+
+func TestPlanningClientBrokers(t *testing.T) {
+	ctx := context.Background()
+
+	planningClient, err := newPlanningClient(
+		ctx,
+		testSnapshotClient(t, testPlanSnapshot()),
+		"test-topic",
+	)
+	require.NoError(t, err)
+
+	_, err = planningClient.UpdateBrokerConfig(
+		ctx,
+		2,
+		[]kafka.ConfigEntry{
+			{
+				ConfigName:  admin.LeaderThrottledKey,
+				ConfigValue: "1000",
+			},
+		},
+		false,
+	)
+	require.NoError(t, err)
+
+	// Simulated broker config changes are visible to later reads
+	brokers, err := planningClient.GetBrokers(ctx, nil)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(brokers))
+	assert.Equal(t, "", brokers[0].Config[admin.LeaderThrottledKey])
+	assert.Equal(t, "1000", brokers[1].Config[admin.LeaderThrottledKey])
+
+	brokers, err = planningClient.GetBrokers(ctx, []int{2})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(brokers))
+	assert.Equal(t, "1000", brokers[0].Config[admin.LeaderThrottledKey])
+	assert.Equal(t, 1, len(planningClient.steps))
+}
+
+func testPlanSnapshot() admin.Snapshot {
+	return admin.Snapshot{
+		Version:   admin.SnapshotVersion,
+		ClusterID: "test-cluster",
+		Brokers: []admin.BrokerInfo{
+			{ID: 1, Rack: "rack1"},
+			{ID: 2, Rack: "rack2"},
+			{ID: 3, Rack: "rack3"},
+		},
+		Topics: []admin.TopicInfo{
+			{
+				Name: "test-topic",
+				Config: map[string]string{
+					admin.RetentionKey: "3600000",
+				},
+				Partitions: []admin.PartitionInfo{
+					{
+						Topic:    "test-topic",
+						ID:       0,
+						Leader:   1,
+						Replicas: []int{1, 2},
+						ISR:      []int{1, 2},
+					},
+				},
+			},
+		},
+	}
+}
+
+func testSnapshotClient(t *testing.T, snapshot admin.Snapshot) admin.Client {
+	contents, err := json.Marshal(snapshot)
+	require.NoError(t, err)
+
+	snapshotPath := filepath.Join(t.TempDir(), "snapshot.json")
+	require.NoError(t, os.WriteFile(snapshotPath, contents, 0644))
+
+	client, err := admin.NewSnapshotAdminClient(
+		admin.SnapshotAdminClientConfig{
+			Path: snapshotPath,
+		},
+	)
+	require.NoError(t, err)
+	return client
+}
+
+func TestUpdateConfigMap(t *testing.T) {
+	configMap := map[string]string{
+		"key1": "value1",
+		"key2": "value2",
+	}
+
+	updated := updateConfigMap(
+		configMap,
+		[]kafka.ConfigEntry{
+			{
+				ConfigName:  "key1",
+				ConfigValue: "new-value1",
+			},
+			{
+				ConfigName:  "key3",
+				ConfigValue: "value3",
+			},
+		},
+		false,
+	)
+	assert.Equal(t, []string{"key3"}, updated)
+	assert.Equal(
+		t,
+		map[string]string{
+			"key1": "value1",
+			"key2": "value2",
+			"key3": "value3",
+		},
+		configMap,
+	)
+
+	updated = updateConfigMap(
+		configMap,
+		[]kafka.ConfigEntry{
+			{
+				ConfigName:  "key1",
+				ConfigValue: "new-value1",
+			},
+			{
+				ConfigName:  "key2",
+				ConfigValue: "",
+			},
+		},
+		true,
+	)
+	assert.Equal(t, []string{"key1", "key2"}, updated)
+	assert.Equal(
+		t,
+		map[string]string{
+			"key1": "new-value1",
+			"key3": "value3",
+		},
+		configMap,
+	)
+}
@@ -0,0 +1,457 @@
+package rebalancers
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/assigners"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultDiskTolerance is the default fraction that the bytes on a broker or log dir can be
+// above the mean before the DiskRebalancer tries to move replicas off of it.
+const DefaultDiskTolerance = 0.1
+
+// similarGainFraction is how close the effects of two moves need to be for the one with the
+// smaller partition to be preferred.
+const similarGainFraction = 0.1
+
+// DiskRebalancer is a Rebalancer that balances the bytes on each broker and log dir instead
+// of the replica counts. The algorithm used is:
+//
+//	for each replica on a broker to be removed:
+//	  move it to the feasible broker with the fewest bytes
+//
+//	while true:
+//	  find the brokers whose bytes are above the mean by more than the tolerance, or that have
+//	    a log dir whose bytes are above the broker's mean by more than the tolerance
+//	  for each replica of the topic on those brokers, and each other broker:
+//	    score moving the replica to the other broker by how much it reduces the imbalance
+//	  if no moves reduce the imbalance, break out of the loop
+//	  make the feasible move with the best score, preferring smaller partitions among the
+//	    moves with similar scores
+//
+// The imbalance is the sum of the squared bytes on each broker and log dir, divided by the
+// broker weights. The bytes of other topics are included but only the replicas of the argument
+// topic are moved. Kafka chooses the log dir for each new replica, so it's assumed to go in the
+// broker's log dir with the fewest bytes.
+//
+// Like the FrequencyRebalancer, moves are only made if the result is consistent with the
+// placement strategy for the topic.
+type DiskRebalancer struct {
+	brokers         []admin.BrokerInfo
+	placementConfig config.TopicPlacementConfig
+	replicaSizes    []admin.ReplicaSize
+	brokerWeights   map[int]float64
+	tolerance       float64
+}
+
+var _ Rebalancer = (*DiskRebalancer)(nil)
+
+// NewDiskRebalancer creates a new DiskRebalancer instance. The replica sizes should include
+// all of the topics on the argument brokers, as returned by the DescribeLogDirs API. The broker
+// weights can be nil, in which case all brokers are weighted equally.
+func NewDiskRebalancer(
+	brokers []admin.BrokerInfo,
+	placementConfig config.TopicPlacementConfig,
+	replicaSizes []admin.ReplicaSize,
+	brokerWeights map[int]float64,
+	tolerance float64,
+) *DiskRebalancer {
+	return &DiskRebalancer{
+		brokers:         brokers,
+		placementConfig: placementConfig,
+		replicaSizes:    replicaSizes,
+		brokerWeights:   brokerWeights,
+		tolerance:       tolerance,
+	}
+}
+
+type diskMove struct {
+	partition int
+	index     int
+	to        int
+	gain      float64
+	size      int64
+}
+
+// diskUsage tracks the bytes in each log dir of each broker.
+type diskUsage struct {
+	dirBytes    map[int]map[string]int64
+	replicaDirs map[[2]int]string
+}
+
+// Rebalance rebalances the argument partition assignments according to the algorithm
+// described earlier.
+func (d *DiskRebalancer) Rebalance(
+	topic string,
+	curr []admin.PartitionAssignment,
+	brokersToRemove []int,
+) ([]admin.PartitionAssignment, error) {
+	ok, err := assigners.EvaluateAssignments(curr, d.brokers, d.placementConfig)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf(
+			"starting assignments on topic %s do not satisfy placement config - assignments: %#v",
+			topic,
+			curr,
+		)
+	}
+
+	desired := admin.CopyAssignments(curr)
+	partitionSizes := admin.PartitionSizes(d.replicaSizes, topic)
+	usage := d.diskUsage(topic, desired, partitionSizes)
+
+	toRemoveMap := map[int]struct{}{}
+	for _, brokerID := range brokersToRemove {
+		toRemoveMap[brokerID] = struct{}{}
+	}
+
+	// First, move all replicas off of the brokers to be removed
+	for p, assignment := range desired {
+		for r, replica := range assignment.Replicas {
+			if _, ok := toRemoveMap[replica]; !ok {
+				continue
+			}
+
+			size := partitionSizes[assignment.ID]
+			candidates := []int{}
+			for _, broker := range d.brokers {
+				if _, ok := toRemoveMap[broker.ID]; !ok && assignment.Index(broker.ID) == -1 {
+					candidates = append(candidates, broker.ID)
+				}
+			}
+			sort.SliceStable(candidates, func(a, b int) bool {
+				return d.weighted(candidates[a], usage.brokerBytes(candidates[a])+size) <
+					d.weighted(candidates[b], usage.brokerBytes(candidates[b])+size)
+			})
+
+			replaced := false
+			for _, candidate := range candidates {
+				if d.tryReplacement(desired, p, r, candidate) {
+					usage.move(assignment.ID, replica, candidate, size)
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				return nil, fmt.Errorf(
+					"Could not find a feasible replacement for broker %d",
+					replica,
+				)
+			}
+		}
+	}
+
+	// Then, move replicas from the brokers and log dirs that are over the tolerance
+	maxMoves := 0
+	for _, assignment := range desired {
+		maxMoves += len(assignment.Replicas)
+	}
+
+	for m := 0; m < maxMoves; m++ {
+		candidates := d.candidateMoves(desired, usage, partitionSizes, toRemoveMap)
+		move, ok := d.bestMove(desired, candidates)
+		if !ok {
+			break
+		}
+
+		from := desired[move.partition].Replicas[move.index]
+		desired[move.partition].Replicas[move.index] = move.to
+		usage.move(desired[move.partition].ID, from, move.to, move.size)
+	}
+
+	return desired, nil
+}
+
+func (d *DiskRebalancer) diskUsage(
+	topic string,
+	assignments []admin.PartitionAssignment,
+	partitionSizes map[int]int64,
+) *diskUsage {
+	usage := &diskUsage{
+		dirBytes:    map[int]map[string]int64{},
+		replicaDirs: map[[2]int]string{},
+	}
+	topicDirs := map[[2]int]string{}
+
+	for _, replicaSize := range d.replicaSizes {
+		if replicaSize.IsFuture {
+			continue
+		}
+		if usage.dirBytes[replicaSize.Broker] == nil {
+			usage.dirBytes[replicaSize.Broker] = map[string]int64{}
+		}
+
+		if replicaSize.Topic == topic {
+			// The sizes for this topic come from the assignments, so only record the log dir
+			if _, ok := usage.dirBytes[replicaSize.Broker][replicaSize.LogDir]; !ok {
+				usage.dirBytes[replicaSize.Broker][replicaSize.LogDir] = 0
+			}
+			topicDirs[[2]int{replicaSize.Partition, replicaSize.Broker}] = replicaSize.LogDir
+			continue
+		}
+		usage.dirBytes[replicaSize.Broker][replicaSize.LogDir] += replicaSize.Size
+	}
+
+	for _, assignment := range assignments {
+		for _, replica := range assignment.Replicas {
+			size := partitionSizes[assignment.ID]
+			key := [2]int{assignment.ID, replica}
+
+			if dir, ok := topicDirs[key]; ok {
+				usage.replicaDirs[key] = dir
+				usage.dirBytes[replica][dir] += size
+			} else {
+				usage.add(assignment.ID, replica, size)
+			}
+		}
+	}
+
+	return usage
+}
+
+// candidateMoves returns all of the moves off of the brokers that are over the tolerance that
+// reduce the imbalance, in descending order of their gains.
+func (d *DiskRebalancer) candidateMoves(
+	assignments []admin.PartitionAssignment,
+	usage *diskUsage,
+	partitionSizes map[int]int64,
+	toRemove map[int]struct{},
+) []diskMove {
+	var totalBytes int64
+	var totalWeight float64
+	for _, broker := range d.brokers {
+		if _, ok := toRemove[broker.ID]; ok {
+			continue
+		}
+		totalBytes += usage.brokerBytes(broker.ID)
+		totalWeight += d.weight(broker.ID)
+	}
+	if totalWeight == 0 {
+		return nil
+	}
+	meanBytes := float64(totalBytes) / totalWeight
+
+	candidates := []diskMove{}
+
+	for p, assignment := range assignments {
+		size := partitionSizes[assignment.ID]
+		if size == 0 {
+			continue
+		}
+
+		for r, from := range assignment.Replicas {
+			if !d.overTolerance(usage, from, usage.replicaDirs[[2]int{assignment.ID, from}], meanBytes) {
+				continue
+			}
+
+			for _, broker := range d.brokers {
+				to := broker.ID
+				if _, ok := toRemove[to]; ok || assignment.Index(to) != -1 {
+					continue
+				}
+
+				gain := d.gain(usage, assignment.ID, from, to, size)
+				if gain <= 0 {
+					continue
+				}
+
+				candidates = append(
+					candidates,
+					diskMove{
+						partition: p,
+						index:     r,
+						to:        to,
+						gain:      gain,
+						size:      size,
+					},
+				)
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].gain > candidates[b].gain
+	})
+
+	return candidates
+}
+
+// bestMove returns the feasible move with the highest gain, or a feasible move with a smaller
+// partition if its gain is similar.
+func (d *DiskRebalancer) bestMove(
+	assignments []admin.PartitionAssignment,
+	candidates []diskMove,
+) (diskMove, bool) {
+	var best diskMove
+	var topGain float64
+	found := false
+
+	for _, candidate := range candidates {
+		if found && candidate.gain < topGain*(1-similarGainFraction) {
+			break
+		}
+		if found && candidate.size >= best.size {
+			continue
+		}
+		if d.feasible(assignments, candidate) {
+			if !found {
+				topGain = candidate.gain
+			}
+			best = candidate
+			found = true
+		}
+	}
+
+	return best, found
+}
+
+func (d *DiskRebalancer) feasible(
+	assignments []admin.PartitionAssignment,
+	move diskMove,
+) bool {
+	replicas := assignments[move.partition].Replicas
+	from := replicas[move.index]
+
+	if !d.tryReplacement(assignments, move.partition, move.index, move.to) {
+		return false
+	}
+
+	// Undo the replacement since the move is only made if it's the best one
+	replicas[move.index] = from
+	return true
+}
+
+func (d *DiskRebalancer) tryReplacement(
+	curr []admin.PartitionAssignment,
+	partition int,
+	index int,
+	to int,
+) bool {
+	from := curr[partition].Replicas[index]
+	curr[partition].Replicas[index] = to
+
+	ok, err := assigners.EvaluateAssignments(curr, d.brokers, d.placementConfig)
+	if ok && err == nil {
+		return true
+	}
+
+	curr[partition].Replicas[index] = from
+	if err != nil {
+		log.Debugf("Error evaluating replacement: %+v", err)
+	}
+	return false
+}
+
+// overTolerance returns whether the argument broker, or the argument log dir in it, has more
+// bytes than allowed by the tolerance.
+func (d *DiskRebalancer) overTolerance(
+	usage *diskUsage,
+	brokerID int,
+	dir string,
+	meanBytes float64,
+) bool {
+	brokerBytes := usage.brokerBytes(brokerID)
+	if float64(brokerBytes)/d.weight(brokerID) > meanBytes*(1+d.tolerance) {
+		return true
+	}
+
+	numDirs := len(usage.dirBytes[brokerID])
+	if numDirs < 2 {
+		return false
+	}
+	meanDirBytes := float64(brokerBytes) / float64(numDirs)
+	return float64(usage.dirBytes[brokerID][dir]) > meanDirBytes*(1+d.tolerance)
+}
+
+// gain returns how much moving the argument replica reduces the imbalance.
+func (d *DiskRebalancer) gain(
+	usage *diskUsage,
+	partition int,
+	from int,
+	to int,
+	size int64,
+) float64 {
+	s := float64(size)
+	fromBytes := float64(usage.brokerBytes(from))
+	toBytes := float64(usage.brokerBytes(to))
+
+	brokerGain := s*(2*fromBytes-s)/d.weight(from) - s*(2*toBytes+s)/d.weight(to)
+
+	// Log dirs are scaled by the number in each broker so that an even split across the dirs
+	// counts the same as the broker itself
+	fromDirBytes := float64(usage.dirBytes[from][usage.replicaDirs[[2]int{partition, from}]])
+	toDirBytes := float64(usage.dirBytes[to][usage.targetDir(to)])
+	fromDirs := float64(len(usage.dirBytes[from]))
+	toDirs := float64(len(usage.dirBytes[to]))
+	if fromDirs == 0 {
+		fromDirs = 1
+	}
+	if toDirs == 0 {
+		toDirs = 1
+	}
+
+	dirGain := fromDirs*s*(2*fromDirBytes-s)/d.weight(from) -
+		toDirs*s*(2*toDirBytes+s)/d.weight(to)
+
+	return brokerGain + dirGain
+}
+
+func (d *DiskRebalancer) weighted(brokerID int, bytes int64) float64 {
+	return float64(bytes) / d.weight(brokerID)
+}
+
+func (d *DiskRebalancer) weight(brokerID int) float64 {
+	if weight, ok := d.brokerWeights[brokerID]; ok && weight > 0 {
+		return weight
+	}
+	return 1.0
+}
+
+func (u *diskUsage) brokerBytes(brokerID int) int64 {
+	var total int64
+	for _, bytes := range u.dirBytes[brokerID] {
+		total += bytes
+	}
+	return total
+}
+
+// targetDir returns the log dir that a new replica on the argument broker is assumed to go in.
+func (u *diskUsage) targetDir(brokerID int) string {
+	dirs := []string{}
+	for dir := range u.dirBytes[brokerID] {
+		dirs = append(dirs, dir)
+	}
+	if len(dirs) == 0 {
+		return ""
+	}
+	sort.Strings(dirs)
+
+	targetDir := dirs[0]
+	for _, dir := range dirs[1:] {
+		if u.dirBytes[brokerID][dir] < u.dirBytes[brokerID][targetDir] {
+			targetDir = dir
+		}
+	}
+	return targetDir
+}
+
+func (u *diskUsage) add(partition int, brokerID int, size int64) {
+	dir := u.targetDir(brokerID)
+	if u.dirBytes[brokerID] == nil {
+		u.dirBytes[brokerID] = map[string]int64{}
+	}
+	u.dirBytes[brokerID][dir] += size
+	u.replicaDirs[[2]int{partition, brokerID}] = dir
+}
+
+func (u *diskUsage) move(partition int, from int, to int, size int64) {
+	key := [2]int{partition, from}
+	u.dirBytes[from][u.replicaDirs[key]] -= size
+	delete(u.replicaDirs, key)
+	u.add(partition, to, size)
+}
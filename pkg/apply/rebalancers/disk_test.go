@@ -0,0 +1,159 @@
+package rebalancers
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+func TestDiskRebalancer(t *testing.T) {
+	type diskTestCase struct {
+		brokers         []admin.BrokerInfo
+		placementConfig config.TopicPlacementConfig
+		replicaSizes    []admin.ReplicaSize
+		testCase        rebalancerTestCase
+	}
+
+	anyPlacement := config.TopicPlacementConfig{
+		Strategy: config.PlacementStrategyAny,
+	}
+
+	testCases := []diskTestCase{
+		{
+			brokers:         testBrokers(3, 3),
+			placementConfig: anyPlacement,
+			replicaSizes: []admin.ReplicaSize{
+				{Topic: "test-topic", Partition: 0, Broker: 1, LogDir: "/data", Size: 100},
+				{Topic: "test-topic", Partition: 1, Broker: 1, LogDir: "/data", Size: 90},
+				{Topic: "other-topic", Partition: 0, Broker: 1, LogDir: "/data", Size: 1000},
+			},
+			testCase: rebalancerTestCase{
+				description: "Smaller partitions with similar effects moved first",
+				topic:       "test-topic",
+				curr: [][]int{
+					{1},
+					{1},
+				},
+				expected: [][]int{
+					{3},
+					{2},
+				},
+			},
+		},
+		{
+			brokers: []admin.BrokerInfo{
+				{ID: 1, Rack: "zone1"},
+				{ID: 2, Rack: "zone1"},
+				{ID: 3, Rack: "zone1"},
+				{ID: 4, Rack: "zone2"},
+				{ID: 5, Rack: "zone2"},
+				{ID: 6, Rack: "zone2"},
+			},
+			placementConfig: config.TopicPlacementConfig{
+				Strategy: config.PlacementStrategyInRack,
+			},
+			replicaSizes: []admin.ReplicaSize{
+				{Topic: "test-topic", Partition: 0, Broker: 1, LogDir: "/data", Size: 100},
+				{Topic: "test-topic", Partition: 0, Broker: 2, LogDir: "/data", Size: 100},
+				{Topic: "test-topic", Partition: 1, Broker: 1, LogDir: "/data", Size: 100},
+				{Topic: "test-topic", Partition: 1, Broker: 3, LogDir: "/data", Size: 100},
+				{Topic: "test-topic", Partition: 2, Broker: 4, LogDir: "/data", Size: 10},
+				{Topic: "test-topic", Partition: 2, Broker: 5, LogDir: "/data", Size: 10},
+			},
+			testCase: rebalancerTestCase{
+				description: "Placement strategy respected",
+				topic:       "test-topic",
+				curr: [][]int{
+					{1, 2},
+					{1, 3},
+					{4, 5},
+				},
+				expected: [][]int{
+					{1, 2},
+					{1, 3},
+					{4, 5},
+				},
+			},
+		},
+		{
+			brokers:         testBrokers(3, 3),
+			placementConfig: anyPlacement,
+			replicaSizes: []admin.ReplicaSize{
+				{Topic: "test-topic", Partition: 0, Broker: 1, LogDir: "/data", Size: 10},
+				{Topic: "test-topic", Partition: 1, Broker: 2, LogDir: "/data", Size: 20},
+				{Topic: "test-topic", Partition: 2, Broker: 3, LogDir: "/data", Size: 30},
+			},
+			testCase: rebalancerTestCase{
+				description: "Brokers removed",
+				topic:       "test-topic",
+				curr: [][]int{
+					{1},
+					{2},
+					{3},
+				},
+				toRemove: []int{3},
+				expected: [][]int{
+					{2},
+					{2},
+					{1},
+				},
+			},
+		},
+		{
+			brokers:         testBrokers(2, 2),
+			placementConfig: anyPlacement,
+			replicaSizes: []admin.ReplicaSize{
+				{Topic: "test-topic", Partition: 0, Broker: 1, LogDir: "/data1", Size: 100},
+				{Topic: "test-topic", Partition: 1, Broker: 1, LogDir: "/data2", Size: 100},
+				{Topic: "other-topic", Partition: 0, Broker: 1, LogDir: "/data2", Size: 100},
+			},
+			testCase: rebalancerTestCase{
+				description: "Replicas moved from fuller log dirs",
+				topic:       "test-topic",
+				curr: [][]int{
+					{1},
+					{1},
+				},
+				expected: [][]int{
+					{1},
+					{2},
+				},
+			},
+		},
+		{
+			brokers:         testBrokers(3, 3),
+			placementConfig: anyPlacement,
+			replicaSizes: []admin.ReplicaSize{
+				{Topic: "test-topic", Partition: 0, Broker: 1, LogDir: "/data", Size: 100},
+				{Topic: "test-topic", Partition: 1, Broker: 2, LogDir: "/data", Size: 95},
+				{Topic: "test-topic", Partition: 2, Broker: 3, LogDir: "/data", Size: 105},
+			},
+			testCase: rebalancerTestCase{
+				description: "Within tolerance",
+				topic:       "test-topic",
+				curr: [][]int{
+					{1},
+					{2},
+					{3},
+				},
+				expected: [][]int{
+					{1},
+					{2},
+					{3},
+				},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		rebalancer := NewDiskRebalancer(
+			testCase.brokers,
+			testCase.placementConfig,
+			testCase.replicaSizes,
+			nil,
+			DefaultDiskTolerance,
+		)
+		testCase.testCase.evaluate(t, rebalancer)
+	}
+}
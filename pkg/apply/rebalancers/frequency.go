@@ -2,6 +2,7 @@ package rebalancers
 
 import (
 	"fmt"
+	"math"
 	"sort"
 
 	"github.com/segmentio/topicctl/pkg/admin"
@@ -36,24 +37,31 @@ import (
 // The picker passed in to the rebalancer is used to sort the partitions for each broker (if it
 // appears more than once for the current index) and also to break ties when sorting and
 // partitioning the brokers.
+//
+// If broker weights are set, then the counts are divided by the weights before they're
+// compared so that brokers end up with replicas in proportion to their weights.
 type FrequencyRebalancer struct {
 	brokers         []admin.BrokerInfo
 	picker          pickers.Picker
 	placementConfig config.TopicPlacementConfig
+	brokerWeights   map[int]float64
 }
 
 var _ Rebalancer = (*FrequencyRebalancer)(nil)
 
-// NewFrequencyRebalancer creates a new FrequencyRebalancer instance.
+// NewFrequencyRebalancer creates a new FrequencyRebalancer instance. The broker weights can be
+// nil, in which case all brokers are weighted equally.
 func NewFrequencyRebalancer(
 	brokers []admin.BrokerInfo,
 	picker pickers.Picker,
 	placementConfig config.TopicPlacementConfig,
+	brokerWeights map[int]float64,
 ) *FrequencyRebalancer {
 	return &FrequencyRebalancer{
 		brokers:         brokers,
 		picker:          picker,
 		placementConfig: placementConfig,
+		brokerWeights:   brokerWeights,
 	}
 }
 
@@ -135,6 +143,7 @@ type brokerCount struct {
 	totalCount  int
 	partitions  []int
 	toBeRemoved bool
+	weight      float64
 
 	// score is used for breaking ties among brokers with the same count
 	score int
@@ -147,12 +156,36 @@ func (b brokerCount) isSmaller(other brokerCount) bool {
 	} else if b.toBeRemoved && !other.toBeRemoved {
 		return false
 	} else {
-		// Then, within each toBeRemoved partition, by the count for this index, followed by the
-		// total count for the topic, followed by the score
-		return b.indexCount < other.indexCount ||
-			(b.indexCount == other.indexCount && b.totalCount < other.totalCount) ||
-			(b.indexCount == other.indexCount && b.totalCount == other.totalCount && b.score < other.score)
+		// Then, within each toBeRemoved partition, by the weighted count for this index,
+		// followed by the weighted total count for the topic, followed by the score
+		bIndex, otherIndex := b.weighted(b.indexCount), other.weighted(other.indexCount)
+		bTotal, otherTotal := b.weighted(b.totalCount), other.weighted(other.totalCount)
+
+		return lessWeighted(bIndex, otherIndex) ||
+			(equalWeighted(bIndex, otherIndex) && lessWeighted(bTotal, otherTotal)) ||
+			(equalWeighted(bIndex, otherIndex) && equalWeighted(bTotal, otherTotal) &&
+				b.score < other.score)
+	}
+}
+
+// weighted returns the argument count divided by the broker's weight. An unset weight is
+// treated as 1.
+func (b brokerCount) weighted(count int) float64 {
+	if b.weight == 0 {
+		return float64(count)
 	}
+	return float64(count) / b.weight
+}
+
+// weightEpsilon is the tolerance used when comparing weighted counts.
+const weightEpsilon = 1e-9
+
+func lessWeighted(a float64, b float64) bool {
+	return a < b-weightEpsilon
+}
+
+func equalWeighted(a float64, b float64) bool {
+	return math.Abs(a-b) < weightEpsilon
 }
 
 func (f *FrequencyRebalancer) brokerCounts(
@@ -190,6 +223,7 @@ func (f *FrequencyRebalancer) brokerCounts(
 				totalCount:  allPositionBrokerCounts[broker.ID],
 				partitions:  indexPartitions[broker.ID],
 				toBeRemoved: toBeRemoved,
+				weight:      f.brokerWeights[broker.ID],
 				score:       f.picker.ScoreBroker(topic, broker.ID, 0, index),
 			},
 		)
@@ -228,12 +262,20 @@ func (f *FrequencyRebalancer) shouldTryReplace(
 	} else if lowerCount.toBeRemoved {
 		// Never try to insert a broker to be removed back into a partition
 		return false
-	} else if lowerCount.indexCount+1 < higherCount.indexCount {
+	}
+
+	lowerIndex := lowerCount.weighted(lowerCount.indexCount + 1)
+	higherIndex := higherCount.weighted(higherCount.indexCount)
+
+	if lessWeighted(lowerIndex, higherIndex) {
 		// Doing this replacement will strictly improve the in-index balance
 		return true
-	} else if lowerCount.indexCount+1 == higherCount.indexCount {
+	} else if equalWeighted(lowerIndex, higherIndex) {
 		// Doing this placement is neutral from an in-index perspective, look at the total topic
-		if lowerCount.totalCount+1 < higherCount.totalCount {
+		if lessWeighted(
+			lowerCount.weighted(lowerCount.totalCount+1),
+			higherCount.weighted(higherCount.totalCount),
+		) {
 			return true
 		}
 	}
@@ -17,6 +17,7 @@ func TestFrequencyRebalancerAnyLowestIndex(t *testing.T) {
 		config.TopicPlacementConfig{
 			Strategy: config.PlacementStrategyAny,
 		},
+		nil,
 	)
 
 	testCases := []rebalancerTestCase{
@@ -131,6 +132,7 @@ func TestFrequencyRebalancerAnyRandomized(t *testing.T) {
 		config.TopicPlacementConfig{
 			Strategy: config.PlacementStrategyAny,
 		},
+		nil,
 	)
 
 	testCases := []rebalancerTestCase{
@@ -191,6 +193,7 @@ func TestFrequencyRebalancerAnyInRack(t *testing.T) {
 		config.TopicPlacementConfig{
 			Strategy: config.PlacementStrategyInRack,
 		},
+		nil,
 	)
 
 	testCases := []rebalancerTestCase{
@@ -220,6 +223,69 @@ func TestFrequencyRebalancerAnyInRack(t *testing.T) {
 	}
 }
 
+func TestFrequencyRebalancerAnyWeighted(t *testing.T) {
+	brokers := testBrokers(6, 3)
+	rebalancer := NewFrequencyRebalancer(
+		brokers,
+		pickers.NewLowestIndexPicker(),
+		config.TopicPlacementConfig{
+			Strategy: config.PlacementStrategyAny,
+		},
+		map[int]float64{1: 2.0, 2: 0.5},
+	)
+
+	testCases := []rebalancerTestCase{
+		{
+			description: "Single replica",
+			// Broker 2 has half the weight of the default, so even a single replica is more load
+			// than broker 1, which has twice the weight, gets with three
+			curr: [][]int{
+				{1},
+				{2},
+				{3},
+				{4},
+				{5},
+				{6},
+				{2},
+			},
+			expected: [][]int{
+				{1},
+				{1},
+				{3},
+				{4},
+				{5},
+				{6},
+				{1},
+			},
+		},
+		{
+			description: "Multiple replicas",
+			curr: [][]int{
+				{1, 2},
+				{2, 3},
+				{3, 4},
+				{4, 5},
+				{5, 6},
+				{6, 1},
+				{2, 3},
+			},
+			expected: [][]int{
+				{1, 2},
+				{1, 3},
+				{3, 4},
+				{4, 5},
+				{5, 6},
+				{6, 1},
+				{1, 3},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		testCase.evaluate(t, rebalancer)
+	}
+}
+
 func TestBrokerCounts(t *testing.T) {
 	brokers := testBrokers(6, 3)
 	rebalancer := NewFrequencyRebalancer(
@@ -228,6 +294,7 @@ func TestBrokerCounts(t *testing.T) {
 		config.TopicPlacementConfig{
 			Strategy: config.PlacementStrategyInRack,
 		},
+		nil,
 	)
 
 	brokerCounts := rebalancer.brokerCounts(
@@ -448,6 +515,7 @@ func TestBrokerCountShouldReplace(t *testing.T) {
 		config.TopicPlacementConfig{
 			Strategy: config.PlacementStrategyAny,
 		},
+		nil,
 	)
 
 	assert.False(t, rebalancer.shouldTryReplace(brokerCount1, brokerCount2))
@@ -456,3 +524,40 @@ func TestBrokerCountShouldReplace(t *testing.T) {
 	assert.False(t, rebalancer.shouldTryReplace(brokerCount3, brokerCount4))
 	assert.True(t, rebalancer.shouldTryReplace(brokerCount4, brokerCount5))
 }
+
+func TestBrokerCountShouldReplaceWeighted(t *testing.T) {
+	brokerCount1 := brokerCount{
+		brokerID:   1,
+		indexCount: 3,
+		totalCount: 6,
+		weight:     2.0,
+	}
+	brokerCount2 := brokerCount{
+		brokerID:   2,
+		indexCount: 3,
+		totalCount: 6,
+	}
+	brokerCount3 := brokerCount{
+		brokerID:   3,
+		indexCount: 2,
+		totalCount: 4,
+		weight:     0.5,
+	}
+
+	brokers := testBrokers(12, 3)
+	rebalancer := NewFrequencyRebalancer(
+		brokers,
+		pickers.NewLowestIndexPicker(),
+		config.TopicPlacementConfig{
+			Strategy: config.PlacementStrategyAny,
+		},
+		nil,
+	)
+
+	assert.True(t, brokerCount1.isSmaller(brokerCount2))
+	assert.True(t, brokerCount2.isSmaller(brokerCount3))
+	assert.True(t, rebalancer.shouldTryReplace(brokerCount1, brokerCount2))
+	assert.True(t, rebalancer.shouldTryReplace(brokerCount1, brokerCount3))
+	assert.False(t, rebalancer.shouldTryReplace(brokerCount2, brokerCount1))
+	assert.False(t, rebalancer.shouldTryReplace(brokerCount3, brokerCount2))
+}
@@ -0,0 +1,102 @@
+package apply
+
+import (
+	"fmt"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// RollbackTopicConfig returns a topic config that, when applied with a TopicApplier, restores
+// the settings and replica assignments recorded in the argument revision. The assignments are
+// expressed as static placements so that the reverse migration goes through the usual
+// throttled, batched path, and retention is restored as a setting so that the retention drop
+// safety checks still apply.
+//
+// Partitions can't be removed, so partitions that were added after the revision keep their
+// current replicas.
+func RollbackTopicConfig(
+	clusterConfig config.ClusterConfig,
+	revision TopicRevision,
+	topicInfo admin.TopicInfo,
+) (config.TopicConfig, error) {
+	if revision.Topic != topicInfo.Name {
+		return config.TopicConfig{}, fmt.Errorf(
+			"Revision is for topic %s, not %s",
+			revision.Topic,
+			topicInfo.Name,
+		)
+	}
+	if revision.Cluster != clusterConfig.Meta.Name ||
+		revision.Environment != clusterConfig.Meta.Environment ||
+		revision.Region != clusterConfig.Meta.Region {
+		return config.TopicConfig{}, fmt.Errorf(
+			"Revision cluster (%s/%s/%s) does not match cluster config (%s/%s/%s)",
+			revision.Cluster,
+			revision.Environment,
+			revision.Region,
+			clusterConfig.Meta.Name,
+			clusterConfig.Meta.Environment,
+			clusterConfig.Meta.Region,
+		)
+	}
+	if len(revision.Assignments) == 0 {
+		return config.TopicConfig{}, fmt.Errorf(
+			"Revision %d has no replica assignments",
+			revision.Revision,
+		)
+	}
+
+	replicationFactor := len(revision.Assignments[0].Replicas)
+	staticAssignments := [][]int{}
+
+	for _, assignment := range revision.Assignments {
+		staticAssignments = append(staticAssignments, assignment.Replicas)
+	}
+
+	currAssignments := topicInfo.ToAssignments()
+
+	for p := len(revision.Assignments); p < len(currAssignments); p++ {
+		if len(currAssignments[p].Replicas) != replicationFactor {
+			return config.TopicConfig{}, fmt.Errorf(
+				"Partition %d was added after revision %d with a different replication factor (%d vs. %d); cannot roll back",
+				p,
+				revision.Revision,
+				len(currAssignments[p].Replicas),
+				replicationFactor,
+			)
+		}
+		log.Warnf(
+			"Partition %d was added after revision %d; keeping its current replicas %+v",
+			p,
+			revision.Revision,
+			currAssignments[p].Replicas,
+		)
+		staticAssignments = append(staticAssignments, currAssignments[p].Replicas)
+	}
+
+	topicConfig := config.TopicConfig{
+		Meta: config.ResourceMeta{
+			Name:        revision.Topic,
+			Cluster:     clusterConfig.Meta.Name,
+			Region:      clusterConfig.Meta.Region,
+			Environment: clusterConfig.Meta.Environment,
+			Description: fmt.Sprintf("Rollback to revision %d", revision.Revision),
+		},
+		Spec: config.TopicSpec{
+			Partitions:        len(staticAssignments),
+			ReplicationFactor: replicationFactor,
+			PlacementConfig: config.TopicPlacementConfig{
+				Strategy:          config.PlacementStrategyStatic,
+				StaticAssignments: staticAssignments,
+			},
+		},
+	}
+	topicConfig.Spec.Settings = config.FromConfigMap(revision.Config)
+	delete(topicConfig.Spec.Settings, admin.LeaderReplicasThrottledKey)
+	delete(topicConfig.Spec.Settings, admin.FollowerReplicasThrottledKey)
+	topicConfig.SetDefaults()
+
+	return topicConfig, nil
+}
@@ -0,0 +1,201 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollbackTopicConfig(t *testing.T) {
+	clusterConfig := config.ClusterConfig{
+		Meta: config.ClusterMeta{
+			Name:        "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+	}
+	revision := TopicRevision{
+		Version:     TopicRevisionVersion,
+		Revision:    2,
+		Cluster:     "test-cluster",
+		Environment: "test-environment",
+		Region:      "test-region",
+		Topic:       "test-topic",
+		Config: map[string]string{
+			"retention.ms":   "3600000",
+			"cleanup.policy": "delete",
+		},
+		Assignments: []admin.PartitionAssignment{
+			{
+				ID:       0,
+				Replicas: []int{1, 2},
+			},
+			{
+				ID:       1,
+				Replicas: []int{2, 3},
+			},
+		},
+	}
+
+	type testCase struct {
+		description    string
+		revision       TopicRevision
+		topicInfo      admin.TopicInfo
+		expectedConfig config.TopicConfig
+		expectedErr    bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "Same partitions",
+			revision:    revision,
+			topicInfo: admin.TopicInfo{
+				Name: "test-topic",
+				Partitions: []admin.PartitionInfo{
+					{
+						ID:       0,
+						Replicas: []int{3, 4, 1},
+					},
+					{
+						ID:       1,
+						Replicas: []int{4, 1, 2},
+					},
+				},
+			},
+			expectedConfig: config.TopicConfig{
+				Meta: config.ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Rollback to revision 2",
+				},
+				Spec: config.TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 2,
+					Settings: config.TopicSettings{
+						"retention.ms":   "3600000",
+						"cleanup.policy": "delete",
+					},
+					PlacementConfig: config.TopicPlacementConfig{
+						Strategy: config.PlacementStrategyStatic,
+						Picker:   config.PickerMethodRandomized,
+						StaticAssignments: [][]int{
+							{1, 2},
+							{2, 3},
+						},
+					},
+					MigrationConfig: &config.TopicMigrationConfig{
+						PartitionBatchSize: 1,
+					},
+				},
+			},
+		},
+		{
+			description: "Partitions added after revision",
+			revision:    revision,
+			topicInfo: admin.TopicInfo{
+				Name: "test-topic",
+				Partitions: []admin.PartitionInfo{
+					{
+						ID:       0,
+						Replicas: []int{3, 4},
+					},
+					{
+						ID:       1,
+						Replicas: []int{4, 1},
+					},
+					{
+						ID:       2,
+						Replicas: []int{1, 4},
+					},
+				},
+			},
+			expectedConfig: config.TopicConfig{
+				Meta: config.ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Rollback to revision 2",
+				},
+				Spec: config.TopicSpec{
+					Partitions:        3,
+					ReplicationFactor: 2,
+					Settings: config.TopicSettings{
+						"retention.ms":   "3600000",
+						"cleanup.policy": "delete",
+					},
+					PlacementConfig: config.TopicPlacementConfig{
+						Strategy: config.PlacementStrategyStatic,
+						Picker:   config.PickerMethodRandomized,
+						StaticAssignments: [][]int{
+							{1, 2},
+							{2, 3},
+							{1, 4},
+						},
+					},
+					MigrationConfig: &config.TopicMigrationConfig{
+						PartitionBatchSize: 1,
+					},
+				},
+			},
+		},
+		{
+			description: "Added partitions with different replication",
+			revision:    revision,
+			topicInfo: admin.TopicInfo{
+				Name: "test-topic",
+				Partitions: []admin.PartitionInfo{
+					{
+						ID:       0,
+						Replicas: []int{3, 4, 1},
+					},
+					{
+						ID:       1,
+						Replicas: []int{4, 1, 2},
+					},
+					{
+						ID:       2,
+						Replicas: []int{1, 4, 2},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			description: "Wrong topic",
+			revision:    revision,
+			topicInfo: admin.TopicInfo{
+				Name: "other-topic",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		topicConfig, err := RollbackTopicConfig(
+			clusterConfig,
+			testCase.revision,
+			testCase.topicInfo,
+		)
+		if testCase.expectedErr {
+			assert.Error(t, err, testCase.description)
+		} else {
+			require.NoError(t, err, testCase.description)
+			assert.Equal(t, testCase.expectedConfig, topicConfig, testCase.description)
+		}
+	}
+
+	otherClusterConfig := clusterConfig
+	otherClusterConfig.Meta.Name = "other-cluster"
+	_, err := RollbackTopicConfig(
+		otherClusterConfig,
+		revision,
+		testCases[0].topicInfo,
+	)
+	assert.Error(t, err)
+}
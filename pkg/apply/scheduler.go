@@ -0,0 +1,424 @@
+package apply
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrationSchedulerConfig contains the configuration for a MigrationScheduler.
+type MigrationSchedulerConfig struct {
+	ClusterConfig config.ClusterConfig
+	DryRun        bool
+
+	// Parallelism is the maximum number of topics that are migrated at once.
+	Parallelism int
+
+	// MaxMovesPerBroker is the maximum number of partitions that can be moving to or from
+	// each broker at once. If zero, then there is no limit.
+	MaxMovesPerBroker int
+
+	// ThrottleBytes is the replication throttle for each broker. Since broker throttles
+	// apply to all throttled replicas on a broker, this budget is shared by all of the
+	// migrations that involve the broker.
+	ThrottleBytes int64
+
+	// Events, if set, receives events when broker throttles are applied or removed.
+	Events *EventWriter
+}
+
+// MigrationScheduler runs placement migrations for multiple topics in the same cluster
+// concurrently. The batches of each migration only start once all of the brokers involved
+// have capacity for more partition moves, so a busy broker doesn't hold up migrations on
+// unrelated brokers. Broker throttles are reference-counted across migrations so that one
+// migration finishing doesn't remove the throttles that another one is relying on.
+type MigrationScheduler struct {
+	config      MigrationSchedulerConfig
+	adminClient admin.Client
+
+	mutex sync.Mutex
+
+	// changed is closed (and replaced) whenever capacity is released
+	changed chan struct{}
+
+	// moves is the number of in-progress partition moves for each broker
+	moves map[int]int
+
+	// throttleRefs is the number of in-progress batches using the throttle on each broker
+	throttleRefs map[int]int
+
+	// ownedThrottles are the brokers whose throttles were set by this scheduler
+	ownedThrottles map[int]bool
+}
+
+// NewMigrationScheduler creates and returns a new MigrationScheduler instance.
+func NewMigrationScheduler(
+	adminClient admin.Client,
+	schedulerConfig MigrationSchedulerConfig,
+) *MigrationScheduler {
+	if schedulerConfig.Parallelism <= 0 {
+		schedulerConfig.Parallelism = 1
+	}
+
+	return &MigrationScheduler{
+		config:         schedulerConfig,
+		adminClient:    adminClient,
+		changed:        make(chan struct{}),
+		moves:          map[int]int{},
+		throttleRefs:   map[int]int{},
+		ownedThrottles: map[int]bool{},
+	}
+}
+
+// Run runs each of the argument migration functions, at most Parallelism at a time, and
+// waits for all of them to finish. The cluster lock is held for the duration of the run,
+// so the topic appliers run by the migration functions should be configured with this
+// scheduler to avoid trying to acquire it again. The returned errors are in the same order
+// as the argument functions.
+func (s *MigrationScheduler) Run(
+	ctx context.Context,
+	migrations []func(ctx context.Context) error,
+) ([]error, error) {
+	if !s.config.DryRun && s.config.ClusterConfig.Spec.ZKLockPath != "" {
+		lockPath := clusterLockPath(
+			s.config.ClusterConfig.Spec.ZKLockPath,
+			s.config.ClusterConfig.Meta.Name,
+			s.config.ClusterConfig.Meta.Environment,
+			s.config.ClusterConfig.Meta.Region,
+		)
+		log.Infof("Acquiring cluster lock: %s", lockPath)
+		lockCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		lock, err := s.adminClient.AcquireLock(lockCtx, lockPath)
+		if err != nil {
+			return nil, err
+		}
+		if lock != nil {
+			defer func() {
+				log.Infof("Releasing cluster lock: %s", lockPath)
+				lock.Unlock()
+			}()
+		}
+	}
+
+	// Remove any throttles that were left behind by failed migrations before the lock is
+	// released
+	defer s.removeLeftoverThrottles(ctx)
+
+	log.Infof(
+		"Running %d migration(s) with parallelism %d, max moves per broker %d, and broker throttle %d MB/sec",
+		len(migrations),
+		s.config.Parallelism,
+		s.config.MaxMovesPerBroker,
+		s.config.ThrottleBytes/1000000,
+	)
+
+	errs := make([]error, len(migrations))
+	semaphore := make(chan struct{}, s.config.Parallelism)
+	wg := sync.WaitGroup{}
+
+	for m, migration := range migrations {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return errs, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(index int, migration func(ctx context.Context) error) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			errs[index] = migration(ctx)
+		}(m, migration)
+	}
+
+	wg.Wait()
+	return errs, nil
+}
+
+// BrokerMoves returns the number of partitions moving to or from each broker when updating
+// from the current to the desired assignments.
+func BrokerMoves(
+	curr []admin.PartitionAssignment,
+	desired []admin.PartitionAssignment,
+) map[int]int {
+	brokerPartitions := map[int]map[int]struct{}{}
+
+	addThrottles := func(throttles []admin.PartitionThrottle) {
+		for _, throttle := range throttles {
+			if _, ok := brokerPartitions[throttle.Broker]; !ok {
+				brokerPartitions[throttle.Broker] = map[int]struct{}{}
+			}
+			brokerPartitions[throttle.Broker][throttle.Partition] = struct{}{}
+		}
+	}
+	addThrottles(admin.LeaderPartitionThrottles(curr, desired))
+	addThrottles(admin.FollowerPartitionThrottles(curr, desired))
+
+	moves := map[int]int{}
+	for broker, partitions := range brokerPartitions {
+		moves[broker] = len(partitions)
+	}
+	return moves
+}
+
+// acquireMoves blocks until all of the brokers in the argument moves have capacity for them.
+// A broker with no in-progress moves always has capacity, so batches that are larger than the
+// limit can still run. The returned function releases the moves.
+func (s *MigrationScheduler) acquireMoves(
+	ctx context.Context,
+	topic string,
+	moves map[int]int,
+) (func(), error) {
+	logged := false
+
+	for {
+		s.mutex.Lock()
+		busyBrokers := s.busyBrokers(moves)
+		if len(busyBrokers) == 0 {
+			for broker, count := range moves {
+				s.moves[broker] += count
+			}
+			s.mutex.Unlock()
+
+			return func() {
+				s.mutex.Lock()
+				defer s.mutex.Unlock()
+
+				for broker, count := range moves {
+					s.moves[broker] -= count
+				}
+				s.notify()
+			}, nil
+		}
+		changed := s.changed
+		s.mutex.Unlock()
+
+		if !logged {
+			log.Infof(
+				"Waiting for capacity on broker(s) %+v before moving partitions in topic %s",
+				busyBrokers,
+				topic,
+			)
+			logged = true
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// busyBrokers returns the brokers that don't have capacity for the argument moves. It must
+// be called with the mutex held.
+func (s *MigrationScheduler) busyBrokers(moves map[int]int) []int {
+	busyBrokers := []int{}
+
+	if s.config.MaxMovesPerBroker <= 0 {
+		return busyBrokers
+	}
+
+	for broker, count := range moves {
+		curr := s.moves[broker]
+		if curr > 0 && curr+count > s.config.MaxMovesPerBroker {
+			busyBrokers = append(busyBrokers, broker)
+		}
+	}
+
+	sort.Ints(busyBrokers)
+	return busyBrokers
+}
+
+func (s *MigrationScheduler) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// throttleBrokers applies the shared throttle to each of the argument brokers that isn't
+// already throttled by another migration. If one of the brokers can't be throttled, then the
+// brokers throttled so far are released again.
+func (s *MigrationScheduler) throttleBrokers(ctx context.Context, brokers []int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	throttled := []int{}
+
+	for _, broker := range brokers {
+		if s.throttleRefs[broker] == 0 {
+			brokerThrottle := admin.BrokerThrottle{
+				Broker:        broker,
+				ThrottleBytes: s.config.ThrottleBytes,
+			}
+			log.Debugf("Applying throttle to broker %d", broker)
+			updatedKeys, err := s.adminClient.UpdateBrokerConfig(
+				ctx,
+				broker,
+				brokerThrottle.ConfigEntries(),
+				false,
+			)
+			if err != nil {
+				if releaseErr := s.releaseBrokers(ctx, throttled); releaseErr != nil {
+					log.Warnf("Error releasing broker throttles: %+v", releaseErr)
+				}
+				return err
+			}
+			if len(updatedKeys) > 0 {
+				s.ownedThrottles[broker] = true
+				s.emitThrottleEvent(EventTypeThrottleApplied, broker)
+			}
+		}
+		s.throttleRefs[broker]++
+		throttled = append(throttled, broker)
+	}
+
+	return nil
+}
+
+// unthrottleBrokers removes the throttles on each of the argument brokers that are no longer
+// in use by any migration.
+func (s *MigrationScheduler) unthrottleBrokers(ctx context.Context, brokers []int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.releaseBrokers(ctx, brokers)
+}
+
+// releaseBrokers decrements the throttle reference counts of the argument brokers and removes
+// the throttles that this scheduler set on the brokers that are no longer in use. It must be
+// called with the mutex held.
+func (s *MigrationScheduler) releaseBrokers(ctx context.Context, brokers []int) error {
+	var err error
+
+	for _, broker := range brokers {
+		s.throttleRefs[broker]--
+		if s.throttleRefs[broker] > 0 {
+			continue
+		}
+		delete(s.throttleRefs, broker)
+
+		if !s.ownedThrottles[broker] {
+			continue
+		}
+		if brokerErr := s.removeBrokerThrottle(ctx, broker); brokerErr != nil {
+			err = multierror.Append(err, brokerErr)
+		}
+	}
+
+	return err
+}
+
+// removeUnusedThrottles removes the throttles on each of the argument brokers that aren't
+// in use by any migration, regardless of who set them.
+func (s *MigrationScheduler) removeUnusedThrottles(ctx context.Context, brokers []int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var err error
+
+	for _, broker := range brokers {
+		if s.throttleRefs[broker] > 0 {
+			continue
+		}
+		if brokerErr := s.removeBrokerThrottle(ctx, broker); brokerErr != nil {
+			err = multierror.Append(err, brokerErr)
+		}
+	}
+
+	return err
+}
+
+// removeLeftoverThrottles removes the throttles that this scheduler set and that are still
+// in place, e.g. because removing them failed when their migrations finished.
+func (s *MigrationScheduler) removeLeftoverThrottles(ctx context.Context) {
+	s.mutex.Lock()
+	leftover := []int{}
+	for broker := range s.ownedThrottles {
+		leftover = append(leftover, broker)
+	}
+	s.mutex.Unlock()
+
+	if len(leftover) == 0 {
+		return
+	}
+	sort.Ints(leftover)
+
+	log.Infof("Removing leftover throttles from brokers %+v", leftover)
+	if err := s.removeUnusedThrottles(ctx, leftover); err != nil {
+		log.Warnf("Error removing leftover broker throttles: %+v", err)
+	}
+}
+
+// removeBrokerThrottle removes the throttle on the argument broker. It must be called with
+// the mutex held.
+func (s *MigrationScheduler) removeBrokerThrottle(ctx context.Context, broker int) error {
+	log.Debugf("Removing throttle from broker %d", broker)
+	_, err := s.adminClient.UpdateBrokerConfig(
+		ctx,
+		broker,
+		[]kafka.ConfigEntry{
+			{
+				ConfigName:  admin.LeaderThrottledKey,
+				ConfigValue: "",
+			},
+			{
+				ConfigName:  admin.FollowerThrottledKey,
+				ConfigValue: "",
+			},
+		},
+		true,
+	)
+	if err != nil {
+		log.Warnf(
+			"Error removing throttle for broker %d: %+v",
+			broker,
+			err,
+		)
+		return err
+	}
+
+	delete(s.ownedThrottles, broker)
+	s.emitThrottleEvent(EventTypeThrottleRemoved, broker)
+	return nil
+}
+
+func (s *MigrationScheduler) emitThrottleEvent(eventType EventType, broker int) {
+	event := Event{
+		Type:        eventType,
+		Cluster:     s.config.ClusterConfig.Meta.Name,
+		Environment: s.config.ClusterConfig.Meta.Environment,
+		Region:      s.config.ClusterConfig.Meta.Region,
+		Brokers:     []int{broker},
+	}
+	if eventType == EventTypeThrottleApplied {
+		event.ThrottleBytes = s.config.ThrottleBytes
+	}
+	s.config.Events.Emit(event)
+}
+
+// unusedBrokers returns the argument brokers that aren't throttled by any in-progress
+// migration.
+func (s *MigrationScheduler) unusedBrokers(brokers []int) []int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	unused := []int{}
+	for _, broker := range brokers {
+		if s.throttleRefs[broker] == 0 {
+			unused = append(unused, broker)
+		}
+	}
+	return unused
+}
@@ -0,0 +1,262 @@
+package apply
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBrokerMoves(t *testing.T) {
+	curr := []admin.PartitionAssignment{
+		{ID: 0, Replicas: []int{1, 2}},
+		{ID: 1, Replicas: []int{2, 3}},
+		{ID: 2, Replicas: []int{3, 4}},
+	}
+	desired := []admin.PartitionAssignment{
+		{ID: 0, Replicas: []int{1, 5}},
+		{ID: 1, Replicas: []int{3, 2}},
+		{ID: 2, Replicas: []int{3, 5}},
+	}
+
+	// Partition 1 is just reordered, so it doesn't count as a move
+	assert.Equal(
+		t,
+		map[int]int{
+			1: 1,
+			2: 1,
+			3: 1,
+			4: 1,
+			5: 2,
+		},
+		BrokerMoves(curr, desired),
+	)
+}
+
+func TestMigrationSchedulerAcquireMoves(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	scheduler := NewMigrationScheduler(
+		nil,
+		MigrationSchedulerConfig{
+			Parallelism:       2,
+			MaxMovesPerBroker: 2,
+		},
+	)
+
+	// Batches larger than the limit can run if the broker is otherwise idle
+	release1, err := scheduler.acquireMoves(ctx, "topic1", map[int]int{1: 3, 2: 1})
+	require.NoError(t, err)
+
+	// Unrelated brokers aren't blocked
+	release2, err := scheduler.acquireMoves(ctx, "topic2", map[int]int{3: 2})
+	require.NoError(t, err)
+	release2()
+
+	release3, err := scheduler.acquireMoves(ctx, "topic3", map[int]int{2: 1})
+	require.NoError(t, err)
+
+	acquired := make(chan struct{})
+	go func() {
+		release4, err := scheduler.acquireMoves(ctx, "topic4", map[int]int{1: 1, 3: 1})
+		if err == nil {
+			close(acquired)
+			release4()
+		}
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Moves acquired before broker 1 had capacity")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	release1()
+
+	select {
+	case <-acquired:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Moves not acquired after broker 1 had capacity")
+	}
+	release3()
+
+	// Waiting is interrupted when the context is cancelled
+	release5, err := scheduler.acquireMoves(ctx, "topic5", map[int]int{1: 2})
+	require.NoError(t, err)
+	defer release5()
+
+	cancelCtx, cancelFunc := context.WithCancel(ctx)
+	cancelFunc()
+	_, err = scheduler.acquireMoves(cancelCtx, "topic6", map[int]int{1: 1})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestMigrationSchedulerThrottles(t *testing.T) {
+	ctx := context.Background()
+	client := &throttleRecordingClient{
+		brokerConfigs: map[int]map[string]string{
+			1: {},
+			2: {},
+			3: {
+				admin.LeaderThrottledKey:   "5000",
+				admin.FollowerThrottledKey: "5000",
+			},
+		},
+	}
+
+	scheduler := NewMigrationScheduler(
+		client,
+		MigrationSchedulerConfig{
+			Parallelism:   2,
+			ThrottleBytes: 1000,
+		},
+	)
+
+	require.NoError(t, scheduler.throttleBrokers(ctx, []int{1, 2, 3}))
+	require.NoError(t, scheduler.throttleBrokers(ctx, []int{2}))
+	assert.Equal(t, "1000", client.brokerConfigs[1][admin.LeaderThrottledKey])
+	assert.Equal(t, "1000", client.brokerConfigs[2][admin.LeaderThrottledKey])
+
+	// Existing throttles aren't overwritten
+	assert.Equal(t, "5000", client.brokerConfigs[3][admin.LeaderThrottledKey])
+	assert.Equal(t, []int{}, scheduler.unusedBrokers([]int{1, 2, 3}))
+
+	require.NoError(t, scheduler.unthrottleBrokers(ctx, []int{1, 2, 3}))
+
+	// Broker 2 is still being used by another migration
+	assert.Equal(t, "", client.brokerConfigs[1][admin.LeaderThrottledKey])
+	assert.Equal(t, "1000", client.brokerConfigs[2][admin.LeaderThrottledKey])
+	assert.Equal(t, "5000", client.brokerConfigs[3][admin.LeaderThrottledKey])
+	assert.Equal(t, []int{1, 3}, scheduler.unusedBrokers([]int{1, 2, 3}))
+
+	require.NoError(t, scheduler.unthrottleBrokers(ctx, []int{2}))
+	assert.Equal(t, "", client.brokerConfigs[2][admin.LeaderThrottledKey])
+}
+
+func TestMigrationSchedulerThrottleErrors(t *testing.T) {
+	ctx := context.Background()
+	client := &throttleRecordingClient{
+		brokerConfigs: map[int]map[string]string{
+			1: {},
+			2: {},
+			3: {},
+		},
+		failBrokers: map[int]bool{
+			3: true,
+		},
+	}
+
+	scheduler := NewMigrationScheduler(
+		client,
+		MigrationSchedulerConfig{
+			Parallelism:   2,
+			ThrottleBytes: 1000,
+		},
+	)
+
+	// The brokers throttled before the failure are released again
+	require.NoError(t, scheduler.throttleBrokers(ctx, []int{2}))
+	require.Error(t, scheduler.throttleBrokers(ctx, []int{1, 2, 3}))
+	assert.Equal(t, "", client.brokerConfigs[1][admin.LeaderThrottledKey])
+	assert.Equal(t, "1000", client.brokerConfigs[2][admin.LeaderThrottledKey])
+	assert.Equal(t, []int{1, 3}, scheduler.unusedBrokers([]int{1, 2, 3}))
+
+	// Unused throttles are removed regardless of who set them
+	client.failBrokers = map[int]bool{}
+	client.brokerConfigs[3][admin.LeaderThrottledKey] = "5000"
+	require.NoError(t, scheduler.removeUnusedThrottles(ctx, []int{2, 3}))
+	assert.Equal(t, "1000", client.brokerConfigs[2][admin.LeaderThrottledKey])
+	assert.Equal(t, "", client.brokerConfigs[3][admin.LeaderThrottledKey])
+
+	// Throttles that can't be removed when their migrations finish are removed at the end
+	// of the run
+	client.failBrokers = map[int]bool{2: true}
+	require.Error(t, scheduler.unthrottleBrokers(ctx, []int{2}))
+	assert.Equal(t, []int{2}, scheduler.unusedBrokers([]int{2}))
+	assert.Equal(t, "1000", client.brokerConfigs[2][admin.LeaderThrottledKey])
+
+	client.failBrokers = map[int]bool{}
+	_, err := scheduler.Run(ctx, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", client.brokerConfigs[2][admin.LeaderThrottledKey])
+	assert.Equal(t, map[int]bool{}, scheduler.ownedThrottles)
+}
+
+func TestMigrationSchedulerRun(t *testing.T) {
+	ctx := context.Background()
+
+	scheduler := NewMigrationScheduler(
+		nil,
+		MigrationSchedulerConfig{
+			DryRun:      true,
+			Parallelism: 2,
+		},
+	)
+
+	var running int32
+	var maxRunning int32
+	mutex := sync.Mutex{}
+
+	migrations := []func(ctx context.Context) error{}
+	for m := 0; m < 5; m++ {
+		m := m
+		migrations = append(migrations, func(ctx context.Context) error {
+			curr := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+
+			mutex.Lock()
+			if curr > maxRunning {
+				maxRunning = curr
+			}
+			mutex.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+			if m == 3 {
+				return errors.New("test error")
+			}
+			return nil
+		})
+	}
+
+	errs, err := scheduler.Run(ctx, migrations)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), maxRunning)
+	assert.Equal(t, 5, len(errs))
+	for e, err := range errs {
+		if e == 3 {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+// throttleRecordingClient is an admin.Client that only supports updating broker configs.
+type throttleRecordingClient struct {
+	admin.Client
+
+	brokerConfigs map[int]map[string]string
+
+	// failBrokers are the brokers whose config updates fail
+	failBrokers map[int]bool
+}
+
+func (c *throttleRecordingClient) UpdateBrokerConfig(
+	ctx context.Context,
+	id int,
+	configEntries []kafka.ConfigEntry,
+	overwrite bool,
+) ([]string, error) {
+	if c.failBrokers[id] {
+		return nil, errors.New("test error")
+	}
+	return updateConfigMap(c.brokerConfigs[id], configEntries, overwrite), nil
+}
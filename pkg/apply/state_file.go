@@ -0,0 +1,48 @@
+package apply
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// writeJSONFileAtomic writes the argument value as indented JSON to the argument path,
+// creating the parent directories if needed. The contents are written to a temp file first
+// and then renamed so that an interruption can't leave a partial file behind.
+func writeJSONFileAtomic(path string, value interface{}) error {
+	contents, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, append(contents, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, path)
+}
+
+// readJSONFile reads the JSON file at the argument path into the argument value. It returns
+// false if the file doesn't exist.
+func readJSONFile(path string, value interface{}) (bool, error) {
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, json.Unmarshal(contents, value)
+}
+
+// removeFile removes the file at the argument path. It's a no-op if the file doesn't exist.
+func removeFile(path string) error {
+	err := os.Remove(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONFile(t *testing.T) {
+	type testValue struct {
+		Name   string `json:"name"`
+		Values []int  `json:"values"`
+	}
+
+	path := filepath.Join(t.TempDir(), "subdir", "value.json")
+
+	value := testValue{}
+	found, err := readJSONFile(path, &value)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Parent directories are created, and the temp file is renamed into place
+	require.NoError(
+		t,
+		writeJSONFileAtomic(path, testValue{Name: "test", Values: []int{1, 2}}),
+	)
+	_, err = os.Stat(path + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	found, err = readJSONFile(path, &value)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, testValue{Name: "test", Values: []int{1, 2}}, value)
+
+	// Existing files are replaced
+	require.NoError(t, writeJSONFileAtomic(path, testValue{Name: "test2"}))
+	value = testValue{}
+	_, err = readJSONFile(path, &value)
+	require.NoError(t, err)
+	assert.Equal(t, testValue{Name: "test2"}, value)
+
+	require.NoError(t, removeFile(path))
+	found, err = readJSONFile(path, &value)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Removing a missing file is a no-op
+	require.NoError(t, removeFile(path))
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0644))
+	_, err = readJSONFile(path, &value)
+	assert.Error(t, err)
+}
@@ -3,9 +3,11 @@ package check
 import (
 	"context"
 	"fmt"
+	"slices"
 	"sort"
 
 	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/apply/assigners"
 	"github.com/segmentio/topicctl/pkg/config"
 	tconfig "github.com/segmentio/topicctl/pkg/config"
 )
@@ -163,6 +165,41 @@ func CheckTopic(ctx context.Context, config CheckConfig) (TopicCheckResults, err
 		)
 	}
 
+	// Check placement constraints
+	placementConfig := config.TopicConfig.Spec.PlacementConfig
+	if placementConfig.HasConstraints() {
+		results.AppendResult(
+			TopicCheckResult{
+				Name: CheckNamePlacementConstraintsMet,
+			},
+		)
+
+		brokers, err := config.AdminClient.GetBrokers(ctx, nil)
+		if err != nil {
+			return results, err
+		}
+		brokers = config.ClusterConfig.Spec.BrokerTags.TagBrokers(brokers)
+
+		violations := assigners.ConstraintViolations(
+			topicInfo.ToAssignments(),
+			brokers,
+			placementConfig,
+		)
+		if len(violations) == 0 {
+			results.UpdateLastResult(true, "")
+		} else {
+			results.UpdateLastResult(
+				false,
+				fmt.Sprintf(
+					"%d/%d partitions have replicas on brokers not allowed by the placement constraints: %v",
+					len(violations),
+					len(topicInfo.Partitions),
+					violations,
+				),
+			)
+		}
+	}
+
 	// Check throttles
 	results.AppendResult(
 		TopicCheckResult{
@@ -208,9 +245,28 @@ func CheckTopic(ctx context.Context, config CheckConfig) (TopicCheckResults, err
 		)
 		wrongLeaderPartitions := topicInfo.WrongLeaderPartitions(nil)
 
-		if len(wrongLeaderPartitions) == 0 {
+		// For the leader-rack strategy, leaders also need to be in the preferred racks
+		wrongRackPartitions := []admin.PartitionInfo{}
+		if placementConfig.Strategy == tconfig.PlacementStrategyLeaderRack {
+			brokers, err := config.AdminClient.GetBrokers(ctx, nil)
+			if err != nil {
+				return results, err
+			}
+			brokerRacks := admin.BrokerRacks(brokers)
+
+			for _, partition := range topicInfo.Partitions {
+				if !slices.Contains(
+					placementConfig.PreferredLeaderRacks,
+					brokerRacks[partition.Leader],
+				) {
+					wrongRackPartitions = append(wrongRackPartitions, partition)
+				}
+			}
+		}
+
+		if len(wrongLeaderPartitions) == 0 && len(wrongRackPartitions) == 0 {
 			results.UpdateLastResult(true, "")
-		} else {
+		} else if len(wrongRackPartitions) == 0 {
 			results.UpdateLastResult(
 				false,
 				fmt.Sprintf(
@@ -219,6 +275,18 @@ func CheckTopic(ctx context.Context, config CheckConfig) (TopicCheckResults, err
 					len(topicInfo.Partitions),
 				),
 			)
+		} else {
+			results.UpdateLastResult(
+				false,
+				fmt.Sprintf(
+					"%d/%d partitions have wrong leaders, %d/%d partitions have leaders outside of racks %v",
+					len(wrongLeaderPartitions),
+					len(topicInfo.Partitions),
+					len(wrongRackPartitions),
+					len(topicInfo.Partitions),
+					placementConfig.PreferredLeaderRacks,
+				),
+			)
 		}
 	}
 
@@ -3,6 +3,8 @@ package check
 import (
 	"bytes"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/olekukonko/tablewriter"
@@ -66,3 +68,115 @@ func FormatResults(results TopicCheckResults) string {
 	table.Render()
 	return string(bytes.TrimRight(buf.Bytes(), "\n"))
 }
+
+// FormatUnmanagedTopics generates a pretty table from the unmanaged topics in an
+// unmanaged topics check.
+func FormatUnmanagedTopics(unmanagedTopics []UnmanagedTopic) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+
+	table.SetHeader([]string{
+		"Name",
+		"Partitions",
+		"Replication",
+		"Size",
+		"Last Write",
+		"Consumer Groups",
+	})
+
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for _, unmanagedTopic := range unmanagedTopics {
+		var sizeStr string
+		if unmanagedTopic.Size >= 0 {
+			sizeStr = util.PrettyBytes(unmanagedTopic.Size)
+		} else {
+			sizeStr = "unknown"
+		}
+
+		var lastWriteStr string
+		if unmanagedTopic.LastWrite.IsZero() {
+			lastWriteStr = "never"
+		} else {
+			lastWriteStr = fmt.Sprintf(
+				"%s (%s ago)",
+				unmanagedTopic.LastWrite.UTC().Format(time.RFC3339),
+				util.PrettyDuration(time.Since(unmanagedTopic.LastWrite)),
+			)
+		}
+
+		table.Append(
+			[]string{
+				unmanagedTopic.TopicInfo.Name,
+				fmt.Sprintf("%d", len(unmanagedTopic.TopicInfo.Partitions)),
+				fmt.Sprintf("%d", unmanagedTopic.TopicInfo.MaxReplication()),
+				sizeStr,
+				lastWriteStr,
+				strings.Join(unmanagedTopic.Groups, ","),
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
+
+// FormatMissingTopics generates a pretty table from the missing topics in an unmanaged
+// topics check.
+func FormatMissingTopics(missingTopics []MissingTopic) string {
+	buf := &bytes.Buffer{}
+
+	table := tablewriter.NewWriter(buf)
+
+	table.SetHeader([]string{
+		"Name",
+		"Config Path",
+	})
+
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment(
+		[]int{
+			tablewriter.ALIGN_LEFT,
+			tablewriter.ALIGN_LEFT,
+		},
+	)
+	table.SetBorders(
+		tablewriter.Border{
+			Left:   false,
+			Top:    true,
+			Right:  false,
+			Bottom: true,
+		},
+	)
+
+	for _, missingTopic := range missingTopics {
+		table.Append(
+			[]string{
+				missingTopic.Name,
+				missingTopic.ConfigPath,
+			},
+		)
+	}
+
+	table.Render()
+	return string(bytes.TrimRight(buf.Bytes(), "\n"))
+}
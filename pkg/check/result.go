@@ -10,6 +10,7 @@ const (
 	CheckNameConfigSettingsCorrect    CheckName = "config settings correct"
 	CheckNameLeadersCorrect           CheckName = "leaders correct"
 	CheckNamePartitionCountCorrect    CheckName = "partition count correct"
+	CheckNamePlacementConstraintsMet  CheckName = "placement constraints met"
 	CheckNameReplicasInSync           CheckName = "replicas in-sync"
 	CheckNameReplicationFactorCorrect CheckName = "replication factor correct"
 	CheckNameThrottlesClear           CheckName = "throttles clear"
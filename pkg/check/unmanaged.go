@@ -0,0 +1,210 @@
+package check
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/segmentio/topicctl/pkg/groups"
+	"github.com/segmentio/topicctl/pkg/messages"
+	log "github.com/sirupsen/logrus"
+)
+
+// UnmanagedCheckConfig contains all of the context necessary to compare the topics in a
+// cluster against the topic configs that are managed for it.
+type UnmanagedCheckConfig struct {
+	AdminClient admin.Client
+
+	// ManagedTopics is a map from topic name to the path of the config that manages it.
+	ManagedTopics map[string]string
+}
+
+// UnmanagedCheckResults stores the results of an unmanaged topics check.
+type UnmanagedCheckResults struct {
+	// Unmanaged contains the topics that are in the cluster but don't have a config.
+	Unmanaged []UnmanagedTopic
+
+	// Missing contains the topics that have a config but aren't in the cluster.
+	Missing []MissingTopic
+}
+
+// UnmanagedTopic contains details about a topic that exists in the cluster but isn't
+// managed by any topic config.
+type UnmanagedTopic struct {
+	TopicInfo admin.TopicInfo
+
+	// Size is the total size of the topic, across all replicas. It's set to -1 if the size
+	// could not be determined.
+	Size int64
+
+	// LastWrite is the time of the most recent message in the topic. It's zero if the topic
+	// is empty or the time could not be determined.
+	LastWrite time.Time
+
+	// Groups are the IDs of the consumer groups with members consuming from the topic.
+	Groups []string
+}
+
+// MissingTopic contains details about a topic config whose topic doesn't exist in the
+// cluster.
+type MissingTopic struct {
+	Name       string
+	ConfigPath string
+}
+
+// AllOK returns true if there are no unmanaged or missing topics, otherwise it returns false.
+func (r UnmanagedCheckResults) AllOK() bool {
+	return len(r.Unmanaged) == 0 && len(r.Missing) == 0
+}
+
+// CheckUnmanagedTopics compares the (non-internal) topics in the cluster against the managed
+// topics in the argument config and returns the differences between them. Unmanaged topics
+// are annotated with their sizes, last write times, and consumer groups.
+func CheckUnmanagedTopics(
+	ctx context.Context,
+	config UnmanagedCheckConfig,
+) (UnmanagedCheckResults, error) {
+	results := UnmanagedCheckResults{}
+
+	topicInfos, err := config.AdminClient.GetTopics(ctx, nil, false)
+	if err != nil {
+		return results, err
+	}
+
+	clusterTopics := []string{}
+	for _, topicInfo := range topicInfos {
+		clusterTopics = append(clusterTopics, topicInfo.Name)
+	}
+
+	unmanagedNames, missingNames := DiffManagedTopics(clusterTopics, config.ManagedTopics)
+
+	for _, name := range missingNames {
+		results.Missing = append(
+			results.Missing,
+			MissingTopic{
+				Name:       name,
+				ConfigPath: config.ManagedTopics[name],
+			},
+		)
+	}
+
+	if len(unmanagedNames) == 0 {
+		return results, nil
+	}
+
+	topicInfosMap := map[string]admin.TopicInfo{}
+	for _, topicInfo := range topicInfos {
+		topicInfosMap[topicInfo.Name] = topicInfo
+	}
+
+	brokerIDs, err := config.AdminClient.GetBrokerIDs(ctx)
+	if err != nil {
+		return results, err
+	}
+
+	var topicSizes map[string]int64
+	replicaSizes, err := config.AdminClient.GetReplicaSizes(ctx, brokerIDs, unmanagedNames)
+	if err != nil {
+		log.Warnf("Could not get topic sizes: %+v", err)
+	} else {
+		topicSizes = admin.TopicSizes(replicaSizes)
+	}
+
+	// Snapshot-backed admin clients can't get consumer groups or partition bounds
+	connector := config.AdminClient.GetConnector()
+
+	topicGroups := map[string][]string{}
+	if connector == nil {
+		log.Warn("Not connected to cluster; skipping consumer groups and last writes")
+	} else {
+		groupCoordinators, err := groups.GetGroups(ctx, connector)
+		if err != nil {
+			log.Warnf("Could not get all consumer groups: %+v", err)
+		}
+		for _, groupCoordinator := range groupCoordinators {
+			for _, topic := range groupCoordinator.Topics {
+				topicGroups[topic] = append(topicGroups[topic], groupCoordinator.GroupID)
+			}
+		}
+	}
+
+	for _, name := range unmanagedNames {
+		unmanagedTopic := UnmanagedTopic{
+			TopicInfo: topicInfosMap[name],
+			Size:      -1,
+			Groups:    topicGroups[name],
+		}
+
+		if topicSizes != nil {
+			unmanagedTopic.Size = topicSizes[name]
+		}
+
+		if connector != nil {
+			bounds, err := messages.GetAllPartitionBounds(ctx, connector, name, nil)
+			if err != nil {
+				log.Warnf("Could not get partition bounds for topic %s: %+v", name, err)
+			} else {
+				unmanagedTopic.LastWrite = lastWriteTime(bounds)
+			}
+		}
+
+		results.Unmanaged = append(results.Unmanaged, unmanagedTopic)
+	}
+
+	return results, nil
+}
+
+// DiffManagedTopics compares the topic names in a cluster against the managed topics
+// map (from topic name to config path). It returns the names of the unmanaged topics,
+// i.e. the ones that are only in the cluster, and the missing topics, i.e. the ones that
+// are only in the configs. Internal topics (ones that start with "__") are never considered
+// unmanaged.
+func DiffManagedTopics(
+	clusterTopics []string,
+	managedTopics map[string]string,
+) ([]string, []string) {
+	unmanaged := []string{}
+	missing := []string{}
+
+	clusterTopicsMap := map[string]struct{}{}
+
+	for _, topic := range clusterTopics {
+		clusterTopicsMap[topic] = struct{}{}
+
+		if strings.HasPrefix(topic, "__") {
+			continue
+		}
+		if _, ok := managedTopics[topic]; !ok {
+			unmanaged = append(unmanaged, topic)
+		}
+	}
+
+	for topic := range managedTopics {
+		if _, ok := clusterTopicsMap[topic]; !ok {
+			missing = append(missing, topic)
+		}
+	}
+
+	sort.Strings(unmanaged)
+	sort.Strings(missing)
+
+	return unmanaged, missing
+}
+
+func lastWriteTime(bounds []messages.Bounds) time.Time {
+	var lastWrite time.Time
+
+	for _, bound := range bounds {
+		// Skip empty partitions
+		if bound.LastOffset <= bound.FirstOffset {
+			continue
+		}
+		if bound.LastTime.After(lastWrite) {
+			lastWrite = bound.LastTime
+		}
+	}
+
+	return lastWrite
+}
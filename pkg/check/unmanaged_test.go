@@ -0,0 +1,68 @@
+package check
+
+import (
+	"testing"
+	"time"
+
+	"github.com/segmentio/topicctl/pkg/messages"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffManagedTopics(t *testing.T) {
+	unmanaged, missing := DiffManagedTopics(
+		[]string{
+			"topic-c",
+			"__consumer_offsets",
+			"topic-a",
+			"topic-b",
+		},
+		map[string]string{
+			"topic-a": "topics/topic-a.yaml",
+			"topic-d": "topics/topic-d.yaml",
+		},
+	)
+	assert.Equal(t, []string{"topic-b", "topic-c"}, unmanaged)
+	assert.Equal(t, []string{"topic-d"}, missing)
+
+	unmanaged, missing = DiffManagedTopics(
+		[]string{"topic-a"},
+		map[string]string{
+			"topic-a": "topics/topic-a.yaml",
+		},
+	)
+	assert.Equal(t, []string{}, unmanaged)
+	assert.Equal(t, []string{}, missing)
+}
+
+func TestLastWriteTime(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(
+		t,
+		now,
+		lastWriteTime(
+			[]messages.Bounds{
+				{
+					Partition:   0,
+					FirstOffset: 10,
+					LastOffset:  20,
+					LastTime:    now.Add(-time.Hour),
+				},
+				{
+					Partition:   1,
+					FirstOffset: 5,
+					LastOffset:  30,
+					LastTime:    now,
+				},
+				{
+					// Empty partition
+					Partition:   2,
+					FirstOffset: 40,
+					LastOffset:  40,
+					LastTime:    now.Add(time.Hour),
+				},
+			},
+		),
+	)
+	assert.True(t, lastWriteTime(nil).IsZero())
+}
@@ -2,6 +2,8 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -27,8 +29,18 @@ import (
 const (
 	spinnerCharSet  = 36
 	spinnerDuration = 200 * time.Millisecond
+
+	// ACLGroupByPrincipal groups bootstrapped ACLs into one config per principal.
+	ACLGroupByPrincipal = "principal"
+
+	// ACLGroupByResource groups bootstrapped ACLs into one config per resource.
+	ACLGroupByResource = "resource"
 )
 
+var nonConfigNameChars = regexp.MustCompile("[^a-z0-9._-]+")
+
+const defaultZKLockPath = "/topicctl/locks"
+
 // CLIRunner is a utility that runs commands from either the command-line or the repl.
 type CLIRunner struct {
 	adminClient admin.Client
@@ -137,6 +149,110 @@ func (c *CLIRunner) ApplyTopic(
 	return changes, err
 }
 
+// PlanTopic computes the changes that an apply run would make for a topic, without making
+// them.
+func (c *CLIRunner) PlanTopic(
+	ctx context.Context,
+	applierConfig apply.TopicApplierConfig,
+) (apply.TopicPlan, error) {
+	highlighter := color.New(color.FgYellow, color.Bold).SprintfFunc()
+
+	c.printer(
+		"Starting plan for topic %s in environment %s, cluster %s",
+		highlighter(applierConfig.TopicConfig.Meta.Name),
+		highlighter(applierConfig.TopicConfig.Meta.Environment),
+		highlighter(applierConfig.TopicConfig.Meta.Cluster),
+	)
+
+	topicPlan, err := apply.PlanTopic(ctx, c.adminClient, applierConfig)
+	if err != nil {
+		return topicPlan, err
+	}
+
+	if len(topicPlan.Steps) == 0 {
+		c.printer("No changes needed for topic %s", applierConfig.TopicConfig.Meta.Name)
+	} else {
+		c.printer(
+			"Planned changes for topic %s:\n%s",
+			applierConfig.TopicConfig.Meta.Name,
+			apply.FormatTopicPlan(topicPlan),
+		)
+	}
+
+	return topicPlan, nil
+}
+
+// ApplyPlan executes each of the topic plans in the argument plan.
+func (c *CLIRunner) ApplyPlan(
+	ctx context.Context,
+	plan apply.Plan,
+	executorConfig apply.PlanExecutorConfig,
+) error {
+	if err := plan.Validate(executorConfig.ClusterConfig); err != nil {
+		return err
+	}
+
+	highlighter := color.New(color.FgYellow, color.Bold).SprintfFunc()
+
+	c.printer(
+		"Applying plan created at %s for %d topic(s) in environment %s, cluster %s",
+		plan.CreatedAt.Format(time.RFC3339),
+		len(plan.Topics),
+		highlighter(plan.Environment),
+		highlighter(plan.Cluster),
+	)
+
+	for _, topicPlan := range plan.Topics {
+		if err := apply.ExecuteTopicPlan(
+			ctx,
+			c.adminClient,
+			topicPlan,
+			executorConfig,
+		); err != nil {
+			return err
+		}
+	}
+
+	c.printer("Plan applied successfully!")
+	return nil
+}
+
+// RollbackTopic restores the settings and replica assignments of a topic to the ones recorded
+// in the argument revision. The topic config in applierConfig is replaced with one built from
+// the revision.
+func (c *CLIRunner) RollbackTopic(
+	ctx context.Context,
+	revision apply.TopicRevision,
+	applierConfig apply.TopicApplierConfig,
+) (apply.NewOrUpdatedChanges, error) {
+	topicInfo, err := c.adminClient.GetTopic(ctx, revision.Topic, false)
+	if err != nil {
+		return nil, err
+	}
+
+	topicConfig, err := apply.RollbackTopicConfig(
+		applierConfig.ClusterConfig,
+		revision,
+		topicInfo,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	c.printer(
+		"Rolling back topic %s to revision %d (recorded at %s)",
+		revision.Topic,
+		revision.Revision,
+		revision.CreatedAt.Format(time.RFC3339),
+	)
+
+	applierConfig.TopicConfig = topicConfig
+	// Keys that were added after the revision should be removed
+	applierConfig.Destructive = true
+
+	return c.ApplyTopic(ctx, applierConfig)
+}
+
 // CreateACL does an apply run according to the spec in the argument config.
 func (c *CLIRunner) CreateACL(
 	ctx context.Context,
@@ -171,6 +287,49 @@ func (c *CLIRunner) CreateACL(
 	return nil
 }
 
+// ApplyACL reconciles the ACLs in the cluster with the spec in the argument config.
+func (c *CLIRunner) ApplyACL(
+	ctx context.Context,
+	aclAdminConfig acl.ACLAdminConfig,
+) error {
+	aclAdmin, err := acl.NewACLAdmin(
+		ctx,
+		c.adminClient,
+		aclAdminConfig,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	highlighter := color.New(color.FgYellow, color.Bold).SprintfFunc()
+
+	aclNames := []string{}
+	if len(aclAdminConfig.ApplyConfigs) > 0 {
+		for _, aclConfig := range aclAdminConfig.ApplyConfigs {
+			aclNames = append(aclNames, aclConfig.Meta.Name)
+		}
+	} else {
+		aclNames = append(aclNames, aclAdminConfig.ACLConfig.Meta.Name)
+	}
+
+	c.printer(
+		"Starting apply for ACLs %s in environment %s, cluster %s",
+		highlighter(strings.Join(aclNames, ", ")),
+		highlighter(aclAdminConfig.ACLConfig.Meta.Environment),
+		highlighter(aclAdminConfig.ACLConfig.Meta.Cluster),
+	)
+
+	err = aclAdmin.Apply(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	c.printer("Apply completed successfully!")
+	return nil
+}
+
 // DeleteACL deletes a single ACL.
 func (c *CLIRunner) DeleteACL(
 	ctx context.Context,
@@ -247,35 +406,370 @@ func (c *CLIRunner) BootstrapTopics(
 			return err
 		}
 
-		if outputDir != "" {
-			outputPath := filepath.Join(
-				outputDir,
-				fmt.Sprintf("%s.yaml", topicConfig.Meta.Name),
+		if err := writeBootstrapConfig(
+			"topic",
+			topicConfig.Meta.Name,
+			yamlStr,
+			outputDir,
+			overwrite,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BootstrapACLs creates configs for the ACLs in the cluster, grouped by either principal
+// or resource.
+func (c *CLIRunner) BootstrapACLs(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	groupBy string,
+	matchRegexpStr string,
+	excludeRegexpStr string,
+	outputDir string,
+	overwrite bool,
+) error {
+	matchRegexp, err := regexp.Compile(matchRegexpStr)
+	if err != nil {
+		return err
+	}
+	excludeRegexp, err := regexp.Compile(excludeRegexpStr)
+	if err != nil {
+		return err
+	}
+
+	aclInfos, err := c.adminClient.GetACLs(ctx, allACLsFilter(""))
+	if err != nil {
+		return err
+	}
+
+	groupedACLs := map[string][]admin.ACLInfo{}
+
+	for _, aclInfo := range aclInfos {
+		var key string
+
+		switch groupBy {
+		case ACLGroupByPrincipal:
+			key = aclInfo.Principal
+		case ACLGroupByResource:
+			key = fmt.Sprintf("%s:%s", aclInfo.ResourceType.String(), aclInfo.ResourceName)
+		default:
+			return fmt.Errorf("Unrecognized ACL grouping: %s", groupBy)
+		}
+
+		if !matchRegexp.MatchString(key) {
+			continue
+		} else if excludeRegexp.MatchString(key) {
+			continue
+		}
+
+		groupedACLs[key] = append(groupedACLs[key], aclInfo)
+	}
+
+	configNames := newBootstrapConfigNamer()
+
+	for _, key := range sortedKeys(groupedACLs) {
+		aclConfig := config.ACLConfigFromACLInfos(
+			clusterConfig,
+			configNames.name(key),
+			groupedACLs[key],
+		)
+
+		yamlStr, err := aclConfig.ToYAML()
+		if err != nil {
+			return err
+		}
+
+		if err := writeBootstrapConfig(
+			"ACLs",
+			aclConfig.Meta.Name,
+			yamlStr,
+			outputDir,
+			overwrite,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BootstrapUsers creates ACL configs for one or more users in the cluster. Each config contains
+// the ACLs for the principal associated with the user; users without any ACLs get an empty
+// config so that they can still be brought under management.
+func (c *CLIRunner) BootstrapUsers(
+	ctx context.Context,
+	users []string,
+	clusterConfig config.ClusterConfig,
+	matchRegexpStr string,
+	excludeRegexpStr string,
+	outputDir string,
+	overwrite bool,
+) error {
+	matchRegexp, err := regexp.Compile(matchRegexpStr)
+	if err != nil {
+		return err
+	}
+	excludeRegexp, err := regexp.Compile(excludeRegexpStr)
+	if err != nil {
+		return err
+	}
+
+	userInfos, err := c.adminClient.GetUsers(ctx, users)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(userInfos, func(a, b int) bool {
+		return userInfos[a].Name < userInfos[b].Name
+	})
+
+	configNames := newBootstrapConfigNamer()
+
+	for _, userInfo := range userInfos {
+		if !matchRegexp.MatchString(userInfo.Name) {
+			continue
+		} else if excludeRegexp.MatchString(userInfo.Name) {
+			continue
+		}
+
+		principal := fmt.Sprintf("User:%s", userInfo.Name)
+		aclInfos, err := c.adminClient.GetACLs(ctx, allACLsFilter(principal))
+		if err != nil {
+			return err
+		}
+
+		aclConfig := config.ACLConfigFromACLInfos(
+			clusterConfig,
+			configNames.name(principal),
+			aclInfos,
+		)
+
+		yamlStr, err := aclConfig.ToYAML()
+		if err != nil {
+			return err
+		}
+
+		if err := writeBootstrapConfig(
+			"user",
+			aclConfig.Meta.Name,
+			yamlStr,
+			outputDir,
+			overwrite,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// BootstrapCluster creates a cluster config based on the current state of the cluster, along
+// with a skeleton directory layout for the topic and ACL configs in the cluster. The argument
+// config should have the metadata and connection settings filled in; everything else is
+// discovered from the cluster.
+func (c *CLIRunner) BootstrapCluster(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	zkAddrs []string,
+	outputDir string,
+	overwrite bool,
+) error {
+	c.startSpinner()
+
+	clusterID, err := c.adminClient.GetClusterID(ctx)
+	if err != nil {
+		c.stopSpinner()
+		return err
+	}
+	brokers, err := c.adminClient.GetBrokers(ctx, nil)
+	if err != nil {
+		c.stopSpinner()
+		return err
+	}
+	throttleMB, err := c.getDefaultThrottleMB(ctx)
+	c.stopSpinner()
+	if err != nil {
+		return err
+	}
+
+	c.printer(
+		"Found cluster %s with %d brokers:\n%s",
+		clusterID,
+		len(brokers),
+		admin.FormatBrokersPerRack(brokers),
+	)
+
+	clusterConfig.Spec.ClusterID = clusterID
+	clusterConfig.Spec.DefaultThrottleMB = throttleMB
+
+	if clusterConfig.Meta.Description == "" {
+		clusterConfig.Meta.Description = fmt.Sprintf(
+			"Bootstrapped via topicctl bootstrap; %d brokers in %d rack(s) at creation time",
+			len(brokers),
+			len(admin.DistinctRacks(brokers)),
+		)
+	}
+
+	if len(zkAddrs) > 0 {
+		if clusterConfig.Spec.TLS.Enabled || clusterConfig.Spec.SASL.Enabled {
+			log.Warn("Omitting zk addresses because TLS and SASL aren't supported with zk access mode")
+		} else if err := checkZKReachable(
+			ctx,
+			zkAddrs,
+			clusterConfig.Spec.ZKPrefix,
+			clusterConfig.Spec.BootstrapAddrs,
+			clusterID,
+		); err != nil {
+			log.Warnf(
+				"Omitting zk addresses because zk isn't reachable (%+v); "+
+					"the cluster will be accessed via broker APIs only",
+				err,
 			)
+		} else {
+			log.Infof("ZooKeeper at %+v is reachable", zkAddrs)
+			clusterConfig.Spec.ZKAddrs = zkAddrs
+			if clusterConfig.Spec.ZKLockPath == "" {
+				clusterConfig.Spec.ZKLockPath = defaultZKLockPath
+			}
+		}
+	}
+	if len(clusterConfig.Spec.ZKAddrs) == 0 {
+		// These only apply when accessing the cluster via zookeeper
+		clusterConfig.Spec.ZKPrefix = ""
+		clusterConfig.Spec.ZKLockPath = ""
+	}
+
+	if clusterConfig.Spec.SASL.Password != "" {
+		log.Warn(
+			"Omitting SASL password from cluster config; set it at runtime via --sasl-password " +
+				"or TOPICCTL_SASL_PASSWORD",
+		)
+		clusterConfig.Spec.SASL.Password = ""
+	}
+
+	if outputDir != "" {
+		clusterConfig.Spec.TLS.CACertPath, err = relativeConfigPath(
+			outputDir,
+			clusterConfig.Spec.TLS.CACertPath,
+		)
+		if err != nil {
+			return err
+		}
+		clusterConfig.Spec.TLS.CertPath, err = relativeConfigPath(
+			outputDir,
+			clusterConfig.Spec.TLS.CertPath,
+		)
+		if err != nil {
+			return err
+		}
+		clusterConfig.Spec.TLS.KeyPath, err = relativeConfigPath(
+			outputDir,
+			clusterConfig.Spec.TLS.KeyPath,
+		)
+		if err != nil {
+			return err
+		}
+	}
 
-			var isNew bool
+	if err := clusterConfig.Validate(); err != nil {
+		return fmt.Errorf("Bootstrapped cluster config is invalid: %+v", err)
+	}
 
-			_, err := os.Stat(outputPath)
-			if err != nil && !os.IsNotExist(err) {
+	yamlStr, err := clusterConfig.ToYAML()
+	if err != nil {
+		return err
+	}
+
+	if outputDir != "" {
+		for _, dir := range []string{
+			outputDir,
+			filepath.Join(outputDir, "topics"),
+			filepath.Join(outputDir, "acls"),
+		} {
+			log.Infof("Creating directory %s", dir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
 				return err
 			}
-			isNew = os.IsNotExist(err)
-
-			if isNew || overwrite {
-				log.Infof("Writing config to %s", outputPath)
-				err = os.WriteFile(outputPath, []byte(yamlStr), 0644)
-				if err != nil {
-					return err
-				}
-			} else {
-				log.Infof("Skipping over existing config %s", outputPath)
+		}
+	}
+
+	return writeBootstrapConfig(
+		"cluster",
+		"cluster",
+		yamlStr,
+		outputDir,
+		overwrite,
+	)
+}
+
+// getDefaultThrottleMB gets the cluster-wide, dynamic replication throttle defaults, if
+// set, and converts them into the MB/sec value used for migrations, rounding up. It returns 0
+// if no defaults are set.
+func (c *CLIRunner) getDefaultThrottleMB(ctx context.Context) (int64, error) {
+	req := kafka.DescribeConfigsRequest{
+		Resources: []kafka.DescribeConfigRequestResource{
+			{
+				// An empty name refers to the cluster-wide broker defaults
+				ResourceType: kafka.ResourceTypeBroker,
+				ResourceName: "",
+				ConfigNames: []string{
+					admin.LeaderThrottledKey,
+					admin.FollowerThrottledKey,
+				},
+			},
+		},
+	}
+	log.Debugf("DescribeConfigs request: %+v", req)
+
+	resp, err := c.adminClient.GetConnector().KafkaClient.DescribeConfigs(ctx, &req)
+	log.Debugf("DescribeConfigs response: %+v (%+v)", resp, err)
+	if err != nil {
+		return 0, err
+	}
+
+	var throttleBytes int64
+
+	for _, resource := range resp.Resources {
+		if resource.Error != nil {
+			return 0, resource.Error
+		}
+
+		for _, entry := range resource.ConfigEntries {
+			if entry.ConfigValue == "" {
+				continue
+			}
+			value, err := strconv.ParseInt(entry.ConfigValue, 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf(
+					"Could not parse value of %s (%s): %+v",
+					entry.ConfigName,
+					entry.ConfigValue,
+					err,
+				)
+			}
+			if throttleBytes == 0 || value < throttleBytes {
+				throttleBytes = value
 			}
-		} else {
-			log.Infof("Config for topic %s:\n%s", topicConfig.Meta.Name, yamlStr)
 		}
 	}
 
-	return nil
+	if throttleBytes > 0 {
+		log.Infof("Found dynamic default replication throttle of %d bytes/sec", throttleBytes)
+	}
+
+	return throttleBytesToMB(throttleBytes), nil
+}
+
+// throttleBytesToMB converts a replication throttle in bytes/sec into MB/sec. The result is
+// rounded up so that throttles below 1 MB/sec aren't treated as unset.
+func throttleBytesToMB(throttleBytes int64) int64 {
+	if throttleBytes <= 0 {
+		return 0
+	}
+	return (throttleBytes + 999999) / 1000000
 }
 
 // CheckTopic runs a topic check against a single topic and prints a summary of the results out.
@@ -305,9 +799,92 @@ func (c *CLIRunner) CheckTopic(
 	return results.AllOK(), err
 }
 
+// CheckUnmanagedTopics compares the topics in the cluster against the argument managed
+// topics (a map from topic name to config path) and prints out the differences. If outputDir
+// is set, then bootstrapped configs are written for the unmanaged topics.
+func (c *CLIRunner) CheckUnmanagedTopics(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	managedTopics map[string]string,
+	outputDir string,
+	overwrite bool,
+) (bool, error) {
+	c.startSpinner()
+	results, err := check.CheckUnmanagedTopics(
+		ctx,
+		check.UnmanagedCheckConfig{
+			AdminClient:   c.adminClient,
+			ManagedTopics: managedTopics,
+		},
+	)
+	c.stopSpinner()
+	if err != nil {
+		return false, err
+	}
+
+	if results.AllOK() {
+		c.printer(
+			"All topics in cluster %s (env=%s) are managed by configs",
+			clusterConfig.Meta.Name,
+			clusterConfig.Meta.Environment,
+		)
+		return true, nil
+	}
+
+	if len(results.Unmanaged) > 0 {
+		c.printer(
+			"Found %d topic(s) in cluster %s (env=%s) without configs:\n%s",
+			len(results.Unmanaged),
+			clusterConfig.Meta.Name,
+			clusterConfig.Meta.Environment,
+			check.FormatUnmanagedTopics(results.Unmanaged),
+		)
+	}
+
+	if len(results.Missing) > 0 {
+		c.printer(
+			"Found %d topic config(s) without topics in cluster %s (env=%s):\n%s",
+			len(results.Missing),
+			clusterConfig.Meta.Name,
+			clusterConfig.Meta.Environment,
+			check.FormatMissingTopics(results.Missing),
+		)
+	}
+
+	if outputDir != "" {
+		for _, unmanagedTopic := range results.Unmanaged {
+			topicConfig := config.TopicConfigFromTopicInfo(
+				clusterConfig,
+				unmanagedTopic.TopicInfo,
+			)
+			yamlStr, err := topicConfig.ToYAML()
+			if err != nil {
+				return false, err
+			}
+
+			if err := writeBootstrapConfig(
+				"topic",
+				topicConfig.Meta.Name,
+				yamlStr,
+				outputDir,
+				overwrite,
+			); err != nil {
+				return false, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
 // GetBrokerBalance evaluates the balance of the brokers for a single topic and prints a summary
-// out for user inspection.
-func (c *CLIRunner) GetBrokerBalance(ctx context.Context, topicName string) error {
+// out for user inspection. If broker weights are set, then the deviation of each broker from
+// its weighted target is also printed.
+func (c *CLIRunner) GetBrokerBalance(
+	ctx context.Context,
+	topicName string,
+	brokerWeights *config.BrokerWeightsConfig,
+) error {
 	c.startSpinner()
 
 	brokers, err := c.adminClient.GetBrokers(ctx, nil)
@@ -332,6 +909,13 @@ func (c *CLIRunner) GetBrokerBalance(ctx context.Context, topicName string) erro
 	c.printer("Broker replicas:\n%s", admin.FormatBrokerReplicas(brokers, topics))
 	c.printer("Broker rack replicas:\n%s", admin.FormatBrokerRackReplicas(brokers, topics))
 
+	if weights := brokerWeights.Weights(brokers); weights != nil {
+		c.printer(
+			"Broker weighted balance:\n%s",
+			admin.FormatBrokerWeightedBalance(brokers, topics, weights),
+		)
+	}
+
 	return nil
 }
 
@@ -401,12 +985,27 @@ func (c *CLIRunner) GetConfig(ctx context.Context, brokerOrTopic string) error {
 
 // GetGroups fetches all consumer groups and prints them out for user inspection.
 func (c *CLIRunner) GetGroups(ctx context.Context) error {
-	c.startSpinner()
+	var groupCoordinators []groups.GroupCoordinator
 
-	groupCoordinators, err := groups.GetGroups(ctx, c.adminClient.GetConnector())
-	c.stopSpinner()
-	if err != nil {
-		return err
+	if snapshotClient, ok := c.adminClient.(*admin.SnapshotAdminClient); ok {
+		groupOffsets, err := snapshotClient.GetGroupOffsets(ctx, "", "")
+		if err != nil {
+			return err
+		}
+		groupCoordinators = groups.GroupCoordinatorsFromOffsets(groupOffsets)
+	} else {
+		connector, err := c.connector()
+		if err != nil {
+			return err
+		}
+
+		c.startSpinner()
+
+		groupCoordinators, err = groups.GetGroups(ctx, connector)
+		c.stopSpinner()
+		if err != nil {
+			return err
+		}
 	}
 
 	c.printer("Groups:\n%s", groups.FormatGroupCoordinators(groupCoordinators))
@@ -415,11 +1014,20 @@ func (c *CLIRunner) GetGroups(ctx context.Context) error {
 
 // GetGroupMembers fetches and prints out information about every member in a consumer group.
 func (c *CLIRunner) GetGroupMembers(ctx context.Context, groupID string, full bool) error {
+	if _, ok := c.adminClient.(*admin.SnapshotAdminClient); ok {
+		return fmt.Errorf("Group members are not included in cluster snapshots")
+	}
+
+	connector, err := c.connector()
+	if err != nil {
+		return err
+	}
+
 	c.startSpinner()
 
 	groupDetails, err := groups.GetGroupDetails(
 		ctx,
-		c.adminClient.GetConnector(),
+		connector,
 		groupID,
 	)
 	c.stopSpinner()
@@ -450,11 +1058,20 @@ func (c *CLIRunner) GetMemberLags(
 	full bool,
 	sortByValues bool,
 ) error {
+	if snapshotClient, ok := c.adminClient.(*admin.SnapshotAdminClient); ok {
+		return c.getSnapshotMemberLags(ctx, snapshotClient, topic, groupID, full, sortByValues)
+	}
+
+	connector, err := c.connector()
+	if err != nil {
+		return err
+	}
+
 	c.startSpinner()
 
 	// Check that topic exists before getting offsets; otherwise, the topic get
 	// created as part of the lag check.
-	_, err := c.adminClient.GetTopic(ctx, topic, false)
+	_, err = c.adminClient.GetTopic(ctx, topic, false)
 	if err != nil {
 		c.stopSpinner()
 		return fmt.Errorf("Error fetching topic info: %+v", err)
@@ -462,7 +1079,7 @@ func (c *CLIRunner) GetMemberLags(
 
 	memberLags, err := groups.GetMemberLags(
 		ctx,
-		c.adminClient.GetConnector(),
+		connector,
 		topic,
 		groupID,
 	)
@@ -546,11 +1163,16 @@ func (c *CLIRunner) GetPartitions(
 // GetOffsets fetches details about all partition offsets in a single topic and prints out
 // a summary.
 func (c *CLIRunner) GetOffsets(ctx context.Context, topic string) error {
+	connector, err := c.connector()
+	if err != nil {
+		return err
+	}
+
 	c.startSpinner()
 
 	// Check that topic exists before getting offsets; otherwise, the topic might
 	// be created as part of the bounds check.
-	_, err := c.adminClient.GetTopic(ctx, topic, false)
+	_, err = c.adminClient.GetTopic(ctx, topic, false)
 	if err != nil {
 		c.stopSpinner()
 		return fmt.Errorf("Error fetching topic info: %+v", err)
@@ -558,7 +1180,7 @@ func (c *CLIRunner) GetOffsets(ctx context.Context, topic string) error {
 
 	bounds, err := messages.GetAllPartitionBounds(
 		ctx,
-		c.adminClient.GetConnector(),
+		connector,
 		topic,
 		nil,
 	)
@@ -704,6 +1326,110 @@ func (c *CLIRunner) GetACLs(
 	return nil
 }
 
+// ExportSnapshot writes a snapshot of the cluster to the argument path, or to stdout if the
+// path is "-".
+func (c *CLIRunner) ExportSnapshot(
+	ctx context.Context,
+	outputPath string,
+	includeSizes bool,
+	includeGroups bool,
+) error {
+	c.startSpinner()
+
+	snapshot, err := admin.NewSnapshot(ctx, c.adminClient, includeSizes)
+	if err != nil {
+		c.stopSpinner()
+		return err
+	}
+
+	if includeGroups {
+		connector, err := c.connector()
+		if err != nil {
+			c.stopSpinner()
+			return err
+		}
+		snapshot.GroupOffsets, err = getGroupOffsets(ctx, connector)
+		if err != nil {
+			c.stopSpinner()
+			return err
+		}
+	}
+	c.stopSpinner()
+
+	contents, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return err
+	}
+	contents = append(contents, '\n')
+
+	if outputPath == "-" {
+		_, err = os.Stdout.Write(contents)
+		return err
+	}
+
+	if err := os.WriteFile(outputPath, contents, 0644); err != nil {
+		return err
+	}
+	c.printer(
+		"Wrote snapshot of %d brokers and %d topics in cluster %s to %s",
+		len(snapshot.Brokers),
+		len(snapshot.Topics),
+		snapshot.ClusterID,
+		outputPath,
+	)
+
+	return nil
+}
+
+// connector returns the connector for the cluster, or an error if the admin client can't
+// connect to the cluster directly (e.g., because it's backed by a snapshot).
+// getSnapshotMemberLags prints the consumer group lag for each partition in a single topic
+// based on the group offsets in a cluster snapshot.
+func (c *CLIRunner) getSnapshotMemberLags(
+	ctx context.Context,
+	snapshotClient *admin.SnapshotAdminClient,
+	topic string,
+	groupID string,
+	full bool,
+	sortByValues bool,
+) error {
+	_, err := snapshotClient.GetTopic(ctx, topic, false)
+	if err != nil {
+		return fmt.Errorf("Error fetching topic info: %+v", err)
+	}
+
+	groupOffsets, err := snapshotClient.GetGroupOffsets(ctx, groupID, topic)
+	if err != nil {
+		return err
+	}
+	if len(groupOffsets) == 0 {
+		return fmt.Errorf("Snapshot has no offsets for group %s in topic %s", groupID, topic)
+	}
+
+	memberLags := groups.MemberLagsFromOffsets(groupOffsets)
+
+	// Snapshots don't include message times, so sort by the offset lags instead.
+	if sortByValues {
+		sort.Slice(memberLags, func(a, b int) bool {
+			return memberLags[a].OffsetLag() < memberLags[b].OffsetLag()
+		})
+	}
+
+	c.printer(
+		"Group member lags (as of snapshot):\n%s",
+		groups.FormatMemberLags(memberLags, full),
+	)
+	return nil
+}
+
+func (c *CLIRunner) connector() (*admin.Connector, error) {
+	connector := c.adminClient.GetConnector()
+	if connector == nil {
+		return nil, errors.New("Command requires a connection to the cluster")
+	}
+	return connector, nil
+}
+
 func (c *CLIRunner) startSpinner() {
 	if c.spinnerObj != nil {
 		c.spinnerObj.Start()
@@ -729,3 +1455,220 @@ func stringsToInts(strs []string) ([]int, error) {
 
 	return ints, nil
 }
+
+// writeBootstrapConfig writes a bootstrapped config to the output directory, or logs it if no
+// output directory is set.
+func writeBootstrapConfig(
+	kind string,
+	name string,
+	yamlStr string,
+	outputDir string,
+	overwrite bool,
+) error {
+	if outputDir == "" {
+		log.Infof("Config for %s %s:\n%s", kind, name, yamlStr)
+		return nil
+	}
+
+	outputPath := filepath.Join(
+		outputDir,
+		fmt.Sprintf("%s.yaml", name),
+	)
+
+	var isNew bool
+
+	_, err := os.Stat(outputPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	isNew = os.IsNotExist(err)
+
+	if isNew || overwrite {
+		log.Infof("Writing config to %s", outputPath)
+		return os.WriteFile(outputPath, []byte(yamlStr), 0644)
+	}
+
+	log.Infof("Skipping over existing config %s", outputPath)
+	return nil
+}
+
+// bootstrapConfigName converts an arbitrary key (e.g., a principal like "User:alice") into
+// a name that's safe to use for both the config metadata and its file name.
+func bootstrapConfigName(key string) string {
+	return strings.Trim(
+		nonConfigNameChars.ReplaceAllString(strings.ToLower(key), "-"),
+		"-",
+	)
+}
+
+// bootstrapConfigNamer generates the config names for a bootstrap run. Since different keys
+// can have the same name after conversion (e.g., "User:Alice" and "User:alice"), a numeric
+// suffix is added to the names of the later keys so that their configs don't overwrite each
+// other.
+type bootstrapConfigNamer struct {
+	usedNames map[string]string
+}
+
+func newBootstrapConfigNamer() *bootstrapConfigNamer {
+	return &bootstrapConfigNamer{
+		usedNames: map[string]string{},
+	}
+}
+
+// name returns a config name for the argument key that's unique within the run.
+func (b *bootstrapConfigNamer) name(key string) string {
+	baseName := bootstrapConfigName(key)
+	name := baseName
+
+	for i := 2; ; i++ {
+		usedKey, ok := b.usedNames[name]
+		if !ok || usedKey == key {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", baseName, i)
+	}
+
+	if name != baseName {
+		log.Warnf(
+			"Config name %s for %s is already used by %s; using %s instead",
+			baseName,
+			key,
+			b.usedNames[baseName],
+			name,
+		)
+	}
+	b.usedNames[name] = key
+
+	return name
+}
+
+func allACLsFilter(principal string) kafka.ACLFilter {
+	return kafka.ACLFilter{
+		ResourceTypeFilter:        kafka.ResourceTypeAny,
+		ResourcePatternTypeFilter: kafka.PatternTypeAny,
+		PrincipalFilter:           principal,
+		Operation:                 kafka.ACLOperationTypeAny,
+		PermissionType:            kafka.ACLPermissionTypeAny,
+	}
+}
+
+func sortedKeys(input map[string][]admin.ACLInfo) []string {
+	keys := []string{}
+
+	for key := range input {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+// checkZKReachable verifies that zookeeper can be accessed with the argument addresses and
+// that it belongs to the cluster with the argument ID.
+func checkZKReachable(
+	ctx context.Context,
+	zkAddrs []string,
+	zkPrefix string,
+	bootstrapAddrs []string,
+	clusterID string,
+) error {
+	zkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	zkClient, err := admin.NewZKAdminClient(
+		zkCtx,
+		admin.ZKAdminClientConfig{
+			ZKAddrs:        zkAddrs,
+			ZKPrefix:       zkPrefix,
+			BootstrapAddrs: bootstrapAddrs,
+			ReadOnly:       true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	defer zkClient.Close()
+
+	zkClusterID, err := zkClient.GetClusterID(zkCtx)
+	if err != nil {
+		return err
+	}
+	if zkClusterID != clusterID {
+		return fmt.Errorf(
+			"ID in zk (%s) does not match the one from the brokers (%s)",
+			zkClusterID,
+			clusterID,
+		)
+	}
+
+	return nil
+}
+
+// relativeConfigPath converts a path relative to the current working directory into one that's
+// relative to the directory containing the cluster config.
+func relativeConfigPath(configDir string, path string) (string, error) {
+	if path == "" || filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	absConfigDir, err := filepath.Abs(configDir)
+	if err != nil {
+		return "", err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Rel(absConfigDir, absPath)
+}
+
+// getGroupOffsets gets the committed offsets of every consumer group in each of the topics
+// that it consumes. Groups whose offsets can't be fetched are skipped with a warning.
+func getGroupOffsets(
+	ctx context.Context,
+	connector *admin.Connector,
+) ([]admin.GroupOffset, error) {
+	groupCoordinators, err := groups.GetGroups(ctx, connector)
+	if err != nil {
+		return nil, err
+	}
+
+	groupOffsets := []admin.GroupOffset{}
+
+	for _, groupCoordinator := range groupCoordinators {
+		for _, topic := range groupCoordinator.Topics {
+			memberLags, err := groups.GetMemberLags(
+				ctx,
+				connector,
+				topic,
+				groupCoordinator.GroupID,
+			)
+			if err != nil {
+				log.Warnf(
+					"Could not get offsets for group %s in topic %s: %+v",
+					groupCoordinator.GroupID,
+					topic,
+					err,
+				)
+				continue
+			}
+
+			for _, memberLag := range memberLags {
+				groupOffsets = append(
+					groupOffsets,
+					admin.GroupOffset{
+						GroupID:      groupCoordinator.GroupID,
+						Coordinator:  groupCoordinator.Coordinator,
+						Topic:        topic,
+						Partition:    memberLag.Partition,
+						Offset:       memberLag.MemberOffset,
+						NewestOffset: memberLag.NewestOffset,
+					},
+				)
+			}
+		}
+	}
+
+	return groupOffsets, nil
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThrottleBytesToMB(t *testing.T) {
+	type testCase struct {
+		throttleBytes int64
+		expected      int64
+	}
+
+	testCases := []testCase{
+		{
+			throttleBytes: 0,
+			expected:      0,
+		},
+		{
+			throttleBytes: 1,
+			expected:      1,
+		},
+		{
+			throttleBytes: 500000,
+			expected:      1,
+		},
+		{
+			throttleBytes: 1000000,
+			expected:      1,
+		},
+		{
+			throttleBytes: 1000001,
+			expected:      2,
+		},
+		{
+			throttleBytes: 120000000,
+			expected:      120,
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(
+			t,
+			testCase.expected,
+			throttleBytesToMB(testCase.throttleBytes),
+			"throttle bytes %d",
+			testCase.throttleBytes,
+		)
+	}
+}
+
+func TestBootstrapConfigNamer(t *testing.T) {
+	configNames := newBootstrapConfigNamer()
+
+	assert.Equal(t, "user-alice", configNames.name("User:Alice"))
+	assert.Equal(t, "user-alice-2", configNames.name("User:alice"))
+	assert.Equal(t, "topic-a-b", configNames.name("Topic:a/b"))
+	assert.Equal(t, "topic-a-b-2", configNames.name("Topic:a b"))
+	assert.Equal(t, "topic-a-b-3", configNames.name("Topic:a-b"))
+	assert.Equal(t, "topic-a.b", configNames.name("Topic:a.b"))
+
+	// The same key always gets the same name
+	assert.Equal(t, "user-alice", configNames.name("User:Alice"))
+	assert.Equal(t, "topic-a-b-2", configNames.name("Topic:a b"))
+
+	// Keys that only collide with a suffixed name get a suffix of their own
+	assert.Equal(t, "user-alice-2-2", configNames.name("User:alice-2"))
+}
@@ -261,7 +261,7 @@ func (r *Repl) executor(in string) {
 				topicName = command.args[2]
 			}
 
-			if err := r.cliRunner.GetBrokerBalance(ctx, topicName); err != nil {
+			if err := r.cliRunner.GetBrokerBalance(ctx, topicName, nil); err != nil {
 				log.Errorf("Error: %+v", err)
 				return
 			}
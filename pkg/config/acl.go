@@ -2,9 +2,13 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 
+	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-multierror"
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
 )
 
 type ACLConfig struct {
@@ -13,7 +17,8 @@ type ACLConfig struct {
 }
 
 type ACLSpec struct {
-	ACLs []ACL `json:"acls"`
+	ACLs  []ACL     `json:"acls"`
+	Roles []ACLRole `json:"roles,omitempty"`
 }
 
 type ACL struct {
@@ -30,6 +35,39 @@ type ACLResource struct {
 	Permission  kafka.ACLPermissionType `json:"permission"`
 }
 
+// ACLRole is a shorthand for the set of ACLs that a typical Kafka client needs. Each role
+// is expanded into the explicit ACL entries for its principal.
+type ACLRole struct {
+	Principal   string            `json:"principal"`
+	Host        string            `json:"host,omitempty"`
+	PatternType kafka.PatternType `json:"patternType,omitempty"`
+
+	Producer *ACLProducerRole `json:"producer,omitempty"`
+	Consumer *ACLConsumerRole `json:"consumer,omitempty"`
+	Admin    *ACLAdminRole    `json:"admin,omitempty"`
+}
+
+// ACLProducerRole grants write access to topics and, optionally, transactional IDs.
+type ACLProducerRole struct {
+	Topics           []string `json:"topics"`
+	TransactionalIDs []string `json:"transactionalIds,omitempty"`
+}
+
+// ACLConsumerRole grants read access to topics and consumer groups.
+type ACLConsumerRole struct {
+	Topics []string `json:"topics"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ACLAdminRole grants all operations on topics and consumer groups.
+type ACLAdminRole struct {
+	Topics []string `json:"topics,omitempty"`
+	Groups []string `json:"groups,omitempty"`
+}
+
+// clusterResourceName is the fixed name that Kafka uses for the cluster resource.
+const clusterResourceName = "kafka-cluster"
+
 func (a ACLConfig) ToNewACLEntries() []kafka.ACLEntry {
 	acls := []kafka.ACLEntry{}
 
@@ -46,6 +84,119 @@ func (a ACLConfig) ToNewACLEntries() []kafka.ACLEntry {
 			})
 		}
 	}
+
+	// Roles can overlap with each other and with the explicit ACLs (e.g., a principal that
+	// both produces to and consumes from a topic), so only add entries that haven't been seen.
+	seen := map[kafka.ACLEntry]struct{}{}
+	for _, acl := range acls {
+		seen[acl] = struct{}{}
+	}
+
+	for _, entry := range a.RoleACLEntries() {
+		if _, ok := seen[entry]; ok {
+			continue
+		}
+		seen[entry] = struct{}{}
+		acls = append(acls, entry)
+	}
+
+	return acls
+}
+
+// RoleACLEntries expands the roles in an ACL config into explicit ACL entries.
+func (a ACLConfig) RoleACLEntries() []kafka.ACLEntry {
+	acls := []kafka.ACLEntry{}
+
+	for _, role := range a.Spec.Roles {
+		acls = append(acls, role.ToACLEntries()...)
+	}
+
+	return acls
+}
+
+// ToACLEntries expands a single role into the ACL entries that its clients need.
+func (r ACLRole) ToACLEntries() []kafka.ACLEntry {
+	acls := []kafka.ACLEntry{}
+
+	add := func(
+		resourceType kafka.ResourceType,
+		name string,
+		patternType kafka.PatternType,
+		operations ...kafka.ACLOperationType,
+	) {
+		for _, operation := range operations {
+			acls = append(acls, kafka.ACLEntry{
+				ResourceType:        resourceType,
+				ResourceName:        name,
+				ResourcePatternType: patternType,
+				Principal:           r.Principal,
+				Host:                r.Host,
+				Operation:           operation,
+				PermissionType:      kafka.ACLPermissionTypeAllow,
+			})
+		}
+	}
+
+	if r.Producer != nil {
+		for _, topic := range r.Producer.Topics {
+			add(
+				kafka.ResourceTypeTopic,
+				topic,
+				r.PatternType,
+				kafka.ACLOperationTypeWrite,
+				kafka.ACLOperationTypeDescribe,
+			)
+		}
+		for _, transactionalID := range r.Producer.TransactionalIDs {
+			add(
+				kafka.ResourceTypeTransactionalID,
+				transactionalID,
+				r.PatternType,
+				kafka.ACLOperationTypeWrite,
+				kafka.ACLOperationTypeDescribe,
+			)
+		}
+
+		// Producers are idempotent by default in recent client versions, which requires
+		// this permission on the cluster resource.
+		add(
+			kafka.ResourceTypeCluster,
+			clusterResourceName,
+			kafka.PatternTypeLiteral,
+			kafka.ACLOperationTypeIdempotentWrite,
+		)
+	}
+
+	if r.Consumer != nil {
+		for _, topic := range r.Consumer.Topics {
+			add(
+				kafka.ResourceTypeTopic,
+				topic,
+				r.PatternType,
+				kafka.ACLOperationTypeRead,
+				kafka.ACLOperationTypeDescribe,
+			)
+		}
+		for _, group := range r.Consumer.Groups {
+			add(
+				kafka.ResourceTypeGroup,
+				group,
+				r.PatternType,
+				kafka.ACLOperationTypeRead,
+				kafka.ACLOperationTypeDescribe,
+			)
+		}
+	}
+
+	if r.Admin != nil {
+		for _, topic := range r.Admin.Topics {
+			add(kafka.ResourceTypeTopic, topic, r.PatternType, kafka.ACLOperationTypeAll)
+		}
+		for _, group := range r.Admin.Groups {
+			add(kafka.ResourceTypeGroup, group, r.PatternType, kafka.ACLOperationTypeAll)
+		}
+	}
+
 	return acls
 }
 
@@ -60,6 +211,15 @@ func (a *ACLConfig) SetDefaults() {
 			a.Spec.ACLs[i].Resource.Permission = kafka.ACLPermissionTypeAllow
 		}
 	}
+
+	for i, role := range a.Spec.Roles {
+		if role.Host == "" {
+			a.Spec.Roles[i].Host = "*"
+		}
+		if role.PatternType == kafka.PatternTypeUnknown {
+			a.Spec.Roles[i].PatternType = kafka.PatternTypeLiteral
+		}
+	}
 }
 
 // Validate evaluates whether the ACL config is valid.
@@ -89,5 +249,155 @@ func (a *ACLConfig) Validate() error {
 		}
 	}
 
+	for _, role := range a.Spec.Roles {
+		if roleErr := role.Validate(); roleErr != nil {
+			err = multierror.Append(err, roleErr)
+		}
+	}
+
+	return err
+}
+
+// Validate evaluates whether an ACL role is valid.
+func (r ACLRole) Validate() error {
+	var err error
+
+	if r.Principal == "" {
+		err = multierror.Append(err, errors.New("ACL role principal cannot be empty"))
+	}
+	if r.Producer == nil && r.Consumer == nil && r.Admin == nil {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("ACL role for %s must set at least one of producer, consumer, or admin", r.Principal),
+		)
+	}
+	if r.Producer != nil && len(r.Producer.Topics) == 0 && len(r.Producer.TransactionalIDs) == 0 {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("ACL producer role for %s must have at least one topic or transactional ID", r.Principal),
+		)
+	}
+	if r.Consumer != nil && len(r.Consumer.Topics) == 0 {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("ACL consumer role for %s must have at least one topic", r.Principal),
+		)
+	}
+	if r.Admin != nil && len(r.Admin.Topics) == 0 && len(r.Admin.Groups) == 0 {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("ACL admin role for %s must have at least one topic or group", r.Principal),
+		)
+	}
+
+	for _, entry := range r.ToACLEntries() {
+		if entry.ResourceName == "" {
+			err = multierror.Append(
+				err,
+				fmt.Errorf("ACL role for %s cannot have empty resource names", r.Principal),
+			)
+			break
+		}
+	}
+
 	return err
 }
+
+// ToYAML converts the current ACLConfig to a YAML string.
+func (a ACLConfig) ToYAML() (string, error) {
+	outBytes, err := yaml.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	return string(outBytes), nil
+}
+
+// ACLConfigFromACLInfos generates an ACLConfig from a ClusterConfig and a set of admin.ACLInfo
+// structs generated from the cluster state. ACLs that only differ in their operations are
+// combined into a single entry.
+func ACLConfigFromACLInfos(
+	clusterConfig ClusterConfig,
+	name string,
+	aclInfos []admin.ACLInfo,
+) ACLConfig {
+	aclConfig := ACLConfig{
+		Meta: ResourceMeta{
+			Name:        name,
+			Cluster:     clusterConfig.Meta.Name,
+			Region:      clusterConfig.Meta.Region,
+			Environment: clusterConfig.Meta.Environment,
+			Description: "Bootstrapped via topicctl bootstrap",
+		},
+		Spec: ACLSpec{
+			ACLs: []ACL{},
+		},
+	}
+
+	resourceIndices := map[ACLResource]int{}
+
+	for _, aclInfo := range aclInfos {
+		resource := ACLResource{
+			Type:        kafka.ResourceType(aclInfo.ResourceType),
+			Name:        aclInfo.ResourceName,
+			PatternType: kafka.PatternType(aclInfo.PatternType),
+			Principal:   aclInfo.Principal,
+			Host:        aclInfo.Host,
+			Permission:  kafka.ACLPermissionType(aclInfo.PermissionType),
+		}
+		operation := kafka.ACLOperationType(aclInfo.Operation)
+
+		index, ok := resourceIndices[resource]
+		if !ok {
+			index = len(aclConfig.Spec.ACLs)
+			resourceIndices[resource] = index
+			aclConfig.Spec.ACLs = append(aclConfig.Spec.ACLs, ACL{Resource: resource})
+		}
+
+		operations := aclConfig.Spec.ACLs[index].Operations
+		if !containsOperation(operations, operation) {
+			aclConfig.Spec.ACLs[index].Operations = append(operations, operation)
+		}
+	}
+
+	for _, acl := range aclConfig.Spec.ACLs {
+		sort.Slice(acl.Operations, func(a, b int) bool {
+			return acl.Operations[a] < acl.Operations[b]
+		})
+	}
+
+	sort.Slice(aclConfig.Spec.ACLs, func(a, b int) bool {
+		resourceA := aclConfig.Spec.ACLs[a].Resource
+		resourceB := aclConfig.Spec.ACLs[b].Resource
+
+		if resourceA.Principal != resourceB.Principal {
+			return resourceA.Principal < resourceB.Principal
+		}
+		if resourceA.Type != resourceB.Type {
+			return resourceA.Type < resourceB.Type
+		}
+		if resourceA.Name != resourceB.Name {
+			return resourceA.Name < resourceB.Name
+		}
+		if resourceA.PatternType != resourceB.PatternType {
+			return resourceA.PatternType < resourceB.PatternType
+		}
+		if resourceA.Host != resourceB.Host {
+			return resourceA.Host < resourceB.Host
+		}
+		return resourceA.Permission < resourceB.Permission
+	})
+
+	return aclConfig
+}
+
+func containsOperation(
+	operations []kafka.ACLOperationType,
+	operation kafka.ACLOperationType,
+) bool {
+	for _, curr := range operations {
+		if curr == operation {
+			return true
+		}
+	}
+	return false
+}
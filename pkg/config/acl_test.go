@@ -4,7 +4,9 @@ import (
 	"testing"
 
 	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestACLValidate(t *testing.T) {
@@ -265,3 +267,313 @@ func TestACLSetDefaults(t *testing.T) {
 		assert.Equal(t, testCase.expConfig, testCase.aclConfig, testCase.description)
 	}
 }
+
+func TestACLRoleValidate(t *testing.T) {
+	type testCase struct {
+		description string
+		role        ACLRole
+		expError    bool
+	}
+
+	testCases := []testCase{
+		{
+			description: "valid producer and consumer role",
+			role: ACLRole{
+				Principal: "User:Alice",
+				Producer: &ACLProducerRole{
+					Topics: []string{"test-topic"},
+				},
+				Consumer: &ACLConsumerRole{
+					Topics: []string{"test-topic"},
+					Groups: []string{"test-group"},
+				},
+			},
+			expError: false,
+		},
+		{
+			description: "empty principal",
+			role: ACLRole{
+				Consumer: &ACLConsumerRole{
+					Topics: []string{"test-topic"},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "no role types set",
+			role: ACLRole{
+				Principal: "User:Alice",
+			},
+			expError: true,
+		},
+		{
+			description: "producer without topics or transactional IDs",
+			role: ACLRole{
+				Principal: "User:Alice",
+				Producer:  &ACLProducerRole{},
+			},
+			expError: true,
+		},
+		{
+			description: "consumer without topics",
+			role: ACLRole{
+				Principal: "User:Alice",
+				Consumer: &ACLConsumerRole{
+					Groups: []string{"test-group"},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "empty resource name",
+			role: ACLRole{
+				Principal: "User:Alice",
+				Admin: &ACLAdminRole{
+					Topics: []string{""},
+				},
+			},
+			expError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.role.Validate()
+		if testCase.expError {
+			assert.Error(t, err, testCase.description)
+		} else {
+			assert.NoError(t, err, testCase.description)
+		}
+	}
+}
+
+func TestACLRolesToNewACLEntries(t *testing.T) {
+	aclConfig := ACLConfig{
+		Meta: ResourceMeta{
+			Name:        "acl-test",
+			Cluster:     "test-cluster",
+			Region:      "test-region",
+			Environment: "test-env",
+		},
+		Spec: ACLSpec{
+			ACLs: []ACL{
+				{
+					Resource: ACLResource{
+						Type:        kafka.ResourceTypeTopic,
+						Name:        "test-topic",
+						PatternType: kafka.PatternTypeLiteral,
+						Principal:   "User:Alice",
+					},
+					Operations: []kafka.ACLOperationType{
+						kafka.ACLOperationTypeDescribe,
+					},
+				},
+			},
+			Roles: []ACLRole{
+				{
+					Principal: "User:Alice",
+					Producer: &ACLProducerRole{
+						Topics:           []string{"test-topic"},
+						TransactionalIDs: []string{"test-txn"},
+					},
+					Consumer: &ACLConsumerRole{
+						Topics: []string{"test-topic"},
+						Groups: []string{"test-group"},
+					},
+				},
+				{
+					Principal:   "User:Bob",
+					PatternType: kafka.PatternTypePrefixed,
+					Admin: &ACLAdminRole{
+						Topics: []string{"bob-"},
+					},
+				},
+			},
+		},
+	}
+	aclConfig.SetDefaults()
+
+	entry := func(
+		resourceType kafka.ResourceType,
+		name string,
+		patternType kafka.PatternType,
+		principal string,
+		operation kafka.ACLOperationType,
+	) kafka.ACLEntry {
+		return kafka.ACLEntry{
+			ResourceType:        resourceType,
+			ResourceName:        name,
+			ResourcePatternType: patternType,
+			Principal:           principal,
+			Host:                "*",
+			Operation:           operation,
+			PermissionType:      kafka.ACLPermissionTypeAllow,
+		}
+	}
+
+	assert.Equal(
+		t,
+		[]kafka.ACLEntry{
+			// Explicit ACL
+			entry(
+				kafka.ResourceTypeTopic,
+				"test-topic",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeDescribe,
+			),
+			// Producer role
+			entry(
+				kafka.ResourceTypeTopic,
+				"test-topic",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeWrite,
+			),
+			entry(
+				kafka.ResourceTypeTransactionalID,
+				"test-txn",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeWrite,
+			),
+			entry(
+				kafka.ResourceTypeTransactionalID,
+				"test-txn",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeDescribe,
+			),
+			entry(
+				kafka.ResourceTypeCluster,
+				"kafka-cluster",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeIdempotentWrite,
+			),
+			// Consumer role
+			entry(
+				kafka.ResourceTypeTopic,
+				"test-topic",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeRead,
+			),
+			entry(
+				kafka.ResourceTypeGroup,
+				"test-group",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeRead,
+			),
+			entry(
+				kafka.ResourceTypeGroup,
+				"test-group",
+				kafka.PatternTypeLiteral,
+				"User:Alice",
+				kafka.ACLOperationTypeDescribe,
+			),
+			// Admin role
+			entry(
+				kafka.ResourceTypeTopic,
+				"bob-",
+				kafka.PatternTypePrefixed,
+				"User:Bob",
+				kafka.ACLOperationTypeAll,
+			),
+		},
+		aclConfig.ToNewACLEntries(),
+	)
+}
+
+func TestACLConfigFromACLInfos(t *testing.T) {
+	clusterConfig := ClusterConfig{
+		Meta: ClusterMeta{
+			Name:        "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+		},
+	}
+
+	aclInfo := func(
+		resourceType kafka.ResourceType,
+		name string,
+		operation kafka.ACLOperationType,
+	) admin.ACLInfo {
+		return admin.ACLInfo{
+			ResourceType:   admin.ResourceType(resourceType),
+			ResourceName:   name,
+			PatternType:    admin.PatternType(kafka.PatternTypeLiteral),
+			Principal:      "User:Alice",
+			Host:           "*",
+			Operation:      admin.ACLOperationType(operation),
+			PermissionType: admin.ACLPermissionType(kafka.ACLPermissionTypeAllow),
+		}
+	}
+
+	aclConfig := ACLConfigFromACLInfos(
+		clusterConfig,
+		"user-alice",
+		[]admin.ACLInfo{
+			aclInfo(kafka.ResourceTypeTopic, "topic2", kafka.ACLOperationTypeDescribe),
+			aclInfo(kafka.ResourceTypeGroup, "group1", kafka.ACLOperationTypeRead),
+			aclInfo(kafka.ResourceTypeTopic, "topic2", kafka.ACLOperationTypeRead),
+			aclInfo(kafka.ResourceTypeTopic, "topic1", kafka.ACLOperationTypeWrite),
+			aclInfo(kafka.ResourceTypeTopic, "topic2", kafka.ACLOperationTypeRead),
+		},
+	)
+
+	resource := func(resourceType kafka.ResourceType, name string) ACLResource {
+		return ACLResource{
+			Type:        resourceType,
+			Name:        name,
+			PatternType: kafka.PatternTypeLiteral,
+			Principal:   "User:Alice",
+			Host:        "*",
+			Permission:  kafka.ACLPermissionTypeAllow,
+		}
+	}
+
+	assert.Equal(
+		t,
+		ACLConfig{
+			Meta: ResourceMeta{
+				Name:        "user-alice",
+				Cluster:     "test-cluster",
+				Region:      "test-region",
+				Environment: "test-environment",
+				Description: "Bootstrapped via topicctl bootstrap",
+			},
+			Spec: ACLSpec{
+				ACLs: []ACL{
+					{
+						Resource: resource(kafka.ResourceTypeTopic, "topic1"),
+						Operations: []kafka.ACLOperationType{
+							kafka.ACLOperationTypeWrite,
+						},
+					},
+					{
+						Resource: resource(kafka.ResourceTypeTopic, "topic2"),
+						Operations: []kafka.ACLOperationType{
+							kafka.ACLOperationTypeRead,
+							kafka.ACLOperationTypeDescribe,
+						},
+					},
+					{
+						Resource: resource(kafka.ResourceTypeGroup, "group1"),
+						Operations: []kafka.ACLOperationType{
+							kafka.ACLOperationTypeRead,
+						},
+					},
+				},
+			},
+		},
+		aclConfig,
+	)
+
+	// Make sure that the config survives a round trip through YAML
+	yamlStr, err := aclConfig.ToYAML()
+	require.NoError(t, err)
+	loadedConfig, err := LoadACLBytes([]byte(yamlStr))
+	require.NoError(t, err)
+	assert.Equal(t, aclConfig, loadedConfig)
+}
@@ -5,9 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-multierror"
 	"github.com/segmentio/topicctl/pkg/admin"
 	log "github.com/sirupsen/logrus"
@@ -73,6 +77,22 @@ type ClusterSpec struct {
 	// SASL stores how we should use SASL with broker connections, if appropriate. Only
 	// applies if using the broker admin.
 	SASL SASLConfig `json:"sasl"`
+
+	// Hooks are commands or webhooks that are run at various points in apply runs against
+	// this cluster.
+	Hooks []HookConfig `json:"hooks,omitempty"`
+
+	// Notifiers are Slack, Datadog, or webhook destinations that are sent the results of
+	// apply and rebalance runs against this cluster.
+	Notifiers []NotifierConfig `json:"notifiers,omitempty"`
+
+	// BrokerWeights are the relative capacities of the brokers in the cluster. If set, then
+	// replicas and leaders are placed in proportion to these instead of evenly.
+	BrokerWeights *BrokerWeightsConfig `json:"brokerWeights,omitempty"`
+
+	// BrokerTags are labels for the brokers in the cluster, e.g. "ssd" or "dedicated-team-x".
+	// Topics can require or avoid brokers with specific tags in their placement configs.
+	BrokerTags *BrokerTagsConfig `json:"brokerTags,omitempty"`
 }
 
 // TLSConfig contains the details required to use TLS in communication with broker clients.
@@ -118,6 +138,403 @@ type SASLConfig struct {
 	SecretsManagerArn string `json:"secretsManagerArn"`
 }
 
+// HookEvent is a point in an apply run at which hooks can be run.
+type HookEvent string
+
+const (
+	// HookEventPreSettings is run before the settings of an existing topic are updated.
+	HookEventPreSettings HookEvent = "preSettings"
+
+	// HookEventPreBatch is run before each batch of partition reassignments.
+	HookEventPreBatch HookEvent = "preBatch"
+
+	// HookEventPostBatch is run after each batch of partition reassignments finishes.
+	HookEventPostBatch HookEvent = "postBatch"
+
+	// HookEventPostApply is run after an apply run that changed a topic, whether or not
+	// it succeeded.
+	HookEventPostApply HookEvent = "postApply"
+)
+
+var allHookEvents = []HookEvent{
+	HookEventPreSettings,
+	HookEventPreBatch,
+	HookEventPostBatch,
+	HookEventPostApply,
+}
+
+// IsPre returns whether the event is run before a change; failures of hooks for these
+// events stop the apply.
+func (e HookEvent) IsPre() bool {
+	return e == HookEventPreSettings || e == HookEventPreBatch
+}
+
+// HookConfig describes a hook that's run during apply. Each hook is either an exec command
+// or an HTTP webhook, and receives a JSON payload describing the change; commands get the
+// payload on stdin and webhooks get it as the body of a POST request.
+type HookConfig struct {
+	// Name identifies the hook in logs.
+	Name string `json:"name"`
+
+	// Events are the events that the hook is run for. If empty, the hook is run for all
+	// events.
+	Events []HookEvent `json:"events,omitempty"`
+
+	// Command is the command and arguments to run. Relative paths to the command, like
+	// ./scripts/hook.sh, are evaluated relative to the cluster config directory; bare command
+	// names are looked up in the PATH.
+	Command []string `json:"command,omitempty"`
+
+	// URL is the address that webhooks are posted to.
+	URL string `json:"url,omitempty"`
+
+	// Headers are extra HTTP headers added to webhook requests.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// TimeoutStr is the maximum amount of time that the hook can run for. Defaults to 30s.
+	TimeoutStr string `json:"timeout,omitempty"`
+}
+
+// RunsFor returns whether the hook should be run for the argument event.
+func (h HookConfig) RunsFor(event HookEvent) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, hookEvent := range h.Events {
+		if hookEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTimeout returns the timeout for the hook.
+func (h HookConfig) GetTimeout() (time.Duration, error) {
+	if h.TimeoutStr == "" {
+		return 30 * time.Second, nil
+	}
+
+	return time.ParseDuration(h.TimeoutStr)
+}
+
+// Validate evaluates whether the hook config is valid.
+func (h HookConfig) Validate() error {
+	var err error
+
+	if h.Name == "" {
+		err = multierror.Append(err, errors.New("Hook name must be set"))
+	}
+	if len(h.Command) == 0 && h.URL == "" {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Hook %s must set either command or url", h.Name),
+		)
+	}
+	if len(h.Command) > 0 && h.URL != "" {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Hook %s cannot set both command and url", h.Name),
+		)
+	}
+	if len(h.Headers) > 0 && h.URL == "" {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Hook %s sets headers but not url", h.Name),
+		)
+	}
+
+	for _, event := range h.Events {
+		found := false
+		for _, validEvent := range allHookEvents {
+			if event == validEvent {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err = multierror.Append(
+				err,
+				fmt.Errorf(
+					"Hook %s has unrecognized event %s; must be one of %+v",
+					h.Name,
+					event,
+					allHookEvents,
+				),
+			)
+		}
+	}
+
+	timeout, timeoutErr := h.GetTimeout()
+	if timeoutErr != nil {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Error parsing timeout for hook %s: %+v", h.Name, timeoutErr),
+		)
+	} else if timeout <= 0 {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Timeout for hook %s must be positive", h.Name),
+		)
+	}
+
+	return err
+}
+
+// NotifierType is the kind of destination that a notifier sends to.
+type NotifierType string
+
+const (
+	// NotifierTypeSlack posts messages to a Slack incoming webhook.
+	NotifierTypeSlack NotifierType = "slack"
+
+	// NotifierTypeDatadog creates events with the Datadog events API.
+	NotifierTypeDatadog NotifierType = "datadog"
+
+	// NotifierTypeWebhook posts a JSON description of the results to an arbitrary URL.
+	NotifierTypeWebhook NotifierType = "webhook"
+)
+
+var allNotifierTypes = []NotifierType{
+	NotifierTypeSlack,
+	NotifierTypeDatadog,
+	NotifierTypeWebhook,
+}
+
+// NotifierConfig describes a destination that's notified when apply and rebalance runs
+// finish or fail. Secrets like the API key and Slack webhook URL can be read from the
+// environment by loading the cluster config with --expand-env.
+type NotifierConfig struct {
+	// Name identifies the notifier in logs.
+	Name string `json:"name"`
+
+	// Type is the type of the notifier; one of slack, datadog, or webhook.
+	Type NotifierType `json:"type"`
+
+	// URL is the address that notifications are posted to. Required for slack and webhook
+	// notifiers; for datadog notifiers, defaults to the events endpoint of the US1 site.
+	URL string `json:"url,omitempty"`
+
+	// APIKey is the Datadog API key. Required for datadog notifiers.
+	APIKey string `json:"apiKey,omitempty"`
+
+	// Tags are extra tags, in key:value form, that are added to Datadog events.
+	Tags []string `json:"tags,omitempty"`
+
+	// Headers are extra HTTP headers added to webhook requests.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// DryRun is whether notifications are also sent for dry runs.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// TimeoutStr is the maximum amount of time that sending a notification can take.
+	// Defaults to 10s.
+	TimeoutStr string `json:"timeout,omitempty"`
+}
+
+// GetTimeout returns the timeout for sending notifications.
+func (n NotifierConfig) GetTimeout() (time.Duration, error) {
+	if n.TimeoutStr == "" {
+		return 10 * time.Second, nil
+	}
+
+	return time.ParseDuration(n.TimeoutStr)
+}
+
+// Validate evaluates whether the notifier config is valid.
+func (n NotifierConfig) Validate() error {
+	var err error
+
+	if n.Name == "" {
+		err = multierror.Append(err, errors.New("Notifier name must be set"))
+	}
+
+	found := false
+	for _, validType := range allNotifierTypes {
+		if n.Type == validType {
+			found = true
+			break
+		}
+	}
+	if !found {
+		err = multierror.Append(
+			err,
+			fmt.Errorf(
+				"Notifier %s has unrecognized type %s; must be one of %+v",
+				n.Name,
+				n.Type,
+				allNotifierTypes,
+			),
+		)
+	}
+
+	if n.URL == "" && n.Type != NotifierTypeDatadog {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Notifier %s must set url", n.Name),
+		)
+	}
+	if n.APIKey == "" && n.Type == NotifierTypeDatadog {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Notifier %s must set apiKey", n.Name),
+		)
+	}
+	if n.APIKey != "" && n.Type != NotifierTypeDatadog {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Notifier %s sets apiKey but isn't a datadog notifier", n.Name),
+		)
+	}
+	if len(n.Tags) > 0 && n.Type != NotifierTypeDatadog {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Notifier %s sets tags but isn't a datadog notifier", n.Name),
+		)
+	}
+	if len(n.Headers) > 0 && n.Type != NotifierTypeWebhook {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Notifier %s sets headers but isn't a webhook notifier", n.Name),
+		)
+	}
+
+	timeout, timeoutErr := n.GetTimeout()
+	if timeoutErr != nil {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Error parsing timeout for notifier %s: %+v", n.Name, timeoutErr),
+		)
+	} else if timeout <= 0 {
+		err = multierror.Append(
+			err,
+			fmt.Errorf("Timeout for notifier %s must be positive", n.Name),
+		)
+	}
+
+	return err
+}
+
+// BrokerWeightsConfig stores the relative capacities of the brokers in a cluster, e.g. if it
+// mixes instance sizes. Brokers that aren't covered have a weight of 1.
+type BrokerWeightsConfig struct {
+	// Racks are the weights of all of the brokers in each rack.
+	Racks map[string]float64 `json:"racks,omitempty"`
+
+	// Brokers are the weights of individual brokers, by ID. These take precedence over the
+	// rack weights.
+	Brokers map[int]float64 `json:"brokers,omitempty"`
+}
+
+// Weights returns the weight of each of the argument brokers. It returns nil if the config
+// is nil or empty, in which case all brokers are weighted equally.
+func (w *BrokerWeightsConfig) Weights(brokers []admin.BrokerInfo) map[int]float64 {
+	if w == nil || (len(w.Racks) == 0 && len(w.Brokers) == 0) {
+		return nil
+	}
+
+	weights := map[int]float64{}
+	for _, broker := range brokers {
+		weight := 1.0
+		if rackWeight, ok := w.Racks[broker.Rack]; ok {
+			weight = rackWeight
+		}
+		if brokerWeight, ok := w.Brokers[broker.ID]; ok {
+			weight = brokerWeight
+		}
+		weights[broker.ID] = weight
+	}
+
+	return weights
+}
+
+// Validate determines whether the broker weights are valid.
+func (w *BrokerWeightsConfig) Validate() error {
+	if w == nil {
+		return nil
+	}
+
+	var err error
+
+	for rack, weight := range w.Racks {
+		if weight <= 0 {
+			err = multierror.Append(
+				err,
+				fmt.Errorf("Weight for rack %s must be positive", rack),
+			)
+		}
+	}
+	for brokerID, weight := range w.Brokers {
+		if weight <= 0 {
+			err = multierror.Append(
+				err,
+				fmt.Errorf("Weight for broker %d must be positive", brokerID),
+			)
+		}
+	}
+
+	return err
+}
+
+// BrokerTagsConfig stores the tags of the brokers in a cluster.
+type BrokerTagsConfig struct {
+	// Racks are the tags of all of the brokers in each rack.
+	Racks map[string][]string `json:"racks,omitempty"`
+
+	// Brokers are the tags of individual brokers, by ID. These are added to the rack tags.
+	Brokers map[int][]string `json:"brokers,omitempty"`
+}
+
+// TagBrokers returns copies of the argument brokers with their tags set. The brokers are
+// returned unchanged if the config is nil.
+func (t *BrokerTagsConfig) TagBrokers(brokers []admin.BrokerInfo) []admin.BrokerInfo {
+	if t == nil {
+		return brokers
+	}
+
+	taggedBrokers := []admin.BrokerInfo{}
+	for _, broker := range brokers {
+		var tags []string
+		for _, tagList := range [][]string{t.Racks[broker.Rack], t.Brokers[broker.ID]} {
+			for _, tag := range tagList {
+				if !slices.Contains(tags, tag) {
+					tags = append(tags, tag)
+				}
+			}
+		}
+		sort.Strings(tags)
+
+		broker.Tags = tags
+		taggedBrokers = append(taggedBrokers, broker)
+	}
+
+	return taggedBrokers
+}
+
+// Validate determines whether the broker tags are valid.
+func (t *BrokerTagsConfig) Validate() error {
+	if t == nil {
+		return nil
+	}
+
+	var err error
+
+	for rack, tags := range t.Racks {
+		if slices.Contains(tags, "") {
+			err = multierror.Append(err, fmt.Errorf("Tags for rack %s cannot be empty", rack))
+		}
+	}
+	for brokerID, tags := range t.Brokers {
+		if slices.Contains(tags, "") {
+			err = multierror.Append(
+				err,
+				fmt.Errorf("Tags for broker %d cannot be empty", brokerID),
+			)
+		}
+	}
+
+	return err
+}
+
 // Validate evaluates whether the cluster config is valid.
 func (c ClusterConfig) Validate() error {
 	var err error
@@ -160,6 +577,35 @@ func (c ClusterConfig) Validate() error {
 		)
 	}
 
+	hookNames := map[string]struct{}{}
+	for _, hook := range c.Spec.Hooks {
+		if hookErr := hook.Validate(); hookErr != nil {
+			err = multierror.Append(err, hookErr)
+		}
+		if _, ok := hookNames[hook.Name]; ok {
+			err = multierror.Append(err, fmt.Errorf("Duplicate hook name %s", hook.Name))
+		}
+		hookNames[hook.Name] = struct{}{}
+	}
+
+	notifierNames := map[string]struct{}{}
+	for _, notifier := range c.Spec.Notifiers {
+		if notifierErr := notifier.Validate(); notifierErr != nil {
+			err = multierror.Append(err, notifierErr)
+		}
+		if _, ok := notifierNames[notifier.Name]; ok {
+			err = multierror.Append(err, fmt.Errorf("Duplicate notifier name %s", notifier.Name))
+		}
+		notifierNames[notifier.Name] = struct{}{}
+	}
+
+	if weightsErr := c.Spec.BrokerWeights.Validate(); weightsErr != nil {
+		err = multierror.Append(err, weightsErr)
+	}
+	if tagsErr := c.Spec.BrokerTags.Validate(); tagsErr != nil {
+		err = multierror.Append(err, tagsErr)
+	}
+
 	if c.Spec.SASL.Enabled {
 		saslMechanism, saslErr := admin.SASLNameToMechanism(c.Spec.SASL.Mechanism)
 		if saslErr != nil {
@@ -188,6 +634,15 @@ func (c ClusterConfig) Validate() error {
 	return err
 }
 
+// ToYAML converts the current ClusterConfig to a YAML string.
+func (c ClusterConfig) ToYAML() (string, error) {
+	outBytes, err := yaml.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return string(outBytes), nil
+}
+
 // GetDefaultRetentionDropStepDuration gets the default step size to use when reducing
 // the message retention in a topic.
 func (c ClusterConfig) GetDefaultRetentionDropStepDuration() (time.Duration, error) {
@@ -289,6 +744,20 @@ func (c ClusterConfig) NewAdminClient(
 	}
 }
 
+// HookCommand returns the command and arguments for the argument hook, with relative paths
+// to the command resolved against the cluster config directory.
+func (c ClusterConfig) HookCommand(hook HookConfig) []string {
+	if len(hook.Command) == 0 {
+		return nil
+	}
+
+	command := append([]string{}, hook.Command...)
+	if strings.ContainsRune(command[0], filepath.Separator) {
+		command[0] = c.absPath(command[0])
+	}
+	return command
+}
+
 func (c ClusterConfig) absPath(relPath string) string {
 	if relPath == "" || c.RootDir == "" || filepath.IsAbs(relPath) {
 		return relPath
@@ -3,7 +3,9 @@ package config
 import (
 	"testing"
 
+	"github.com/segmentio/topicctl/pkg/admin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestClusterValidate(t *testing.T) {
@@ -136,6 +138,241 @@ func TestClusterValidate(t *testing.T) {
 			},
 			expError: true,
 		},
+		{
+			description: "valid hooks",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					Hooks: []HookConfig{
+						{
+							Name:    "pause-consumers",
+							Events:  []HookEvent{HookEventPreBatch, HookEventPostBatch},
+							Command: []string{"./scripts/pause.sh"},
+						},
+						{
+							Name:       "dashboard",
+							URL:        "http://localhost:8080/hooks",
+							Headers:    map[string]string{"Authorization": "Bearer token"},
+							TimeoutStr: "5s",
+						},
+					},
+				},
+			},
+			expError: false,
+		},
+		{
+			description: "invalid hooks",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					Hooks: []HookConfig{
+						{
+							Name:    "both-command-and-url",
+							Command: []string{"./scripts/pause.sh"},
+							URL:     "http://localhost:8080/hooks",
+						},
+						{
+							Name:    "bad-event",
+							Events:  []HookEvent{"preDelete"},
+							Command: []string{"./scripts/pause.sh"},
+						},
+						{
+							Name:       "bad-timeout",
+							URL:        "http://localhost:8080/hooks",
+							TimeoutStr: "10xxx",
+						},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "duplicate hook names",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					Hooks: []HookConfig{
+						{
+							Name: "dashboard",
+							URL:  "http://localhost:8080/hooks",
+						},
+						{
+							Name: "dashboard",
+							URL:  "http://localhost:8081/hooks",
+						},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "valid notifiers",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					Notifiers: []NotifierConfig{
+						{
+							Name: "slack",
+							Type: NotifierTypeSlack,
+							URL:  "https://hooks.slack.com/services/xxx",
+						},
+						{
+							Name:   "datadog",
+							Type:   NotifierTypeDatadog,
+							APIKey: "test-key",
+							Tags:   []string{"team:data"},
+						},
+						{
+							Name:       "webhook",
+							Type:       NotifierTypeWebhook,
+							URL:        "http://localhost:8080/notify",
+							Headers:    map[string]string{"Authorization": "Bearer token"},
+							TimeoutStr: "5s",
+						},
+					},
+				},
+			},
+			expError: false,
+		},
+		{
+			description: "invalid notifiers",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					Notifiers: []NotifierConfig{
+						{
+							Name: "bad-type",
+							Type: "pagerduty",
+							URL:  "http://localhost:8080/notify",
+						},
+						{
+							Name: "datadog-without-key",
+							Type: NotifierTypeDatadog,
+						},
+						{
+							Name:    "slack-with-headers",
+							Type:    NotifierTypeSlack,
+							URL:     "https://hooks.slack.com/services/xxx",
+							Headers: map[string]string{"Authorization": "Bearer token"},
+						},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "duplicate notifier names",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					Notifiers: []NotifierConfig{
+						{
+							Name: "slack",
+							Type: NotifierTypeSlack,
+							URL:  "https://hooks.slack.com/services/xxx",
+						},
+						{
+							Name: "slack",
+							Type: NotifierTypeSlack,
+							URL:  "https://hooks.slack.com/services/yyy",
+						},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "valid broker weights",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					BrokerWeights: &BrokerWeightsConfig{
+						Racks:   map[string]float64{"us-east-1a": 2.0},
+						Brokers: map[int]float64{3: 0.5},
+					},
+				},
+			},
+			expError: false,
+		},
+		{
+			description: "non-positive broker weights",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					BrokerWeights: &BrokerWeightsConfig{
+						Racks:   map[string]float64{"us-east-1a": 0},
+						Brokers: map[int]float64{3: -1.0},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "empty broker tag",
+			clusterConfig: ClusterConfig{
+				Meta: ClusterMeta{
+					Name:        "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "test-description",
+				},
+				Spec: ClusterSpec{
+					BootstrapAddrs: []string{"broker-addr"},
+					BrokerTags: &BrokerTagsConfig{
+						Brokers: map[int][]string{3: {""}},
+					},
+				},
+			},
+			expError: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -147,3 +384,92 @@ func TestClusterValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestBrokerWeights(t *testing.T) {
+	brokers := []admin.BrokerInfo{
+		{ID: 1, Rack: "rack1"},
+		{ID: 2, Rack: "rack1"},
+		{ID: 3, Rack: "rack2"},
+		{ID: 4, Rack: "rack3"},
+	}
+
+	var nilWeights *BrokerWeightsConfig
+	assert.Nil(t, nilWeights.Weights(brokers))
+	assert.Nil(t, (&BrokerWeightsConfig{}).Weights(brokers))
+
+	weights := &BrokerWeightsConfig{
+		Racks: map[string]float64{
+			"rack1": 2.0,
+			"rack2": 0.5,
+		},
+		Brokers: map[int]float64{
+			2: 3.0,
+		},
+	}
+	assert.Equal(
+		t,
+		map[int]float64{
+			1: 2.0,
+			2: 3.0,
+			3: 0.5,
+			4: 1.0,
+		},
+		weights.Weights(brokers),
+	)
+}
+
+func TestBrokerTags(t *testing.T) {
+	brokers := []admin.BrokerInfo{
+		{ID: 1, Rack: "rack1"},
+		{ID: 2, Rack: "rack1"},
+		{ID: 3, Rack: "rack2"},
+	}
+
+	var nilTags *BrokerTagsConfig
+	assert.Equal(t, brokers, nilTags.TagBrokers(brokers))
+
+	tags := &BrokerTagsConfig{
+		Racks: map[string][]string{
+			"rack1": {"ssd"},
+		},
+		Brokers: map[int][]string{
+			2: {"dedicated-team-x", "ssd"},
+			3: {"draining"},
+		},
+	}
+	taggedBrokers := tags.TagBrokers(brokers)
+	assert.Equal(t, []string{"ssd"}, taggedBrokers[0].Tags)
+	assert.Equal(t, []string{"dedicated-team-x", "ssd"}, taggedBrokers[1].Tags)
+	assert.Equal(t, []string{"draining"}, taggedBrokers[2].Tags)
+
+	// The argument brokers shouldn't be changed
+	assert.Nil(t, brokers[0].Tags)
+}
+
+func TestClusterConfigToYAML(t *testing.T) {
+	clusterConfig := ClusterConfig{
+		Meta: ClusterMeta{
+			Name:        "test-cluster",
+			Region:      "test-region",
+			Environment: "test-environment",
+			Description: "Test cluster",
+		},
+		Spec: ClusterSpec{
+			BootstrapAddrs:    []string{"broker-addr:9092"},
+			ClusterID:         "test-cluster-id",
+			DefaultThrottleMB: 50,
+			TLS: TLSConfig{
+				Enabled:    true,
+				CACertPath: "certs/ca.crt",
+			},
+		},
+	}
+
+	yamlStr, err := clusterConfig.ToYAML()
+	require.NoError(t, err)
+
+	loadedConfig := ClusterConfig{}
+	require.NoError(t, unmarshalYAMLStrict([]byte(yamlStr), &loadedConfig))
+	assert.Equal(t, clusterConfig, loadedConfig)
+	assert.NoError(t, loadedConfig.Validate())
+}
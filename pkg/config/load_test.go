@@ -164,6 +164,27 @@ func TestLoadACLsFile(t *testing.T) {
 	assert.Equal(t, 2, len(multiAclConfigs))
 	assert.Equal(t, "acl-test1", multiAclConfigs[0].Meta.Name)
 	assert.Equal(t, "acl-test2", multiAclConfigs[1].Meta.Name)
+
+	roleAclConfigs, err := LoadACLsFile("testdata/test-cluster/acls/acl-test-roles.yaml")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(roleAclConfigs))
+	assert.Equal(
+		t,
+		[]ACLRole{
+			{
+				Principal: "User:Alice",
+				Producer: &ACLProducerRole{
+					Topics:           []string{"test-topic"},
+					TransactionalIDs: []string{"test-txn"},
+				},
+				Consumer: &ACLConsumerRole{
+					Topics: []string{"test-topic"},
+					Groups: []string{"test-group"},
+				},
+			},
+		},
+		roleAclConfigs[0].Spec.Roles,
+	)
 }
 
 func TestCheckConsistency(t *testing.T) {
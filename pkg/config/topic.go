@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/ghodss/yaml"
 	"github.com/hashicorp/go-multierror"
@@ -39,6 +40,11 @@ const (
 	// are chosen from the rack in a static list, but the specific replicas within each partition
 	// aren't specified.
 	PlacementStrategyStaticInRack PlacementStrategy = "static-in-rack"
+
+	// PlacementStrategyLeaderRack is a strategy in which the leaders are balanced across the
+	// racks in a preferred list and the followers in each partition are spread to separate
+	// racks from the leader and each other.
+	PlacementStrategyLeaderRack PlacementStrategy = "leader-rack"
 )
 
 var allPlacementStrategies = []PlacementStrategy{
@@ -48,6 +54,7 @@ var allPlacementStrategies = []PlacementStrategy{
 	PlacementStrategyCrossRack,
 	PlacementStrategyStatic,
 	PlacementStrategyStaticInRack,
+	PlacementStrategyLeaderRack,
 }
 
 // PickerMethod is a string type that stores a picker method for breaking ties when choosing
@@ -105,6 +112,57 @@ type TopicPlacementConfig struct {
 	// StaticRackAssignments is a list of list of desired replica assignments. It's used
 	// for the "static-in-rack" strategy only.
 	StaticRackAssignments []string `json:"staticRackAssignments,omitempty"`
+
+	// PreferredLeaderRacks is a list of racks that the partition leaders should be in. It's
+	// used for the "leader-rack" strategy only.
+	PreferredLeaderRacks []string `json:"preferredLeaderRacks,omitempty"`
+
+	// RequireTags, AvoidTags, and ExcludeBrokers restrict the brokers that replicas can be
+	// placed on, in addition to the strategy. Tags are set in the cluster config. They apply
+	// to all strategies.
+	RequireTags    []string `json:"requireTags,omitempty"`
+	AvoidTags      []string `json:"avoidTags,omitempty"`
+	ExcludeBrokers []int    `json:"excludeBrokers,omitempty"`
+}
+
+// HasConstraints returns whether the placement config restricts the brokers that replicas
+// can be placed on.
+func (p TopicPlacementConfig) HasConstraints() bool {
+	return len(p.RequireTags) > 0 || len(p.AvoidTags) > 0 || len(p.ExcludeBrokers) > 0
+}
+
+// BrokerAllowed returns whether replicas can be placed on the argument broker. The broker
+// must have all of the required tags, none of the avoided ones, and not be excluded.
+func (p TopicPlacementConfig) BrokerAllowed(broker admin.BrokerInfo) bool {
+	if slices.Contains(p.ExcludeBrokers, broker.ID) {
+		return false
+	}
+	for _, tag := range p.RequireTags {
+		if !slices.Contains(broker.Tags, tag) {
+			return false
+		}
+	}
+	for _, tag := range p.AvoidTags {
+		if slices.Contains(broker.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// AllowedBrokers returns the subset of the argument brokers that replicas can be placed on.
+func (p TopicPlacementConfig) AllowedBrokers(brokers []admin.BrokerInfo) []admin.BrokerInfo {
+	if !p.HasConstraints() {
+		return brokers
+	}
+
+	allowed := []admin.BrokerInfo{}
+	for _, broker := range brokers {
+		if p.BrokerAllowed(broker) {
+			allowed = append(allowed, broker)
+		}
+	}
+	return allowed
 }
 
 // TopicMigrationConfig configures the throttles and batch sizes used when
@@ -113,6 +171,26 @@ type TopicPlacementConfig struct {
 type TopicMigrationConfig struct {
 	ThrottleMB         int64 `json:"throttleMB"`
 	PartitionBatchSize int   `json:"partitionBatchSize"`
+
+	// BatchBytes, if set, packs partition reassignments into batches that copy at most this
+	// many bytes to new replicas, based on the current partition sizes. If PartitionBatchSize
+	// is also set, then batches are limited by both.
+	BatchBytes int64 `json:"batchBytes,omitempty"`
+
+	// AdaptiveThrottle, if set, adjusts the broker throttles during migrations based on
+	// the health of the cluster and the progress of each batch.
+	AdaptiveThrottle *AdaptiveThrottleConfig `json:"adaptiveThrottle,omitempty"`
+}
+
+// AdaptiveThrottleConfig sets the bounds for adaptive throttles. The throttle starts
+// at the regular (non-adaptive) value, clamped to these bounds.
+type AdaptiveThrottleConfig struct {
+	MinThrottleMB int64 `json:"minThrottleMB"`
+	MaxThrottleMB int64 `json:"maxThrottleMB"`
+
+	// StepPercent is the percentage by which the throttle is raised or lowered in each
+	// adjustment. Defaults to 20 if unset.
+	StepPercent int `json:"stepPercent,omitempty"`
 }
 
 // ToNewTopicConfig converts a TopicConfig to a kafka.TopicConfig that can be
@@ -152,11 +230,17 @@ func (t *TopicConfig) SetDefaults() {
 		t.Spec.MigrationConfig = &TopicMigrationConfig{}
 	}
 
-	if t.Spec.MigrationConfig.PartitionBatchSize == 0 {
+	if t.Spec.MigrationConfig.PartitionBatchSize == 0 &&
+		t.Spec.MigrationConfig.BatchBytes == 0 {
 		// Migration partitions one at a time
 		t.Spec.MigrationConfig.PartitionBatchSize = 1
 	}
 
+	if t.Spec.MigrationConfig.AdaptiveThrottle != nil &&
+		t.Spec.MigrationConfig.AdaptiveThrottle.StepPercent == 0 {
+		t.Spec.MigrationConfig.AdaptiveThrottle.StepPercent = 20
+	}
+
 	if t.Spec.PlacementConfig.Picker == "" {
 		t.Spec.PlacementConfig.Picker = PickerMethodRandomized
 	}
@@ -195,6 +279,27 @@ func (t TopicConfig) Validate(numRacks int) error {
 		)
 	}
 
+	if t.Spec.MigrationConfig != nil && t.Spec.MigrationConfig.BatchBytes < 0 {
+		err = multierror.Append(err, errors.New("Migration batch bytes must be >= 0"))
+	}
+
+	if t.Spec.MigrationConfig != nil && t.Spec.MigrationConfig.AdaptiveThrottle != nil {
+		adaptiveThrottle := t.Spec.MigrationConfig.AdaptiveThrottle
+
+		if adaptiveThrottle.MinThrottleMB <= 0 {
+			err = multierror.Append(err, errors.New("Adaptive throttle min must be > 0"))
+		}
+		if adaptiveThrottle.MaxThrottleMB < adaptiveThrottle.MinThrottleMB {
+			err = multierror.Append(err, errors.New("Adaptive throttle max must be >= min"))
+		}
+		if adaptiveThrottle.StepPercent < 0 || adaptiveThrottle.StepPercent >= 100 {
+			err = multierror.Append(
+				err,
+				errors.New("Adaptive throttle step percent must be between 0 and 100"),
+			)
+		}
+	}
+
 	placement := t.Spec.PlacementConfig
 
 	strategyIndex := -1
@@ -259,6 +364,23 @@ func (t TopicConfig) Validate(numRacks int) error {
 				),
 			)
 		}
+	case PlacementStrategyLeaderRack:
+		if len(placement.PreferredLeaderRacks) == 0 {
+			err = multierror.Append(
+				err,
+				errors.New("Preferred leader racks must be set for leader-rack placement"),
+			)
+		}
+		if numRacks > 0 && t.Spec.ReplicationFactor > numRacks {
+			err = multierror.Append(
+				err,
+				fmt.Errorf(
+					"Replication factor (%d) cannot be larger than the number of racks (%d)",
+					t.Spec.ReplicationFactor,
+					numRacks,
+				),
+			)
+		}
 	case PlacementStrategyInRack:
 	case PlacementStrategyStatic:
 		if len(placement.StaticAssignments) != t.Spec.Partitions {
@@ -286,6 +408,27 @@ func (t TopicConfig) Validate(numRacks int) error {
 		}
 	}
 
+	for _, tag := range placement.RequireTags {
+		if slices.Contains(placement.AvoidTags, tag) {
+			err = multierror.Append(
+				err,
+				fmt.Errorf("Tag %s cannot be both required and avoided", tag),
+			)
+		}
+	}
+	if placement.Strategy == PlacementStrategyStatic {
+		for _, replicas := range placement.StaticAssignments {
+			for _, replica := range replicas {
+				if slices.Contains(placement.ExcludeBrokers, replica) {
+					err = multierror.Append(
+						err,
+						fmt.Errorf("Static assignments cannot use excluded broker %d", replica),
+					)
+				}
+			}
+		}
+	}
+
 	// Warn about the partition count in the non-balanced-leaders case
 	if numRacks > 0 &&
 		placement.Strategy != PlacementStrategyBalancedLeaders &&
@@ -300,6 +300,192 @@ func TestTopicValidate(t *testing.T) {
 			},
 			expError: true,
 		},
+		{
+			description: "valid adaptive throttle",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 3,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy: PlacementStrategyAny,
+					},
+					MigrationConfig: &TopicMigrationConfig{
+						AdaptiveThrottle: &AdaptiveThrottleConfig{
+							MinThrottleMB: 10,
+							MaxThrottleMB: 100,
+							StepPercent:   20,
+						},
+					},
+				},
+			},
+			expError: false,
+		},
+		{
+			description: "adaptive throttle max below min",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 3,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy: PlacementStrategyAny,
+					},
+					MigrationConfig: &TopicMigrationConfig{
+						AdaptiveThrottle: &AdaptiveThrottleConfig{
+							MinThrottleMB: 100,
+							MaxThrottleMB: 10,
+						},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "negative batch bytes",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 3,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy: PlacementStrategyAny,
+					},
+					MigrationConfig: &TopicMigrationConfig{
+						BatchBytes: -1,
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "placement constraints",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Bootstrapped via topicctl bootstrap",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 2,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy:       PlacementStrategyAny,
+						RequireTags:    []string{"ssd"},
+						AvoidTags:      []string{"draining"},
+						ExcludeBrokers: []int{3},
+					},
+				},
+			},
+			expError: false,
+		},
+		{
+			description: "tag both required and avoided",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Bootstrapped via topicctl bootstrap",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 2,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy:    PlacementStrategyAny,
+						RequireTags: []string{"ssd"},
+						AvoidTags:   []string{"ssd"},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "static placement with excluded broker",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Bootstrapped via topicctl bootstrap",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 2,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy: PlacementStrategyStatic,
+						StaticAssignments: [][]int{
+							{1, 2},
+							{2, 3},
+						},
+						ExcludeBrokers: []int{3},
+					},
+				},
+			},
+			expError: true,
+		},
+		{
+			description: "leader-rack placement",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Bootstrapped via topicctl bootstrap",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 2,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy:             PlacementStrategyLeaderRack,
+						PreferredLeaderRacks: []string{"zone1", "zone2"},
+					},
+				},
+			},
+			numRacks: 3,
+			expError: false,
+		},
+		{
+			description: "leader-rack placement without preferred racks",
+			topicConfig: TopicConfig{
+				Meta: ResourceMeta{
+					Name:        "test-topic",
+					Cluster:     "test-cluster",
+					Region:      "test-region",
+					Environment: "test-environment",
+					Description: "Bootstrapped via topicctl bootstrap",
+				},
+				Spec: TopicSpec{
+					Partitions:        2,
+					ReplicationFactor: 2,
+					PlacementConfig: TopicPlacementConfig{
+						Strategy: PlacementStrategyLeaderRack,
+					},
+				},
+			},
+			numRacks: 3,
+			expError: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -313,6 +499,29 @@ func TestTopicValidate(t *testing.T) {
 	}
 }
 
+func TestTopicPlacementAllowedBrokers(t *testing.T) {
+	brokers := []admin.BrokerInfo{
+		{ID: 1, Tags: []string{"ssd"}},
+		{ID: 2, Tags: []string{"draining", "ssd"}},
+		{ID: 3, Tags: []string{"ssd"}},
+		{ID: 4},
+	}
+
+	assert.Equal(t, brokers, TopicPlacementConfig{}.AllowedBrokers(brokers))
+
+	placementConfig := TopicPlacementConfig{
+		RequireTags:    []string{"ssd"},
+		AvoidTags:      []string{"draining"},
+		ExcludeBrokers: []int{3},
+	}
+	assert.True(t, placementConfig.HasConstraints())
+	assert.Equal(
+		t,
+		[]int{1},
+		admin.BrokerIDs(placementConfig.AllowedBrokers(brokers)),
+	)
+}
+
 func TestTopicConfigFromTopicInfo(t *testing.T) {
 	type testCase struct {
 		description    string
@@ -243,8 +243,14 @@ func FormatMemberLags(memberLags []MemberPartitionLag, full bool) string {
 		}
 
 		var memberTimeStr string
+		var newestTimeStr string
 		var timeLagStr string
 
+		// Lags from cluster snapshots don't have any times set.
+		if !memberLag.NewestTime.IsZero() {
+			newestTimeStr = memberLag.NewestTime.Format(time.RFC3339)
+		}
+
 		// For whatever reason, the time on the last member message sometimes isn't properly set;
 		// only show this and the time lag if it's set.
 		if !memberLag.MemberTime.IsZero() {
@@ -259,7 +265,7 @@ func FormatMemberLags(memberLags []MemberPartitionLag, full bool) string {
 				fmt.Sprintf("%d", memberLag.MemberOffset),
 				memberTimeStr,
 				fmt.Sprintf("%d", memberLag.NewestOffset),
-				memberLag.NewestTime.Format(time.RFC3339),
+				newestTimeStr,
 				fmt.Sprintf("%d", memberLag.OffsetLag()),
 				timeLagStr,
 			},
@@ -0,0 +1,68 @@
+package groups
+
+import (
+	"sort"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+)
+
+// GroupCoordinatorsFromOffsets returns the consumer groups in a set of offsets from a cluster
+// snapshot.
+func GroupCoordinatorsFromOffsets(groupOffsets []admin.GroupOffset) []GroupCoordinator {
+	groupsMap := map[string]*GroupCoordinator{}
+	topicsMap := map[string]map[string]struct{}{}
+
+	for _, groupOffset := range groupOffsets {
+		groupCoordinator, ok := groupsMap[groupOffset.GroupID]
+		if !ok {
+			groupCoordinator = &GroupCoordinator{
+				GroupID:     groupOffset.GroupID,
+				Coordinator: groupOffset.Coordinator,
+				Topics:      []string{},
+			}
+			groupsMap[groupOffset.GroupID] = groupCoordinator
+			topicsMap[groupOffset.GroupID] = map[string]struct{}{}
+		}
+
+		if _, ok := topicsMap[groupOffset.GroupID][groupOffset.Topic]; !ok {
+			topicsMap[groupOffset.GroupID][groupOffset.Topic] = struct{}{}
+			groupCoordinator.Topics = append(groupCoordinator.Topics, groupOffset.Topic)
+		}
+	}
+
+	groupCoordinators := []GroupCoordinator{}
+	for _, groupCoordinator := range groupsMap {
+		sort.Strings(groupCoordinator.Topics)
+		groupCoordinators = append(groupCoordinators, *groupCoordinator)
+	}
+
+	sort.Slice(groupCoordinators, func(a, b int) bool {
+		return groupCoordinators[a].GroupID < groupCoordinators[b].GroupID
+	})
+
+	return groupCoordinators
+}
+
+// MemberLagsFromOffsets returns the lag of each partition in a set of offsets from a cluster
+// snapshot. Snapshots don't include group members or message times, so these are left unset.
+func MemberLagsFromOffsets(groupOffsets []admin.GroupOffset) []MemberPartitionLag {
+	memberLags := []MemberPartitionLag{}
+
+	for _, groupOffset := range groupOffsets {
+		memberLags = append(
+			memberLags,
+			MemberPartitionLag{
+				Topic:        groupOffset.Topic,
+				Partition:    groupOffset.Partition,
+				NewestOffset: groupOffset.NewestOffset,
+				MemberOffset: groupOffset.Offset,
+			},
+		)
+	}
+
+	sort.Slice(memberLags, func(a, b int) bool {
+		return memberLags[a].Partition < memberLags[b].Partition
+	})
+
+	return memberLags
+}
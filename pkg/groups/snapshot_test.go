@@ -0,0 +1,45 @@
+package groups
+
+import (
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/admin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupCoordinatorsFromOffsets(t *testing.T) {
+	assert.Equal(
+		t,
+		[]GroupCoordinator{
+			{GroupID: "group1", Coordinator: 1, Topics: []string{"topic1", "topic2"}},
+			{GroupID: "group2", Coordinator: 2, Topics: []string{"topic1"}},
+		},
+		GroupCoordinatorsFromOffsets(
+			[]admin.GroupOffset{
+				{GroupID: "group2", Coordinator: 2, Topic: "topic1", Partition: 0},
+				{GroupID: "group1", Coordinator: 1, Topic: "topic2", Partition: 0},
+				{GroupID: "group1", Coordinator: 1, Topic: "topic1", Partition: 1},
+				{GroupID: "group1", Coordinator: 1, Topic: "topic1", Partition: 0},
+			},
+		),
+	)
+	assert.Equal(t, []GroupCoordinator{}, GroupCoordinatorsFromOffsets(nil))
+}
+
+func TestMemberLagsFromOffsets(t *testing.T) {
+	memberLags := MemberLagsFromOffsets(
+		[]admin.GroupOffset{
+			{GroupID: "group1", Topic: "topic1", Partition: 1, Offset: 5, NewestOffset: 20},
+			{GroupID: "group1", Topic: "topic1", Partition: 0, Offset: 10, NewestOffset: 15},
+		},
+	)
+	assert.Equal(
+		t,
+		[]MemberPartitionLag{
+			{Topic: "topic1", Partition: 0, MemberOffset: 10, NewestOffset: 15},
+			{Topic: "topic1", Partition: 1, MemberOffset: 5, NewestOffset: 20},
+		},
+		memberLags,
+	)
+	assert.Equal(t, int64(15), memberLags[1].OffsetLag())
+}
@@ -0,0 +1,316 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/segmentio/topicctl/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultDatadogURL = "https://api.datadoghq.com/api/v1/events"
+
+// Notification describes the results of an apply or rebalance run that are sent to the
+// notifiers configured for a cluster.
+type Notification struct {
+	Title       string `json:"title"`
+	Cluster     string `json:"cluster"`
+	Environment string `json:"environment"`
+	Region      string `json:"region"`
+	Topic       string `json:"topic,omitempty"`
+	DryRun      bool   `json:"dryRun"`
+
+	// Error is set if the run failed.
+	Error string `json:"error,omitempty"`
+
+	// Changes are the changes made to the topic, for apply notifications.
+	Changes apply.NewOrUpdatedChanges `json:"changes,omitempty"`
+
+	// TopicErrors are the errors for each failed topic, for rebalance notifications.
+	TopicErrors map[string]string `json:"topicErrors,omitempty"`
+
+	// Text is the body of the notification as a plain-text table.
+	Text string `json:"text"`
+
+	headers []string
+	rows    [][]string
+}
+
+// TopicApplyNotification returns a notification describing the results of applying a topic.
+// It returns nil if nothing changed and there was no error.
+func TopicApplyNotification(
+	clusterConfig config.ClusterConfig,
+	topicName string,
+	dryRun bool,
+	changes apply.NewOrUpdatedChanges,
+	applyErr error,
+) *Notification {
+	headers, rows := changeRows(changes)
+	if len(rows) == 0 && applyErr == nil {
+		return nil
+	}
+
+	notification := &Notification{
+		Cluster:     clusterConfig.Meta.Name,
+		Environment: clusterConfig.Meta.Environment,
+		Region:      clusterConfig.Meta.Region,
+		Topic:       topicName,
+		DryRun:      dryRun,
+		headers:     headers,
+		rows:        rows,
+	}
+	if len(rows) > 0 {
+		notification.Changes = changes
+	}
+
+	if applyErr != nil {
+		notification.Error = applyErr.Error()
+		notification.Title = fmt.Sprintf(
+			"Topicctl failed to apply topic %s in cluster %s",
+			topicName,
+			clusterConfig.Meta.Name,
+		)
+	} else {
+		notification.Title = fmt.Sprintf(
+			"Topicctl ran apply on topic %s in cluster %s",
+			topicName,
+			clusterConfig.Meta.Name,
+		)
+	}
+	notification.finish()
+
+	return notification
+}
+
+// RebalanceNotification returns a notification describing the results of a rebalance run.
+// The argument topic errors map contains an entry for each topic in the run, with nil values
+// for the topics that were rebalanced successfully. The changes map contains the changes made
+// to each topic, if any.
+func RebalanceNotification(
+	clusterConfig config.ClusterConfig,
+	dryRun bool,
+	topicErrors map[string]error,
+	changes map[string]apply.NewOrUpdatedChanges,
+) *Notification {
+	notification := &Notification{
+		Cluster:     clusterConfig.Meta.Name,
+		Environment: clusterConfig.Meta.Environment,
+		Region:      clusterConfig.Meta.Region,
+		DryRun:      dryRun,
+		headers:     []string{"Topic", "Partitions Moved", "Result"},
+		rows:        [][]string{},
+	}
+
+	topicNames := []string{}
+	for topicName := range topicErrors {
+		topicNames = append(topicNames, topicName)
+	}
+	sort.Strings(topicNames)
+
+	for _, topicName := range topicNames {
+		result := "success"
+		if topicErr := topicErrors[topicName]; topicErr != nil {
+			if notification.TopicErrors == nil {
+				notification.TopicErrors = map[string]string{}
+			}
+			notification.TopicErrors[topicName] = topicErr.Error()
+			result = "error"
+		}
+
+		notification.rows = append(
+			notification.rows,
+			[]string{topicName, fmt.Sprintf("%d", numMoves(changes[topicName])), result},
+		)
+	}
+
+	if len(notification.TopicErrors) > 0 {
+		errorTopics := []string{}
+		for _, topicName := range topicNames {
+			if topicErr, ok := notification.TopicErrors[topicName]; ok {
+				errorTopics = append(errorTopics, fmt.Sprintf("%s: %s", topicName, topicErr))
+			}
+		}
+		notification.Error = strings.Join(errorTopics, "\n")
+		notification.Title = fmt.Sprintf(
+			"Topicctl rebalance of cluster %s failed for %d of %d topics",
+			clusterConfig.Meta.Name,
+			len(notification.TopicErrors),
+			len(topicNames),
+		)
+	} else {
+		notification.Title = fmt.Sprintf(
+			"Topicctl rebalanced %d topics in cluster %s",
+			len(topicNames),
+			clusterConfig.Meta.Name,
+		)
+	}
+	notification.finish()
+
+	return notification
+}
+
+func (n *Notification) finish() {
+	if n.DryRun {
+		n.Title = fmt.Sprintf("Dry run: %s", n.Title)
+	}
+	n.Text = n.body(config.NotifierTypeWebhook)
+}
+
+func (n *Notification) body(notifierType config.NotifierType) string {
+	return makeTable(n.headers, n.rows, n.Error, notifierType)
+}
+
+// Send sends the argument notification to each of the notifiers in the cluster config.
+// Failures are logged and otherwise ignored so that they don't affect the results of the run.
+// It's a no-op if the notification is nil.
+func Send(
+	ctx context.Context,
+	clusterConfig config.ClusterConfig,
+	notification *Notification,
+) {
+	if notification == nil {
+		return
+	}
+
+	for _, notifier := range clusterConfig.Spec.Notifiers {
+		if notification.DryRun && !notifier.DryRun {
+			continue
+		}
+
+		log.Debugf("Sending notification to %s", notifier.Name)
+		if err := send(ctx, notifier, *notification); err != nil {
+			log.Warnf("Error sending notification to %s: %+v", notifier.Name, err)
+		}
+	}
+}
+
+func send(
+	ctx context.Context,
+	notifier config.NotifierConfig,
+	notification Notification,
+) error {
+	timeout, err := notifier.GetTimeout()
+	if err != nil {
+		return err
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var url string
+	var body interface{}
+	headers := map[string]string{}
+
+	switch notifier.Type {
+	case config.NotifierTypeSlack:
+		url = notifier.URL
+		body = map[string]string{
+			"text": fmt.Sprintf(
+				"*%s*\n%s",
+				notification.Title,
+				truncate(notification.body(config.NotifierTypeSlack), slackMaxLength),
+			),
+		}
+	case config.NotifierTypeDatadog:
+		url = notifier.URL
+		if url == "" {
+			url = defaultDatadogURL
+		}
+		headers["DD-API-KEY"] = notifier.APIKey
+
+		alertType := "info"
+		if notification.Error != "" {
+			alertType = "error"
+		}
+		tags := []string{
+			"source:topicctl",
+			fmt.Sprintf("cluster:%s", notification.Cluster),
+			fmt.Sprintf("environment:%s", notification.Environment),
+			fmt.Sprintf("region:%s", notification.Region),
+		}
+		if notification.Topic != "" {
+			tags = append(tags, fmt.Sprintf("topicctl_topic:%s", notification.Topic))
+		}
+		tags = append(tags, notifier.Tags...)
+
+		body = map[string]interface{}{
+			"title": notification.Title,
+			"text": truncate(
+				notification.body(config.NotifierTypeDatadog),
+				datadogMaxLength,
+			),
+			"tags":             tags,
+			"alert_type":       alertType,
+			"source_type_name": "topicctl",
+		}
+	case config.NotifierTypeWebhook:
+		url = notifier.URL
+		for key, value := range notifier.Headers {
+			headers[key] = value
+		}
+		body = notification
+	default:
+		return fmt.Errorf("Unrecognized notifier type: %s", notifier.Type)
+	}
+
+	contents, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(
+		sendCtx,
+		http.MethodPost,
+		url,
+		bytes.NewReader(contents),
+	)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf(
+			"Notifier returned status %d: %s",
+			resp.StatusCode,
+			strings.TrimSpace(string(respBody)),
+		)
+	}
+
+	return nil
+}
+
+// numMoves returns the number of partitions whose replicas were changed. Partitions without
+// updated replicas are tracked but weren't moved, so they aren't counted.
+func numMoves(changes apply.NewOrUpdatedChanges) int {
+	updateChanges, ok := changes.(*apply.UpdateChangesTracker)
+	if !ok || updateChanges == nil || updateChanges.ReplicaAssignments == nil {
+		return 0
+	}
+
+	moves := 0
+	for _, assignment := range *updateChanges.ReplicaAssignments {
+		if assignment.UpdatedReplicas != nil &&
+			!util.SameElements(assignment.CurrentReplicas, assignment.UpdatedReplicas) {
+			moves++
+		}
+	}
+	return moves
+}
@@ -0,0 +1,281 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testRequest struct {
+	path    string
+	headers http.Header
+	body    map[string]interface{}
+}
+
+func TestSend(t *testing.T) {
+	ctx := context.Background()
+
+	var lock sync.Mutex
+	requests := []testRequest{}
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			contents, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			body := map[string]interface{}{}
+			require.NoError(t, json.Unmarshal(contents, &body))
+			requests = append(
+				requests,
+				testRequest{
+					path:    r.URL.Path,
+					headers: r.Header,
+					body:    body,
+				},
+			)
+
+			if r.URL.Path == "/fail" {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}),
+	)
+	defer server.Close()
+
+	clusterConfig := config.ClusterConfig{
+		Meta: config.ClusterMeta{
+			Name:        "test-cluster",
+			Environment: "test-env",
+			Region:      "test-region",
+		},
+		Spec: config.ClusterSpec{
+			Notifiers: []config.NotifierConfig{
+				{
+					Name: "failing",
+					Type: config.NotifierTypeWebhook,
+					URL:  server.URL + "/fail",
+				},
+				{
+					Name: "slack",
+					Type: config.NotifierTypeSlack,
+					URL:  server.URL + "/slack",
+				},
+				{
+					Name:   "datadog",
+					Type:   config.NotifierTypeDatadog,
+					URL:    server.URL + "/datadog",
+					APIKey: "test-key",
+					Tags:   []string{"team:data"},
+					DryRun: true,
+				},
+				{
+					Name: "webhook",
+					Type: config.NotifierTypeWebhook,
+					URL:  server.URL + "/webhook",
+					Headers: map[string]string{
+						"X-Test-Header": "test-value",
+					},
+				},
+			},
+		},
+	}
+
+	configEntries := []apply.NewConfigEntry{}
+	for i := 0; i < 200; i++ {
+		configEntries = append(
+			configEntries,
+			apply.NewConfigEntry{
+				Name:  "test.config.key",
+				Value: strings.Repeat("x", 20),
+			},
+		)
+	}
+	changes := &apply.NewChangesTracker{
+		Action:            apply.ActionEnumCreate,
+		Topic:             "test-topic",
+		NumPartitions:     3,
+		ReplicationFactor: 2,
+		ConfigEntries:     &configEntries,
+	}
+
+	// Topics without changes or errors aren't sent
+	assert.Nil(
+		t,
+		TopicApplyNotification(
+			clusterConfig,
+			"test-topic",
+			false,
+			(*apply.UpdateChangesTracker)(nil),
+			nil,
+		),
+	)
+	Send(ctx, clusterConfig, nil)
+
+	Send(
+		ctx,
+		clusterConfig,
+		TopicApplyNotification(
+			clusterConfig,
+			"test-topic",
+			false,
+			changes,
+			errors.New("test error"),
+		),
+	)
+
+	require.Equal(t, 4, len(requests))
+	assert.Equal(t, "/fail", requests[0].path)
+
+	// Slack messages are truncated
+	assert.Equal(t, "/slack", requests[1].path)
+	slackText := requests[1].body["text"].(string)
+	assert.True(
+		t,
+		strings.HasPrefix(
+			slackText,
+			"*Topicctl failed to apply topic test-topic in cluster test-cluster*\n"+
+				":warning: *ERROR",
+		),
+	)
+	assert.True(t, strings.HasSuffix(slackText, "\n..."))
+	assert.Less(t, len(slackText), slackMaxLength+100)
+
+	// Datadog events are truncated and tagged
+	assert.Equal(t, "/datadog", requests[2].path)
+	assert.Equal(t, "test-key", requests[2].headers.Get("DD-API-KEY"))
+	assert.Equal(t, "error", requests[2].body["alert_type"])
+	assert.Equal(
+		t,
+		[]interface{}{
+			"source:topicctl",
+			"cluster:test-cluster",
+			"environment:test-env",
+			"region:test-region",
+			"topicctl_topic:test-topic",
+			"team:data",
+		},
+		requests[2].body["tags"],
+	)
+	datadogText := requests[2].body["text"].(string)
+	assert.True(t, strings.HasPrefix(datadogText, "%%%\n# ERROR"))
+	assert.True(t, strings.HasSuffix(datadogText, "\n..."))
+	assert.Equal(t, datadogMaxLength+4, len(datadogText))
+
+	// Webhooks get the full notification
+	assert.Equal(t, "/webhook", requests[3].path)
+	assert.Equal(t, "test-value", requests[3].headers.Get("X-Test-Header"))
+	assert.Equal(t, "test error", requests[3].body["error"])
+	assert.Equal(t, "test-topic", requests[3].body["topic"])
+	assert.Equal(t, false, requests[3].body["dryRun"])
+	assert.Equal(
+		t,
+		float64(3),
+		requests[3].body["changes"].(map[string]interface{})["numPartitions"],
+	)
+	assert.Greater(t, len(requests[3].body["text"].(string)), slackMaxLength)
+
+	// Dry runs are only sent to the notifiers that ask for them
+	requests = []testRequest{}
+	Send(
+		ctx,
+		clusterConfig,
+		RebalanceNotification(
+			clusterConfig,
+			true,
+			map[string]error{
+				"topic1": nil,
+				"topic2": errors.New("timed out"),
+			},
+			map[string]apply.NewOrUpdatedChanges{
+				"topic1": &apply.UpdateChangesTracker{
+					ReplicaAssignments: &[]apply.ReplicaAssignmentChanges{
+						{
+							Partition:       0,
+							CurrentReplicas: []int{1, 2},
+							UpdatedReplicas: []int{3, 2},
+						},
+						{
+							Partition:       1,
+							CurrentReplicas: []int{2, 3},
+							UpdatedReplicas: []int{2, 3},
+						},
+					},
+				},
+				"topic2": nil,
+			},
+		),
+	)
+	require.Equal(t, 1, len(requests))
+	assert.Equal(t, "/datadog", requests[0].path)
+	assert.Equal(
+		t,
+		"Dry run: Topicctl rebalance of cluster test-cluster failed for 1 of 2 topics",
+		requests[0].body["title"],
+	)
+	assert.Equal(
+		t,
+		"%%%\n# ERROR - the following error occurred while processing this topic:\n"+
+			"topic2: timed out\n\n# The following changes were still made:\n"+
+			"| Topic  | Partitions Moved | Result  |\n"+
+			"| ------ | ---------------- | ------- |\n"+
+			"| topic1 | 1                | success |\n"+
+			"| topic2 | 0                | error   |\n%%%",
+		requests[0].body["text"],
+	)
+}
+
+func TestNumMoves(t *testing.T) {
+	assert.Equal(t, 0, numMoves(nil))
+	assert.Equal(t, 0, numMoves((*apply.UpdateChangesTracker)(nil)))
+	assert.Equal(t, 0, numMoves(&apply.NewChangesTracker{}))
+
+	// Partitions that weren't moved have nil updated replicas and aren't counted
+	assert.Equal(
+		t,
+		2,
+		numMoves(
+			&apply.UpdateChangesTracker{
+				ReplicaAssignments: &[]apply.ReplicaAssignmentChanges{
+					{
+						Partition:       0,
+						CurrentReplicas: []int{1, 2},
+						UpdatedReplicas: []int{3, 2},
+					},
+					{
+						Partition:       1,
+						CurrentReplicas: []int{2, 3},
+						UpdatedReplicas: nil,
+					},
+					{
+						Partition:       2,
+						CurrentReplicas: []int{3, 1},
+						UpdatedReplicas: []int{1, 3},
+					},
+					{
+						Partition:       3,
+						CurrentReplicas: []int{1, 3},
+						UpdatedReplicas: []int{2, 3},
+					},
+					{
+						Partition:       4,
+						CurrentReplicas: []int{2, 1},
+						UpdatedReplicas: nil,
+					},
+				},
+			},
+		),
+	)
+}
@@ -0,0 +1,235 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/config"
+)
+
+const (
+	// Datadog events can be at most 4000 characters and Slack messages at most 3000; the
+	// limits are a bit lower to leave room for titles.
+	datadogMaxLength = 3900
+	slackMaxLength   = 2900
+)
+
+// makeTable formats an ASCII table for the argument destination, since Slack's markdown
+// support doesn't include tables. If errorMessage is set, then the error is shown above the
+// table.
+func makeTable(
+	headers []string,
+	rows [][]string,
+	errorMessage string,
+	notifierType config.NotifierType,
+) string {
+	if len(headers) == 0 && len(rows) == 0 && errorMessage == "" {
+		return ""
+	}
+
+	widths := make([]int, len(headers))
+	for i, header := range headers {
+		widths[i] = len(header)
+	}
+	for _, row := range rows {
+		for i, value := range row {
+			if len(value) > widths[i] {
+				widths[i] = len(value)
+			}
+		}
+	}
+
+	makeRow := func(row []string) string {
+		var b strings.Builder
+		b.WriteString("|")
+		for i, value := range row {
+			b.WriteString(" ")
+			b.WriteString(value)
+			b.WriteString(strings.Repeat(" ", widths[i]-len(value)))
+			b.WriteString(" |")
+		}
+		b.WriteString("\n")
+		return b.String()
+	}
+
+	var table string
+	if len(rows) > 0 {
+		lines := make([]string, len(headers))
+		for i, width := range widths {
+			lines[i] = strings.Repeat("-", width)
+		}
+
+		var b strings.Builder
+		b.WriteString(makeRow(headers))
+		b.WriteString(makeRow(lines))
+		for _, row := range rows {
+			b.WriteString(makeRow(row))
+		}
+		table = b.String()
+
+		if notifierType == config.NotifierTypeSlack {
+			table = fmt.Sprintf("```\n%s```", table)
+		}
+	}
+
+	if errorMessage != "" {
+		errorHeader := "ERROR - the following error occurred while processing this topic:"
+		errorFooter := "No changes were made."
+		if len(rows) > 0 {
+			errorFooter = "The following changes were still made:"
+		}
+
+		switch notifierType {
+		case config.NotifierTypeDatadog:
+			errorHeader = fmt.Sprintf("# %s\n", errorHeader)
+			errorFooter = fmt.Sprintf("# %s\n", errorFooter)
+		case config.NotifierTypeSlack:
+			errorHeader = fmt.Sprintf(":warning: *%s*\n", errorHeader)
+			errorFooter = fmt.Sprintf(":warning: *%s*\n", errorFooter)
+		default:
+			errorHeader += "\n"
+			errorFooter += "\n"
+		}
+		table = fmt.Sprintf("%s%s\n\n%s%s", errorHeader, errorMessage, errorFooter, table)
+	}
+
+	if notifierType == config.NotifierTypeDatadog {
+		table = fmt.Sprintf("%%%%%%\n%s%%%%%%", table)
+	}
+
+	return table
+}
+
+// truncate shortens the argument string to at most maxLength characters, followed by an
+// ellipsis if anything was removed.
+func truncate(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	return string(runes[:maxLength]) + "\n..."
+}
+
+// changeRows returns the table headers and rows that describe the argument changes. No rows
+// are returned if nothing changed.
+func changeRows(changes apply.NewOrUpdatedChanges) ([]string, [][]string) {
+	switch typedChanges := changes.(type) {
+	case *apply.NewChangesTracker:
+		headers := []string{"Parameter", "Value"}
+		if typedChanges == nil {
+			return headers, nil
+		}
+
+		rows := [][]string{}
+		if typedChanges.NumPartitions != 0 {
+			rows = append(
+				rows,
+				[]string{"Partition Count", fmt.Sprintf("%d", typedChanges.NumPartitions)},
+			)
+		}
+		if typedChanges.ReplicationFactor != 0 {
+			rows = append(
+				rows,
+				[]string{
+					"Replication Factor",
+					fmt.Sprintf("%d", typedChanges.ReplicationFactor),
+				},
+			)
+		}
+		if typedChanges.ConfigEntries != nil {
+			for _, entry := range *typedChanges.ConfigEntries {
+				rows = append(rows, []string{entry.Name, entry.Value})
+			}
+		}
+		if len(rows) == 0 {
+			return headers, nil
+		}
+
+		return headers, append(
+			[][]string{
+				{"Topic Name", typedChanges.Topic},
+				{"Action (create/update)", string(typedChanges.Action)},
+			},
+			rows...,
+		)
+	case *apply.UpdateChangesTracker:
+		headers := []string{"Parameter", "Old Value", "New Value"}
+		if typedChanges == nil {
+			return headers, nil
+		}
+
+		rows := [][]string{}
+		if typedChanges.NumPartitions != nil &&
+			typedChanges.NumPartitions.Current != 0 &&
+			typedChanges.NumPartitions.Updated != 0 {
+			rows = append(
+				rows,
+				[]string{
+					"Partition Count",
+					fmt.Sprintf("%d", typedChanges.NumPartitions.Current),
+					fmt.Sprintf("%d", typedChanges.NumPartitions.Updated),
+				},
+			)
+		}
+		if typedChanges.ReplicationFactor != nil &&
+			typedChanges.ReplicationFactor.Current != 0 &&
+			typedChanges.ReplicationFactor.Updated != 0 {
+			rows = append(
+				rows,
+				[]string{
+					"Replication Factor",
+					fmt.Sprintf("%d", typedChanges.ReplicationFactor.Current),
+					fmt.Sprintf("%d", typedChanges.ReplicationFactor.Updated),
+				},
+			)
+		}
+		if typedChanges.NewConfigEntries != nil {
+			for _, entry := range *typedChanges.NewConfigEntries {
+				rows = append(rows, []string{entry.Name, "", entry.Value})
+			}
+		}
+		if typedChanges.UpdatedConfigEntries != nil {
+			for _, entry := range *typedChanges.UpdatedConfigEntries {
+				rows = append(rows, []string{entry.Name, entry.Current, entry.Updated})
+			}
+		}
+		for _, key := range typedChanges.MissingKeys {
+			rows = append(rows, []string{key, "", "REMOVED"})
+		}
+		if typedChanges.ReplicaAssignments != nil {
+			for _, assignment := range *typedChanges.ReplicaAssignments {
+				rows = append(
+					rows,
+					[]string{
+						fmt.Sprintf("Partition %d assignments", assignment.Partition),
+						formatReplicas(assignment.CurrentReplicas),
+						formatReplicas(assignment.UpdatedReplicas),
+					},
+				)
+			}
+		}
+		if len(rows) == 0 {
+			return headers, nil
+		}
+
+		return headers, append(
+			[][]string{{"Action (create/update)", string(typedChanges.Action), ""}},
+			rows...,
+		)
+	default:
+		return nil, nil
+	}
+}
+
+func formatReplicas(replicas []int) string {
+	if replicas == nil {
+		return ""
+	}
+
+	values := make([]string, len(replicas))
+	for i, replica := range replicas {
+		values[i] = fmt.Sprintf("%d", replica)
+	}
+	return fmt.Sprintf("[%s]", strings.Join(values, ", "))
+}
@@ -0,0 +1,207 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/segmentio/topicctl/pkg/apply"
+	"github.com/segmentio/topicctl/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeTable(t *testing.T) {
+	type testCase struct {
+		description  string
+		headers      []string
+		rows         [][]string
+		notifierType config.NotifierType
+		expected     string
+	}
+
+	testCases := []testCase{
+		{
+			description:  "empty datadog table",
+			notifierType: config.NotifierTypeDatadog,
+			expected:     "",
+		},
+		{
+			description:  "datadog table with just headers",
+			headers:      []string{"col1", "col2"},
+			notifierType: config.NotifierTypeDatadog,
+			expected:     "%%%\n%%%",
+		},
+		{
+			description:  "datadog table with headers and rows",
+			headers:      []string{"col1", "col2"},
+			rows:         [][]string{{"val1", "val2"}, {"val3", "val4"}},
+			notifierType: config.NotifierTypeDatadog,
+			expected:     "%%%\n| col1 | col2 |\n| ---- | ---- |\n| val1 | val2 |\n| val3 | val4 |\n%%%",
+		},
+		{
+			description:  "empty slack table",
+			notifierType: config.NotifierTypeSlack,
+			expected:     "",
+		},
+		{
+			description:  "slack table with just headers",
+			headers:      []string{"col1", "col2"},
+			notifierType: config.NotifierTypeSlack,
+			expected:     "",
+		},
+		{
+			description:  "slack table with headers and rows",
+			headers:      []string{"col1", "col2"},
+			rows:         [][]string{{"val1", "val2"}, {"val3", "val4"}},
+			notifierType: config.NotifierTypeSlack,
+			expected:     "```\n| col1 | col2 |\n| ---- | ---- |\n| val1 | val2 |\n| val3 | val4 |\n```",
+		},
+		{
+			description:  "webhook table with headers and rows",
+			headers:      []string{"col1", "col2"},
+			rows:         [][]string{{"value1", "val2"}},
+			notifierType: config.NotifierTypeWebhook,
+			expected:     "| col1   | col2 |\n| ------ | ---- |\n| value1 | val2 |\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(
+			t,
+			testCase.expected,
+			makeTable(testCase.headers, testCase.rows, "", testCase.notifierType),
+			testCase.description,
+		)
+	}
+}
+
+const newTopicTable = `
+| Parameter              | Value    |
+| ---------------------- | -------- |
+| Topic Name             | my_topic |
+| Action (create/update) | create   |
+| Partition Count        | 16       |
+| Replication Factor     | 3        |
+| cleanup.policy         | delete   |
+| max.message.bytes      | 5542880  |
+`
+
+const updateTopicTable = `
+| Parameter               | Old Value  | New Value     |
+| ----------------------- | ---------- | ------------- |
+| Action (create/update)  | update     |               |
+| cleanup.policy          |            | delete        |
+| message.timestamp.type  | CreateTime | LogAppendTime |
+| max.message.bytes       |            | REMOVED       |
+| Partition 0 assignments | [5, 4]     | [3, 4]        |
+| Partition 1 assignments | [2, 6]     | [5, 6]        |
+`
+
+func TestChangeTables(t *testing.T) {
+	newChanges := &apply.NewChangesTracker{
+		Action:            apply.ActionEnumCreate,
+		Topic:             "my_topic",
+		NumPartitions:     16,
+		ReplicationFactor: 3,
+		ConfigEntries: &[]apply.NewConfigEntry{
+			{
+				Name:  "cleanup.policy",
+				Value: "delete",
+			},
+			{
+				Name:  "max.message.bytes",
+				Value: "5542880",
+			},
+		},
+	}
+	headers, rows := changeRows(newChanges)
+	assert.Equal(
+		t,
+		"%%%"+newTopicTable+"%%%",
+		makeTable(headers, rows, "", config.NotifierTypeDatadog),
+	)
+	assert.Equal(
+		t,
+		"```"+newTopicTable+"```",
+		makeTable(headers, rows, "", config.NotifierTypeSlack),
+	)
+
+	// A new topic without any changes only shows the error
+	headers, rows = changeRows(
+		&apply.NewChangesTracker{
+			Action: apply.ActionEnumCreate,
+			Topic:  "my_topic",
+		},
+	)
+	assert.Equal(t, 0, len(rows))
+	assert.Equal(
+		t,
+		"%%%\n# ERROR - the following error occurred while processing this topic:\n"+
+			"this is an error\n\n# No changes were made.\n%%%",
+		makeTable(headers, rows, "this is an error", config.NotifierTypeDatadog),
+	)
+	assert.Equal(
+		t,
+		":warning: *ERROR - the following error occurred while processing this topic:*\n"+
+			"this is an error\n\n:warning: *No changes were made.*\n",
+		makeTable(headers, rows, "this is an error", config.NotifierTypeSlack),
+	)
+
+	updateChanges := &apply.UpdateChangesTracker{
+		Action: apply.ActionEnumUpdate,
+		Topic:  "topic-default",
+		NewConfigEntries: &[]apply.NewConfigEntry{
+			{
+				Name:  "cleanup.policy",
+				Value: "delete",
+			},
+		},
+		UpdatedConfigEntries: &[]apply.ConfigEntryChanges{
+			{
+				Name:    "message.timestamp.type",
+				Current: "CreateTime",
+				Updated: "LogAppendTime",
+			},
+		},
+		MissingKeys: []string{"max.message.bytes"},
+		ReplicaAssignments: &[]apply.ReplicaAssignmentChanges{
+			{
+				Partition:       0,
+				CurrentReplicas: []int{5, 4},
+				UpdatedReplicas: []int{3, 4},
+			},
+			{
+				Partition:       1,
+				CurrentReplicas: []int{2, 6},
+				UpdatedReplicas: []int{5, 6},
+			},
+		},
+	}
+	headers, rows = changeRows(updateChanges)
+	assert.Equal(
+		t,
+		"%%%\n# ERROR - the following error occurred while processing this topic:\n"+
+			"this is an error\n\n# The following changes were still made:"+
+			updateTopicTable+"%%%",
+		makeTable(headers, rows, "this is an error", config.NotifierTypeDatadog),
+	)
+	assert.Equal(
+		t,
+		":warning: *ERROR - the following error occurred while processing this topic:*\n"+
+			"this is an error\n\n:warning: *The following changes were still made:*\n```"+
+			updateTopicTable+"```",
+		makeTable(headers, rows, "this is an error", config.NotifierTypeSlack),
+	)
+
+	// Nil changes have no rows
+	_, rows = changeRows((*apply.UpdateChangesTracker)(nil))
+	assert.Equal(t, 0, len(rows))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 10))
+	assert.Equal(t, "0123456789", truncate("0123456789", 10))
+	assert.Equal(t, "01234\n...", truncate("0123456789", 5))
+
+	long := strings.Repeat("x", slackMaxLength+100)
+	assert.Equal(t, slackMaxLength+4, len(truncate(long, slackMaxLength)))
+}
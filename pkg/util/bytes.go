@@ -0,0 +1,21 @@
+package util
+
+import "fmt"
+
+// PrettyBytes returns a human-formatted size string given a number of bytes. Decimal
+// units are used to be consistent with the way that throttles are specified.
+func PrettyBytes(bytes int64) string {
+	size := float64(bytes)
+
+	if size < 1e3 {
+		return fmt.Sprintf("%dB", bytes)
+	} else if size < 1e6 {
+		return fmt.Sprintf("%0.1fKB", size/1e3)
+	} else if size < 1e9 {
+		return fmt.Sprintf("%0.1fMB", size/1e6)
+	} else if size < 1e12 {
+		return fmt.Sprintf("%0.1fGB", size/1e9)
+	} else {
+		return fmt.Sprintf("%0.1fTB", size/1e12)
+	}
+}
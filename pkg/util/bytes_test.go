@@ -0,0 +1,49 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrettyBytes(t *testing.T) {
+	type testCase struct {
+		bytes    int64
+		expected string
+	}
+
+	testCases := []testCase{
+		{
+			bytes:    0,
+			expected: "0B",
+		},
+		{
+			bytes:    512,
+			expected: "512B",
+		},
+		{
+			bytes:    2500,
+			expected: "2.5KB",
+		},
+		{
+			bytes:    125000000,
+			expected: "125.0MB",
+		},
+		{
+			bytes:    3200000000,
+			expected: "3.2GB",
+		},
+		{
+			bytes:    1500000000000,
+			expected: "1.5TB",
+		},
+	}
+
+	for _, testCaseObj := range testCases {
+		assert.Equal(
+			t,
+			testCaseObj.expected,
+			PrettyBytes(testCaseObj.bytes),
+		)
+	}
+}